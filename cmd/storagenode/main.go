@@ -14,6 +14,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
+	monkit "gopkg.in/spacemonkeygo/monkit.v2"
 
 	"storj.io/storj/internal/fpath"
 	"storj.io/storj/internal/memory"
@@ -34,6 +35,8 @@ type StorageNodeFlags struct {
 }
 
 var (
+	mon = monkit.Package()
+
 	rootCmd = &cobra.Command{
 		Use:   "storagenode",
 		Short: "StorageNode",
@@ -111,6 +114,17 @@ func databaseConfig(config storagenode.Config) storagenodedb.Config {
 		Info:    filepath.Join(config.Storage.Path, "piecestore.db"),
 		Info2:   filepath.Join(config.Storage.Path, "info.db"),
 		Pieces:  config.Storage.Path,
+
+		Driver:         config.Storage.DatabaseDriver,
+		DSN:            config.Storage.DatabaseURL,
+		DatabasePrefix: config.Storage.DatabasePrefix,
+		AllowDegraded:  config.Storage.AllowDegradedDatabases,
+
+		WALAutocheckpoint:  config.Storage.WALAutocheckpoint,
+		VersionHistoryTail: config.Storage.VersionHistoryTail,
+		UsedSerialsSalt:    []byte(config.Storage.UsedSerialsSalt),
+
+		BandwidthSummaryCacheInterval: config.Bandwidth.SummaryCacheInterval,
 	}
 }
 
@@ -139,6 +153,9 @@ func cmdRun(cmd *cobra.Command, args []string) (err error) {
 		err = errs.Combine(err, db.Close())
 	}()
 
+	// the node is the only one in the process, so the databases don't need a node specific name
+	mon.Chain("db_disk_usage", db)
+
 	revocationDB, err := revocation.NewDBFromCfg(runCfg.Server.Config)
 	if err != nil {
 		return errs.New("Error creating revocation database: %+v", err)
@@ -168,6 +185,16 @@ func cmdRun(cmd *cobra.Command, args []string) (err error) {
 		return errs.New("Error creating tables for master database on storagenode: %+v", err)
 	}
 
+	if err := db.CompactVersionHistory(ctx); err != nil {
+		log.Sugar().Warn("Failed to compact database version history: ", err)
+	}
+
+	if skew, err := db.DetectClockSkew(ctx); err != nil {
+		log.Sugar().Warn("Failed to detect clock skew: ", err)
+	} else if skew > 0 {
+		log.Warn("Database contains timestamps in the future, the system clock may be set back and cause premature expiration", zap.Duration("skew", skew))
+	}
+
 	if err := peer.Storage2.CacheService.Init(ctx); err != nil {
 		zap.S().Error("Failed to initialize CacheService: ", err)
 	}
@@ -443,6 +443,7 @@ func newNetwork(flags *Flags) (*Processes, error) {
 		process.Arguments = withCommon(process.Directory, Arguments{
 			"setup": {
 				"--identity-dir", process.Directory,
+				"--minimum-difficulty", "8",
 				"--console.address", net.JoinHostPort(host, port(storagenodePeer, i, publicHTTP)),
 				"--console.static-dir", filepath.Join(storjRoot, "web/storagenode/"),
 				"--server.address", process.Address,
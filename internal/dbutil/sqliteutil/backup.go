@@ -0,0 +1,58 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package sqliteutil
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/zeebo/errs"
+)
+
+// ErrBackup is error class for Backup
+var ErrBackup = errs.Class("backup:")
+
+// backupPagesPerStep is the number of pages copied between progress reports.
+const backupPagesPerStep = 100
+
+// Backup copies srcDB into destDB using the sqlite3 backup API.
+//
+// The copy is done in steps, progress is called after every step with the number of
+// copied pages and the total number of pages, so that long backups can report a percentage.
+// progress may be nil.
+func Backup(ctx context.Context, srcDB, destDB *sql.DB, progress func(done, total int)) error {
+	return ErrBackup.Wrap(withSqliteConns(ctx, srcDB, destDB, func(srcSqliteConn, destSqliteConn *sqlite3.SQLiteConn) error {
+		return backupConnsWithProgress(ctx, srcSqliteConn, destSqliteConn, progress)
+	}))
+}
+
+// backupConnsWithProgress copies sourceDB into destDB a few pages at a time and reports the progress.
+func backupConnsWithProgress(ctx context.Context, sourceDB *sqlite3.SQLiteConn, destDB *sqlite3.SQLiteConn, progress func(done, total int)) (err error) {
+	backup, err := destDB.Backup("main", sourceDB, "main")
+	if err != nil {
+		return err
+	}
+	defer func() { err = errs.Combine(err, backup.Finish()) }()
+
+	for {
+		isDone, err := backup.Step(backupPagesPerStep)
+		if err != nil {
+			return err
+		}
+
+		if progress != nil {
+			total := backup.PageCount()
+			progress(total-backup.Remaining(), total)
+		}
+
+		if isDone {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package sqliteutil_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/internal/dbutil/sqliteutil"
+	"storj.io/storj/internal/memory"
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+)
+
+func TestBackup(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	srcDB := newMemDB(t)
+	defer ctx.Check(srcDB.Close)
+	srcDB.SetMaxOpenConns(1)
+
+	destDB := newMemDB(t)
+	defer ctx.Check(destDB.Close)
+	destDB.SetMaxOpenConns(1)
+
+	// enough data for the backup to take several steps
+	execSQL(t, srcDB, "CREATE TABLE blobs(data BLOB);")
+	for i := 0; i < 1000; i++ {
+		execSQL(t, srcDB, "INSERT INTO blobs VALUES (?);", testrand.Bytes(memory.KiB))
+	}
+
+	var calls, lastDone, lastTotal int
+	err := sqliteutil.Backup(ctx, srcDB, destDB, func(done, total int) {
+		require.True(t, done >= lastDone, "progress went backwards")
+		require.True(t, done <= total)
+		calls++
+		lastDone, lastTotal = done, total
+	})
+	require.NoError(t, err)
+
+	require.True(t, calls > 1)
+	require.NotZero(t, lastTotal)
+	require.Equal(t, lastTotal, lastDone)
+
+	var count int
+	require.NoError(t, destDB.QueryRow("SELECT COUNT(*) FROM blobs").Scan(&count))
+	require.Equal(t, 1000, count)
+
+	t.Run("canceled", func(t *testing.T) {
+		canceledCtx, cancel := context.WithCancel(ctx)
+
+		otherDB := newMemDB(t)
+		defer ctx.Check(otherDB.Close)
+		otherDB.SetMaxOpenConns(1)
+
+		err := sqliteutil.Backup(canceledCtx, srcDB, otherDB, func(done, total int) {
+			cancel()
+		})
+		require.Error(t, err)
+	})
+}
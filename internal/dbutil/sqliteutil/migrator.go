@@ -34,49 +34,50 @@ func MigrateTablesToDatabase(ctx context.Context, srcDB, destDB *sql.DB, tablesT
 }
 
 func backupDBs(ctx context.Context, srcDB, destDB *sql.DB) error {
+	return ErrMigrateTables.Wrap(withSqliteConns(ctx, srcDB, destDB, func(srcSqliteConn, destSqliteConn *sqlite3.SQLiteConn) error {
+		return ErrMigrateTables.Wrap(backupConns(ctx, srcSqliteConn, destSqliteConn))
+	}))
+}
+
+// withSqliteConns calls fn with the raw Sqlite3 driver connections of srcDB and destDB.
+func withSqliteConns(ctx context.Context, srcDB, destDB *sql.DB, fn func(srcSqliteConn, destSqliteConn *sqlite3.SQLiteConn) error) (err error) {
 	// Retrieve the raw Sqlite3 driver connections for the src and dest so that
 	// we can execute the backup API for a corruption safe clone.
 	srcConn, err := srcDB.Conn(ctx)
 	if err != nil {
-		return ErrMigrateTables.Wrap(err)
+		return err
 	}
 
 	defer func() {
-		err = errs.Combine(err, ErrMigrateTables.Wrap(srcConn.Close()))
+		err = errs.Combine(err, srcConn.Close())
 	}()
 
 	destConn, err := destDB.Conn(ctx)
 	if err != nil {
-		return ErrMigrateTables.Wrap(err)
+		return err
 	}
 
 	defer func() {
-		err = errs.Combine(err, ErrMigrateTables.Wrap(destConn.Close()))
+		err = errs.Combine(err, destConn.Close())
 	}()
 
 	// The references to the driver connections are only guaranteed to be valid
 	// for the life of the callback so we must do the work within both callbacks.
-	err = srcConn.Raw(func(srcDriverConn interface{}) error {
+	return srcConn.Raw(func(srcDriverConn interface{}) error {
 		srcSqliteConn, ok := srcDriverConn.(*sqlite3.SQLiteConn)
 		if !ok {
-			return ErrMigrateTables.New("unable to get database driver")
+			return errs.New("unable to get database driver")
 		}
 
-		err := destConn.Raw(func(destDriverConn interface{}) error {
+		return destConn.Raw(func(destDriverConn interface{}) error {
 			destSqliteConn, ok := destDriverConn.(*sqlite3.SQLiteConn)
 			if !ok {
-				return ErrMigrateTables.New("unable to get database driver")
+				return errs.New("unable to get database driver")
 			}
 
-			return ErrMigrateTables.Wrap(backupConns(ctx, srcSqliteConn, destSqliteConn))
+			return fn(srcSqliteConn, destSqliteConn)
 		})
-		if err != nil {
-			return ErrMigrateTables.Wrap(err)
-		}
-
-		return nil
 	})
-	return ErrMigrateTables.Wrap(err)
 }
 
 // backupConns executes the sqlite3 backup process that safely ensures that no other
@@ -165,12 +165,8 @@ func (size *Size) Set(s string) error {
 	}
 
 	p := len(s)
-	for isLetter(s[p-1]) {
+	for p > 0 && isLetter(s[p-1]) {
 		p--
-
-		if p < 0 {
-			return errors.New("p out of bounds")
-		}
 	}
 
 	value, suffix := s[:p], s[p:]
@@ -173,6 +173,8 @@ func TestInvalidParse(t *testing.T) {
 		"z1.0Q",
 		"1.0zQ",
 		"1.0zQB",
+		"lots",
+		"KB",
 	}
 
 	for i, test := range tests {
@@ -30,7 +30,7 @@ Scenarios it doesn't handle properly.
 
 2. Undoing migrations.
 
-	Intentionally left out, because we do not gain that much from currently.
+	Only the steps with a Rollback action can be undone, see Migration.Rollback.
 
 3. Snapshotting the whole state.
 
@@ -51,6 +51,7 @@ type Step struct {
 	Description string
 	Version     int // Versions should start at 0
 	Action      Action
+	Rollback    Action // Undoes Action, the step can't be rolled back when nil
 }
 
 // Action is something that needs to be done
@@ -58,6 +59,18 @@ type Action interface {
 	Run(log *zap.Logger, db DB, tx *sql.Tx) error
 }
 
+// ErrIrreversible is returned when rolling back a step which can't be undone.
+var ErrIrreversible = errs.Class("irreversible migration step")
+
+// Irreversible is the Rollback of a step which destroys data, so that it can't be undone.
+// The string explains why.
+type Irreversible string
+
+// Run fails with ErrIrreversible.
+func (reason Irreversible) Run(log *zap.Logger, db DB, tx *sql.Tx) error {
+	return ErrIrreversible.New("%s", string(reason))
+}
+
 // TargetVersion returns migration with steps upto specified version
 func (migration *Migration) TargetVersion(version int) *Migration {
 	m := *migration
@@ -154,6 +167,125 @@ func (migration *Migration) Run(log *zap.Logger) error {
 	return nil
 }
 
+// Plan returns the steps which Run would apply, in the order they would be applied, without
+// changing the databases. The version table is only created within a transaction which is rolled back.
+func (migration *Migration) Plan(log *zap.Logger) ([]*Step, error) {
+	err := migration.ValidTableName()
+	if err != nil {
+		return nil, err
+	}
+
+	err = migration.ValidateSteps()
+	if err != nil {
+		return nil, err
+	}
+
+	latest := map[DB]int{}
+	var steps []*Step
+	for _, step := range migration.Steps {
+		if step.DB == nil {
+			return nil, Error.New("step.DB is nil for step %d", step.Version)
+		}
+
+		version, ok := latest[step.DB]
+		if !ok {
+			version, err = migration.peekLatestVersion(step.DB)
+			if err != nil {
+				return nil, Error.Wrap(err)
+			}
+			latest[step.DB] = version
+		}
+
+		if step.Version > version {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+// Rollback undoes the applied steps with a version above toVersion, latest first, and
+// records the version preceding each undone step as the version of its database.
+//
+// All the steps which need to be undone must have a Rollback, which isn't Irreversible,
+// otherwise nothing is undone. When a Rollback fails, the steps undone before stay undone.
+func (migration *Migration) Rollback(log *zap.Logger, toVersion int) error {
+	err := migration.ValidTableName()
+	if err != nil {
+		return err
+	}
+
+	err = migration.ValidateSteps()
+	if err != nil {
+		return err
+	}
+
+	// the versions are read before undoing anything, so that nothing is undone when one of the steps can't be
+	latest := map[DB]int{}
+	var steps []*Step
+	for i := len(migration.Steps) - 1; i >= 0; i-- {
+		step := migration.Steps[i]
+		if step.Version <= toVersion {
+			break
+		}
+		if step.DB == nil {
+			return Error.New("step.DB is nil for step %d", step.Version)
+		}
+
+		version, ok := latest[step.DB]
+		if !ok {
+			err = migration.ensureVersionTable(log, step.DB)
+			if err != nil {
+				return Error.New("creating version table failed: %v", err)
+			}
+
+			version, err = migration.getLatestVersion(log, step.DB)
+			if err != nil {
+				return Error.Wrap(err)
+			}
+			latest[step.DB] = version
+		}
+
+		if step.Version > version {
+			continue
+		}
+
+		if step.Rollback == nil {
+			return ErrIrreversible.New("step %d has no rollback: %s", step.Version, step.Description)
+		}
+		if reason, ok := step.Rollback.(Irreversible); ok {
+			return ErrIrreversible.New("step %d: %s: %s", step.Version, step.Description, string(reason))
+		}
+		steps = append(steps, step)
+	}
+
+	for _, step := range steps {
+		stepLog := log.Named(strconv.Itoa(step.Version))
+		stepLog.Info("Rolling back: " + step.Description)
+
+		tx, err := step.DB.Begin()
+		if err != nil {
+			return Error.Wrap(err)
+		}
+
+		err = step.Rollback.Run(stepLog, step.DB, tx)
+		if err != nil {
+			return Error.Wrap(errs.Combine(err, tx.Rollback()))
+		}
+
+		err = migration.removeVersion(tx, step.DB, step.Version)
+		if err != nil {
+			return Error.Wrap(errs.Combine(err, tx.Rollback()))
+		}
+
+		if err := tx.Commit(); err != nil {
+			return Error.Wrap(err)
+		}
+	}
+
+	log.Info("Database Version", zap.Int("version", toVersion))
+	return nil
+}
+
 // createVersionTable creates a new version table
 func (migration *Migration) ensureVersionTable(log *zap.Logger, db DB) error {
 	tx, err := db.Begin()
@@ -188,6 +320,29 @@ func (migration *Migration) getLatestVersion(log *zap.Logger, db DB) (int, error
 	return int(version.Int64), Error.Wrap(tx.Commit())
 }
 
+// peekLatestVersion finds the latest version like getLatestVersion, but without creating the version table.
+func (migration *Migration) peekLatestVersion(db DB) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return -1, Error.Wrap(err)
+	}
+
+	_, err = tx.Exec(rebind(db, `CREATE TABLE IF NOT EXISTS `+migration.Table+` (version int, commited_at text)`)) //nolint:misspell
+	if err != nil {
+		return -1, Error.Wrap(errs.Combine(err, tx.Rollback()))
+	}
+
+	var version sql.NullInt64
+	err = tx.QueryRow(rebind(db, `SELECT MAX(version) FROM `+migration.Table)).Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		return -1, Error.Wrap(errs.Combine(err, tx.Rollback()))
+	}
+	if !version.Valid {
+		return -1, Error.Wrap(tx.Rollback())
+	}
+	return int(version.Int64), Error.Wrap(tx.Rollback())
+}
+
 // addVersion adds information about a new migration
 func (migration *Migration) addVersion(tx *sql.Tx, db DB, version int) error {
 	_, err := tx.Exec(rebind(db, `
@@ -197,6 +352,16 @@ func (migration *Migration) addVersion(tx *sql.Tx, db DB, version int) error {
 	return err
 }
 
+// removeVersion removes the information about the migration of version and the ones after it,
+// recording the preceding version instead, as the history before it may have been compacted.
+func (migration *Migration) removeVersion(tx *sql.Tx, db DB, version int) error {
+	_, err := tx.Exec(rebind(db, `DELETE FROM `+migration.Table+` WHERE version >= ?`), version)
+	if err != nil || version <= 0 {
+		return err
+	}
+	return migration.addVersion(tx, db, version-1)
+}
+
 // SQL statements that are executed on the database
 type SQL []string
 
@@ -218,3 +383,39 @@ type Func func(log *zap.Logger, db DB, tx *sql.Tx) error
 func (fn Func) Run(log *zap.Logger, db DB, tx *sql.Tx) error {
 	return fn(log, db, tx)
 }
+
+// DefaultBatchSize is the chunk size used by Batch when Size is not set.
+const DefaultBatchSize = 1000
+
+// Batch is an operation which processes a lot of data in bounded chunks, logging the progress
+// after each chunk, so that large migrations neither do everything in one call nor look hung.
+type Batch struct {
+	// Size is the maximum number of items processed by a single call of Fn.
+	Size int
+	// Fn processes at most limit items and returns how many it processed.
+	// The batch is finished when Fn processes fewer than limit items.
+	Fn func(log *zap.Logger, db DB, tx *sql.Tx, limit int) (processed int, err error)
+}
+
+// Run runs Fn until all the items have been processed.
+func (batch Batch) Run(log *zap.Logger, db DB, tx *sql.Tx) error {
+	limit := batch.Size
+	if limit <= 0 {
+		limit = DefaultBatchSize
+	}
+
+	total := 0
+	for chunk := 1; ; chunk++ {
+		processed, err := batch.Fn(log, db, tx, limit)
+		if err != nil {
+			return err
+		}
+		total += processed
+
+		log.Debug("batch progress", zap.Int("chunk", chunk), zap.Int("processed", total))
+		if processed < limit {
+			log.Info("batch finished", zap.Int("processed", total))
+			return nil
+		}
+	}
+}
@@ -293,6 +293,155 @@ func TestInvalidStepsOrder(t *testing.T) {
 	require.Error(t, err, "migrate: steps have incorrect order")
 }
 
+func TestBatchMigrationSqlite(t *testing.T) {
+	for _, tt := range []struct {
+		items  int
+		chunks []int
+	}{
+		{items: 25, chunks: []int{10, 10, 5}},
+		{items: 20, chunks: []int{10, 10, 0}},
+		{items: 0, chunks: []int{0}},
+	} {
+		tt := tt
+		t.Run(fmt.Sprint(tt.items), func(t *testing.T) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			require.NoError(t, err)
+			defer func() { assert.NoError(t, db.Close()) }()
+
+			var chunks []int
+			m := migrate.Migration{
+				Table: "versions",
+				Steps: []*migrate.Step{
+					{
+						DB:          db,
+						Description: "Initialize Table",
+						Version:     1,
+						Action: migrate.Func(func(log *zap.Logger, db migrate.DB, tx *sql.Tx) error {
+							if _, err := tx.Exec(`CREATE TABLE items (id int)`); err != nil {
+								return err
+							}
+							for i := 0; i < tt.items; i++ {
+								if _, err := tx.Exec(`INSERT INTO items (id) VALUES (?)`, i); err != nil {
+									return err
+								}
+							}
+							return nil
+						}),
+					},
+					{
+						DB:          db,
+						Description: "Delete items in chunks",
+						Version:     2,
+						Action: migrate.Batch{
+							Size: 10,
+							Fn: func(log *zap.Logger, db migrate.DB, tx *sql.Tx, limit int) (int, error) {
+								result, err := tx.Exec(`DELETE FROM items WHERE id IN (SELECT id FROM items LIMIT ?)`, limit)
+								if err != nil {
+									return 0, err
+								}
+								deleted, err := result.RowsAffected()
+								chunks = append(chunks, int(deleted))
+								return int(deleted), err
+							},
+						},
+					},
+				},
+			}
+
+			require.NoError(t, m.Run(zap.NewNop()))
+			require.Equal(t, tt.chunks, chunks)
+
+			var count int
+			require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM items`).Scan(&count))
+			require.Zero(t, count)
+
+			var version int
+			require.NoError(t, db.QueryRow(`SELECT MAX(version) FROM versions`).Scan(&version))
+			require.Equal(t, 2, version)
+		})
+	}
+}
+
+func TestRollbackSqlite(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, db.Close()) }()
+
+	m := migrate.Migration{
+		Table: "versions",
+		Steps: []*migrate.Step{
+			{
+				DB:          db,
+				Description: "Initialize Table",
+				Version:     1,
+				Action:      migrate.SQL{`CREATE TABLE users (id int)`},
+				Rollback:    migrate.Irreversible("can't be undone"),
+			},
+			{
+				DB:          db,
+				Description: "Add names",
+				Version:     2,
+				Action:      migrate.SQL{`CREATE TABLE names (id int)`},
+				Rollback:    migrate.SQL{`DROP TABLE names`},
+			},
+			{
+				DB:          db,
+				Description: "Add emails",
+				Version:     3,
+				Action:      migrate.SQL{`CREATE TABLE emails (id int)`},
+				Rollback:    migrate.SQL{`DROP TABLE emails`},
+			},
+			{
+				DB:          db,
+				Description: "Add teams",
+				Version:     4,
+				Action:      migrate.SQL{`CREATE TABLE teams (id int)`},
+				Rollback:    migrate.SQL{`DROP TABLE teams`},
+			},
+		},
+	}
+	require.NoError(t, m.Run(zap.NewNop()))
+
+	tableExists := func(name string) bool {
+		var count int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count))
+		return count > 0
+	}
+	latestVersion := func() int {
+		var version int
+		require.NoError(t, db.QueryRow(`SELECT MAX(version) FROM versions`).Scan(&version))
+		return version
+	}
+
+	// nothing is undone when an irreversible step is in the way
+	err = m.Rollback(zap.NewNop(), 0)
+	require.True(t, migrate.ErrIrreversible.Has(err))
+	require.True(t, tableExists("teams"))
+	require.Equal(t, 4, latestVersion())
+
+	// all the steps after version 1 are undone
+	require.NoError(t, m.Rollback(zap.NewNop(), 1))
+	require.False(t, tableExists("teams"))
+	require.False(t, tableExists("names"))
+	require.False(t, tableExists("emails"))
+	require.True(t, tableExists("users"))
+	require.Equal(t, 1, latestVersion())
+
+	// the undone steps are applied again
+	require.NoError(t, m.Run(zap.NewNop()))
+	require.True(t, tableExists("teams"))
+	require.True(t, tableExists("names"))
+	require.True(t, tableExists("emails"))
+	require.Equal(t, 4, latestVersion())
+
+	// only the steps after the version are undone
+	require.NoError(t, m.Rollback(zap.NewNop(), 2))
+	require.False(t, tableExists("teams"))
+	require.False(t, tableExists("emails"))
+	require.True(t, tableExists("names"))
+	require.Equal(t, 2, latestVersion())
+}
+
 func dropTables(db *sql.DB, names ...string) error {
 	var errlist errs.Group
 	for _, name := range names {
@@ -302,3 +451,58 @@ func dropTables(db *sql.DB, names ...string) error {
 
 	return errlist.Err()
 }
+
+func TestPlanSqlite(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, db.Close()) }()
+
+	m := migrate.Migration{
+		Table: "versions",
+		Steps: []*migrate.Step{
+			{
+				DB:          db,
+				Description: "Initialize Table",
+				Version:     1,
+				Action:      migrate.SQL{`CREATE TABLE users (id int)`},
+			},
+			{
+				DB:          db,
+				Description: "Add names",
+				Version:     2,
+				Action:      migrate.SQL{`CREATE TABLE names (id int)`},
+			},
+			{
+				DB:          db,
+				Description: "Add emails",
+				Version:     3,
+				Action:      migrate.SQL{`CREATE TABLE emails (id int)`},
+			},
+		},
+	}
+
+	versionTableExists := func() bool {
+		var count int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'versions'`).Scan(&count))
+		return count > 0
+	}
+
+	// all the steps are pending and the version table isn't created
+	steps, err := m.Plan(zap.NewNop())
+	require.NoError(t, err)
+	require.Equal(t, m.Steps, steps)
+	require.False(t, versionTableExists())
+
+	require.NoError(t, m.TargetVersion(1).Run(zap.NewNop()))
+
+	// the steps after version 1 are pending
+	steps, err = m.Plan(zap.NewNop())
+	require.NoError(t, err)
+	require.Equal(t, m.Steps[1:], steps)
+
+	require.NoError(t, m.Run(zap.NewNop()))
+
+	steps, err = m.Plan(zap.NewNop())
+	require.NoError(t, err)
+	require.Empty(t, steps)
+}
@@ -167,6 +167,11 @@ func (planet *Planet) newSatellites(count int) ([]*SatelliteSystem, error) {
 			},
 			DBCleanup: dbcleanup.Config{
 				SerialsInterval: defaultInterval,
+
+				GracefulExitQueueInterval: defaultInterval,
+				GracefulExitQueueTTL:      30 * 24 * time.Hour,
+
+				APIKeysInterval: defaultInterval,
 			},
 			Tally: tally.Config{
 				Interval: defaultInterval,
@@ -22,16 +22,19 @@ import (
 	"storj.io/storj/pkg/server"
 	"storj.io/storj/pkg/storj"
 	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/analyze"
 	"storj.io/storj/storagenode/bandwidth"
 	"storj.io/storj/storagenode/collector"
 	"storj.io/storj/storagenode/console/consoleserver"
 	"storj.io/storj/storagenode/contact"
+	"storj.io/storj/storagenode/dbhealth"
 	"storj.io/storj/storagenode/monitor"
 	"storj.io/storj/storagenode/nodestats"
 	"storj.io/storj/storagenode/orders"
 	"storj.io/storj/storagenode/piecestore"
 	"storj.io/storj/storagenode/retain"
 	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/vacuum"
 )
 
 // newStorageNodes initializes storage nodes
@@ -87,6 +90,16 @@ func (planet *Planet) newStorageNodes(count int, whitelistedSatellites storj.Nod
 				WhitelistedSatellites:  whitelistedSatellites,
 			},
 			Collector: collector.Config{
+				Interval:            defaultInterval,
+				ExpirationBatchSize: 1000,
+			},
+			Analyze: analyze.Config{
+				Interval: defaultInterval,
+			},
+			DBHealth: dbhealth.Config{
+				Interval: defaultInterval,
+			},
+			Vacuum: vacuum.Config{
 				Interval: defaultInterval,
 			},
 			Nodestats: nodestats.Config{
@@ -113,6 +126,11 @@ func (planet *Planet) newStorageNodes(count int, whitelistedSatellites storj.Nod
 					MinimumBandwidth: 100 * memory.MB,
 					MinimumDiskSpace: 100 * memory.MB,
 				},
+				UsedSerials: piecestore.UsedSerialsCacheConfig{
+					ExpectedSerials:   1000,
+					FalsePositiveRate: 0.01,
+					MaxMemory:         memory.MB,
+				},
 			},
 			Retain: retain.Config{
 				Status:      retain.Enabled,
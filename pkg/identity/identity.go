@@ -258,6 +258,18 @@ func NodeIDFromKey(k crypto.PublicKey, version storj.IDVersion) (storj.NodeID, e
 	return storj.NewVersionedID(idBytes, version), nil
 }
 
+// VerifyDifficulty returns an error if the difficulty of the node ID is below minimum.
+func VerifyDifficulty(id storj.NodeID, minimum uint16) error {
+	difficulty, err := id.Difficulty()
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if difficulty < minimum {
+		return Error.New("difficulty of node ID %s is %d, the minimum is %d", id, difficulty, minimum)
+	}
+	return nil
+}
+
 // NewFullIdentity creates a new ID for nodes with difficulty and concurrency params.
 func NewFullIdentity(ctx context.Context, opts NewCAOptions) (*FullIdentity, error) {
 	ca, err := NewCA(ctx, opts)
@@ -219,6 +219,23 @@ func TestVersionedNodeIDFromKey(t *testing.T) {
 	}
 }
 
+func TestVerifyDifficulty(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	_, id, err := identity.GenerateKey(ctx, 4, storj.LatestIDVersion())
+	require.NoError(t, err)
+
+	difficulty, err := id.Difficulty()
+	require.NoError(t, err)
+
+	assert.NoError(t, identity.VerifyDifficulty(id, difficulty))
+
+	err = identity.VerifyDifficulty(id, difficulty+1)
+	require.Error(t, err)
+	assert.True(t, identity.Error.Has(err))
+}
+
 func TestVerifyPeer(t *testing.T) {
 	ca, err := identity.NewCA(context.Background(), identity.NewCAOptions{
 		Difficulty:  12,
@@ -16,26 +16,60 @@ import (
 type APIKeys interface {
 	// GetPagedByProjectID is a method for querying API keys from the database by projectID and cursor
 	GetPagedByProjectID(ctx context.Context, projectID uuid.UUID, cursor APIKeyCursor) (akp *APIKeyPage, err error)
+	// StreamByProjectID calls fn for every API key of the project ordered by creation date, without the secrets
+	StreamByProjectID(ctx context.Context, projectID uuid.UUID, fn func(APIKeyInfo) error) error
 	// Get retrieves APIKeyInfo with given ID
 	Get(ctx context.Context, id uuid.UUID) (*APIKeyInfo, error)
 	// GetByHead retrieves APIKeyInfo for given key head
 	GetByHead(ctx context.Context, head []byte) (*APIKeyInfo, error)
+	// GetByHeadPrefix retrieves the APIKeyInfos whose head starts with prefix, without the secrets
+	GetByHeadPrefix(ctx context.Context, prefix []byte) ([]APIKeyInfo, error)
 	// Create creates and stores new APIKeyInfo
 	Create(ctx context.Context, head []byte, info APIKeyInfo) (*APIKeyInfo, error)
-	// Update updates APIKeyInfo in store
+	// Update updates the name and the expiration of APIKeyInfo in store, the fields which aren't set are kept
 	Update(ctx context.Context, key APIKeyInfo) error
+	// UpdateLastUsed records that the api key was used at the given time, unless it was already recorded
+	// as used less than APIKeyLastUsedResolution before, which keeps the writes for busy keys low
+	UpdateLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+	// RotateSecret replaces the head and secret of the api key, keeping its id, name, project and creation date
+	RotateSecret(ctx context.Context, id uuid.UUID, newHead []byte, newSecret []byte) (*APIKeyInfo, error)
 	// Delete deletes APIKeyInfo from store
 	Delete(ctx context.Context, id uuid.UUID) error
+	// DeleteAllByProjectID deletes all api keys of the project and returns the number of deleted keys
+	DeleteAllByProjectID(ctx context.Context, projectID uuid.UUID) (int64, error)
+	// DeleteExpired deletes the api keys which expired at or before asOf and returns the number of deleted keys
+	DeleteExpired(ctx context.Context, asOf time.Time) (int64, error)
+	// ProjectStats returns the number of api keys for the project and the creation time of the most recent one
+	ProjectStats(ctx context.Context, projectID uuid.UUID) (total int, lastCreated *time.Time, err error)
 }
 
 // APIKeyInfo describing api key model in the database
 type APIKeyInfo struct {
-	ID        uuid.UUID `json:"id"`
-	ProjectID uuid.UUID `json:"projectId"`
-	PartnerID uuid.UUID `json:"partnerId"`
-	Name      string    `json:"name"`
-	Secret    []byte    `json:"-"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID         uuid.UUID  `json:"id"`
+	ProjectID  uuid.UUID  `json:"projectId"`
+	PartnerID  uuid.UUID  `json:"partnerId"`
+	Name       string     `json:"name"`
+	Secret     []byte     `json:"-"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	// Caveats is the serialized macaroon.Caveat which restricts every use of the key, e.g. to reads
+	// or to a bucket prefix, in addition to the caveats of the key itself. It's set on creation only, see
+	// Service.CreateRestrictedAPIKey.
+	Caveats []byte `json:"-"`
+}
+
+// APIKeyLastUsedResolution is how precisely APIKeyInfo.LastUsedAt is tracked.
+const APIKeyLastUsedResolution = time.Hour
+
+// NeedsLastUsedUpdate returns whether using the api key at the given time should be recorded, see APIKeys.UpdateLastUsed.
+func (info *APIKeyInfo) NeedsLastUsedUpdate(at time.Time) bool {
+	return info.LastUsedAt == nil || at.Sub(*info.LastUsedAt) >= APIKeyLastUsedResolution
+}
+
+// IsExpired returns whether the api key expired at or before now, keys without ExpiresAt never expire.
+func (info *APIKeyInfo) IsExpired(now time.Time) bool {
+	return info.ExpiresAt != nil && !now.Before(*info.ExpiresAt)
 }
 
 // APIKeyCursor holds info for api keys cursor pagination
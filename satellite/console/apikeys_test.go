@@ -6,10 +6,16 @@ package console_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/gogo/protobuf/proto"
+	"github.com/skyrings/skyring-common/tools/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
 
 	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
 	"storj.io/storj/pkg/macaroon"
 	"storj.io/storj/satellite"
 	"storj.io/storj/satellite/console"
@@ -147,6 +153,23 @@ func TestApiKeysRepository(t *testing.T) {
 			assert.NoError(t, err)
 		})
 
+		t.Run("GetPagedByProjectID with page out of range", func(t *testing.T) {
+			cursor := console.APIKeyCursor{
+				Page:   7,
+				Limit:  2,
+				Search: "",
+			}
+			page, err := apikeys.GetPagedByProjectID(ctx, project.ID, cursor)
+			assert.NoError(t, err)
+			if assert.NotNil(t, page) {
+				// 9 keys are left, so the last page contains a single key
+				assert.Equal(t, uint(5), page.PageCount)
+				assert.Equal(t, uint(5), page.CurrentPage)
+				assert.Equal(t, uint64(8), page.Offset)
+				assert.Equal(t, 1, len(page.APIKeys))
+			}
+		})
+
 		t.Run("GetPageByProjectID with 0 page error", func(t *testing.T) {
 			cursor := console.APIKeyCursor{
 				Page:   0,
@@ -159,5 +182,435 @@ func TestApiKeysRepository(t *testing.T) {
 			assert.Error(t, err)
 		})
 
+		t.Run("ProjectStats success", func(t *testing.T) {
+			total, lastCreated, err := apikeys.ProjectStats(ctx, project.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, 9, total)
+
+			page, err := apikeys.GetPagedByProjectID(ctx, project.ID, console.APIKeyCursor{
+				Page:  1,
+				Limit: 10,
+				Order: console.CreationDate,
+			})
+			assert.NoError(t, err)
+
+			var latest time.Time
+			for _, key := range page.APIKeys {
+				if key.CreatedAt.After(latest) {
+					latest = key.CreatedAt
+				}
+			}
+			if assert.NotNil(t, lastCreated) {
+				assert.True(t, latest.Equal(*lastCreated))
+			}
+		})
+
+		t.Run("ProjectStats without keys", func(t *testing.T) {
+			emptyProject, err := projects.Insert(ctx, &console.Project{
+				Name: "EmptyProject",
+			})
+			assert.NoError(t, err)
+
+			total, lastCreated, err := apikeys.ProjectStats(ctx, emptyProject.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, total)
+			assert.Nil(t, lastCreated)
+		})
+
+		t.Run("RotateSecret success", func(t *testing.T) {
+			oldKey, err := macaroon.NewAPIKey([]byte("oldSecret"))
+			assert.NoError(t, err)
+
+			key, err := apikeys.Create(ctx, oldKey.Head(), console.APIKeyInfo{
+				Name:      "rotated key",
+				ProjectID: project.ID,
+				Secret:    []byte("oldSecret"),
+			})
+			assert.NoError(t, err)
+
+			newKey, err := macaroon.NewAPIKey([]byte("newSecret"))
+			assert.NoError(t, err)
+
+			rotated, err := apikeys.RotateSecret(ctx, key.ID, newKey.Head(), []byte("newSecret"))
+			assert.NoError(t, err)
+			if assert.NotNil(t, rotated) {
+				assert.Equal(t, key.ID, rotated.ID)
+				assert.Equal(t, key.Name, rotated.Name)
+				assert.Equal(t, key.ProjectID, rotated.ProjectID)
+				assert.True(t, key.CreatedAt.Equal(rotated.CreatedAt))
+				assert.Equal(t, []byte("newSecret"), rotated.Secret)
+			}
+
+			byHead, err := apikeys.GetByHead(ctx, newKey.Head())
+			assert.NoError(t, err)
+			if assert.NotNil(t, byHead) {
+				assert.Equal(t, key.ID, byHead.ID)
+			}
+
+			byHead, err = apikeys.GetByHead(ctx, oldKey.Head())
+			assert.Error(t, err)
+			assert.Nil(t, byHead)
+		})
+
+		t.Run("RotateSecret unknown key", func(t *testing.T) {
+			id, err := uuid.New()
+			assert.NoError(t, err)
+
+			rotated, err := apikeys.RotateSecret(ctx, *id, []byte("head"), []byte("secret"))
+			assert.Error(t, err)
+			assert.Nil(t, rotated)
+		})
+	})
+}
+
+func TestApiKeysStreamByProjectID(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		projects := db.Console().Projects()
+		apikeys := db.Console().APIKeys()
+
+		project, err := projects.Insert(ctx, &console.Project{Name: "ProjectName"})
+		require.NoError(t, err)
+		otherProject, err := projects.Insert(ctx, &console.Project{Name: "OtherProject"})
+		require.NoError(t, err)
+
+		createKey := func(projectID uuid.UUID, name string) *console.APIKeyInfo {
+			key, err := macaroon.NewAPIKey([]byte("testSecret"))
+			require.NoError(t, err)
+
+			info, err := apikeys.Create(ctx, key.Head(), console.APIKeyInfo{
+				Name:      name,
+				ProjectID: projectID,
+				Secret:    []byte("testSecret"),
+			})
+			require.NoError(t, err)
+			return info
+		}
+
+		const keyCount = 150
+		expected := map[uuid.UUID]string{}
+		for i := 0; i < keyCount; i++ {
+			info := createKey(project.ID, fmt.Sprintf("key %d", i))
+			expected[info.ID] = info.Name
+		}
+		createKey(otherProject.ID, "other key")
+
+		streamed := map[uuid.UUID]string{}
+		var lastCreated time.Time
+		err = apikeys.StreamByProjectID(ctx, project.ID, func(info console.APIKeyInfo) error {
+			require.Equal(t, project.ID, info.ProjectID)
+			require.Nil(t, info.Secret)
+			require.False(t, info.CreatedAt.Before(lastCreated))
+			lastCreated = info.CreatedAt
+
+			streamed[info.ID] = info.Name
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, streamed)
+
+		// the callback can use the database
+		err = apikeys.StreamByProjectID(ctx, project.ID, func(info console.APIKeyInfo) error {
+			key, err := apikeys.Get(ctx, info.ID)
+			if err != nil {
+				return err
+			}
+			require.Equal(t, info.Name, key.Name)
+			return nil
+		})
+		require.NoError(t, err)
+
+		// an error of the callback stops the iteration
+		calls := 0
+		stop := errs.New("stop")
+		err = apikeys.StreamByProjectID(ctx, project.ID, func(info console.APIKeyInfo) error {
+			calls++
+			return stop
+		})
+		require.Equal(t, stop, err)
+		require.Equal(t, 1, calls)
+
+		// projects without keys don't call the callback
+		err = apikeys.StreamByProjectID(ctx, testrand.UUID(), func(info console.APIKeyInfo) error {
+			return errs.New("unexpected key")
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestApiKeysGetByHeadPrefix(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		projects := db.Console().Projects()
+		apikeys := db.Console().APIKeys()
+
+		project, err := projects.Insert(ctx, &console.Project{Name: "ProjectName"})
+		require.NoError(t, err)
+
+		heads := map[string][]byte{
+			"first":  {0x12, 0x34, 0x00},
+			"second": {0x12, 0x34, 0xff, 0x01},
+			"third":  {0x12, 0x35},
+			"fourth": {0xff, 0xff, 0x01},
+		}
+		for name, head := range heads {
+			_, err := apikeys.Create(ctx, head, console.APIKeyInfo{
+				Name:      name,
+				ProjectID: project.ID,
+				Secret:    []byte("testSecret"),
+			})
+			require.NoError(t, err)
+		}
+
+		names := func(prefix []byte) []string {
+			infos, err := apikeys.GetByHeadPrefix(ctx, prefix)
+			require.NoError(t, err)
+
+			var names []string
+			for _, info := range infos {
+				require.Equal(t, project.ID, info.ProjectID)
+				require.Nil(t, info.Secret)
+				names = append(names, info.Name)
+			}
+			return names
+		}
+
+		assert.Equal(t, []string{"first", "second"}, names([]byte{0x12, 0x34}))
+		assert.Equal(t, []string{"first", "second", "third"}, names([]byte{0x12}))
+		assert.Equal(t, []string{"second"}, names([]byte{0x12, 0x34, 0xff}))
+		assert.Equal(t, []string{"fourth"}, names([]byte{0xff, 0xff}))
+		assert.Empty(t, names([]byte{0x13}))
+
+		// an empty prefix would match every key
+		_, err = apikeys.GetByHeadPrefix(ctx, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestApiKeysExpiration(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		projects := db.Console().Projects()
+		apikeys := db.Console().APIKeys()
+
+		project, err := projects.Insert(ctx, &console.Project{Name: "ProjectName"})
+		require.NoError(t, err)
+
+		now := time.Now().UTC().Truncate(time.Second)
+		expired := now.Add(-time.Hour)
+		expiring := now.Add(time.Hour)
+
+		create := func(name string, expiresAt *time.Time) *console.APIKeyInfo {
+			key, err := macaroon.NewAPIKey([]byte("testSecret"))
+			require.NoError(t, err)
+
+			info, err := apikeys.Create(ctx, key.Head(), console.APIKeyInfo{
+				Name:      name,
+				ProjectID: project.ID,
+				Secret:    []byte("testSecret"),
+				ExpiresAt: expiresAt,
+			})
+			require.NoError(t, err)
+			return info
+		}
+
+		expiredKey := create("expired", &expired)
+		expiringKey := create("expiring", &expiring)
+		permanentKey := create("permanent", nil)
+
+		require.NotNil(t, expiredKey.ExpiresAt)
+		assert.True(t, expired.Equal(*expiredKey.ExpiresAt))
+		assert.True(t, expiredKey.IsExpired(now))
+		assert.False(t, expiringKey.IsExpired(now))
+		assert.True(t, expiringKey.IsExpired(expiring))
+		assert.Nil(t, permanentKey.ExpiresAt)
+		assert.False(t, permanentKey.IsExpired(now.Add(100*365*24*time.Hour)))
+
+		// the expiration can be extended
+		expiredKey.ExpiresAt = &expiring
+		require.NoError(t, apikeys.Update(ctx, *expiredKey))
+		updated, err := apikeys.Get(ctx, expiredKey.ID)
+		require.NoError(t, err)
+		require.NotNil(t, updated.ExpiresAt)
+		assert.True(t, expiring.Equal(*updated.ExpiresAt))
+
+		updated.ExpiresAt = &expired
+		require.NoError(t, apikeys.Update(ctx, *updated))
+
+		// the fields which aren't set are kept
+		require.NoError(t, apikeys.Update(ctx, console.APIKeyInfo{ID: expiringKey.ID, Name: "renamed"}))
+		renamed, err := apikeys.Get(ctx, expiringKey.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "renamed", renamed.Name)
+		require.NotNil(t, renamed.ExpiresAt)
+		assert.True(t, expiring.Equal(*renamed.ExpiresAt))
+
+		deleted, err := apikeys.DeleteExpired(ctx, now)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, deleted)
+
+		_, err = apikeys.Get(ctx, expiredKey.ID)
+		assert.Error(t, err)
+
+		var names []string
+		err = apikeys.StreamByProjectID(ctx, project.ID, func(info console.APIKeyInfo) error {
+			names = append(names, info.Name)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"renamed", "permanent"}, names)
+
+		// a key expiring exactly at asOf is expired
+		deleted, err = apikeys.DeleteExpired(ctx, expiring)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, deleted)
+	})
+}
+
+func TestApiKeysUpdateLastUsed(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		projects := db.Console().Projects()
+		apikeys := db.Console().APIKeys()
+
+		project, err := projects.Insert(ctx, &console.Project{Name: "ProjectName"})
+		require.NoError(t, err)
+
+		key, err := macaroon.NewAPIKey([]byte("testSecret"))
+		require.NoError(t, err)
+
+		created, err := apikeys.Create(ctx, key.Head(), console.APIKeyInfo{
+			Name:      "key",
+			ProjectID: project.ID,
+			Secret:    []byte("testSecret"),
+		})
+		require.NoError(t, err)
+		require.Nil(t, created.LastUsedAt)
+
+		lastUsed := func() *time.Time {
+			page, err := apikeys.GetPagedByProjectID(ctx, project.ID, console.APIKeyCursor{Page: 1, Limit: 10})
+			require.NoError(t, err)
+			require.Len(t, page.APIKeys, 1)
+			return page.APIKeys[0].LastUsedAt
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		assert.True(t, created.NeedsLastUsedUpdate(now))
+
+		require.NoError(t, apikeys.UpdateLastUsed(ctx, created.ID, now))
+		used := lastUsed()
+		require.NotNil(t, used)
+		assert.True(t, now.Equal(*used))
+
+		// the uses within the resolution aren't recorded
+		later := now.Add(console.APIKeyLastUsedResolution - time.Minute)
+		require.NoError(t, apikeys.UpdateLastUsed(ctx, created.ID, later))
+		assert.True(t, now.Equal(*lastUsed()))
+
+		info, err := apikeys.GetByHead(ctx, key.Head())
+		require.NoError(t, err)
+		require.NotNil(t, info.LastUsedAt)
+		assert.False(t, info.NeedsLastUsedUpdate(later))
+
+		later = now.Add(console.APIKeyLastUsedResolution)
+		assert.True(t, info.NeedsLastUsedUpdate(later))
+		require.NoError(t, apikeys.UpdateLastUsed(ctx, created.ID, later))
+		assert.True(t, later.Equal(*lastUsed()))
+	})
+}
+
+func TestApiKeysCaveats(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		projects := db.Console().Projects()
+		apikeys := db.Console().APIKeys()
+
+		project, err := projects.Insert(ctx, &console.Project{Name: "ProjectName"})
+		require.NoError(t, err)
+
+		caveats, err := proto.Marshal(&macaroon.Caveat{DisallowWrites: true})
+		require.NoError(t, err)
+
+		restricted, err := macaroon.NewAPIKey([]byte("testSecret"))
+		require.NoError(t, err)
+		_, err = apikeys.Create(ctx, restricted.Head(), console.APIKeyInfo{
+			Name:      "restricted",
+			ProjectID: project.ID,
+			Secret:    []byte("testSecret"),
+			Caveats:   caveats,
+		})
+		require.NoError(t, err)
+
+		unrestricted, err := macaroon.NewAPIKey([]byte("testSecret"))
+		require.NoError(t, err)
+		_, err = apikeys.Create(ctx, unrestricted.Head(), console.APIKeyInfo{
+			Name:      "unrestricted",
+			ProjectID: project.ID,
+			Secret:    []byte("testSecret"),
+		})
+		require.NoError(t, err)
+
+		info, err := apikeys.GetByHead(ctx, restricted.Head())
+		require.NoError(t, err)
+		assert.Equal(t, caveats, info.Caveats)
+
+		info, err = apikeys.GetByHead(ctx, unrestricted.Head())
+		require.NoError(t, err)
+		assert.Empty(t, info.Caveats)
+	})
+}
+
+func TestApiKeysDeleteAllByProjectID(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		projects := db.Console().Projects()
+		apikeys := db.Console().APIKeys()
+
+		project, err := projects.Insert(ctx, &console.Project{Name: "ProjectName"})
+		require.NoError(t, err)
+		other, err := projects.Insert(ctx, &console.Project{Name: "OtherProjectName"})
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			for _, projectID := range []uuid.UUID{project.ID, other.ID} {
+				key, err := macaroon.NewAPIKey([]byte("testSecret"))
+				require.NoError(t, err)
+
+				_, err = apikeys.Create(ctx, key.Head(), console.APIKeyInfo{
+					Name:      fmt.Sprintf("key %d", i),
+					ProjectID: projectID,
+					Secret:    []byte("testSecret"),
+				})
+				require.NoError(t, err)
+			}
+		}
+
+		deleted, err := apikeys.DeleteAllByProjectID(ctx, project.ID)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, deleted)
+
+		total, _, err := apikeys.ProjectStats(ctx, project.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, total)
+
+		total, _, err = apikeys.ProjectStats(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, total)
+
+		deleted, err = apikeys.DeleteAllByProjectID(ctx, project.ID)
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, deleted)
 	})
 }
@@ -17,6 +17,18 @@ const (
 	CreateAPIKeyType = "graphqlCreateAPIKey"
 	// FieldKey is field name for the actual key in createAPIKey
 	FieldKey = "key"
+	// FieldLastUsedAt is a field name for the last used at timestamp of api key
+	FieldLastUsedAt = "lastUsedAt"
+	// FieldDisallowReads is a field name for disallowing reads with the created api key
+	FieldDisallowReads = "disallowReads"
+	// FieldDisallowWrites is a field name for disallowing writes with the created api key
+	FieldDisallowWrites = "disallowWrites"
+	// FieldDisallowLists is a field name for disallowing lists with the created api key
+	FieldDisallowLists = "disallowLists"
+	// FieldDisallowDeletes is a field name for disallowing deletes with the created api key
+	FieldDisallowDeletes = "disallowDeletes"
+	// FieldAllowedBuckets is a field name for the buckets the created api key is restricted to
+	FieldAllowedBuckets = "allowedBuckets"
 )
 
 // graphqlAPIKeyInfo creates satellite.APIKeyInfo graphql object
@@ -39,6 +51,9 @@ func graphqlAPIKeyInfo() *graphql.Object {
 			FieldPartnerID: &graphql.Field{
 				Type: graphql.String,
 			},
+			FieldLastUsedAt: &graphql.Field{
+				Type: graphql.DateTime,
+			},
 		},
 	})
 }
@@ -9,6 +9,7 @@ import (
 	"go.uber.org/zap"
 
 	"storj.io/storj/internal/post"
+	"storj.io/storj/pkg/macaroon"
 	"storj.io/storj/satellite/console"
 	"storj.io/storj/satellite/mailservice"
 )
@@ -399,6 +400,21 @@ func rootMutation(log *zap.Logger, service *console.Service, mailService *mailse
 					FieldName: &graphql.ArgumentConfig{
 						Type: graphql.NewNonNull(graphql.String),
 					},
+					FieldDisallowReads: &graphql.ArgumentConfig{
+						Type: graphql.Boolean,
+					},
+					FieldDisallowWrites: &graphql.ArgumentConfig{
+						Type: graphql.Boolean,
+					},
+					FieldDisallowLists: &graphql.ArgumentConfig{
+						Type: graphql.Boolean,
+					},
+					FieldDisallowDeletes: &graphql.ArgumentConfig{
+						Type: graphql.Boolean,
+					},
+					FieldAllowedBuckets: &graphql.ArgumentConfig{
+						Type: graphql.NewList(graphql.String),
+					},
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					projectID, _ := p.Args[FieldProjectID].(string)
@@ -409,7 +425,22 @@ func rootMutation(log *zap.Logger, service *console.Service, mailService *mailse
 						return nil, err
 					}
 
-					info, key, err := service.CreateAPIKey(p.Context, *pID, name)
+					// the caveat restricts every use of the key, see console.APIKeyInfo.Caveats
+					var caveat macaroon.Caveat
+					caveat.DisallowReads, _ = p.Args[FieldDisallowReads].(bool)
+					caveat.DisallowWrites, _ = p.Args[FieldDisallowWrites].(bool)
+					caveat.DisallowLists, _ = p.Args[FieldDisallowLists].(bool)
+					caveat.DisallowDeletes, _ = p.Args[FieldDisallowDeletes].(bool)
+
+					buckets, _ := p.Args[FieldAllowedBuckets].([]interface{})
+					for _, bucket := range buckets {
+						bucket, _ := bucket.(string)
+						caveat.AllowedPaths = append(caveat.AllowedPaths, &macaroon.Caveat_Path{
+							Bucket: []byte(bucket),
+						})
+					}
+
+					info, key, err := service.CreateRestrictedAPIKey(p.Context, *pID, name, caveat)
 					if err != nil {
 						return nil, HandleError(err)
 					}
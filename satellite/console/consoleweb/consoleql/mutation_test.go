@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/graphql-go/graphql"
 	"github.com/skyrings/skyring-common/tools/uuid"
 	"github.com/stretchr/testify/assert"
@@ -20,6 +21,7 @@ import (
 	"storj.io/storj/internal/post"
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/pkg/auth"
+	"storj.io/storj/pkg/macaroon"
 	"storj.io/storj/satellite"
 	"storj.io/storj/satellite/console"
 	"storj.io/storj/satellite/console/consoleauth"
@@ -535,6 +537,34 @@ func TestGrapqhlMutation(t *testing.T) {
 			keyID = keyInfo[consoleql.FieldID].(string)
 		})
 
+		t.Run("Create restricted api key mutation", func(t *testing.T) {
+			query := fmt.Sprintf(
+				"mutation {createAPIKey(projectID:\"%s\",name:\"%s\",disallowWrites:true,allowedBuckets:[\"%s\"]){key,keyInfo{id}}}",
+				project.ID.String(),
+				"restricted",
+				"bucket",
+			)
+
+			result := testQuery(t, query)
+
+			data := result.(map[string]interface{})
+			createAPIKey := data[consoleql.CreateAPIKeyMutation].(map[string]interface{})
+			keyInfo := createAPIKey[consoleql.APIKeyInfoType].(map[string]interface{})
+
+			id, err := uuid.Parse(keyInfo[consoleql.FieldID].(string))
+			require.NoError(t, err)
+
+			info, err := service.GetAPIKeyInfo(authCtx, *id)
+			require.NoError(t, err)
+
+			var caveat macaroon.Caveat
+			require.NoError(t, proto.Unmarshal(info.Caveats, &caveat))
+			assert.True(t, caveat.DisallowWrites)
+			assert.False(t, caveat.DisallowReads)
+			require.Len(t, caveat.AllowedPaths, 1)
+			assert.Equal(t, []byte("bucket"), caveat.AllowedPaths[0].Bucket)
+		})
+
 		t.Run("Delete api key mutation", func(t *testing.T) {
 			id, err := uuid.Parse(keyID)
 			require.NoError(t, err)
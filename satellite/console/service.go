@@ -8,6 +8,7 @@ import (
 	"crypto/subtle"
 	"time"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/skyrings/skyring-common/tools/uuid"
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
@@ -768,6 +769,18 @@ func (s *Service) GetProjectMembers(ctx context.Context, projectID uuid.UUID, cu
 // CreateAPIKey creates new api key
 func (s *Service) CreateAPIKey(ctx context.Context, projectID uuid.UUID, name string) (_ *APIKeyInfo, _ *macaroon.APIKey, err error) {
 	defer mon.Task()(&ctx)(&err)
+	return s.CreateRestrictedAPIKey(ctx, projectID, name, macaroon.Caveat{})
+}
+
+// CreateRestrictedAPIKey creates new api key whose every use is restricted by caveat, see APIKeyInfo.Caveats.
+// An empty caveat doesn't restrict the key.
+func (s *Service) CreateRestrictedAPIKey(ctx context.Context, projectID uuid.UUID, name string, caveat macaroon.Caveat) (_ *APIKeyInfo, _ *macaroon.APIKey, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	caveats, err := proto.Marshal(&caveat)
+	if err != nil {
+		return nil, nil, ErrConsoleInternal.Wrap(err)
+	}
 
 	auth, err := GetAuth(ctx)
 	if err != nil {
@@ -794,6 +807,7 @@ func (s *Service) CreateAPIKey(ctx context.Context, projectID uuid.UUID, name st
 		ProjectID: projectID,
 		Secret:    secret,
 		PartnerID: auth.User.PartnerID,
+		Caveats:   caveats,
 	}
 
 	info, err := s.store.APIKeys().Create(ctx, key.Head(), apikey)
@@ -9,9 +9,12 @@ import (
 
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"gopkg.in/spacemonkeygo/monkit.v2"
 
 	"storj.io/storj/internal/sync2"
+	"storj.io/storj/satellite/console"
+	"storj.io/storj/satellite/gracefulexit"
 	"storj.io/storj/satellite/orders"
 )
 
@@ -25,32 +28,54 @@ var (
 // Config defines configuration struct for dbcleanup chore.
 type Config struct {
 	SerialsInterval time.Duration `help:"how often to delete expired serial numbers" default:"24h"`
+
+	GracefulExitQueueInterval time.Duration `help:"how often to delete abandoned graceful exit transfer queue items" default:"24h"`
+	GracefulExitQueueTTL      time.Duration `help:"how long a graceful exit transfer queue item may wait without being requested before it is deleted" default:"720h"`
+
+	APIKeysInterval time.Duration `help:"how often to delete expired api keys" default:"1h"`
 }
 
 // Chore for deleting DB entries that are no longer needed.
 //
 // architecture: Chore
 type Chore struct {
-	log    *zap.Logger
-	orders orders.DB
+	log          *zap.Logger
+	orders       orders.DB
+	gracefulExit gracefulexit.DB
+	apiKeys      console.APIKeys
+
+	gracefulExitQueueTTL time.Duration
 
-	Serials sync2.Cycle
+	Serials           sync2.Cycle
+	GracefulExitQueue sync2.Cycle
+	APIKeys           sync2.Cycle
 }
 
 // NewChore creates new chore for deleting DB entries.
-func NewChore(log *zap.Logger, orders orders.DB, config Config) *Chore {
+func NewChore(log *zap.Logger, orders orders.DB, gracefulExit gracefulexit.DB, apiKeys console.APIKeys, config Config) *Chore {
 	return &Chore{
-		log:    log,
-		orders: orders,
+		log:          log,
+		orders:       orders,
+		gracefulExit: gracefulExit,
+		apiKeys:      apiKeys,
 
-		Serials: *sync2.NewCycle(config.SerialsInterval),
+		gracefulExitQueueTTL: config.GracefulExitQueueTTL,
+
+		Serials:           *sync2.NewCycle(config.SerialsInterval),
+		GracefulExitQueue: *sync2.NewCycle(config.GracefulExitQueueInterval),
+		APIKeys:           *sync2.NewCycle(config.APIKeysInterval),
 	}
 }
 
 // Run starts the db cleanup chore.
 func (chore *Chore) Run(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(&err)
-	return chore.Serials.Run(ctx, chore.deleteExpiredSerials)
+
+	var group errgroup.Group
+	chore.Serials.Start(ctx, &group, chore.deleteExpiredSerials)
+	chore.GracefulExitQueue.Start(ctx, &group, chore.deleteAbandonedQueueItems)
+	chore.APIKeys.Start(ctx, &group, chore.deleteExpiredAPIKeys)
+	return group.Wait()
 }
 
 func (chore *Chore) deleteExpiredSerials(ctx context.Context) (err error) {
@@ -67,8 +92,38 @@ func (chore *Chore) deleteExpiredSerials(ctx context.Context) (err error) {
 	return nil
 }
 
+func (chore *Chore) deleteAbandonedQueueItems(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+	chore.log.Debug("deleting abandoned graceful exit transfer queue items")
+
+	deleted, err := chore.gracefulExit.DeleteAbandonedQueueItems(ctx, chore.gracefulExitQueueTTL)
+	if err != nil {
+		chore.log.Error("deleting abandoned graceful exit transfer queue items", zap.Error(err))
+		return nil
+	}
+
+	chore.log.Debug("abandoned graceful exit transfer queue items deleted", zap.Int("items deleted", deleted))
+	return nil
+}
+
+func (chore *Chore) deleteExpiredAPIKeys(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+	chore.log.Debug("deleting expired api keys")
+
+	deleted, err := chore.apiKeys.DeleteExpired(ctx, time.Now().UTC())
+	if err != nil {
+		chore.log.Error("deleting expired api keys", zap.Error(err))
+		return nil
+	}
+
+	chore.log.Debug("expired api keys deleted", zap.Int64("items deleted", deleted))
+	return nil
+}
+
 // Close stops the dbcleanup chore.
 func (chore *Chore) Close() error {
 	chore.Serials.Close()
+	chore.GracefulExitQueue.Close()
+	chore.APIKeys.Close()
 	return nil
 }
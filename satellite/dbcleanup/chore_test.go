@@ -11,7 +11,9 @@ import (
 
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testplanet"
+	"storj.io/storj/pkg/macaroon"
 	"storj.io/storj/pkg/storj"
+	"storj.io/storj/satellite/console"
 )
 
 func TestDeleteExpiredSerials(t *testing.T) {
@@ -66,3 +68,41 @@ func TestDeleteExpiredSerials(t *testing.T) {
 		}
 	})
 }
+
+func TestDeleteExpiredAPIKeys(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		satellite := planet.Satellites[0]
+		satellite.DBCleanup.Chore.APIKeys.Pause()
+
+		apiKeys := satellite.DB.Console().APIKeys()
+		projectID := planet.Uplinks[0].ProjectID[satellite.ID()]
+
+		create := func(name string, expiresAt time.Time) *console.APIKeyInfo {
+			key, err := macaroon.NewAPIKey([]byte("testSecret"))
+			require.NoError(t, err)
+
+			info, err := apiKeys.Create(ctx, key.Head(), console.APIKeyInfo{
+				Name:      name,
+				ProjectID: projectID,
+				Secret:    []byte("testSecret"),
+				ExpiresAt: &expiresAt,
+			})
+			require.NoError(t, err)
+			return info
+		}
+
+		expired := create("expired", time.Now().Add(-time.Hour))
+		fresh := create("fresh", time.Now().Add(time.Hour))
+
+		// trigger expired api key deletion
+		satellite.DBCleanup.Chore.APIKeys.TriggerWait()
+
+		_, err := apiKeys.Get(ctx, expired.ID)
+		require.Error(t, err)
+
+		_, err = apiKeys.Get(ctx, fresh.ID)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,14 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package gracefulexit
+
+import (
+	"time"
+)
+
+// Config contains the configurable values for graceful exit.
+type Config struct {
+	EnqueueLimit  int           `help:"maximum number of transfer queue items enqueued for an exiting node within the enqueue window, 0 is unlimited" default:"0"`
+	EnqueueWindow time.Duration `help:"the window in which at most enqueue-limit transfer queue items are enqueued for an exiting node" default:"1h"`
+}
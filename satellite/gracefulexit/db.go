@@ -5,11 +5,21 @@ package gracefulexit
 
 import (
 	"context"
+	"io"
 	"time"
 
+	"github.com/zeebo/errs"
+
 	"storj.io/storj/pkg/storj"
 )
 
+// ErrTransferQueueItemNotFound is returned when the graceful exit transfer queue entry doesn't exist.
+var ErrTransferQueueItemNotFound = errs.Class("graceful exit transfer queue item not found")
+
+// MaxFailedPiecesPercentage is the highest percentage of pieces, which failed to transfer,
+// that still allows a graceful exit to succeed.
+const MaxFailedPiecesPercentage = 10
+
 // Progress represents the persisted graceful exit progress record.
 type Progress struct {
 	NodeID            storj.NodeID
@@ -19,6 +29,27 @@ type Progress struct {
 	UpdatedAt         time.Time
 }
 
+// ExitCompletion is how far along the graceful exit of a node is.
+type ExitCompletion struct {
+	// PiecesTransferred and PiecesFailed are the pieces recorded in the progress.
+	PiecesTransferred int64
+	PiecesFailed      int64
+	// PiecesTotal is the number of finished pieces and incomplete transfer queue entries.
+	PiecesTotal int64
+	// QueueBuilding is set until all the pieces of the node were added to the transfer queue,
+	// meanwhile PiecesTotal only counts the pieces queued so far.
+	QueueBuilding bool
+}
+
+// Percent returns the percentage of the pieces which were finished, either transferred or failed.
+// It's zero while the transfer queue is still being built, as the total isn't known yet.
+func (completion ExitCompletion) Percent() float64 {
+	if completion.QueueBuilding || completion.PiecesTotal == 0 {
+		return 0
+	}
+	return float64(completion.PiecesTransferred+completion.PiecesFailed) * 100 / float64(completion.PiecesTotal)
+}
+
 // TransferQueueItem represents the persisted graceful exit queue record.
 type TransferQueueItem struct {
 	NodeID          storj.NodeID
@@ -39,21 +70,65 @@ type TransferQueueItem struct {
 type DB interface {
 	// IncrementProgress increments transfer stats for a node.
 	IncrementProgress(ctx context.Context, nodeID storj.NodeID, bytes int64, successfulTransfers int64, failedTransfers int64) error
+	// RecordTransfer increments transfer stats for a node by a successful transfer and records the transferred bytes of the path.
+	RecordTransfer(ctx context.Context, nodeID storj.NodeID, path []byte, bytes int64) error
+	// GetTransferredBytes returns the sum of the recorded transferred bytes of a node.
+	GetTransferredBytes(ctx context.Context, nodeID storj.NodeID) (int64, error)
+	// TransferredPerDay returns the recorded transferred bytes of a node for every UTC day between from and to inclusive, keyed by the start of the day.
+	TransferredPerDay(ctx context.Context, nodeID storj.NodeID, from, to time.Time) (map[time.Time]int64, error)
 	// GetProgress gets a graceful exit progress entry.
 	GetProgress(ctx context.Context, nodeID storj.NodeID) (*Progress, error)
+	// GetProgressBatch gets the graceful exit progress entries of the nodes, nodes without an entry are missing from the result.
+	GetProgressBatch(ctx context.Context, nodeIDs []storj.NodeID) (map[storj.NodeID]*Progress, error)
+	// ReconcileProgress recomputes the graceful exit progress of a node from its finished transfer queue entries and recorded transfers and rewrites it.
+	ReconcileProgress(ctx context.Context, nodeID storj.NodeID) (*Progress, error)
+	// GetExitCompletion returns the finished and total pieces of the graceful exit of a node and whether its transfer queue is still being built.
+	GetExitCompletion(ctx context.Context, nodeID storj.NodeID) (*ExitCompletion, error)
 
-	// Enqueue batch inserts graceful exit transfer queue entries it does not exist.
-	Enqueue(ctx context.Context, items []TransferQueueItem) error
-	// UpdateTransferQueueItem creates a graceful exit transfer queue entry.
+	// Enqueue batch inserts graceful exit transfer queue entries it does not exist and returns the number of inserted entries.
+	Enqueue(ctx context.Context, items []TransferQueueItem) (inserted int, err error)
+	// UpdateTransferQueueItem updates a graceful exit transfer queue entry, rejecting invalid transitions with ErrInvalidTransition.
 	UpdateTransferQueueItem(ctx context.Context, item TransferQueueItem) error
+	// UpdateTransferQueueItemsBatch updates graceful exit transfer queue entries in a single transaction, either all of them or none.
+	UpdateTransferQueueItemsBatch(ctx context.Context, items []TransferQueueItem) error
 	// DeleteTransferQueueItem deletes a graceful exit transfer queue entry.
 	DeleteTransferQueueItem(ctx context.Context, nodeID storj.NodeID, path []byte) error
 	// DeleteTransferQueueItem deletes a graceful exit transfer queue entries by nodeID.
 	DeleteTransferQueueItems(ctx context.Context, nodeID storj.NodeID) error
 	// DeleteFinishedTransferQueueItem deletes finiahed graceful exit transfer queue entries.
 	DeleteFinishedTransferQueueItems(ctx context.Context, nodeID storj.NodeID) error
+	// RequeueTransferItem clears the finished date and the failures of a graceful exit transfer queue entry and queues it again, so that it is transferred again.
+	RequeueTransferItem(ctx context.Context, nodeID storj.NodeID, path []byte) error
+	// PurgeQueueForFinishedExits deletes all graceful exit transfer queue entries for nodes that have finished exiting.
+	PurgeQueueForFinishedExits(ctx context.Context, finished []storj.NodeID) (int, error)
+	// DeleteAbandonedQueueItems deletes incomplete graceful exit transfer queue entries queued more than olderThan ago which haven't been requested.
+	DeleteAbandonedQueueItems(ctx context.Context, olderThan time.Duration) (int, error)
 	// GetTransferQueueItem gets a graceful exit transfer queue entry.
 	GetTransferQueueItem(ctx context.Context, nodeID storj.NodeID, path []byte) (*TransferQueueItem, error)
+	// GetTransferQueueItems gets the graceful exit transfer queue entries of the paths, keyed by the hex encoded path.
+	GetTransferQueueItems(ctx context.Context, nodeID storj.NodeID, paths [][]byte) (map[string]*TransferQueueItem, error)
 	// GetIncomplete gets incomplete graceful exit transfer queue entries ordered by the queued date ascending.
 	GetIncomplete(ctx context.Context, nodeID storj.NodeID, limit int, offset int64) ([]*TransferQueueItem, error)
+	// GetFailedItems gets incomplete graceful exit transfer queue entries which have failed fewer than maxFailures times and aren't requested again, ordered by the last failure ascending.
+	GetFailedItems(ctx context.Context, nodeID storj.NodeID, maxFailures int, limit int) ([]*TransferQueueItem, error)
+	// GetIncompleteByDurability gets incomplete graceful exit transfer queue entries ordered by the durability ratio ascending.
+	GetIncompleteByDurability(ctx context.Context, nodeID storj.NodeID, limit int, offset int64) ([]*TransferQueueItem, error)
+	// GetIncompleteExcludingExhausted gets incomplete graceful exit transfer queue entries which have failed fewer than maxFailures times, ordered by the failure count ascending.
+	GetIncompleteExcludingExhausted(ctx context.Context, nodeID storj.NodeID, maxFailures int, limit int) ([]*TransferQueueItem, error)
+	// DurabilityHistogram returns the number of incomplete graceful exit transfer queue entries for a node in each durability bucket.
+	DurabilityHistogram(ctx context.Context, nodeID storj.NodeID, buckets []float64) (map[float64]int64, error)
+	// ValidateExitComplete checks whether the graceful exit of the node may be marked as succeeded and returns the reason when it may not.
+	ValidateExitComplete(ctx context.Context, nodeID storj.NodeID) (complete bool, reason string, err error)
+	// CompactQueue reclaims the storage of deleted graceful exit transfer queue entries, see the implementations for the backend differences.
+	CompactQueue(ctx context.Context) error
+	// QueueStorageSize returns the number of bytes the graceful exit transfer queue takes up.
+	QueueStorageSize(ctx context.Context) (int64, error)
+	// ExportQueue writes the incomplete graceful exit transfer queue entries of a node to w in the format, see ExportHeader for the columns.
+	ExportQueue(ctx context.Context, nodeID storj.NodeID, w io.Writer, format string) error
+	// ImportQueue enqueues the graceful exit transfer queue entries read from r in the format, as written by ExportQueue, for a node.
+	ImportQueue(ctx context.Context, nodeID storj.NodeID, r io.Reader, format string) (imported, skipped int, err error)
+	// CountTransferQueueItems returns the number of all, finished and failed graceful exit transfer queue entries for a node, where the failed entries are finished too.
+	CountTransferQueueItems(ctx context.Context, nodeID storj.NodeID) (total, finished, failed int64, err error)
+	// EstimateQueueSize returns an estimate of the number of incomplete graceful exit transfer queue entries for a node.
+	EstimateQueueSize(ctx context.Context, nodeID storj.NodeID) (int64, error)
 }
@@ -4,17 +4,27 @@
 package gracefulexit_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/lib/pq"
+	sqlite3 "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/require"
 
 	"storj.io/storj/internal/memory"
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
 	"storj.io/storj/pkg/storj"
 	"storj.io/storj/satellite"
 	"storj.io/storj/satellite/gracefulexit"
+	"storj.io/storj/satellite/overlay"
+	dbx "storj.io/storj/satellite/satellitedb/dbx"
 	"storj.io/storj/satellite/satellitedb/satellitedbtest"
 )
 
@@ -56,6 +66,269 @@ func TestProgress(t *testing.T) {
 	})
 }
 
+func TestGetProgressBatch(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		exiting1 := testrand.NodeID()
+		exiting2 := testrand.NodeID()
+		require.NoError(t, geDB.IncrementProgress(ctx, exiting1, 10, 2, 1))
+		require.NoError(t, geDB.IncrementProgress(ctx, exiting2, 20, 4, 0))
+
+		// enough absent nodes to need several queries
+		nodeIDs := []storj.NodeID{exiting1}
+		for i := 0; i < 600; i++ {
+			nodeIDs = append(nodeIDs, testrand.NodeID())
+		}
+		nodeIDs = append(nodeIDs, exiting2)
+
+		progress, err := geDB.GetProgressBatch(ctx, nodeIDs)
+		require.NoError(t, err)
+		require.Len(t, progress, 2)
+
+		require.Equal(t, exiting1, progress[exiting1].NodeID)
+		require.Equal(t, int64(10), progress[exiting1].BytesTransferred)
+		require.Equal(t, int64(2), progress[exiting1].PiecesTransferred)
+		require.Equal(t, int64(1), progress[exiting1].PiecesFailed)
+
+		require.Equal(t, exiting2, progress[exiting2].NodeID)
+		require.Equal(t, int64(20), progress[exiting2].BytesTransferred)
+		require.Equal(t, int64(4), progress[exiting2].PiecesTransferred)
+		require.Equal(t, int64(0), progress[exiting2].PiecesFailed)
+
+		progress, err = geDB.GetProgressBatch(ctx, nil)
+		require.NoError(t, err)
+		require.Len(t, progress, 0)
+	})
+}
+
+func TestRecordTransfer(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		otherNodeID := testrand.NodeID()
+
+		total, err := geDB.GetTransferredBytes(ctx, nodeID)
+		require.NoError(t, err)
+		require.Zero(t, total)
+
+		sizes := []int64{1024, 2048, 4096}
+		for _, size := range sizes {
+			require.NoError(t, geDB.RecordTransfer(ctx, nodeID, testrand.Bytes(memory.B*32), size))
+		}
+		require.NoError(t, geDB.RecordTransfer(ctx, otherNodeID, testrand.Bytes(memory.B*32), 100))
+
+		// the recorded transfers add up to the aggregated progress
+		progress, err := geDB.GetProgress(ctx, nodeID)
+		require.NoError(t, err)
+		require.EqualValues(t, 7168, progress.BytesTransferred)
+		require.EqualValues(t, len(sizes), progress.PiecesTransferred)
+		require.Zero(t, progress.PiecesFailed)
+
+		total, err = geDB.GetTransferredBytes(ctx, nodeID)
+		require.NoError(t, err)
+		require.Equal(t, progress.BytesTransferred, total)
+
+		// increments without a record show up as a difference
+		require.NoError(t, geDB.IncrementProgress(ctx, nodeID, 500, 1, 0))
+
+		progress, err = geDB.GetProgress(ctx, nodeID)
+		require.NoError(t, err)
+		total, err = geDB.GetTransferredBytes(ctx, nodeID)
+		require.NoError(t, err)
+		require.EqualValues(t, 500, progress.BytesTransferred-total)
+
+		total, err = geDB.GetTransferredBytes(ctx, otherNodeID)
+		require.NoError(t, err)
+		require.EqualValues(t, 100, total)
+	})
+}
+
+func TestReconcileProgress(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		var items []gracefulexit.TransferQueueItem
+		for i := 0; i < 3; i++ {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.9,
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+
+		now := time.Now().UTC()
+
+		// the first item is transferred
+		transferred := items[0]
+		transferred.RequestedAt = now
+		transferred.FinishedAt = now
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, transferred))
+		require.NoError(t, geDB.RecordTransfer(ctx, nodeID, transferred.Path, 1024))
+
+		// the second item is given up after failing
+		failed := items[1]
+		failed.RequestedAt = now
+		failed.LastFailedAt = now.Add(time.Second)
+		failed.LastFailedCode = 1
+		failed.FailedCount = 1
+		failed.FinishedAt = now.Add(time.Second)
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, failed))
+
+		// the third item is still incomplete and the progress is out of sync
+		require.NoError(t, geDB.IncrementProgress(ctx, nodeID, 5000, 10, 4))
+
+		progress, err := geDB.ReconcileProgress(ctx, nodeID)
+		require.NoError(t, err)
+		require.Equal(t, nodeID, progress.NodeID)
+		require.EqualValues(t, 1024, progress.BytesTransferred)
+		require.EqualValues(t, 1, progress.PiecesTransferred)
+		require.EqualValues(t, 1, progress.PiecesFailed)
+
+		stored, err := geDB.GetProgress(ctx, nodeID)
+		require.NoError(t, err)
+		require.Equal(t, progress.BytesTransferred, stored.BytesTransferred)
+		require.Equal(t, progress.PiecesTransferred, stored.PiecesTransferred)
+		require.Equal(t, progress.PiecesFailed, stored.PiecesFailed)
+
+		// the transferred bytes are kept when no transfers were recorded
+		unrecordedNodeID := testrand.NodeID()
+		require.NoError(t, geDB.IncrementProgress(ctx, unrecordedNodeID, 5000, 10, 4))
+		progress, err = geDB.ReconcileProgress(ctx, unrecordedNodeID)
+		require.NoError(t, err)
+		require.EqualValues(t, 5000, progress.BytesTransferred)
+		require.Zero(t, progress.PiecesTransferred)
+		require.Zero(t, progress.PiecesFailed)
+
+		// a node without progress gets an empty one
+		otherNodeID := testrand.NodeID()
+		progress, err = geDB.ReconcileProgress(ctx, otherNodeID)
+		require.NoError(t, err)
+		require.Zero(t, progress.BytesTransferred)
+		require.Zero(t, progress.PiecesTransferred)
+		require.Zero(t, progress.PiecesFailed)
+
+		_, err = geDB.GetProgress(ctx, otherNodeID)
+		require.NoError(t, err)
+	})
+}
+
+func TestGetExitCompletion(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		require.NoError(t, db.OverlayCache().UpdateAddress(ctx, &pb.Node{Id: nodeID}, overlay.NodeSelectionConfig{}))
+
+		// nothing queued yet
+		completion, err := geDB.GetExitCompletion(ctx, nodeID)
+		require.NoError(t, err)
+		require.True(t, completion.QueueBuilding)
+		require.Zero(t, completion.PiecesTotal)
+		require.Zero(t, completion.Percent())
+
+		var items []gracefulexit.TransferQueueItem
+		for i := 0; i < 4; i++ {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.9,
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+
+		// one piece is transferred and one failed, the finished entries are deleted
+		now := time.Now().UTC()
+		for _, item := range items[:2] {
+			item.RequestedAt = now
+			item.FinishedAt = now
+			require.NoError(t, geDB.UpdateTransferQueueItem(ctx, item))
+		}
+		require.NoError(t, geDB.IncrementProgress(ctx, nodeID, 1024, 1, 1))
+		require.NoError(t, geDB.DeleteFinishedTransferQueueItems(ctx, nodeID))
+
+		// the queue may still grow, so no percentage is reported
+		completion, err = geDB.GetExitCompletion(ctx, nodeID)
+		require.NoError(t, err)
+		require.True(t, completion.QueueBuilding)
+		require.EqualValues(t, 1, completion.PiecesTransferred)
+		require.EqualValues(t, 1, completion.PiecesFailed)
+		require.EqualValues(t, 4, completion.PiecesTotal)
+		require.Zero(t, completion.Percent())
+
+		require.NoError(t, db.OverlayCache().UpdateExitLoopCompleted(ctx, nodeID, now))
+
+		completion, err = geDB.GetExitCompletion(ctx, nodeID)
+		require.NoError(t, err)
+		require.False(t, completion.QueueBuilding)
+		require.EqualValues(t, 4, completion.PiecesTotal)
+		require.Equal(t, 50.0, completion.Percent())
+
+		// an unknown node is still building its queue
+		completion, err = geDB.GetExitCompletion(ctx, testrand.NodeID())
+		require.NoError(t, err)
+		require.True(t, completion.QueueBuilding)
+		require.Zero(t, completion.PiecesTotal)
+	})
+}
+
+func TestTransferredPerDay(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		otherNodeID := testrand.NodeID()
+
+		sizes := []int64{1024, 2048, 4096}
+		for _, size := range sizes {
+			require.NoError(t, geDB.RecordTransfer(ctx, nodeID, testrand.Bytes(memory.B*32), size))
+		}
+		require.NoError(t, geDB.RecordTransfer(ctx, otherNodeID, testrand.Bytes(memory.B*32), 100))
+
+		day := 24 * time.Hour
+		today := time.Now().UTC().Truncate(day)
+
+		// the window spans from two days ago until tomorrow
+		perDay, err := geDB.TransferredPerDay(ctx, nodeID, time.Now().Add(-2*day), time.Now().Add(day))
+		require.NoError(t, err)
+		require.Equal(t, map[time.Time]int64{
+			today.Add(-2 * day): 0,
+			today.Add(-day):     0,
+			today:               7168,
+			today.Add(day):      0,
+		}, perDay)
+
+		// days before the transfers are zero filled
+		perDay, err = geDB.TransferredPerDay(ctx, nodeID, time.Now().Add(-3*day), time.Now().Add(-day))
+		require.NoError(t, err)
+		require.Equal(t, map[time.Time]int64{
+			today.Add(-3 * day): 0,
+			today.Add(-2 * day): 0,
+			today.Add(-day):     0,
+		}, perDay)
+
+		// an inverted window is empty
+		perDay, err = geDB.TransferredPerDay(ctx, nodeID, time.Now().Add(day), time.Now().Add(-day))
+		require.NoError(t, err)
+		require.Empty(t, perDay)
+	})
+}
+
 func TestTransferQueueItem(t *testing.T) {
 	// test basic graceful exit transfer queue crud
 	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
@@ -96,7 +369,7 @@ func TestTransferQueueItem(t *testing.T) {
 
 		// test basic create, update, get delete
 		{
-			err := geDB.Enqueue(ctx, items)
+			_, err := geDB.Enqueue(ctx, items)
 			require.NoError(t, err)
 
 			for _, tqi := range items {
@@ -135,6 +408,10 @@ func TestTransferQueueItem(t *testing.T) {
 				require.Equal(t, nodeID1, queueItem.NodeID)
 				require.Equal(t, path2, queueItem.Path)
 			}
+
+			count, err := geDB.EstimateQueueSize(ctx, nodeID1)
+			require.NoError(t, err)
+			require.EqualValues(t, 1, count)
 		}
 
 		// test delete finished queue items. Only path1 should be removed
@@ -166,3 +443,826 @@ func TestTransferQueueItem(t *testing.T) {
 		}
 	})
 }
+
+func TestPurgeQueueForFinishedExits(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		finished1 := testrand.NodeID()
+		finished2 := testrand.NodeID()
+		active := testrand.NodeID()
+
+		var items []gracefulexit.TransferQueueItem
+		for _, nodeID := range []storj.NodeID{finished1, finished2, active} {
+			for i := 0; i < 2; i++ {
+				items = append(items, gracefulexit.TransferQueueItem{
+					NodeID:          nodeID,
+					Path:            testrand.Bytes(memory.B * 32),
+					PieceNum:        int32(i),
+					DurabilityRatio: 0.9,
+				})
+			}
+		}
+		enqueue(ctx, t, geDB, items)
+
+		// nothing to purge
+		deleted, err := geDB.PurgeQueueForFinishedExits(ctx, nil)
+		require.NoError(t, err)
+		require.Equal(t, 0, deleted)
+
+		deleted, err = geDB.PurgeQueueForFinishedExits(ctx, []storj.NodeID{finished1, finished2})
+		require.NoError(t, err)
+		require.Equal(t, 4, deleted)
+
+		for _, nodeID := range []storj.NodeID{finished1, finished2} {
+			queueItems, err := geDB.GetIncomplete(ctx, nodeID, 10, 0)
+			require.NoError(t, err)
+			require.Len(t, queueItems, 0)
+		}
+
+		queueItems, err := geDB.GetIncomplete(ctx, active, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, queueItems, 2)
+	})
+}
+
+func TestExportImportQueue(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+		exporting := testrand.NodeID()
+		importing := testrand.NodeID()
+
+		var items []gracefulexit.TransferQueueItem
+		for i := 0; i < 5; i++ {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          exporting,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.1 * float64(i+1),
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+
+		var exported bytes.Buffer
+		require.NoError(t, geDB.ExportQueue(ctx, exporting, &exported, gracefulexit.ExportCSV))
+
+		// append an invalid row and a finished one
+		exported.WriteString("zz,1,0.5,,,,0,0,\n")
+		exported.WriteString(hex.EncodeToString(testrand.Bytes(memory.B*32)) + ",1,0.5,,,,0,0,2019-10-01T00:00:00Z\n")
+
+		reimport := exported.Bytes()
+
+		imported, skipped, err := geDB.ImportQueue(ctx, importing, &exported, gracefulexit.ExportCSV)
+		require.NoError(t, err)
+		require.Equal(t, len(items), imported)
+		require.Equal(t, 2, skipped)
+
+		// the entries which are already queued are skipped
+		imported, skipped, err = geDB.ImportQueue(ctx, importing, bytes.NewReader(reimport), gracefulexit.ExportCSV)
+		require.NoError(t, err)
+		require.Zero(t, imported)
+		require.Equal(t, len(items)+2, skipped)
+
+		queueItems, err := geDB.GetIncomplete(ctx, importing, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, queueItems, len(items))
+		for _, item := range items {
+			importedItem, err := geDB.GetTransferQueueItem(ctx, importing, item.Path)
+			require.NoError(t, err)
+			require.Equal(t, item.PieceNum, importedItem.PieceNum)
+			require.Equal(t, item.DurabilityRatio, importedItem.DurabilityRatio)
+		}
+
+		_, _, err = geDB.ImportQueue(ctx, importing, strings.NewReader("unknown\n"), gracefulexit.ExportCSV)
+		require.True(t, gracefulexit.ErrImport.Has(err))
+
+		_, _, err = geDB.ImportQueue(ctx, importing, &exported, "xml")
+		require.True(t, gracefulexit.ErrImport.Has(err))
+	})
+}
+
+func TestCompactQueue(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+		nodeID := testrand.NodeID()
+
+		var items []gracefulexit.TransferQueueItem
+		for i := 0; i < 2000; i++ {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 256),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.9,
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+
+		for _, item := range items {
+			item.RequestedAt = time.Now()
+			require.NoError(t, geDB.UpdateTransferQueueItem(ctx, item))
+			item.FinishedAt = time.Now()
+			require.NoError(t, geDB.UpdateTransferQueueItem(ctx, item))
+		}
+		require.NoError(t, geDB.DeleteFinishedTransferQueueItems(ctx, nodeID))
+
+		before, err := geDB.QueueStorageSize(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, geDB.CompactQueue(ctx))
+
+		after, err := geDB.QueueStorageSize(ctx)
+		require.NoError(t, err)
+
+		// TODO: remove dependency on *dbx.DB
+		dbAccess := db.(interface{ TestDBAccess() *dbx.DB }).TestDBAccess()
+		switch d := dbAccess.DB.Driver().(type) {
+		case *sqlite3.SQLiteDriver:
+			// sqlite returns the free pages to the file system
+			require.True(t, after < before, "size before compaction %d, after %d", before, after)
+		case *pq.Driver:
+			// postgres only makes the space reusable
+			require.True(t, after <= before, "size before compaction %d, after %d", before, after)
+		default:
+			t.Errorf("Unsupported database type %t", d)
+		}
+	})
+}
+
+func TestDeleteAbandonedQueueItems(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+		nodeID := testrand.NodeID()
+
+		newItems := func(count int) []gracefulexit.TransferQueueItem {
+			var items []gracefulexit.TransferQueueItem
+			for i := 0; i < count; i++ {
+				items = append(items, gracefulexit.TransferQueueItem{
+					NodeID:          nodeID,
+					Path:            testrand.Bytes(memory.B * 32),
+					PieceNum:        int32(i),
+					DurabilityRatio: 0.9,
+				})
+			}
+			return items
+		}
+
+		oldItems := newItems(4)
+		enqueue(ctx, t, geDB, oldItems)
+
+		// old items which made progress must be kept
+		requested := oldItems[0]
+		requested.RequestedAt = time.Now().UTC()
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, requested))
+
+		finished := oldItems[1]
+		finished.RequestedAt = time.Now().UTC()
+		finished.FinishedAt = time.Now().UTC()
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, finished))
+
+		time.Sleep(500 * time.Millisecond)
+		boundary := time.Now()
+
+		freshItems := newItems(3)
+		enqueue(ctx, t, geDB, freshItems)
+
+		// nothing is older than an hour
+		deleted, err := geDB.DeleteAbandonedQueueItems(ctx, time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, 0, deleted)
+
+		// the cutoff falls between the old and the fresh items
+		deleted, err = geDB.DeleteAbandonedQueueItems(ctx, time.Since(boundary)+250*time.Millisecond)
+		require.NoError(t, err)
+		require.Equal(t, 2, deleted)
+
+		for _, item := range oldItems[2:] {
+			_, err := geDB.GetTransferQueueItem(ctx, nodeID, item.Path)
+			require.Error(t, err)
+		}
+		for _, item := range append(oldItems[:2], freshItems...) {
+			_, err := geDB.GetTransferQueueItem(ctx, nodeID, item.Path)
+			require.NoError(t, err)
+		}
+	})
+}
+
+func TestValidateExitComplete(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		// the exit hasn't started
+		complete, reason, err := geDB.ValidateExitComplete(ctx, testrand.NodeID())
+		require.NoError(t, err)
+		require.False(t, complete)
+		require.NotEmpty(t, reason)
+
+		nodeID := testrand.NodeID()
+		var items []gracefulexit.TransferQueueItem
+		for i := 0; i < 2; i++ {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.9,
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+		require.NoError(t, geDB.IncrementProgress(ctx, nodeID, 1000, 19, 0))
+
+		// the queue still has incomplete items
+		complete, reason, err = geDB.ValidateExitComplete(ctx, nodeID)
+		require.NoError(t, err)
+		require.False(t, complete)
+		require.Contains(t, reason, "incomplete")
+
+		for _, item := range items {
+			item.RequestedAt = time.Now().UTC()
+			item.FinishedAt = time.Now().UTC()
+			require.NoError(t, geDB.UpdateTransferQueueItem(ctx, item))
+		}
+		require.NoError(t, geDB.IncrementProgress(ctx, nodeID, 100, 0, 1))
+
+		// 1 of 20 pieces failed, which is below the limit
+		complete, reason, err = geDB.ValidateExitComplete(ctx, nodeID)
+		require.NoError(t, err)
+		require.True(t, complete)
+		require.Empty(t, reason)
+
+		// 3 of 22 pieces failed, which exceeds the limit
+		require.NoError(t, geDB.IncrementProgress(ctx, nodeID, 0, 0, 2))
+		complete, reason, err = geDB.ValidateExitComplete(ctx, nodeID)
+		require.NoError(t, err)
+		require.False(t, complete)
+		require.Contains(t, reason, "failed")
+	})
+}
+
+func TestUpdateTransferQueueItemTransitions(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		path := testrand.Bytes(memory.B * 32)
+		enqueue(ctx, t, geDB, []gracefulexit.TransferQueueItem{{
+			NodeID:          nodeID,
+			Path:            path,
+			DurabilityRatio: 0.9,
+		}})
+
+		item, err := geDB.GetTransferQueueItem(ctx, nodeID, path)
+		require.NoError(t, err)
+
+		// a queued item can't fail
+		failed := *item
+		failed.LastFailedAt = time.Now().UTC()
+		failed.FailedCount = 1
+		err = geDB.UpdateTransferQueueItem(ctx, failed)
+		require.True(t, gracefulexit.ErrInvalidTransition.Has(err))
+
+		latest, err := geDB.GetTransferQueueItem(ctx, nodeID, path)
+		require.NoError(t, err)
+		require.Zero(t, latest.FailedCount)
+
+		// once requested it can
+		failed.RequestedAt = time.Now().UTC()
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, failed))
+
+		// the failed count can't go back
+		decreased := failed
+		decreased.FailedCount = 0
+		err = geDB.UpdateTransferQueueItem(ctx, decreased)
+		require.True(t, gracefulexit.ErrInvalidTransition.Has(err))
+
+		finished := failed
+		finished.FinishedAt = time.Now().UTC()
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, finished))
+
+		// finished items can only be requeued
+		failedAgain := finished
+		failedAgain.FailedCount = 2
+		err = geDB.UpdateTransferQueueItem(ctx, failedAgain)
+		require.True(t, gracefulexit.ErrInvalidTransition.Has(err))
+
+		require.NoError(t, geDB.RequeueTransferItem(ctx, nodeID, path))
+		requested := gracefulexit.TransferQueueItem{
+			NodeID:          nodeID,
+			Path:            path,
+			DurabilityRatio: 0.9,
+			RequestedAt:     time.Now().UTC(),
+		}
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, requested))
+
+		// updating a missing item fails
+		requested.Path = testrand.Bytes(memory.B * 32)
+		err = geDB.UpdateTransferQueueItem(ctx, requested)
+		require.True(t, gracefulexit.ErrTransferQueueItemNotFound.Has(err))
+	})
+}
+
+func TestRequeueTransferItem(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		path := testrand.Bytes(memory.B * 32)
+		enqueue(ctx, t, geDB, []gracefulexit.TransferQueueItem{{
+			NodeID:          nodeID,
+			Path:            path,
+			PieceNum:        1,
+			DurabilityRatio: 0.9,
+		}})
+
+		item, err := geDB.GetTransferQueueItem(ctx, nodeID, path)
+		require.NoError(t, err)
+
+		now := time.Now().UTC()
+		item.RequestedAt = now
+		item.LastFailedAt = now
+		item.LastFailedCode = 1
+		item.FailedCount = 2
+		item.FinishedAt = now
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, *item))
+
+		incomplete, err := geDB.GetIncomplete(ctx, nodeID, 10, 0)
+		require.NoError(t, err)
+		require.Empty(t, incomplete)
+
+		time.Sleep(100 * time.Millisecond)
+		require.NoError(t, geDB.RequeueTransferItem(ctx, nodeID, path))
+
+		// the requeued item isn't abandoned, although it was queued first before
+		deleted, err := geDB.DeleteAbandonedQueueItems(ctx, 50*time.Millisecond)
+		require.NoError(t, err)
+		require.Zero(t, deleted)
+
+		incomplete, err = geDB.GetIncomplete(ctx, nodeID, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, incomplete, 1)
+		require.Equal(t, path, incomplete[0].Path)
+		require.True(t, incomplete[0].FinishedAt.IsZero())
+		require.True(t, incomplete[0].RequestedAt.IsZero())
+		require.True(t, incomplete[0].LastFailedAt.IsZero())
+		require.Zero(t, incomplete[0].LastFailedCode)
+		require.Zero(t, incomplete[0].FailedCount)
+		require.True(t, incomplete[0].QueuedAt.After(item.QueuedAt))
+
+		err = geDB.RequeueTransferItem(ctx, nodeID, testrand.Bytes(memory.B*32))
+		require.Error(t, err)
+		require.True(t, gracefulexit.ErrTransferQueueItemNotFound.Has(err))
+
+		err = geDB.RequeueTransferItem(ctx, testrand.NodeID(), path)
+		require.True(t, gracefulexit.ErrTransferQueueItemNotFound.Has(err))
+	})
+}
+
+func TestGetTransferQueueItems(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		otherNodeID := testrand.NodeID()
+
+		var items []gracefulexit.TransferQueueItem
+		for i := 0; i < 3; i++ {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.9,
+			})
+		}
+		// belongs to another node
+		items = append(items, gracefulexit.TransferQueueItem{
+			NodeID:          otherNodeID,
+			Path:            testrand.Bytes(memory.B * 32),
+			DurabilityRatio: 0.9,
+		})
+		enqueue(ctx, t, geDB, items)
+
+		// mix present paths with enough absent ones to need several queries
+		var paths [][]byte
+		for i := 0; i < 1200; i++ {
+			paths = append(paths, testrand.Bytes(memory.B*32))
+		}
+		paths = append(paths, items[0].Path, items[3].Path)
+		paths = append([][]byte{items[1].Path, items[2].Path}, paths...)
+
+		found, err := geDB.GetTransferQueueItems(ctx, nodeID, paths)
+		require.NoError(t, err)
+		require.Len(t, found, 3)
+
+		for _, item := range items[:3] {
+			queueItem, ok := found[hex.EncodeToString(item.Path)]
+			require.True(t, ok)
+			require.Equal(t, nodeID, queueItem.NodeID)
+			require.Equal(t, item.Path, queueItem.Path)
+			require.Equal(t, item.PieceNum, queueItem.PieceNum)
+			require.Equal(t, item.DurabilityRatio, queueItem.DurabilityRatio)
+		}
+
+		found, err = geDB.GetTransferQueueItems(ctx, nodeID, nil)
+		require.NoError(t, err)
+		require.Len(t, found, 0)
+	})
+}
+
+func TestGetIncompleteExcludingExhausted(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+
+		failedCounts := []int{2, 0, 5, 1, 0}
+
+		var items []gracefulexit.TransferQueueItem
+		for i := range failedCounts {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.9,
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+
+		for i, failedCount := range failedCounts {
+			item := items[i]
+			item.RequestedAt = time.Now()
+			if failedCount > 0 {
+				item.LastFailedAt = time.Now()
+			}
+			item.FailedCount = failedCount
+			if i == len(failedCounts)-1 {
+				// finished items are never returned
+				item.FinishedAt = time.Now()
+			}
+			require.NoError(t, geDB.UpdateTransferQueueItem(ctx, item))
+		}
+
+		queueItems, err := geDB.GetIncompleteExcludingExhausted(ctx, nodeID, 3, 10)
+		require.NoError(t, err)
+		require.Len(t, queueItems, 3)
+		for i, expected := range []int{1, 3, 0} {
+			require.Equal(t, items[expected].Path, queueItems[i].Path)
+			require.Equal(t, failedCounts[expected], queueItems[i].FailedCount)
+		}
+
+		queueItems, err = geDB.GetIncompleteExcludingExhausted(ctx, nodeID, 3, 2)
+		require.NoError(t, err)
+		require.Len(t, queueItems, 2)
+		require.Equal(t, items[1].Path, queueItems[0].Path)
+		require.Equal(t, items[3].Path, queueItems[1].Path)
+
+		queueItems, err = geDB.GetIncompleteExcludingExhausted(ctx, nodeID, 10, 10)
+		require.NoError(t, err)
+		require.Len(t, queueItems, 4)
+		require.Equal(t, items[2].Path, queueItems[3].Path)
+	})
+}
+
+func TestUpdateTransferQueueItemsBatch(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		var items []gracefulexit.TransferQueueItem
+		for i := 0; i < 4; i++ {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.9,
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+
+		require.NoError(t, geDB.UpdateTransferQueueItemsBatch(ctx, nil))
+
+		now := time.Now().UTC()
+
+		requested := items[0]
+		requested.RequestedAt = now
+
+		failed := items[1]
+		failed.RequestedAt = now
+		failed.LastFailedAt = now.Add(time.Second)
+		failed.LastFailedCode = 2
+		failed.FailedCount = 1
+
+		finished := items[2]
+		finished.RequestedAt = now
+		finished.FinishedAt = now.Add(time.Second)
+
+		require.NoError(t, geDB.UpdateTransferQueueItemsBatch(ctx, []gracefulexit.TransferQueueItem{requested, failed, finished}))
+
+		stored, err := geDB.GetTransferQueueItem(ctx, nodeID, requested.Path)
+		require.NoError(t, err)
+		require.True(t, stored.RequestedAt.Equal(now))
+		require.True(t, stored.FinishedAt.IsZero())
+
+		stored, err = geDB.GetTransferQueueItem(ctx, nodeID, failed.Path)
+		require.NoError(t, err)
+		require.True(t, stored.LastFailedAt.Equal(failed.LastFailedAt))
+		require.Equal(t, 2, stored.LastFailedCode)
+		require.Equal(t, 1, stored.FailedCount)
+
+		stored, err = geDB.GetTransferQueueItem(ctx, nodeID, finished.Path)
+		require.NoError(t, err)
+		require.True(t, stored.FinishedAt.Equal(finished.FinishedAt))
+
+		// an invalid transition rolls back the whole batch
+		requestedAgain := items[3]
+		requestedAgain.RequestedAt = now
+
+		reopened := finished
+		reopened.FinishedAt = time.Time{}
+
+		err = geDB.UpdateTransferQueueItemsBatch(ctx, []gracefulexit.TransferQueueItem{requestedAgain, reopened})
+		require.True(t, gracefulexit.ErrInvalidTransition.Has(err), err)
+
+		stored, err = geDB.GetTransferQueueItem(ctx, nodeID, requestedAgain.Path)
+		require.NoError(t, err)
+		require.True(t, stored.RequestedAt.IsZero())
+
+		// so does a missing entry
+		missing := requestedAgain
+		missing.Path = testrand.Bytes(memory.B * 32)
+
+		err = geDB.UpdateTransferQueueItemsBatch(ctx, []gracefulexit.TransferQueueItem{requestedAgain, missing})
+		require.True(t, gracefulexit.ErrTransferQueueItemNotFound.Has(err), err)
+
+		stored, err = geDB.GetTransferQueueItem(ctx, nodeID, requestedAgain.Path)
+		require.NoError(t, err)
+		require.True(t, stored.RequestedAt.IsZero())
+	})
+}
+
+func TestGetFailedItems(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+
+		// the failed count and how many minutes after the request the last failure was
+		failures := []struct {
+			count    int
+			failedIn time.Duration
+		}{
+			{count: 2, failedIn: 3},
+			{count: 0},
+			{count: 1, failedIn: 1},
+			{count: 5, failedIn: 2},
+			{count: 1, failedIn: 4},
+			{count: 1, failedIn: 5},
+		}
+
+		var items []gracefulexit.TransferQueueItem
+		for i := range failures {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.9,
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+
+		requestedAt := time.Now().UTC()
+		for i, failure := range failures {
+			item := items[i]
+			item.RequestedAt = requestedAt
+			if failure.count > 0 {
+				item.LastFailedAt = requestedAt.Add(failure.failedIn * time.Minute)
+				item.LastFailedCode = 1
+			}
+			item.FailedCount = failure.count
+			if i == len(failures)-1 {
+				// finished items are never returned
+				item.FinishedAt = item.LastFailedAt
+			}
+			require.NoError(t, geDB.UpdateTransferQueueItem(ctx, item))
+		}
+
+		// items which never failed or failed too often are left out
+		failed, err := geDB.GetFailedItems(ctx, nodeID, 3, 10)
+		require.NoError(t, err)
+		require.Len(t, failed, 3)
+		for i, expected := range []int{2, 0, 4} {
+			require.Equal(t, items[expected].Path, failed[i].Path)
+			require.Equal(t, failures[expected].count, failed[i].FailedCount)
+		}
+
+		failed, err = geDB.GetFailedItems(ctx, nodeID, 3, 1)
+		require.NoError(t, err)
+		require.Len(t, failed, 1)
+		require.Equal(t, items[2].Path, failed[0].Path)
+
+		failed, err = geDB.GetFailedItems(ctx, nodeID, 10, 10)
+		require.NoError(t, err)
+		require.Len(t, failed, 4)
+		require.Equal(t, items[3].Path, failed[1].Path)
+
+		// items which were requested again after the failure are in flight
+		retried := items[2]
+		retried.RequestedAt = requestedAt.Add(10 * time.Minute)
+		retried.LastFailedAt = requestedAt.Add(failures[2].failedIn * time.Minute)
+		retried.LastFailedCode = 1
+		retried.FailedCount = failures[2].count
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, retried))
+
+		failed, err = geDB.GetFailedItems(ctx, nodeID, 3, 10)
+		require.NoError(t, err)
+		require.Len(t, failed, 2)
+		require.Equal(t, items[0].Path, failed[0].Path)
+		require.Equal(t, items[4].Path, failed[1].Path)
+
+		failed, err = geDB.GetFailedItems(ctx, testrand.NodeID(), 10, 10)
+		require.NoError(t, err)
+		require.Empty(t, failed)
+	})
+}
+
+func TestCountTransferQueueItems(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+
+		total, finished, failed, err := geDB.CountTransferQueueItems(ctx, nodeID)
+		require.NoError(t, err)
+		require.Zero(t, total)
+		require.Zero(t, finished)
+		require.Zero(t, failed)
+
+		var items []gracefulexit.TransferQueueItem
+		for i := 0; i < 5; i++ {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: 0.9,
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+
+		// entries of other nodes aren't counted
+		enqueue(ctx, t, geDB, []gracefulexit.TransferQueueItem{{
+			NodeID:          testrand.NodeID(),
+			Path:            testrand.Bytes(memory.B * 32),
+			DurabilityRatio: 0.9,
+		}})
+
+		now := time.Now().UTC()
+
+		// transferred
+		transferred := items[0]
+		transferred.RequestedAt = now
+		transferred.FinishedAt = now
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, transferred))
+
+		// given up after failing
+		givenUp := items[1]
+		givenUp.RequestedAt = now
+		givenUp.LastFailedAt = now.Add(time.Second)
+		givenUp.LastFailedCode = 1
+		givenUp.FailedCount = 1
+		givenUp.FinishedAt = now.Add(time.Second)
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, givenUp))
+
+		// failed, but still to be retried
+		retried := items[2]
+		retried.RequestedAt = now
+		retried.LastFailedAt = now.Add(time.Second)
+		retried.LastFailedCode = 1
+		retried.FailedCount = 1
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, retried))
+
+		total, finished, failed, err = geDB.CountTransferQueueItems(ctx, nodeID)
+		require.NoError(t, err)
+		require.EqualValues(t, 5, total)
+		require.EqualValues(t, 2, finished)
+		require.EqualValues(t, 1, failed)
+	})
+}
+
+func TestDurabilityHistogram(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		otherNodeID := testrand.NodeID()
+
+		var items []gracefulexit.TransferQueueItem
+		for i, durability := range []float64{0.1, 0.5, 0.5, 0.8, 1.0, 1.5, 2.0} {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: durability,
+			})
+		}
+		items = append(items, gracefulexit.TransferQueueItem{
+			NodeID:          otherNodeID,
+			Path:            testrand.Bytes(memory.B * 32),
+			DurabilityRatio: 0.1,
+		})
+		enqueue(ctx, t, geDB, items)
+
+		// finished items are not counted
+		finished, err := geDB.GetTransferQueueItem(ctx, nodeID, items[0].Path)
+		require.NoError(t, err)
+		finished.RequestedAt = time.Now()
+		finished.FinishedAt = time.Now()
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, *finished))
+
+		histogram, err := geDB.DurabilityHistogram(ctx, nodeID, []float64{1.0, 0.5})
+		require.NoError(t, err)
+		require.Equal(t, map[float64]int64{
+			0.5:         2,
+			1.0:         2,
+			math.Inf(1): 2,
+		}, histogram)
+
+		histogram, err = geDB.DurabilityHistogram(ctx, testrand.NodeID(), []float64{1.0})
+		require.NoError(t, err)
+		require.Equal(t, map[float64]int64{
+			1.0:         0,
+			math.Inf(1): 0,
+		}, histogram)
+	})
+}
+
+// enqueue enqueues all the items, which must be new.
+func enqueue(ctx context.Context, t *testing.T, geDB gracefulexit.DB, items []gracefulexit.TransferQueueItem) {
+	inserted, err := geDB.Enqueue(ctx, items)
+	require.NoError(t, err)
+	require.Equal(t, len(items), inserted)
+}
+
+func TestGetIncompleteByDurability(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		nodeID := testrand.NodeID()
+		durabilities := []float64{0.9, 0.3, 1.2, 0.6, 0.1}
+		var items []gracefulexit.TransferQueueItem
+		for i, durability := range durabilities {
+			items = append(items, gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				PieceNum:        int32(i),
+				DurabilityRatio: durability,
+			})
+		}
+		enqueue(ctx, t, geDB, items)
+
+		// the least durable entry is finished
+		finished := items[4]
+		finished.RequestedAt = time.Now().UTC()
+		finished.FinishedAt = time.Now().UTC()
+		require.NoError(t, geDB.UpdateTransferQueueItem(ctx, finished))
+
+		incomplete, err := geDB.GetIncompleteByDurability(ctx, nodeID, 10, 0)
+		require.NoError(t, err)
+		var got []float64
+		for _, item := range incomplete {
+			got = append(got, item.DurabilityRatio)
+		}
+		require.Equal(t, []float64{0.3, 0.6, 0.9, 1.2}, got)
+
+		// paging
+		incomplete, err = geDB.GetIncompleteByDurability(ctx, nodeID, 2, 2)
+		require.NoError(t, err)
+		require.Len(t, incomplete, 2)
+		require.Equal(t, 0.9, incomplete[0].DurabilityRatio)
+		require.Equal(t, 1.2, incomplete[1].DurabilityRatio)
+	})
+}
@@ -0,0 +1,209 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package gracefulexit
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+var (
+	// ErrExport is the error class for transfer queue exports.
+	ErrExport = errs.Class("graceful exit queue export error")
+	// ErrImport is the error class for transfer queue imports, it's also used for rejected rows.
+	ErrImport = errs.Class("graceful exit queue import error")
+)
+
+// ExportCSV is the comma separated values format with a header row, supported by DB.ExportQueue and DB.ImportQueue.
+const ExportCSV = "csv"
+
+// ExportHeader are the columns of the exported transfer queue:
+//
+//	path             - hex encoded path of the segment
+//	piece_num        - number of the piece in the segment
+//	durability_ratio - durability ratio of the segment
+//	queued_at        - when the entry was queued, formatted as RFC 3339
+//	requested_at     - when the entry was last requested, empty when never
+//	last_failed_at   - when the transfer last failed, empty when never
+//	last_failed_code - error code of the last failure
+//	failed_count     - number of failed transfers
+//	finished_at      - when the entry was finished, empty when incomplete
+var ExportHeader = []string{
+	"path", "piece_num", "durability_ratio", "queued_at", "requested_at",
+	"last_failed_at", "last_failed_code", "failed_count", "finished_at",
+}
+
+// QueueWriter writes exported transfer queue entries to the underlying writer as they are added.
+type QueueWriter interface {
+	// Write writes a single entry.
+	Write(item TransferQueueItem) error
+	// Close finishes the export, it doesn't close the underlying writer.
+	Close() error
+}
+
+// QueueReader reads exported transfer queue entries from the underlying reader.
+type QueueReader interface {
+	// Read reads the next entry, it returns io.EOF when there are no more entries.
+	// Invalid entries are rejected with ErrImport, the following entries can still be read.
+	Read() (TransferQueueItem, error)
+}
+
+// NewQueueWriter returns a QueueWriter for the format.
+func NewQueueWriter(w io.Writer, format string) (QueueWriter, error) {
+	switch format {
+	case ExportCSV:
+		writer := &csvQueueWriter{csv: csv.NewWriter(w)}
+		return writer, writer.writeHeader()
+	default:
+		return nil, ErrExport.New("unsupported format %q", format)
+	}
+}
+
+// NewQueueReader returns a QueueReader for the format.
+func NewQueueReader(r io.Reader, format string) (QueueReader, error) {
+	switch format {
+	case ExportCSV:
+		reader := &csvQueueReader{csv: csv.NewReader(r)}
+		return reader, reader.readHeader()
+	default:
+		return nil, ErrImport.New("unsupported format %q", format)
+	}
+}
+
+// csvQueueWriter writes entries as comma separated values.
+type csvQueueWriter struct {
+	csv *csv.Writer
+}
+
+func (writer *csvQueueWriter) writeHeader() error {
+	return ErrExport.Wrap(writer.csv.Write(ExportHeader))
+}
+
+// Write writes a single entry.
+func (writer *csvQueueWriter) Write(item TransferQueueItem) error {
+	return ErrExport.Wrap(writer.csv.Write([]string{
+		hex.EncodeToString(item.Path),
+		strconv.FormatInt(int64(item.PieceNum), 10),
+		strconv.FormatFloat(item.DurabilityRatio, 'g', -1, 64),
+		formatExportTime(item.QueuedAt),
+		formatExportTime(item.RequestedAt),
+		formatExportTime(item.LastFailedAt),
+		strconv.Itoa(item.LastFailedCode),
+		strconv.Itoa(item.FailedCount),
+		formatExportTime(item.FinishedAt),
+	}))
+}
+
+// Close flushes the buffered entries.
+func (writer *csvQueueWriter) Close() error {
+	writer.csv.Flush()
+	return ErrExport.Wrap(writer.csv.Error())
+}
+
+// csvQueueReader reads entries written by csvQueueWriter.
+type csvQueueReader struct {
+	csv *csv.Reader
+	row int
+}
+
+func (reader *csvQueueReader) readHeader() error {
+	header, err := reader.csv.Read()
+	if err != nil {
+		return ErrImport.New("invalid header: %v", err)
+	}
+	if len(header) != len(ExportHeader) {
+		return ErrImport.New("invalid header: expected %d columns, got %d", len(ExportHeader), len(header))
+	}
+	for i, column := range ExportHeader {
+		if header[i] != column {
+			return ErrImport.New("invalid header: expected column %q, got %q", column, header[i])
+		}
+	}
+	return nil
+}
+
+// Read reads the next entry.
+func (reader *csvQueueReader) Read() (item TransferQueueItem, err error) {
+	record, err := reader.csv.Read()
+	if err == io.EOF {
+		return item, err
+	}
+	reader.row++
+	if err != nil {
+		return item, ErrImport.New("row %d: %v", reader.row, err)
+	}
+
+	defer func() {
+		if err != nil {
+			err = ErrImport.New("row %d: %v", reader.row, err)
+		}
+	}()
+
+	item.Path, err = hex.DecodeString(record[0])
+	if err != nil {
+		return item, err
+	}
+	if len(item.Path) == 0 {
+		return item, errs.New("empty path")
+	}
+
+	pieceNum, err := strconv.ParseInt(record[1], 10, 32)
+	if err != nil {
+		return item, err
+	}
+	if pieceNum < 0 {
+		return item, errs.New("negative piece number %d", pieceNum)
+	}
+	item.PieceNum = int32(pieceNum)
+
+	item.DurabilityRatio, err = strconv.ParseFloat(record[2], 64)
+	if err != nil {
+		return item, err
+	}
+	if math.IsNaN(item.DurabilityRatio) || math.IsInf(item.DurabilityRatio, 0) || item.DurabilityRatio < 0 {
+		return item, errs.New("invalid durability ratio %s", record[2])
+	}
+
+	if item.QueuedAt, err = parseExportTime(record[3]); err != nil {
+		return item, err
+	}
+	if item.RequestedAt, err = parseExportTime(record[4]); err != nil {
+		return item, err
+	}
+	if item.LastFailedAt, err = parseExportTime(record[5]); err != nil {
+		return item, err
+	}
+	if item.LastFailedCode, err = strconv.Atoi(record[6]); err != nil {
+		return item, err
+	}
+	if item.FailedCount, err = strconv.Atoi(record[7]); err != nil {
+		return item, err
+	}
+	if item.FinishedAt, err = parseExportTime(record[8]); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// formatExportTime formats t as RFC 3339, the zero time is formatted as an empty string.
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// parseExportTime parses a time formatted by formatExportTime.
+func parseExportTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
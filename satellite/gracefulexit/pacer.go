@@ -0,0 +1,101 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package gracefulexit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gopkg.in/spacemonkeygo/monkit.v2"
+
+	"storj.io/storj/pkg/storj"
+)
+
+var mon = monkit.Package()
+
+// Pacer enqueues graceful exit transfer queue items, at most config.EnqueueLimit new items for a node
+// within config.EnqueueWindow, to pace the exit of small nodes.
+//
+// The enqueued items are counted in memory, so the limit applies to a single satellite process and starts
+// over when the process restarts. The items which were already queued don't count towards the limit.
+//
+// architecture: Service
+type Pacer struct {
+	db     DB
+	config Config
+
+	mu      sync.Mutex
+	windows map[storj.NodeID]*enqueueWindow
+}
+
+// enqueueWindow is the number of items enqueued for a node since the start of its window.
+type enqueueWindow struct {
+	start    time.Time
+	enqueued int
+}
+
+// NewPacer instantiates a pacer enqueueing into db.
+func NewPacer(db DB, config Config) *Pacer {
+	return &Pacer{
+		db:      db,
+		config:  config,
+		windows: make(map[storj.NodeID]*enqueueWindow),
+	}
+}
+
+// Enqueue enqueues the items which are within the enqueue limit of their node and returns the deferred
+// items, which exceed the limit and should be enqueued again later.
+func (pacer *Pacer) Enqueue(ctx context.Context, items []TransferQueueItem) (deferred []TransferQueueItem, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if pacer.config.EnqueueLimit <= 0 {
+		_, err := pacer.db.Enqueue(ctx, items)
+		return nil, err
+	}
+
+	// the lock is held while enqueueing, so that concurrent callers can't exceed the limit together
+	pacer.mu.Lock()
+	defer pacer.mu.Unlock()
+
+	now := time.Now()
+	for nodeID, window := range pacer.windows {
+		if now.Sub(window.start) >= pacer.config.EnqueueWindow {
+			delete(pacer.windows, nodeID)
+		}
+	}
+
+	accepted := make(map[storj.NodeID][]TransferQueueItem)
+	for _, item := range items {
+		window := pacer.window(item.NodeID, now)
+		if window.enqueued+len(accepted[item.NodeID]) >= pacer.config.EnqueueLimit {
+			deferred = append(deferred, item)
+			continue
+		}
+		accepted[item.NodeID] = append(accepted[item.NodeID], item)
+	}
+
+	for nodeID, nodeItems := range accepted {
+		inserted, err := pacer.db.Enqueue(ctx, nodeItems)
+		if err != nil {
+			return nil, err
+		}
+		pacer.windows[nodeID].enqueued += inserted
+	}
+
+	if len(deferred) > 0 {
+		mon.Meter("graceful_exit_enqueue_deferred").Mark(len(deferred))
+	}
+	return deferred, nil
+}
+
+// window returns the current enqueue window of a node, starting a new one when it has none.
+func (pacer *Pacer) window(nodeID storj.NodeID, now time.Time) *enqueueWindow {
+	window, ok := pacer.windows[nodeID]
+	if !ok {
+		window = &enqueueWindow{start: now}
+		pacer.windows[nodeID] = window
+	}
+	return window
+}
@@ -0,0 +1,83 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package gracefulexit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/internal/memory"
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/satellite"
+	"storj.io/storj/satellite/gracefulexit"
+	"storj.io/storj/satellite/satellitedb/satellitedbtest"
+)
+
+func TestPacer(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+		pacer := gracefulexit.NewPacer(geDB, gracefulexit.Config{
+			EnqueueLimit:  3,
+			EnqueueWindow: time.Hour,
+		})
+
+		smallNode, otherNode := testrand.NodeID(), testrand.NodeID()
+		newItem := func(nodeID storj.NodeID) gracefulexit.TransferQueueItem {
+			return gracefulexit.TransferQueueItem{
+				NodeID:          nodeID,
+				Path:            testrand.Bytes(memory.B * 32),
+				DurabilityRatio: 0.9,
+			}
+		}
+		queued := func(nodeID storj.NodeID) int64 {
+			count, err := geDB.EstimateQueueSize(ctx, nodeID)
+			require.NoError(t, err)
+			return count
+		}
+
+		items := []gracefulexit.TransferQueueItem{
+			newItem(smallNode), newItem(otherNode), newItem(smallNode),
+			newItem(otherNode), newItem(smallNode), newItem(smallNode),
+			newItem(otherNode),
+		}
+
+		// only the fourth item of the small node exceeds its limit
+		deferred, err := pacer.Enqueue(ctx, items)
+		require.NoError(t, err)
+		require.Equal(t, []gracefulexit.TransferQueueItem{items[5]}, deferred)
+		require.EqualValues(t, 3, queued(smallNode))
+		require.EqualValues(t, 3, queued(otherNode))
+
+		// the items enqueued within the window count towards the limit
+		deferred, err = pacer.Enqueue(ctx, deferred)
+		require.NoError(t, err)
+		require.Len(t, deferred, 1)
+
+		// the items which are already queued don't
+		duplicates := gracefulexit.NewPacer(geDB, gracefulexit.Config{
+			EnqueueLimit:  1,
+			EnqueueWindow: time.Hour,
+		})
+		deferred, err = duplicates.Enqueue(ctx, items[:1])
+		require.NoError(t, err)
+		require.Empty(t, deferred)
+		deferred, err = duplicates.Enqueue(ctx, items[5:6])
+		require.NoError(t, err)
+		require.Empty(t, deferred)
+		require.EqualValues(t, 4, queued(smallNode))
+
+		// without a limit all items are enqueued
+		unlimited := gracefulexit.NewPacer(geDB, gracefulexit.Config{})
+		deferred, err = unlimited.Enqueue(ctx, []gracefulexit.TransferQueueItem{newItem(smallNode), newItem(smallNode)})
+		require.NoError(t, err)
+		require.Empty(t, deferred)
+		require.EqualValues(t, 6, queued(smallNode))
+	})
+}
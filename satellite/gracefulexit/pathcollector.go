@@ -0,0 +1,129 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package gracefulexit
+
+import (
+	"context"
+
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/satellite/metainfo"
+)
+
+var _ metainfo.Observer = (*PathCollector)(nil)
+
+// PathCollector uses the metainfo loop to add the pieces of exiting nodes to the transfer queue.
+//
+// The pieces are enqueued in batches of batchSize through the pacer, so the memory use doesn't grow with
+// the number of pieces. The transfer queue entries are unique per node and path, so the pieces which were
+// enqueued before are skipped and building the queue can be resumed by running the collector again, e.g.
+// after the loop was interrupted or the pacer deferred some of the pieces.
+//
+// architecture: Observer
+type PathCollector struct {
+	pacer     *Pacer
+	nodeIDs   map[storj.NodeID]struct{}
+	batchSize int
+	buffer    []TransferQueueItem
+	enqueued  int
+	deferred  int
+}
+
+// NewPathCollector instantiates a path collector for the exiting nodes.
+func NewPathCollector(pacer *Pacer, nodeIDs []storj.NodeID, batchSize int) *PathCollector {
+	collector := &PathCollector{
+		pacer:     pacer,
+		nodeIDs:   make(map[storj.NodeID]struct{}, len(nodeIDs)),
+		batchSize: batchSize,
+	}
+	for _, nodeID := range nodeIDs {
+		collector.nodeIDs[nodeID] = struct{}{}
+	}
+	return collector
+}
+
+// BuildQueue joins the metainfo loop for one full cycle to add the pieces of the exiting nodes to the
+// transfer queue and returns the number of accepted pieces, see PathCollector.
+func BuildQueue(ctx context.Context, loop *metainfo.Loop, pacer *Pacer, nodeIDs []storj.NodeID, batchSize int) (enqueued int, err error) {
+	collector := NewPathCollector(pacer, nodeIDs, batchSize)
+	if err := loop.Join(ctx, collector); err != nil {
+		return 0, err
+	}
+	if err := collector.Flush(ctx); err != nil {
+		return 0, err
+	}
+	return collector.Enqueued(), nil
+}
+
+// RemoteSegment takes a remote segment found in metainfo and adds the pieces of the exiting nodes to the buffer,
+// which is enqueued when it's full.
+func (collector *PathCollector) RemoteSegment(ctx context.Context, path metainfo.ScopedPath, pointer *pb.Pointer) (err error) {
+	remote := pointer.GetRemote()
+	pieces := remote.GetRemotePieces()
+
+	for _, piece := range pieces {
+		if _, ok := collector.nodeIDs[piece.NodeId]; !ok {
+			continue
+		}
+		collector.buffer = append(collector.buffer, TransferQueueItem{
+			NodeID:          piece.NodeId,
+			Path:            []byte(path.Raw),
+			PieceNum:        piece.PieceNum,
+			DurabilityRatio: durabilityRatio(remote.GetRedundancy(), len(pieces)),
+		})
+		if len(collector.buffer) >= collector.batchSize {
+			if err := collector.Flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Object returns nil because graceful exit only transfers segments.
+func (collector *PathCollector) Object(ctx context.Context, path metainfo.ScopedPath, pointer *pb.Pointer) (err error) {
+	return nil
+}
+
+// InlineSegment returns nil because inline segments aren't stored on the nodes.
+func (collector *PathCollector) InlineSegment(ctx context.Context, path metainfo.ScopedPath, pointer *pb.Pointer) (err error) {
+	return nil
+}
+
+// Flush enqueues the buffered pieces. The pieces deferred by the pacer are dropped from the buffer,
+// they are enqueued by running the collector again.
+func (collector *PathCollector) Flush(ctx context.Context) (err error) {
+	if len(collector.buffer) == 0 {
+		return nil
+	}
+	deferred, err := collector.pacer.Enqueue(ctx, collector.buffer)
+	if err != nil {
+		return err
+	}
+	collector.enqueued += len(collector.buffer) - len(deferred)
+	collector.deferred += len(deferred)
+	collector.buffer = collector.buffer[:0]
+	return nil
+}
+
+// Enqueued returns the number of pieces accepted by the transfer queue so far,
+// including the ones which were already queued.
+func (collector *PathCollector) Enqueued() int {
+	return collector.enqueued
+}
+
+// Deferred returns the number of pieces deferred by the pacer so far.
+func (collector *PathCollector) Deferred() int {
+	return collector.deferred
+}
+
+// durabilityRatio returns the number of pieces of a segment relative to its success threshold.
+// It's a placeholder until the segment health is known, ordering the queue by how endangered the segments are.
+func durabilityRatio(redundancy *pb.RedundancyScheme, pieces int) float64 {
+	threshold := redundancy.GetSuccessThreshold()
+	if threshold <= 0 {
+		return 0
+	}
+	return float64(pieces) / float64(threshold)
+}
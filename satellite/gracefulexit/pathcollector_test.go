@@ -0,0 +1,104 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package gracefulexit_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/satellite"
+	"storj.io/storj/satellite/gracefulexit"
+	"storj.io/storj/satellite/metainfo"
+	"storj.io/storj/satellite/satellitedb/satellitedbtest"
+)
+
+func TestPathCollector(t *testing.T) {
+	satellitedbtest.Run(t, func(t *testing.T, db satellite.DB) {
+		ctx := testcontext.New(t)
+
+		geDB := db.GracefulExit()
+
+		exiting := testrand.NodeID()
+		otherExiting := testrand.NodeID()
+		staying := testrand.NodeID()
+
+		type segment struct {
+			path    metainfo.ScopedPath
+			pointer *pb.Pointer
+		}
+		var segments []segment
+		var expected []string
+		for i := 0; i < 5; i++ {
+			pieces := []*pb.RemotePiece{{NodeId: staying, PieceNum: 0}}
+			if i%2 == 0 {
+				pieces = append(pieces, &pb.RemotePiece{NodeId: exiting, PieceNum: int32(i)})
+				expected = append(expected, fmt.Sprintf("path%d/%d", i, i))
+			}
+			if i == 4 {
+				// the other exiting node replaces the staying one, its piece follows the one of the exiting node
+				pieces = append(pieces[1:], &pb.RemotePiece{NodeId: otherExiting, PieceNum: 5})
+			}
+			segments = append(segments, segment{
+				path: metainfo.ScopedPath{Raw: fmt.Sprintf("path%d", i)},
+				pointer: &pb.Pointer{
+					Type: pb.Pointer_REMOTE,
+					Remote: &pb.RemoteSegment{
+						Redundancy:   &pb.RedundancyScheme{SuccessThreshold: 4},
+						RemotePieces: pieces,
+					},
+				},
+			})
+		}
+
+		collect := func(config gracefulexit.Config, batchSize int) (enqueued, deferred int) {
+			pacer := gracefulexit.NewPacer(geDB, config)
+			collector := gracefulexit.NewPathCollector(pacer, []storj.NodeID{exiting, otherExiting}, batchSize)
+			for _, segment := range segments {
+				require.NoError(t, collector.RemoteSegment(ctx, segment.path, segment.pointer))
+				require.NoError(t, collector.InlineSegment(ctx, segment.path, &pb.Pointer{Type: pb.Pointer_INLINE}))
+			}
+			require.NoError(t, collector.Flush(ctx))
+			return collector.Enqueued(), collector.Deferred()
+		}
+
+		queued := func(nodeID storj.NodeID) []string {
+			items, err := geDB.GetIncomplete(ctx, nodeID, 10, 0)
+			require.NoError(t, err)
+
+			var queued []string
+			for _, item := range items {
+				require.Equal(t, 0.5, item.DurabilityRatio)
+				queued = append(queued, fmt.Sprintf("%s/%d", item.Path, item.PieceNum))
+			}
+			sort.Strings(queued)
+			return queued
+		}
+
+		// the enqueue limit defers the pieces which don't fit, but not the pieces of other nodes after them
+		enqueued, deferred := collect(gracefulexit.Config{EnqueueLimit: 2, EnqueueWindow: time.Hour}, 10)
+		require.Equal(t, 3, enqueued)
+		require.Equal(t, 1, deferred)
+		require.Equal(t, expected[:2], queued(exiting))
+		require.Equal(t, []string{"path4/5"}, queued(otherExiting))
+
+		// collecting again adds the missing pieces only
+		enqueued, deferred = collect(gracefulexit.Config{}, 2)
+		require.Equal(t, len(expected)+1, enqueued)
+		require.Zero(t, deferred)
+		require.Equal(t, expected, queued(exiting))
+		require.Empty(t, queued(staying))
+
+		total, _, _, err := geDB.CountTransferQueueItems(ctx, exiting)
+		require.NoError(t, err)
+		require.EqualValues(t, len(expected), total)
+	})
+}
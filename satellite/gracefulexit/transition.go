@@ -0,0 +1,51 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package gracefulexit
+
+import (
+	"github.com/zeebo/errs"
+)
+
+// ErrInvalidTransition is returned when an update would move a graceful exit transfer queue entry into an invalid state.
+var ErrInvalidTransition = errs.Class("invalid graceful exit transfer queue item transition")
+
+// ValidateTransition checks whether a graceful exit transfer queue entry may be updated from current to next.
+//
+// An entry is queued when it's enqueued, requested when the transfer is sent to the exiting node,
+// and finished when the transfer succeeded or was given up. A requested entry can fail any number
+// of times before it's finished and it can be requested again after a failure. The allowed
+// transitions are:
+//
+//	queued    -> requested, finished
+//	requested -> requested, failed, finished
+//	failed    -> requested, failed, finished
+//
+// where an entry can only fail or finish when it has been requested. A finished entry can't be
+// updated anymore, it has to be requeued with RequeueTransferItem instead. Zero timestamps of
+// next leave the timestamps unchanged, so they can't be cleared. The failure count never
+// decreases and every failure has to set the time of the last failure.
+func ValidateTransition(current, next TransferQueueItem) error {
+	if !current.FinishedAt.IsZero() {
+		return ErrInvalidTransition.New("item is already finished")
+	}
+
+	requested := !current.RequestedAt.IsZero() || !next.RequestedAt.IsZero()
+
+	if next.FailedCount < current.FailedCount {
+		return ErrInvalidTransition.New("failed count decreased from %d to %d", current.FailedCount, next.FailedCount)
+	}
+	failed := next.FailedCount > current.FailedCount || !next.LastFailedAt.IsZero() || next.LastFailedCode != current.LastFailedCode
+	if failed && !requested {
+		return ErrInvalidTransition.New("item failed without being requested")
+	}
+	if next.FailedCount > current.FailedCount && next.LastFailedAt.IsZero() {
+		return ErrInvalidTransition.New("failed count increased without last failed date")
+	}
+
+	if !next.FinishedAt.IsZero() && !requested {
+		return ErrInvalidTransition.New("item finished without being requested")
+	}
+
+	return nil
+}
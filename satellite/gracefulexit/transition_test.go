@@ -0,0 +1,69 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package gracefulexit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/satellite/gracefulexit"
+)
+
+func TestValidateTransition(t *testing.T) {
+	now := time.Now()
+
+	queued := gracefulexit.TransferQueueItem{QueuedAt: now}
+
+	requested := queued
+	requested.RequestedAt = now
+
+	failed := requested
+	failed.LastFailedAt = now
+	failed.LastFailedCode = 1
+	failed.FailedCount = 1
+
+	finished := requested
+	finished.FinishedAt = now
+
+	failedAgain := failed
+	failedAgain.FailedCount = 2
+
+	decreased := failed
+	decreased.FailedCount = 0
+
+	countOnly := requested
+	countOnly.FailedCount = 1
+
+	for _, tt := range []struct {
+		name    string
+		current gracefulexit.TransferQueueItem
+		next    gracefulexit.TransferQueueItem
+		valid   bool
+	}{
+		{"queued to requested", queued, requested, true},
+		{"queued to finished", queued, finished, true},
+		{"queued to queued", queued, queued, true},
+		{"requested to failed", requested, failed, true},
+		{"requested to finished", requested, finished, true},
+		{"failed to failed", failed, failedAgain, true},
+		{"failed to requested", failed, failed, true},
+
+		{"queued to failed", queued, gracefulexit.TransferQueueItem{LastFailedAt: now, FailedCount: 1}, false},
+		{"queued to finished without request", queued, gracefulexit.TransferQueueItem{FinishedAt: now}, false},
+		{"failed count decreased", failed, decreased, false},
+		{"failed count without date", requested, countOnly, false},
+		{"finished to requested", finished, requested, false},
+		{"finished to failed", finished, failed, false},
+	} {
+		err := gracefulexit.ValidateTransition(tt.current, tt.next)
+		if tt.valid {
+			require.NoError(t, err, tt.name)
+		} else {
+			require.Error(t, err, tt.name)
+			require.True(t, gracefulexit.ErrInvalidTransition.Has(err), tt.name)
+		}
+	}
+}
@@ -50,6 +50,7 @@ var (
 // architecture: Database
 type APIKeys interface {
 	GetByHead(ctx context.Context, head []byte) (*console.APIKeyInfo, error)
+	UpdateLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
 }
 
 // Revocations is the revocations store methods used by the endpoint
@@ -774,7 +775,7 @@ func (endpoint *Endpoint) ListBuckets(ctx context.Context, req *pb.BucketListReq
 		return nil, rpcstatus.Error(rpcstatus.Unauthenticated, err.Error())
 	}
 
-	allowedBuckets, err := getAllowedBuckets(ctx, req.Header, action)
+	allowedBuckets, err := getAllowedBuckets(ctx, req.Header, keyInfo, action)
 	if err != nil {
 		return nil, err
 	}
@@ -803,11 +804,15 @@ func (endpoint *Endpoint) ListBuckets(ctx context.Context, req *pb.BucketListReq
 	}, nil
 }
 
-func getAllowedBuckets(ctx context.Context, header *pb.RequestHeader, action macaroon.Action) (_ macaroon.AllowedBuckets, err error) {
+func getAllowedBuckets(ctx context.Context, header *pb.RequestHeader, keyInfo *console.APIKeyInfo, action macaroon.Action) (_ macaroon.AllowedBuckets, err error) {
 	key, err := getAPIKey(ctx, header)
 	if err != nil {
 		return macaroon.AllowedBuckets{}, rpcstatus.Errorf(rpcstatus.InvalidArgument, "Invalid API credentials: %v", err)
 	}
+	key, err = restrictAPIKey(key, keyInfo)
+	if err != nil {
+		return macaroon.AllowedBuckets{}, rpcstatus.Errorf(rpcstatus.InvalidArgument, "Invalid API credentials: %v", err)
+	}
 	allowedBuckets, err := key.GetAllowedBuckets(ctx, action)
 	if err != nil {
 		return macaroon.AllowedBuckets{}, rpcstatus.Errorf(rpcstatus.Internal, "GetAllowedBuckets: %v", err)
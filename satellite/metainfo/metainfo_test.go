@@ -28,6 +28,7 @@ import (
 	"storj.io/storj/pkg/signing"
 	"storj.io/storj/pkg/storj"
 	"storj.io/storj/satellite"
+	"storj.io/storj/satellite/console"
 	"storj.io/storj/uplink/eestream"
 	"storj.io/storj/uplink/metainfo"
 )
@@ -188,6 +189,46 @@ func TestRestrictedAPIKey(t *testing.T) {
 	}
 }
 
+func TestStoredCaveatAPIKey(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		satellite := planet.Satellites[0]
+
+		secret := testrand.BytesInt(32)
+		key, err := macaroon.NewAPIKey(secret)
+		require.NoError(t, err)
+
+		caveats, err := proto.Marshal(&macaroon.Caveat{
+			DisallowWrites:  true,
+			DisallowDeletes: true,
+		})
+		require.NoError(t, err)
+
+		_, err = satellite.DB.Console().APIKeys().Create(ctx, key.Head(), console.APIKeyInfo{
+			Name:      "read only",
+			ProjectID: planet.Uplinks[0].ProjectID[satellite.ID()],
+			Secret:    secret,
+			Caveats:   caveats,
+		})
+		require.NoError(t, err)
+
+		client, err := planet.Uplinks[0].DialMetainfo(ctx, satellite, key)
+		require.NoError(t, err)
+		defer ctx.Check(client.Close)
+
+		// the stored caveat applies although the key itself is unrestricted
+		_, _, _, err = client.CreateSegment(ctx, "testbucket", "testpath", 1, &pb.RedundancyScheme{}, 123, time.Now().Add(time.Hour))
+		assertUnauthenticated(t, err, false)
+
+		_, _, err = client.DeleteSegment(ctx, "testbucket", "testpath", 0)
+		assertUnauthenticated(t, err, false)
+
+		_, _, _, err = client.ReadSegment(ctx, "testbucket", "testpath", 0)
+		assert.False(t, errs2.IsRPC(err, rpcstatus.Unauthenticated))
+	})
+}
+
 func assertUnauthenticated(t *testing.T, err error, allowed bool) {
 	t.Helper()
 
@@ -120,6 +120,20 @@ func (requests *createRequests) cleanup() {
 	}
 }
 
+// restrictAPIKey attaches the caveat stored with the api key to the key, so that it's checked along
+// with the caveats of the key itself.
+func restrictAPIKey(key *macaroon.APIKey, keyInfo *console.APIKeyInfo) (_ *macaroon.APIKey, err error) {
+	if len(keyInfo.Caveats) == 0 {
+		return key, nil
+	}
+
+	var caveat macaroon.Caveat
+	if err := proto.Unmarshal(keyInfo.Caveats, &caveat); err != nil {
+		return nil, macaroon.ErrFormat.New("invalid stored caveat: %v", err)
+	}
+	return key.Restrict(caveat)
+}
+
 func getAPIKey(ctx context.Context, header *pb.RequestHeader) (key *macaroon.APIKey, err error) {
 	defer mon.Task()(&ctx)(&err)
 	if header != nil {
@@ -149,6 +163,17 @@ func (endpoint *Endpoint) validateAuth(ctx context.Context, header *pb.RequestHe
 		return nil, rpcstatus.Error(rpcstatus.PermissionDenied, "Unauthorized API credentials")
 	}
 
+	if keyInfo.IsExpired(time.Now()) {
+		endpoint.log.Debug("unauthorized request", zap.Error(errs.New("api key expired")))
+		return nil, rpcstatus.Error(rpcstatus.PermissionDenied, "Unauthorized API credentials")
+	}
+
+	key, err = restrictAPIKey(key, keyInfo)
+	if err != nil {
+		endpoint.log.Debug("unauthorized request", zap.Error(err))
+		return nil, rpcstatus.Error(rpcstatus.PermissionDenied, "Unauthorized API credentials")
+	}
+
 	// Revocations are currently handled by just deleting the key.
 	err = key.Check(ctx, keyInfo.Secret, action, nil)
 	if err != nil {
@@ -156,6 +181,13 @@ func (endpoint *Endpoint) validateAuth(ctx context.Context, header *pb.RequestHe
 		return nil, rpcstatus.Error(rpcstatus.PermissionDenied, "Unauthorized API credentials")
 	}
 
+	// the last use is only recorded once per resolution, so that busy keys don't cause a write for every request
+	if now := time.Now(); keyInfo.NeedsLastUsedUpdate(now) {
+		if err := endpoint.apiKeys.UpdateLastUsed(ctx, keyInfo.ID, now); err != nil {
+			endpoint.log.Warn("unable to update api key last use", zap.Error(err))
+		}
+	}
+
 	return keyInfo, nil
 }
 
@@ -70,6 +70,8 @@ type DB interface {
 	UpdateUptime(ctx context.Context, nodeID storj.NodeID, isUp bool, lambda, weight, uptimeDQ float64) (stats *NodeStats, err error)
 	// UpdateCheckIn updates a single storagenode's check-in stats.
 	UpdateCheckIn(ctx context.Context, node NodeCheckInInfo, config NodeSelectionConfig) (err error)
+	// UpdateExitLoopCompleted records when all the pieces of an exiting node were added to the graceful exit transfer queue.
+	UpdateExitLoopCompleted(ctx context.Context, nodeID storj.NodeID, completedAt time.Time) (err error)
 
 	// AllPieceCounts returns a map of node IDs to piece counts from the db.
 	AllPieceCounts(ctx context.Context) (pieceCounts map[storj.NodeID]int, err error)
@@ -497,7 +497,7 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB exten
 
 	{ // setup db cleanup
 		log.Debug("Setting up db cleanup")
-		peer.DBCleanup.Chore = dbcleanup.NewChore(peer.Log.Named("dbcleanup"), peer.DB.Orders(), config.DBCleanup)
+		peer.DBCleanup.Chore = dbcleanup.NewChore(peer.Log.Named("dbcleanup"), peer.DB.Orders(), peer.DB.GracefulExit(), peer.DB.Console().APIKeys(), config.DBCleanup)
 	}
 
 	{ // setup accounting
@@ -5,7 +5,9 @@ package satellitedb
 
 import (
 	"context"
+	"database/sql"
 	"strings"
+	"time"
 
 	"github.com/skyrings/skyring-common/tools/uuid"
 	"github.com/zeebo/errs"
@@ -58,14 +60,25 @@ func (keys *apikeys) GetPagedByProjectID(ctx context.Context, projectID uuid.UUI
 		return nil, err
 	}
 	if page.TotalCount == 0 {
+		page.Offset = 0
 		return page, nil
 	}
-	if page.Offset > page.TotalCount-1 {
-		return nil, errs.New("page is out of range")
+
+	page.PageCount = uint(page.TotalCount / uint64(cursor.Limit))
+	if page.TotalCount%uint64(cursor.Limit) != 0 {
+		page.PageCount++
+	}
+
+	// keys may have been deleted since the previous page was requested,
+	// in that case return the last page instead of an empty one
+	page.CurrentPage = cursor.Page
+	if page.CurrentPage > page.PageCount {
+		page.CurrentPage = page.PageCount
+		page.Offset = uint64((page.CurrentPage - 1) * cursor.Limit)
 	}
 
 	repoundQuery := keys.db.Rebind(`
-		SELECT ak.id, ak.project_id, ak.name, ak.partner_id, ak.created_at 
+		SELECT ak.id, ak.project_id, ak.name, ak.partner_id, ak.created_at, ak.expires_at, ak.last_used_at
 		FROM api_keys ak
 		WHERE ak.project_id = ?
 		AND ak.name LIKE ?
@@ -94,7 +107,7 @@ func (keys *apikeys) GetPagedByProjectID(ctx context.Context, projectID uuid.UUI
 		var partnerIDBytes []uint8
 		var partnerID uuid.UUID
 
-		err = rows.Scan(&uuidScan{&ak.ID}, &uuidScan{&ak.ProjectID}, &ak.Name, &partnerIDBytes, &ak.CreatedAt)
+		err = rows.Scan(&uuidScan{&ak.ID}, &uuidScan{&ak.ProjectID}, &ak.Name, &partnerIDBytes, &ak.CreatedAt, &ak.ExpiresAt, &ak.LastUsedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -114,19 +127,89 @@ func (keys *apikeys) GetPagedByProjectID(ctx context.Context, projectID uuid.UUI
 	page.APIKeys = apiKeys
 	page.Order = cursor.Order
 
-	page.PageCount = uint(page.TotalCount / uint64(cursor.Limit))
-	if page.TotalCount%uint64(cursor.Limit) != 0 {
-		page.PageCount++
+	err = rows.Err()
+	if err != nil {
+		return nil, err
 	}
 
-	page.CurrentPage = cursor.Page
+	return page, err
+}
 
-	err = rows.Err()
+// streamBatchSize is how many api keys StreamByProjectID reads at once.
+const streamBatchSize = 100
+
+// StreamByProjectID implements satellite.APIKeys
+// The keys are read in batches, continuing after the last key of the previous batch, and fn is called
+// after the rows of a batch are closed, so that it can use the database.
+func (keys *apikeys) StreamByProjectID(ctx context.Context, projectID uuid.UUID, fn func(console.APIKeyInfo) error) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var last *console.APIKeyInfo
+	for {
+		batch, err := keys.streamBatch(ctx, projectID, last)
+		if err != nil {
+			return err
+		}
+
+		for _, info := range batch {
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < streamBatchSize {
+			return nil
+		}
+		last = &batch[len(batch)-1]
+	}
+}
+
+// streamBatch reads the next batch of the api keys of the project after the key last, ordered by
+// creation date and id, from the start when last is nil.
+func (keys *apikeys) streamBatch(ctx context.Context, projectID uuid.UUID, last *console.APIKeyInfo) (_ []console.APIKeyInfo, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var after time.Time
+	var afterID []byte
+	if last != nil {
+		after, afterID = last.CreatedAt, last.ID[:]
+	}
+
+	rows, err := keys.db.QueryContext(ctx, keys.db.Rebind(`
+		SELECT ak.id, ak.project_id, ak.name, ak.partner_id, ak.created_at, ak.expires_at, ak.last_used_at
+		FROM api_keys ak
+		WHERE ak.project_id = ?
+			AND (? OR ak.created_at > ? OR (ak.created_at = ? AND ak.id > ?))
+		ORDER BY ak.created_at, ak.id
+		LIMIT ?`), projectID[:], last == nil, after, after, afterID, streamBatchSize)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		err = errs.Combine(err, rows.Close())
+	}()
 
-	return page, err
+	var batch []console.APIKeyInfo
+	for rows.Next() {
+		ak := console.APIKeyInfo{}
+		var partnerIDBytes []uint8
+
+		err = rows.Scan(&uuidScan{&ak.ID}, &uuidScan{&ak.ProjectID}, &ak.Name, &partnerIDBytes, &ak.CreatedAt, &ak.ExpiresAt, &ak.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if partnerIDBytes != nil {
+			ak.PartnerID, err = bytesToUUID(partnerIDBytes)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		batch = append(batch, ak)
+	}
+
+	return batch, rows.Err()
 }
 
 // Get implements satellite.APIKeys
@@ -151,6 +234,71 @@ func (keys *apikeys) GetByHead(ctx context.Context, head []byte) (_ *console.API
 	return fromDBXAPIKey(ctx, dbKey)
 }
 
+// GetByHeadPrefix implements satellite.APIKeys
+func (keys *apikeys) GetByHeadPrefix(ctx context.Context, prefix []byte) (_ []console.APIKeyInfo, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if len(prefix) == 0 {
+		return nil, errs.New("head prefix cannot be empty")
+	}
+
+	// the heads starting with prefix are the ones in the range [prefix, upper),
+	// which is open ended when the prefix consists of 0xff bytes only
+	query := `
+		SELECT ak.id, ak.project_id, ak.name, ak.partner_id, ak.created_at, ak.expires_at, ak.last_used_at
+		FROM api_keys ak
+		WHERE ak.head >= ?`
+	args := []interface{}{prefix}
+	if upper := headPrefixUpperBound(prefix); upper != nil {
+		query += ` AND ak.head < ?`
+		args = append(args, upper)
+	}
+	query += ` ORDER BY ak.head`
+
+	rows, err := keys.db.QueryContext(ctx, keys.db.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = errs.Combine(err, rows.Close())
+	}()
+
+	var apiKeys []console.APIKeyInfo
+	for rows.Next() {
+		ak := console.APIKeyInfo{}
+		var partnerIDBytes []uint8
+
+		err = rows.Scan(&uuidScan{&ak.ID}, &uuidScan{&ak.ProjectID}, &ak.Name, &partnerIDBytes, &ak.CreatedAt, &ak.ExpiresAt, &ak.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if partnerIDBytes != nil {
+			ak.PartnerID, err = bytesToUUID(partnerIDBytes)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		apiKeys = append(apiKeys, ak)
+	}
+
+	return apiKeys, rows.Err()
+}
+
+// headPrefixUpperBound returns the smallest byte string greater than all the strings starting with prefix,
+// or nil when there is none.
+func headPrefixUpperBound(prefix []byte) []byte {
+	upper := append([]byte{}, prefix...)
+	for i := len(upper) - 1; i >= 0; i-- {
+		if upper[i] < 0xff {
+			upper[i]++
+			return upper[:i+1]
+		}
+	}
+	return nil
+}
+
 // Create implements satellite.APIKeys
 func (keys *apikeys) Create(ctx context.Context, head []byte, info console.APIKeyInfo) (_ *console.APIKeyInfo, err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -163,6 +311,12 @@ func (keys *apikeys) Create(ctx context.Context, head []byte, info console.APIKe
 	if !info.PartnerID.IsZero() {
 		optional.PartnerId = dbx.ApiKey_PartnerId(info.PartnerID[:])
 	}
+	if info.ExpiresAt != nil {
+		optional.ExpiresAt = dbx.ApiKey_ExpiresAt(info.ExpiresAt.UTC())
+	}
+	if len(info.Caveats) > 0 {
+		optional.Caveats = dbx.ApiKey_Caveats(info.Caveats)
+	}
 
 	dbKey, err := keys.methods.Create_ApiKey(
 		ctx,
@@ -184,15 +338,58 @@ func (keys *apikeys) Create(ctx context.Context, head []byte, info console.APIKe
 // Update implements satellite.APIKeys
 func (keys *apikeys) Update(ctx context.Context, key console.APIKeyInfo) (err error) {
 	defer mon.Task()(&ctx)(&err)
+
+	var update dbx.ApiKey_Update_Fields
+	if key.Name != "" {
+		update.Name = dbx.ApiKey_Name(key.Name)
+	}
+	if key.ExpiresAt != nil {
+		update.ExpiresAt = dbx.ApiKey_ExpiresAt(key.ExpiresAt.UTC())
+	}
+
 	return keys.methods.UpdateNoReturn_ApiKey_By_Id(
 		ctx,
 		dbx.ApiKey_Id(key.ID[:]),
-		dbx.ApiKey_Update_Fields{
-			Name: dbx.ApiKey_Name(key.Name),
-		},
+		update,
 	)
 }
 
+// UpdateLastUsed implements satellite.APIKeys
+func (keys *apikeys) UpdateLastUsed(ctx context.Context, id uuid.UUID, at time.Time) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	at = at.UTC()
+	_, err = keys.db.ExecContext(ctx, keys.db.Rebind(`
+		UPDATE api_keys
+		SET last_used_at = ?
+		WHERE id = ? AND (last_used_at IS NULL OR last_used_at <= ?)`),
+		at, id[:], at.Add(-console.APIKeyLastUsedResolution))
+	return err
+}
+
+// RotateSecret implements satellite.APIKeys
+func (keys *apikeys) RotateSecret(ctx context.Context, id uuid.UUID, newHead []byte, newSecret []byte) (_ *console.APIKeyInfo, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := keys.db.ExecContext(ctx, keys.db.Rebind(`
+		UPDATE api_keys
+		SET head = ?, secret = ?
+		WHERE id = ?`), newHead, newSecret, id[:])
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, errs.New("api key %s not found", id)
+	}
+
+	return keys.Get(ctx, id)
+}
+
 // Delete implements satellite.APIKeys
 func (keys *apikeys) Delete(ctx context.Context, id uuid.UUID) (err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -200,6 +397,55 @@ func (keys *apikeys) Delete(ctx context.Context, id uuid.UUID) (err error) {
 	return err
 }
 
+// DeleteAllByProjectID implements satellite.APIKeys
+func (keys *apikeys) DeleteAllByProjectID(ctx context.Context, projectID uuid.UUID) (_ int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := keys.db.ExecContext(ctx, keys.db.Rebind(`
+		DELETE FROM api_keys
+		WHERE project_id = ?`), projectID[:])
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteExpired implements satellite.APIKeys
+func (keys *apikeys) DeleteExpired(ctx context.Context, asOf time.Time) (_ int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := keys.db.ExecContext(ctx, keys.db.Rebind(`
+		DELETE FROM api_keys
+		WHERE expires_at IS NOT NULL AND expires_at <= ?`), asOf.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// ProjectStats implements satellite.APIKeys
+func (keys *apikeys) ProjectStats(ctx context.Context, projectID uuid.UUID) (total int, lastCreated *time.Time, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var createdAt time.Time
+	err = keys.db.QueryRowContext(ctx, keys.db.Rebind(`
+		SELECT COUNT(*) OVER (), ak.created_at
+		FROM api_keys ak
+		WHERE ak.project_id = ?
+		ORDER BY ak.created_at DESC
+		LIMIT 1`), projectID[:]).Scan(&total, &createdAt)
+	if err == sql.ErrNoRows {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return total, &createdAt, nil
+}
+
 // fromDBXAPIKey converts dbx.ApiKey to satellite.APIKeyInfo
 func fromDBXAPIKey(ctx context.Context, key *dbx.ApiKey) (_ *console.APIKeyInfo, err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -214,11 +460,14 @@ func fromDBXAPIKey(ctx context.Context, key *dbx.ApiKey) (_ *console.APIKeyInfo,
 	}
 
 	result := &console.APIKeyInfo{
-		ID:        id,
-		ProjectID: projectID,
-		Name:      key.Name,
-		CreatedAt: key.CreatedAt,
-		Secret:    key.Secret,
+		ID:         id,
+		ProjectID:  projectID,
+		Name:       key.Name,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		Secret:     key.Secret,
+		Caveats:    key.Caveats,
 	}
 
 	if key.PartnerId != nil {
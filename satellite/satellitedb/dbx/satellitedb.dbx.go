@@ -348,6 +348,13 @@ CREATE TABLE graceful_exit_transfer_queue (
 	finished_at timestamp,
 	PRIMARY KEY ( node_id, path )
 );
+CREATE TABLE graceful_exit_transferred (
+	node_id bytea NOT NULL,
+	path bytea NOT NULL,
+	bytes bigint NOT NULL,
+	transferred_at timestamp NOT NULL,
+	PRIMARY KEY ( node_id, path, transferred_at )
+);
 CREATE TABLE injuredsegments (
 	path bytea NOT NULL,
 	data bytea NOT NULL,
@@ -506,6 +513,9 @@ CREATE TABLE api_keys (
 	secret bytea NOT NULL,
 	partner_id bytea,
 	created_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone,
+	last_used_at timestamp with time zone,
+	caveats bytea,
 	PRIMARY KEY ( id ),
 	UNIQUE ( head ),
 	UNIQUE ( name, project_id )
@@ -579,6 +589,7 @@ CREATE TABLE project_payments (
 );
 CREATE INDEX bucket_name_project_id_interval_start_interval_seconds ON bucket_bandwidth_rollups ( bucket_name, project_id, interval_start, interval_seconds );
 CREATE UNIQUE INDEX bucket_id_rollup ON bucket_usages ( bucket_id, rollup_end_time );
+CREATE INDEX graceful_exit_transfer_queue_durability_ratio ON graceful_exit_transfer_queue ( node_id, finished_at, durability_ratio );
 CREATE INDEX injuredsegments_attempted_index ON injuredsegments ( attempted );
 CREATE INDEX node_last_ip ON nodes ( last_net );
 CREATE UNIQUE INDEX serial_number ON serial_numbers ( serial_number );
@@ -723,6 +734,13 @@ CREATE TABLE graceful_exit_transfer_queue (
 	finished_at TIMESTAMP,
 	PRIMARY KEY ( node_id, path )
 );
+CREATE TABLE graceful_exit_transferred (
+	node_id BLOB NOT NULL,
+	path BLOB NOT NULL,
+	bytes INTEGER NOT NULL,
+	transferred_at TIMESTAMP NOT NULL,
+	PRIMARY KEY ( node_id, path, transferred_at )
+);
 CREATE TABLE injuredsegments (
 	path BLOB NOT NULL,
 	data BLOB NOT NULL,
@@ -881,6 +899,9 @@ CREATE TABLE api_keys (
 	secret BLOB NOT NULL,
 	partner_id BLOB,
 	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	caveats BLOB,
 	PRIMARY KEY ( id ),
 	UNIQUE ( head ),
 	UNIQUE ( name, project_id )
@@ -954,6 +975,7 @@ CREATE TABLE project_payments (
 );
 CREATE INDEX bucket_name_project_id_interval_start_interval_seconds ON bucket_bandwidth_rollups ( bucket_name, project_id, interval_start, interval_seconds );
 CREATE UNIQUE INDEX bucket_id_rollup ON bucket_usages ( bucket_id, rollup_end_time );
+CREATE INDEX graceful_exit_transfer_queue_durability_ratio ON graceful_exit_transfer_queue ( node_id, finished_at, durability_ratio );
 CREATE INDEX injuredsegments_attempted_index ON injuredsegments ( attempted );
 CREATE INDEX node_last_ip ON nodes ( last_net );
 CREATE UNIQUE INDEX serial_number ON serial_numbers ( serial_number );
@@ -2280,6 +2302,95 @@ func (f GracefulExitTransferQueue_FinishedAt_Field) value() interface{} {
 
 func (GracefulExitTransferQueue_FinishedAt_Field) _Column() string { return "finished_at" }
 
+type GracefulExitTransferred struct {
+	NodeId        []byte
+	Path          []byte
+	Bytes         int64
+	TransferredAt time.Time
+}
+
+func (GracefulExitTransferred) _Table() string { return "graceful_exit_transferred" }
+
+type GracefulExitTransferred_Update_Fields struct {
+}
+
+type GracefulExitTransferred_NodeId_Field struct {
+	_set   bool
+	_null  bool
+	_value []byte
+}
+
+func GracefulExitTransferred_NodeId(v []byte) GracefulExitTransferred_NodeId_Field {
+	return GracefulExitTransferred_NodeId_Field{_set: true, _value: v}
+}
+
+func (f GracefulExitTransferred_NodeId_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (GracefulExitTransferred_NodeId_Field) _Column() string { return "node_id" }
+
+type GracefulExitTransferred_Path_Field struct {
+	_set   bool
+	_null  bool
+	_value []byte
+}
+
+func GracefulExitTransferred_Path(v []byte) GracefulExitTransferred_Path_Field {
+	return GracefulExitTransferred_Path_Field{_set: true, _value: v}
+}
+
+func (f GracefulExitTransferred_Path_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (GracefulExitTransferred_Path_Field) _Column() string { return "path" }
+
+type GracefulExitTransferred_Bytes_Field struct {
+	_set   bool
+	_null  bool
+	_value int64
+}
+
+func GracefulExitTransferred_Bytes(v int64) GracefulExitTransferred_Bytes_Field {
+	return GracefulExitTransferred_Bytes_Field{_set: true, _value: v}
+}
+
+func (f GracefulExitTransferred_Bytes_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (GracefulExitTransferred_Bytes_Field) _Column() string { return "bytes" }
+
+type GracefulExitTransferred_TransferredAt_Field struct {
+	_set   bool
+	_null  bool
+	_value time.Time
+}
+
+func GracefulExitTransferred_TransferredAt(v time.Time) GracefulExitTransferred_TransferredAt_Field {
+	v = toUTC(v)
+	return GracefulExitTransferred_TransferredAt_Field{_set: true, _value: v}
+}
+
+func (f GracefulExitTransferred_TransferredAt_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (GracefulExitTransferred_TransferredAt_Field) _Column() string { return "transferred_at" }
+
 type Injuredsegment struct {
 	Path      []byte
 	Data      []byte
@@ -4771,23 +4882,31 @@ func (f ValueAttribution_LastUpdated_Field) value() interface{} {
 func (ValueAttribution_LastUpdated_Field) _Column() string { return "last_updated" }
 
 type ApiKey struct {
-	Id        []byte
-	ProjectId []byte
-	Head      []byte
-	Name      string
-	Secret    []byte
-	PartnerId []byte
-	CreatedAt time.Time
+	Id         []byte
+	ProjectId  []byte
+	Head       []byte
+	Name       string
+	Secret     []byte
+	PartnerId  []byte
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	Caveats    []byte
 }
 
 func (ApiKey) _Table() string { return "api_keys" }
 
 type ApiKey_Create_Fields struct {
-	PartnerId ApiKey_PartnerId_Field
+	PartnerId  ApiKey_PartnerId_Field
+	ExpiresAt  ApiKey_ExpiresAt_Field
+	LastUsedAt ApiKey_LastUsedAt_Field
+	Caveats    ApiKey_Caveats_Field
 }
 
 type ApiKey_Update_Fields struct {
-	Name ApiKey_Name_Field
+	Name       ApiKey_Name_Field
+	ExpiresAt  ApiKey_ExpiresAt_Field
+	LastUsedAt ApiKey_LastUsedAt_Field
 }
 
 type ApiKey_Id_Field struct {
@@ -4936,6 +5055,102 @@ func (f ApiKey_CreatedAt_Field) value() interface{} {
 
 func (ApiKey_CreatedAt_Field) _Column() string { return "created_at" }
 
+type ApiKey_ExpiresAt_Field struct {
+	_set   bool
+	_null  bool
+	_value *time.Time
+}
+
+func ApiKey_ExpiresAt(v time.Time) ApiKey_ExpiresAt_Field {
+	return ApiKey_ExpiresAt_Field{_set: true, _value: &v}
+}
+
+func ApiKey_ExpiresAt_Raw(v *time.Time) ApiKey_ExpiresAt_Field {
+	if v == nil {
+		return ApiKey_ExpiresAt_Null()
+	}
+	return ApiKey_ExpiresAt(*v)
+}
+
+func ApiKey_ExpiresAt_Null() ApiKey_ExpiresAt_Field {
+	return ApiKey_ExpiresAt_Field{_set: true, _null: true}
+}
+
+func (f ApiKey_ExpiresAt_Field) isnull() bool { return !f._set || f._null || f._value == nil }
+
+func (f ApiKey_ExpiresAt_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (ApiKey_ExpiresAt_Field) _Column() string { return "expires_at" }
+
+type ApiKey_LastUsedAt_Field struct {
+	_set   bool
+	_null  bool
+	_value *time.Time
+}
+
+func ApiKey_LastUsedAt(v time.Time) ApiKey_LastUsedAt_Field {
+	return ApiKey_LastUsedAt_Field{_set: true, _value: &v}
+}
+
+func ApiKey_LastUsedAt_Raw(v *time.Time) ApiKey_LastUsedAt_Field {
+	if v == nil {
+		return ApiKey_LastUsedAt_Null()
+	}
+	return ApiKey_LastUsedAt(*v)
+}
+
+func ApiKey_LastUsedAt_Null() ApiKey_LastUsedAt_Field {
+	return ApiKey_LastUsedAt_Field{_set: true, _null: true}
+}
+
+func (f ApiKey_LastUsedAt_Field) isnull() bool { return !f._set || f._null || f._value == nil }
+
+func (f ApiKey_LastUsedAt_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (ApiKey_LastUsedAt_Field) _Column() string { return "last_used_at" }
+
+type ApiKey_Caveats_Field struct {
+	_set   bool
+	_null  bool
+	_value []byte
+}
+
+func ApiKey_Caveats(v []byte) ApiKey_Caveats_Field {
+	return ApiKey_Caveats_Field{_set: true, _value: v}
+}
+
+func ApiKey_Caveats_Raw(v []byte) ApiKey_Caveats_Field {
+	if v == nil {
+		return ApiKey_Caveats_Null()
+	}
+	return ApiKey_Caveats(v)
+}
+
+func ApiKey_Caveats_Null() ApiKey_Caveats_Field {
+	return ApiKey_Caveats_Field{_set: true, _null: true}
+}
+
+func (f ApiKey_Caveats_Field) isnull() bool { return !f._set || f._null || f._value == nil }
+
+func (f ApiKey_Caveats_Field) value() interface{} {
+	if !f._set || f._null {
+		return nil
+	}
+	return f._value
+}
+
+func (ApiKey_Caveats_Field) _Column() string { return "caveats" }
+
 type BucketMetainfo struct {
 	Id                              []byte
 	ProjectId                       []byte
@@ -6556,14 +6771,17 @@ func (obj *postgresImpl) Create_ApiKey(ctx context.Context,
 	__secret_val := api_key_secret.value()
 	__partner_id_val := optional.PartnerId.value()
 	__created_at_val := __now
+	__expires_at_val := optional.ExpiresAt.value()
+	__last_used_at_val := optional.LastUsedAt.value()
+	__caveats_val := optional.Caveats.value()
 
-	var __embed_stmt = __sqlbundle_Literal("INSERT INTO api_keys ( id, project_id, head, name, secret, partner_id, created_at ) VALUES ( ?, ?, ?, ?, ?, ?, ? ) RETURNING api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at")
+	var __embed_stmt = __sqlbundle_Literal("INSERT INTO api_keys ( id, project_id, head, name, secret, partner_id, created_at, expires_at, last_used_at, caveats ) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ? ) RETURNING api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.caveats")
 
 	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
-	obj.logStmt(__stmt, __id_val, __project_id_val, __head_val, __name_val, __secret_val, __partner_id_val, __created_at_val)
+	obj.logStmt(__stmt, __id_val, __project_id_val, __head_val, __name_val, __secret_val, __partner_id_val, __created_at_val, __expires_at_val, __last_used_at_val, __caveats_val)
 
 	api_key = &ApiKey{}
-	err = obj.driver.QueryRow(__stmt, __id_val, __project_id_val, __head_val, __name_val, __secret_val, __partner_id_val, __created_at_val).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt)
+	err = obj.driver.QueryRow(__stmt, __id_val, __project_id_val, __head_val, __name_val, __secret_val, __partner_id_val, __created_at_val, __expires_at_val, __last_used_at_val, __caveats_val).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt, &api_key.ExpiresAt, &api_key.LastUsedAt, &api_key.Caveats)
 	if err != nil {
 		return nil, obj.makeErr(err)
 	}
@@ -7781,7 +7999,7 @@ func (obj *postgresImpl) Get_ApiKey_By_Id(ctx context.Context,
 	api_key_id ApiKey_Id_Field) (
 	api_key *ApiKey, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at FROM api_keys WHERE api_keys.id = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.caveats FROM api_keys WHERE api_keys.id = ?")
 
 	var __values []interface{}
 	__values = append(__values, api_key_id.value())
@@ -7790,7 +8008,7 @@ func (obj *postgresImpl) Get_ApiKey_By_Id(ctx context.Context,
 	obj.logStmt(__stmt, __values...)
 
 	api_key = &ApiKey{}
-	err = obj.driver.QueryRow(__stmt, __values...).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt)
+	err = obj.driver.QueryRow(__stmt, __values...).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt, &api_key.ExpiresAt, &api_key.LastUsedAt, &api_key.Caveats)
 	if err != nil {
 		return nil, obj.makeErr(err)
 	}
@@ -7802,7 +8020,7 @@ func (obj *postgresImpl) Get_ApiKey_By_Head(ctx context.Context,
 	api_key_head ApiKey_Head_Field) (
 	api_key *ApiKey, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at FROM api_keys WHERE api_keys.head = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.caveats FROM api_keys WHERE api_keys.head = ?")
 
 	var __values []interface{}
 	__values = append(__values, api_key_head.value())
@@ -7811,7 +8029,7 @@ func (obj *postgresImpl) Get_ApiKey_By_Head(ctx context.Context,
 	obj.logStmt(__stmt, __values...)
 
 	api_key = &ApiKey{}
-	err = obj.driver.QueryRow(__stmt, __values...).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt)
+	err = obj.driver.QueryRow(__stmt, __values...).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt, &api_key.ExpiresAt, &api_key.LastUsedAt, &api_key.Caveats)
 	if err != nil {
 		return nil, obj.makeErr(err)
 	}
@@ -7823,7 +8041,7 @@ func (obj *postgresImpl) All_ApiKey_By_ProjectId_OrderBy_Asc_Name(ctx context.Co
 	api_key_project_id ApiKey_ProjectId_Field) (
 	rows []*ApiKey, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at FROM api_keys WHERE api_keys.project_id = ? ORDER BY api_keys.name")
+	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.caveats FROM api_keys WHERE api_keys.project_id = ? ORDER BY api_keys.name")
 
 	var __values []interface{}
 	__values = append(__values, api_key_project_id.value())
@@ -7839,7 +8057,7 @@ func (obj *postgresImpl) All_ApiKey_By_ProjectId_OrderBy_Asc_Name(ctx context.Co
 
 	for __rows.Next() {
 		api_key := &ApiKey{}
-		err = __rows.Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt)
+		err = __rows.Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt, &api_key.ExpiresAt, &api_key.LastUsedAt, &api_key.Caveats)
 		if err != nil {
 			return nil, obj.makeErr(err)
 		}
@@ -9362,6 +9580,16 @@ func (obj *postgresImpl) UpdateNoReturn_ApiKey_By_Id(ctx context.Context,
 		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("name = ?"))
 	}
 
+	if update.ExpiresAt._set {
+		__values = append(__values, update.ExpiresAt.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("expires_at = ?"))
+	}
+
+	if update.LastUsedAt._set {
+		__values = append(__values, update.LastUsedAt.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("last_used_at = ?"))
+	}
+
 	if len(__sets_sql.SQLs) == 0 {
 		return emptyUpdate()
 	}
@@ -10465,6 +10693,16 @@ func (obj *postgresImpl) deleteAll(ctx context.Context) (count int64, err error)
 		return 0, obj.makeErr(err)
 	}
 
+	__count, err = __res.RowsAffected()
+	if err != nil {
+		return 0, obj.makeErr(err)
+	}
+	count += __count
+	__res, err = obj.driver.Exec("DELETE FROM graceful_exit_transferred;")
+	if err != nil {
+		return 0, obj.makeErr(err)
+	}
+
 	__count, err = __res.RowsAffected()
 	if err != nil {
 		return 0, obj.makeErr(err)
@@ -10972,13 +11210,16 @@ func (obj *sqlite3Impl) Create_ApiKey(ctx context.Context,
 	__secret_val := api_key_secret.value()
 	__partner_id_val := optional.PartnerId.value()
 	__created_at_val := __now
+	__expires_at_val := optional.ExpiresAt.value()
+	__last_used_at_val := optional.LastUsedAt.value()
+	__caveats_val := optional.Caveats.value()
 
-	var __embed_stmt = __sqlbundle_Literal("INSERT INTO api_keys ( id, project_id, head, name, secret, partner_id, created_at ) VALUES ( ?, ?, ?, ?, ?, ?, ? )")
+	var __embed_stmt = __sqlbundle_Literal("INSERT INTO api_keys ( id, project_id, head, name, secret, partner_id, created_at, expires_at, last_used_at, caveats ) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )")
 
 	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
-	obj.logStmt(__stmt, __id_val, __project_id_val, __head_val, __name_val, __secret_val, __partner_id_val, __created_at_val)
+	obj.logStmt(__stmt, __id_val, __project_id_val, __head_val, __name_val, __secret_val, __partner_id_val, __created_at_val, __expires_at_val, __last_used_at_val, __caveats_val)
 
-	__res, err := obj.driver.Exec(__stmt, __id_val, __project_id_val, __head_val, __name_val, __secret_val, __partner_id_val, __created_at_val)
+	__res, err := obj.driver.Exec(__stmt, __id_val, __project_id_val, __head_val, __name_val, __secret_val, __partner_id_val, __created_at_val, __expires_at_val, __last_used_at_val, __caveats_val)
 	if err != nil {
 		return nil, obj.makeErr(err)
 	}
@@ -12218,7 +12459,7 @@ func (obj *sqlite3Impl) Get_ApiKey_By_Id(ctx context.Context,
 	api_key_id ApiKey_Id_Field) (
 	api_key *ApiKey, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at FROM api_keys WHERE api_keys.id = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.caveats FROM api_keys WHERE api_keys.id = ?")
 
 	var __values []interface{}
 	__values = append(__values, api_key_id.value())
@@ -12227,7 +12468,7 @@ func (obj *sqlite3Impl) Get_ApiKey_By_Id(ctx context.Context,
 	obj.logStmt(__stmt, __values...)
 
 	api_key = &ApiKey{}
-	err = obj.driver.QueryRow(__stmt, __values...).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt)
+	err = obj.driver.QueryRow(__stmt, __values...).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt, &api_key.ExpiresAt, &api_key.LastUsedAt, &api_key.Caveats)
 	if err != nil {
 		return nil, obj.makeErr(err)
 	}
@@ -12239,7 +12480,7 @@ func (obj *sqlite3Impl) Get_ApiKey_By_Head(ctx context.Context,
 	api_key_head ApiKey_Head_Field) (
 	api_key *ApiKey, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at FROM api_keys WHERE api_keys.head = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.caveats FROM api_keys WHERE api_keys.head = ?")
 
 	var __values []interface{}
 	__values = append(__values, api_key_head.value())
@@ -12248,7 +12489,7 @@ func (obj *sqlite3Impl) Get_ApiKey_By_Head(ctx context.Context,
 	obj.logStmt(__stmt, __values...)
 
 	api_key = &ApiKey{}
-	err = obj.driver.QueryRow(__stmt, __values...).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt)
+	err = obj.driver.QueryRow(__stmt, __values...).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt, &api_key.ExpiresAt, &api_key.LastUsedAt, &api_key.Caveats)
 	if err != nil {
 		return nil, obj.makeErr(err)
 	}
@@ -12260,7 +12501,7 @@ func (obj *sqlite3Impl) All_ApiKey_By_ProjectId_OrderBy_Asc_Name(ctx context.Con
 	api_key_project_id ApiKey_ProjectId_Field) (
 	rows []*ApiKey, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at FROM api_keys WHERE api_keys.project_id = ? ORDER BY api_keys.name")
+	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.caveats FROM api_keys WHERE api_keys.project_id = ? ORDER BY api_keys.name")
 
 	var __values []interface{}
 	__values = append(__values, api_key_project_id.value())
@@ -12276,7 +12517,7 @@ func (obj *sqlite3Impl) All_ApiKey_By_ProjectId_OrderBy_Asc_Name(ctx context.Con
 
 	for __rows.Next() {
 		api_key := &ApiKey{}
-		err = __rows.Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt)
+		err = __rows.Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt, &api_key.ExpiresAt, &api_key.LastUsedAt, &api_key.Caveats)
 		if err != nil {
 			return nil, obj.makeErr(err)
 		}
@@ -13849,6 +14090,16 @@ func (obj *sqlite3Impl) UpdateNoReturn_ApiKey_By_Id(ctx context.Context,
 		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("name = ?"))
 	}
 
+	if update.ExpiresAt._set {
+		__values = append(__values, update.ExpiresAt.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("expires_at = ?"))
+	}
+
+	if update.LastUsedAt._set {
+		__values = append(__values, update.LastUsedAt.value())
+		__sets_sql.SQLs = append(__sets_sql.SQLs, __sqlbundle_Literal("last_used_at = ?"))
+	}
+
 	if len(__sets_sql.SQLs) == 0 {
 		return emptyUpdate()
 	}
@@ -14964,13 +15215,13 @@ func (obj *sqlite3Impl) getLastApiKey(ctx context.Context,
 	pk int64) (
 	api_key *ApiKey, err error) {
 
-	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at FROM api_keys WHERE _rowid_ = ?")
+	var __embed_stmt = __sqlbundle_Literal("SELECT api_keys.id, api_keys.project_id, api_keys.head, api_keys.name, api_keys.secret, api_keys.partner_id, api_keys.created_at, api_keys.expires_at, api_keys.last_used_at, api_keys.caveats FROM api_keys WHERE _rowid_ = ?")
 
 	var __stmt = __sqlbundle_Render(obj.dialect, __embed_stmt)
 	obj.logStmt(__stmt, pk)
 
 	api_key = &ApiKey{}
-	err = obj.driver.QueryRow(__stmt, pk).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt)
+	err = obj.driver.QueryRow(__stmt, pk).Scan(&api_key.Id, &api_key.ProjectId, &api_key.Head, &api_key.Name, &api_key.Secret, &api_key.PartnerId, &api_key.CreatedAt, &api_key.ExpiresAt, &api_key.LastUsedAt, &api_key.Caveats)
 	if err != nil {
 		return nil, obj.makeErr(err)
 	}
@@ -15445,6 +15696,16 @@ func (obj *sqlite3Impl) deleteAll(ctx context.Context) (count int64, err error)
 		return 0, obj.makeErr(err)
 	}
 
+	__count, err = __res.RowsAffected()
+	if err != nil {
+		return 0, obj.makeErr(err)
+	}
+	count += __count
+	__res, err = obj.driver.Exec("DELETE FROM graceful_exit_transferred;")
+	if err != nil {
+		return 0, obj.makeErr(err)
+	}
+
 	__count, err = __res.RowsAffected()
 	if err != nil {
 		return 0, obj.makeErr(err)
@@ -76,6 +76,13 @@ CREATE TABLE graceful_exit_transfer_queue (
 	finished_at timestamp,
 	PRIMARY KEY ( node_id, path )
 );
+CREATE TABLE graceful_exit_transferred (
+	node_id bytea NOT NULL,
+	path bytea NOT NULL,
+	bytes bigint NOT NULL,
+	transferred_at timestamp NOT NULL,
+	PRIMARY KEY ( node_id, path, transferred_at )
+);
 CREATE TABLE injuredsegments (
 	path bytea NOT NULL,
 	data bytea NOT NULL,
@@ -234,6 +241,9 @@ CREATE TABLE api_keys (
 	secret bytea NOT NULL,
 	partner_id bytea,
 	created_at timestamp with time zone NOT NULL,
+	expires_at timestamp with time zone,
+	last_used_at timestamp with time zone,
+	caveats bytea,
 	PRIMARY KEY ( id ),
 	UNIQUE ( head ),
 	UNIQUE ( name, project_id )
@@ -307,6 +317,7 @@ CREATE TABLE project_payments (
 );
 CREATE INDEX bucket_name_project_id_interval_start_interval_seconds ON bucket_bandwidth_rollups ( bucket_name, project_id, interval_start, interval_seconds );
 CREATE UNIQUE INDEX bucket_id_rollup ON bucket_usages ( bucket_id, rollup_end_time );
+CREATE INDEX graceful_exit_transfer_queue_durability_ratio ON graceful_exit_transfer_queue ( node_id, finished_at, durability_ratio );
 CREATE INDEX injuredsegments_attempted_index ON injuredsegments ( attempted );
 CREATE INDEX node_last_ip ON nodes ( last_net );
 CREATE UNIQUE INDEX serial_number ON serial_numbers ( serial_number );
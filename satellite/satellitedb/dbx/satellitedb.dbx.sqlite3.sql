@@ -76,6 +76,13 @@ CREATE TABLE graceful_exit_transfer_queue (
 	finished_at TIMESTAMP,
 	PRIMARY KEY ( node_id, path )
 );
+CREATE TABLE graceful_exit_transferred (
+	node_id BLOB NOT NULL,
+	path BLOB NOT NULL,
+	bytes INTEGER NOT NULL,
+	transferred_at TIMESTAMP NOT NULL,
+	PRIMARY KEY ( node_id, path, transferred_at )
+);
 CREATE TABLE injuredsegments (
 	path BLOB NOT NULL,
 	data BLOB NOT NULL,
@@ -234,6 +241,9 @@ CREATE TABLE api_keys (
 	secret BLOB NOT NULL,
 	partner_id BLOB,
 	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	caveats BLOB,
 	PRIMARY KEY ( id ),
 	UNIQUE ( head ),
 	UNIQUE ( name, project_id )
@@ -307,6 +317,7 @@ CREATE TABLE project_payments (
 );
 CREATE INDEX bucket_name_project_id_interval_start_interval_seconds ON bucket_bandwidth_rollups ( bucket_name, project_id, interval_start, interval_seconds );
 CREATE UNIQUE INDEX bucket_id_rollup ON bucket_usages ( bucket_id, rollup_end_time );
+CREATE INDEX graceful_exit_transfer_queue_durability_ratio ON graceful_exit_transfer_queue ( node_id, finished_at, durability_ratio );
 CREATE INDEX injuredsegments_attempted_index ON injuredsegments ( attempted );
 CREATE INDEX node_last_ip ON nodes ( last_net );
 CREATE UNIQUE INDEX serial_number ON serial_numbers ( serial_number );
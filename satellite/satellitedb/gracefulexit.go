@@ -6,11 +6,18 @@ package satellitedb
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
 	sqlite3 "github.com/mattn/go-sqlite3"
+	"github.com/zeebo/errs"
 
 	"storj.io/storj/pkg/storj"
 	"storj.io/storj/satellite/gracefulexit"
@@ -25,7 +32,90 @@ type gracefulexitDB struct {
 func (db *gracefulexitDB) IncrementProgress(ctx context.Context, nodeID storj.NodeID, bytes int64, successfulTransfers int64, failedTransfers int64) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	statement := db.db.Rebind(
+	_, err = db.db.ExecContext(ctx, incrementProgressStatement(db.db), nodeID, bytes, successfulTransfers, failedTransfers, time.Now().UTC())
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	return nil
+}
+
+// RecordTransfer increments the transfer stats for a node by a single successful transfer of the path
+// and keeps a record of the transfer, so that the bytes transferred can be reconciled later.
+func (db *gracefulexitDB) RecordTransfer(ctx context.Context, nodeID storj.NodeID, path []byte, bytes int64) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	now := time.Now().UTC()
+	err = db.db.WithTx(ctx, func(ctx context.Context, tx *dbx.Tx) error {
+		_, err := tx.Tx.ExecContext(ctx, incrementProgressStatement(db.db), nodeID, bytes, 1, 0, now)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Tx.ExecContext(ctx, db.db.Rebind(`
+			INSERT INTO graceful_exit_transferred (node_id, path, bytes, transferred_at) VALUES (?, ?, ?, ?)`,
+		), nodeID, path, bytes, now)
+		return err
+	})
+	return Error.Wrap(err)
+}
+
+// GetTransferredBytes returns the sum of the bytes of all the recorded transfers of a node.
+func (db *gracefulexitDB) GetTransferredBytes(ctx context.Context, nodeID storj.NodeID) (total int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = db.db.QueryRowContext(ctx, db.db.Rebind(
+		`SELECT COALESCE(SUM(bytes), 0) FROM graceful_exit_transferred WHERE node_id = ?`,
+	), nodeID).Scan(&total)
+	if err != nil {
+		return 0, Error.Wrap(err)
+	}
+
+	return total, nil
+}
+
+// TransferredPerDay returns the bytes of the recorded transfers of a node bucketed by day.
+// Days are in UTC and keyed by their start. Every day between from and to inclusive is
+// present in the result, days without transfers having zero bytes.
+func (db *gracefulexitDB) TransferredPerDay(ctx context.Context, nodeID storj.NodeID, from, to time.Time) (_ map[time.Time]int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	const day = 24 * time.Hour
+	firstDay := from.UTC().Truncate(day)
+	lastDay := to.UTC().Truncate(day)
+
+	perDay := make(map[time.Time]int64)
+	for d := firstDay; !d.After(lastDay); d = d.Add(day) {
+		perDay[d] = 0
+	}
+	if len(perDay) == 0 {
+		return perDay, nil
+	}
+
+	rows, err := db.db.QueryContext(ctx, db.db.Rebind(`
+		SELECT transferred_at, bytes FROM graceful_exit_transferred
+		WHERE node_id = ? AND transferred_at >= ? AND transferred_at < ?`,
+	), nodeID, firstDay, lastDay.Add(day))
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, Error.Wrap(rows.Close())) }()
+
+	for rows.Next() {
+		var transferredAt time.Time
+		var bytes int64
+		if err := rows.Scan(&transferredAt, &bytes); err != nil {
+			return nil, Error.Wrap(err)
+		}
+		perDay[transferredAt.UTC().Truncate(day)] += bytes
+	}
+
+	return perDay, Error.Wrap(rows.Err())
+}
+
+// incrementProgressStatement returns the statement adding bytes, successful and failed transfers to the progress of a node.
+func incrementProgressStatement(db *dbx.DB) string {
+	return db.Rebind(
 		`INSERT INTO graceful_exit_progress (node_id, bytes_transferred, pieces_transferred, pieces_failed, updated_at) VALUES (?, ?, ?, ?, ?)
 		 ON CONFLICT(node_id)
 		 DO UPDATE SET bytes_transferred = graceful_exit_progress.bytes_transferred + excluded.bytes_transferred, 
@@ -33,13 +123,6 @@ func (db *gracefulexitDB) IncrementProgress(ctx context.Context, nodeID storj.No
 		 	pieces_failed = graceful_exit_progress.pieces_failed + excluded.pieces_failed,
 		 	updated_at = excluded.updated_at;`,
 	)
-	now := time.Now().UTC()
-	_, err = db.db.ExecContext(ctx, statement, nodeID, bytes, successfulTransfers, failedTransfers, now)
-	if err != nil {
-		return Error.Wrap(err)
-	}
-
-	return nil
 }
 
 // GetProgress gets a graceful exit progress entry.
@@ -65,8 +148,173 @@ func (db *gracefulexitDB) GetProgress(ctx context.Context, nodeID storj.NodeID)
 	return progress, Error.Wrap(err)
 }
 
-// Enqueue batch inserts graceful exit transfer queue entries it does not exist.
-func (db *gracefulexitDB) Enqueue(ctx context.Context, items []gracefulexit.TransferQueueItem) (err error) {
+// GetProgressBatch gets the graceful exit progress entries of the nodes. Nodes without an entry are missing from the result.
+func (db *gracefulexitDB) GetProgressBatch(ctx context.Context, nodeIDs []storj.NodeID) (_ map[storj.NodeID]*gracefulexit.Progress, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	progress := make(map[storj.NodeID]*gracefulexit.Progress, len(nodeIDs))
+	for len(nodeIDs) > 0 {
+		batch := nodeIDs
+		if len(batch) > maxItemsPerQuery {
+			batch = batch[:maxItemsPerQuery]
+		}
+		nodeIDs = nodeIDs[len(batch):]
+
+		err = db.getProgressBatch(ctx, progress, batch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return progress, nil
+}
+
+// getProgressBatch adds the graceful exit progress entries of the nodes to progress.
+func (db *gracefulexitDB) getProgressBatch(ctx context.Context, progress map[storj.NodeID]*gracefulexit.Progress, nodeIDs []storj.NodeID) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	args := make([]interface{}, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		args = append(args, nodeID.Bytes())
+	}
+
+	rows, err := db.db.QueryContext(ctx, db.db.Rebind(`
+		SELECT node_id, bytes_transferred, pieces_transferred, pieces_failed, updated_at
+		FROM graceful_exit_progress
+		WHERE node_id IN (?`+strings.Repeat(", ?", len(args)-1)+`)`,
+	), args...)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var nodeID []byte
+		var entry gracefulexit.Progress
+		err := rows.Scan(&nodeID, &entry.BytesTransferred, &entry.PiecesTransferred, &entry.PiecesFailed, &entry.UpdatedAt)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+
+		entry.NodeID, err = storj.NodeIDFromBytes(nodeID)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+		progress[entry.NodeID] = &entry
+	}
+
+	return Error.Wrap(rows.Err())
+}
+
+// ReconcileProgress recomputes the graceful exit progress of a node and rewrites it, e.g. when it drifted
+// because of crashes between finishing transfers and updating the progress.
+//
+// The transferred and failed pieces are counted from the finished transfer queue entries, where an entry
+// failed when it was given up after its last request failed. The transferred bytes are summed from the
+// recorded transfers, the transferred bytes of the progress are kept when the node has no recorded
+// transfers, as they were counted without recording the transfers then. Finished entries which have
+// already been deleted aren't counted, so the progress should only be reconciled before the finished
+// entries of the node are deleted.
+func (db *gracefulexitDB) ReconcileProgress(ctx context.Context, nodeID storj.NodeID) (_ *gracefulexit.Progress, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	progress := &gracefulexit.Progress{
+		NodeID:    nodeID,
+		UpdatedAt: time.Now().UTC(),
+	}
+	err = db.db.WithTx(ctx, func(ctx context.Context, tx *dbx.Tx) error {
+		var finished int64
+		err := tx.Tx.QueryRowContext(ctx, db.db.Rebind(`
+			SELECT
+				COUNT(*),
+				COALESCE(SUM(CASE WHEN last_failed_at IS NOT NULL AND (requested_at IS NULL OR last_failed_at >= requested_at) THEN 1 ELSE 0 END), 0)
+			FROM graceful_exit_transfer_queue
+			WHERE node_id = ? AND finished_at IS NOT NULL`,
+		), nodeID.Bytes()).Scan(&finished, &progress.PiecesFailed)
+		if err != nil {
+			return err
+		}
+		progress.PiecesTransferred = finished - progress.PiecesFailed
+
+		var recorded int64
+		err = tx.Tx.QueryRowContext(ctx, db.db.Rebind(
+			`SELECT COUNT(*), COALESCE(SUM(bytes), 0) FROM graceful_exit_transferred WHERE node_id = ?`,
+		), nodeID).Scan(&recorded, &progress.BytesTransferred)
+		if err != nil {
+			return err
+		}
+		if recorded == 0 {
+			err = tx.Tx.QueryRowContext(ctx, db.db.Rebind(
+				`SELECT bytes_transferred FROM graceful_exit_progress WHERE node_id = ?`,
+			), nodeID).Scan(&progress.BytesTransferred)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+		}
+
+		_, err = tx.Tx.ExecContext(ctx, db.db.Rebind(
+			`INSERT INTO graceful_exit_progress (node_id, bytes_transferred, pieces_transferred, pieces_failed, updated_at) VALUES (?, ?, ?, ?, ?)
+			 ON CONFLICT(node_id)
+			 DO UPDATE SET bytes_transferred = excluded.bytes_transferred,
+			 	pieces_transferred = excluded.pieces_transferred,
+			 	pieces_failed = excluded.pieces_failed,
+			 	updated_at = excluded.updated_at`,
+		), nodeID, progress.BytesTransferred, progress.PiecesTransferred, progress.PiecesFailed, progress.UpdatedAt)
+		return err
+	})
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	return progress, nil
+}
+
+// GetExitCompletion returns the finished and total pieces of the graceful exit of a node and whether its
+// transfer queue is still being built.
+//
+// The finished pieces come from the progress and the total adds the incomplete transfer queue entries to
+// them, so it stays valid after the finished entries are deleted. The queue is being built until the
+// exit loop completion of the node is recorded, also when the node is unknown.
+func (db *gracefulexitDB) GetExitCompletion(ctx context.Context, nodeID storj.NodeID) (_ *gracefulexit.ExitCompletion, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	completion := &gracefulexit.ExitCompletion{}
+	err = db.db.WithTx(ctx, func(ctx context.Context, tx *dbx.Tx) error {
+		err := tx.Tx.QueryRowContext(ctx, db.db.Rebind(
+			`SELECT pieces_transferred, pieces_failed FROM graceful_exit_progress WHERE node_id = ?`,
+		), nodeID.Bytes()).Scan(&completion.PiecesTransferred, &completion.PiecesFailed)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		var incomplete int64
+		err = tx.Tx.QueryRowContext(ctx, db.db.Rebind(
+			`SELECT COUNT(*) FROM graceful_exit_transfer_queue WHERE node_id = ? AND finished_at IS NULL`,
+		), nodeID.Bytes()).Scan(&incomplete)
+		if err != nil {
+			return err
+		}
+		completion.PiecesTotal = completion.PiecesTransferred + completion.PiecesFailed + incomplete
+
+		var loopCompletedAt *time.Time
+		err = tx.Tx.QueryRowContext(ctx, db.db.Rebind(
+			`SELECT exit_loop_completed_at FROM nodes WHERE id = ?`,
+		), nodeID.Bytes()).Scan(&loopCompletedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		completion.QueueBuilding = loopCompletedAt == nil
+		return nil
+	})
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	return completion, nil
+}
+
+// Enqueue batch inserts graceful exit transfer queue entries it does not exist and returns the number of inserted entries.
+func (db *gracefulexitDB) Enqueue(ctx context.Context, items []gracefulexit.TransferQueueItem) (inserted int, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	switch t := db.db.Driver().(type) {
@@ -76,11 +324,16 @@ func (db *gracefulexitDB) Enqueue(ctx context.Context, items []gracefulexit.Tran
 			 VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING;`,
 		)
 		for _, item := range items {
-			_, err = db.db.ExecContext(ctx, statement,
+			result, err := db.db.ExecContext(ctx, statement,
 				item.NodeID.Bytes(), item.Path, item.PieceNum, item.DurabilityRatio, time.Now().UTC())
 			if err != nil {
-				return Error.Wrap(err)
+				return 0, Error.Wrap(err)
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return 0, Error.Wrap(err)
 			}
+			inserted += int(affected)
 		}
 	case *pq.Driver:
 		sort.Slice(items, func(i, k int) bool {
@@ -102,23 +355,58 @@ func (db *gracefulexitDB) Enqueue(ctx context.Context, items []gracefulexit.Tran
 			durabilities = append(durabilities, item.DurabilityRatio)
 		}
 
-		_, err := db.db.ExecContext(ctx, `
+		result, err := db.db.ExecContext(ctx, `
 			INSERT INTO graceful_exit_transfer_queue(node_id, path, piece_num, durability_ratio, queued_at)
 			SELECT unnest($1::bytea[]), unnest($2::bytea[]), unnest($3::integer[]), unnest($4::float8[]), $5
 			ON CONFLICT DO NOTHING;`, postgresNodeIDList(nodeIDs), pq.ByteaArray(paths), pq.Array(pieceNums), pq.Array(durabilities), time.Now().UTC())
 		if err != nil {
-			return Error.Wrap(err)
+			return 0, Error.Wrap(err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, Error.Wrap(err)
 		}
+		inserted = int(affected)
 	default:
-		return Error.New("Unsupported database %t", t)
+		return 0, Error.New("Unsupported database %t", t)
 	}
 
-	return nil
+	return inserted, nil
 }
 
-// UpdateTransferQueueItem creates a graceful exit transfer queue entry.
+// UpdateTransferQueueItem updates a graceful exit transfer queue entry.
+// The update is rejected when it's not a valid transition of the entry, see gracefulexit.ValidateTransition.
 func (db *gracefulexitDB) UpdateTransferQueueItem(ctx context.Context, item gracefulexit.TransferQueueItem) (err error) {
 	defer mon.Task()(&ctx)(&err)
+
+	err = db.db.WithTx(ctx, func(ctx context.Context, tx *dbx.Tx) error {
+		return updateTransferQueueItem(ctx, tx, item)
+	})
+	return Error.Wrap(err)
+}
+
+// UpdateTransferQueueItemsBatch updates graceful exit transfer queue entries in a single transaction.
+// Either all the entries are updated or none, when any update is rejected like by UpdateTransferQueueItem.
+func (db *gracefulexitDB) UpdateTransferQueueItemsBatch(ctx context.Context, items []gracefulexit.TransferQueueItem) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	err = db.db.WithTx(ctx, func(ctx context.Context, tx *dbx.Tx) error {
+		for _, item := range items {
+			if err := updateTransferQueueItem(ctx, tx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return Error.Wrap(err)
+}
+
+// updateTransferQueueItem validates the transition of a graceful exit transfer queue entry and updates it.
+func updateTransferQueueItem(ctx context.Context, tx *dbx.Tx, item gracefulexit.TransferQueueItem) error {
 	update := dbx.GracefulExitTransferQueue_Update_Fields{
 		DurabilityRatio: dbx.GracefulExitTransferQueue_DurabilityRatio(item.DurabilityRatio),
 		LastFailedCode:  dbx.GracefulExitTransferQueue_LastFailedCode_Raw(&item.LastFailedCode),
@@ -135,7 +423,27 @@ func (db *gracefulexitDB) UpdateTransferQueueItem(ctx context.Context, item grac
 		update.FinishedAt = dbx.GracefulExitTransferQueue_FinishedAt_Raw(&item.FinishedAt)
 	}
 
-	return db.db.UpdateNoReturn_GracefulExitTransferQueue_By_NodeId_And_Path(ctx,
+	dbxTransferQueue, err := tx.Get_GracefulExitTransferQueue_By_NodeId_And_Path(ctx,
+		dbx.GracefulExitTransferQueue_NodeId(item.NodeID.Bytes()),
+		dbx.GracefulExitTransferQueue_Path(item.Path))
+	if err == sql.ErrNoRows {
+		return gracefulexit.ErrTransferQueueItemNotFound.New("node %v, path %x", item.NodeID, item.Path)
+	}
+	if err != nil {
+		return err
+	}
+
+	current, err := dbxToTransferQueueItem(dbxTransferQueue)
+	if err != nil {
+		return err
+	}
+
+	err = gracefulexit.ValidateTransition(*current, item)
+	if err != nil {
+		return err
+	}
+
+	return tx.UpdateNoReturn_GracefulExitTransferQueue_By_NodeId_And_Path(ctx,
 		dbx.GracefulExitTransferQueue_NodeId(item.NodeID.Bytes()),
 		dbx.GracefulExitTransferQueue_Path(item.Path),
 		update,
@@ -163,6 +471,83 @@ func (db *gracefulexitDB) DeleteFinishedTransferQueueItems(ctx context.Context,
 	return Error.Wrap(err)
 }
 
+// RequeueTransferItem clears the finished date and the failures of a graceful exit transfer queue entry,
+// so that it is returned by GetIncomplete again, e.g. when the satellite didn't receive the transferred piece.
+// The entry is reset to the state it had when it was queued and is queued again now, so that
+// DeleteAbandonedQueueItems doesn't take it for an abandoned entry.
+func (db *gracefulexitDB) RequeueTransferItem(ctx context.Context, nodeID storj.NodeID, path []byte) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := db.db.ExecContext(ctx, db.db.Rebind(`
+		UPDATE graceful_exit_transfer_queue
+		SET queued_at = ?, finished_at = NULL, requested_at = NULL,
+			last_failed_at = NULL, last_failed_code = NULL, failed_count = NULL
+		WHERE node_id = ? AND path = ?`,
+	), time.Now().UTC(), nodeID.Bytes(), path)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if count == 0 {
+		return gracefulexit.ErrTransferQueueItemNotFound.New("node %v, path %x", nodeID, path)
+	}
+
+	return nil
+}
+
+// PurgeQueueForFinishedExits deletes all graceful exit transfer queue entries for nodes that have finished exiting.
+// Once an exit has finished, any remaining entries for the node can no longer be transferred, so they are removed
+// regardless of whether they were completed. It returns the number of deleted entries.
+func (db *gracefulexitDB) PurgeQueueForFinishedExits(ctx context.Context, finished []storj.NodeID) (deleted int, err error) {
+	defer mon.Task()(&ctx)(&err)
+	if len(finished) == 0 {
+		return 0, nil
+	}
+
+	err = db.db.WithTx(ctx, func(ctx context.Context, tx *dbx.Tx) error {
+		for _, nodeID := range finished {
+			count, err := tx.Delete_GracefulExitTransferQueue_By_NodeId(ctx, dbx.GracefulExitTransferQueue_NodeId(nodeID.Bytes()))
+			if err != nil {
+				return err
+			}
+			deleted += int(count)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, Error.Wrap(err)
+	}
+
+	return deleted, nil
+}
+
+// DeleteAbandonedQueueItems deletes incomplete graceful exit transfer queue entries which were queued more than
+// olderThan ago and have never been requested by the node. It returns the number of deleted entries.
+func (db *gracefulexitDB) DeleteAbandonedQueueItems(ctx context.Context, olderThan time.Duration) (deleted int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := db.db.ExecContext(ctx, db.db.Rebind(`
+		DELETE FROM graceful_exit_transfer_queue
+		WHERE queued_at < ?
+			AND requested_at IS NULL
+			AND finished_at IS NULL`,
+	), time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		return 0, Error.Wrap(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, Error.Wrap(err)
+	}
+
+	return int(count), nil
+}
+
 // GetTransferQueueItem gets a graceful exit transfer queue entry.
 func (db *gracefulexitDB) GetTransferQueueItem(ctx context.Context, nodeID storj.NodeID, path []byte) (_ *gracefulexit.TransferQueueItem, err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -181,6 +566,165 @@ func (db *gracefulexitDB) GetTransferQueueItem(ctx context.Context, nodeID storj
 	return transferQueueItem, Error.Wrap(err)
 }
 
+// maxItemsPerQuery is the number of paths or node ids looked up with a single query
+// to stay well below the parameter limits of the databases.
+const maxItemsPerQuery = 500
+
+// GetTransferQueueItems gets the graceful exit transfer queue entries of the paths, keyed by the hex encoded path.
+// Paths without an entry are missing from the result.
+func (db *gracefulexitDB) GetTransferQueueItems(ctx context.Context, nodeID storj.NodeID, paths [][]byte) (_ map[string]*gracefulexit.TransferQueueItem, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	items := make(map[string]*gracefulexit.TransferQueueItem, len(paths))
+	for len(paths) > 0 {
+		batch := paths
+		if len(batch) > maxItemsPerQuery {
+			batch = batch[:maxItemsPerQuery]
+		}
+		paths = paths[len(batch):]
+
+		args := make([]interface{}, 0, len(batch)+1)
+		args = append(args, nodeID.Bytes())
+		for _, path := range batch {
+			args = append(args, path)
+		}
+
+		err = db.getTransferQueueItems(ctx, items, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+// getTransferQueueItems adds the entries matching the node id and paths in args to items.
+func (db *gracefulexitDB) getTransferQueueItems(ctx context.Context, items map[string]*gracefulexit.TransferQueueItem, args []interface{}) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.db.QueryContext(ctx, db.db.Rebind(`
+		SELECT node_id, path, piece_num, durability_ratio, queued_at, requested_at, last_failed_at, last_failed_code, failed_count, finished_at
+		FROM graceful_exit_transfer_queue
+		WHERE node_id = ? AND path IN (?`+strings.Repeat(", ?", len(args)-2)+`)`,
+	), args...)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		item, err := scanTransferQueueItem(rows)
+		if err != nil {
+			return err
+		}
+		items[hex.EncodeToString(item.Path)] = item
+	}
+
+	return Error.Wrap(rows.Err())
+}
+
+// GetIncompleteExcludingExhausted gets incomplete graceful exit transfer queue entries which have failed fewer than maxFailures times.
+// Entries with fewer failures are returned first, so that repeatedly failing pieces don't block the healthy ones.
+func (db *gracefulexitDB) GetIncompleteExcludingExhausted(ctx context.Context, nodeID storj.NodeID, maxFailures int, limit int) (_ []*gracefulexit.TransferQueueItem, err error) {
+	defer mon.Task()(&ctx)(&err)
+	return db.getIncompleteExcludingExhausted(ctx, nodeID, maxFailures, limit, ``, `COALESCE(failed_count, 0) ASC, queued_at ASC`)
+}
+
+// GetFailedItems gets incomplete graceful exit transfer queue entries which have failed at least once but fewer than maxFailures
+// times, ordered by the last failure ascending, so that the entries which failed the longest ago are retried first.
+// Entries which were requested again after their last failure are in flight and left out.
+func (db *gracefulexitDB) GetFailedItems(ctx context.Context, nodeID storj.NodeID, maxFailures int, limit int) (_ []*gracefulexit.TransferQueueItem, err error) {
+	defer mon.Task()(&ctx)(&err)
+	return db.getIncompleteExcludingExhausted(ctx, nodeID, maxFailures, limit,
+		`AND last_failed_at IS NOT NULL AND (requested_at IS NULL OR last_failed_at >= requested_at)`,
+		`last_failed_at ASC`)
+}
+
+// getIncompleteExcludingExhausted gets incomplete graceful exit transfer queue entries which have failed fewer than maxFailures
+// times and match the additional filter, in the given order.
+func (db *gracefulexitDB) getIncompleteExcludingExhausted(ctx context.Context, nodeID storj.NodeID, maxFailures int, limit int, filter, orderBy string) (_ []*gracefulexit.TransferQueueItem, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.db.QueryContext(ctx, db.db.Rebind(`
+		SELECT node_id, path, piece_num, durability_ratio, queued_at, requested_at, last_failed_at, last_failed_code, failed_count, finished_at
+		FROM graceful_exit_transfer_queue
+		WHERE node_id = ?
+			AND finished_at IS NULL
+			AND COALESCE(failed_count, 0) < ?
+			`+filter+`
+		ORDER BY `+orderBy+`
+		LIMIT ?`,
+	), nodeID.Bytes(), maxFailures, limit)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	var items []*gracefulexit.TransferQueueItem
+	for rows.Next() {
+		item, err := scanTransferQueueItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, Error.Wrap(rows.Err())
+}
+
+// GetIncompleteByDurability gets incomplete graceful exit transfer queue entries ordered by the durability ratio ascending,
+// so that the pieces of the most fragile segments leave the exiting node first. Entries with the same durability ratio
+// are ordered by the queued date.
+func (db *gracefulexitDB) GetIncompleteByDurability(ctx context.Context, nodeID storj.NodeID, limit int, offset int64) (_ []*gracefulexit.TransferQueueItem, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.db.QueryContext(ctx, db.db.Rebind(`
+		SELECT node_id, path, piece_num, durability_ratio, queued_at, requested_at, last_failed_at, last_failed_code, failed_count, finished_at
+		FROM graceful_exit_transfer_queue
+		WHERE node_id = ?
+			AND finished_at IS NULL
+		ORDER BY durability_ratio ASC, queued_at ASC
+		LIMIT ? OFFSET ?`,
+	), nodeID.Bytes(), limit, offset)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	var items []*gracefulexit.TransferQueueItem
+	for rows.Next() {
+		item, err := scanTransferQueueItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, Error.Wrap(rows.Err())
+}
+
+// scanTransferQueueItem scans a graceful exit transfer queue entry with all the columns selected.
+func scanTransferQueueItem(rows *sql.Rows) (*gracefulexit.TransferQueueItem, error) {
+	var dbxTransferQueue dbx.GracefulExitTransferQueue
+	err := rows.Scan(
+		&dbxTransferQueue.NodeId,
+		&dbxTransferQueue.Path,
+		&dbxTransferQueue.PieceNum,
+		&dbxTransferQueue.DurabilityRatio,
+		&dbxTransferQueue.QueuedAt,
+		&dbxTransferQueue.RequestedAt,
+		&dbxTransferQueue.LastFailedAt,
+		&dbxTransferQueue.LastFailedCode,
+		&dbxTransferQueue.FailedCount,
+		&dbxTransferQueue.FinishedAt,
+	)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	return dbxToTransferQueueItem(&dbxTransferQueue)
+}
+
 // GetIncomplete gets incomplete graceful exit transfer queue entries in the database ordered by the queued date ascending.
 func (db *gracefulexitDB) GetIncomplete(ctx context.Context, nodeID storj.NodeID, limit int, offset int64) (_ []*gracefulexit.TransferQueueItem, err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -232,3 +776,256 @@ func dbxToTransferQueueItem(dbxTransferQueue *dbx.GracefulExitTransferQueue) (it
 
 	return item, nil
 }
+
+// exportQueueBatchSize is the number of transfer queue entries read or written at once during exports and imports.
+const exportQueueBatchSize = 1000
+
+// ExportQueue writes the incomplete graceful exit transfer queue entries of a node to w, ordered by the queued date.
+// The entries are read in batches, so entries queued or finished during the export may be missed or written twice.
+func (db *gracefulexitDB) ExportQueue(ctx context.Context, nodeID storj.NodeID, w io.Writer, format string) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	writer, err := gracefulexit.NewQueueWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	for {
+		items, err := db.GetIncomplete(ctx, nodeID, exportQueueBatchSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := writer.Write(*item); err != nil {
+				return err
+			}
+		}
+		if len(items) < exportQueueBatchSize {
+			break
+		}
+		offset += int64(len(items))
+	}
+
+	return writer.Close()
+}
+
+// ImportQueue enqueues the graceful exit transfer queue entries read from r for a node and returns
+// the number of imported and skipped entries.
+//
+// Only the path, the piece number and the durability ratio are imported, the entries are queued anew
+// as if they were never requested. Invalid and finished entries are skipped, as are the entries which
+// are already queued.
+func (db *gracefulexitDB) ImportQueue(ctx context.Context, nodeID storj.NodeID, r io.Reader, format string) (imported, skipped int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	reader, err := gracefulexit.NewQueueReader(r, format)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var batch []gracefulexit.TransferQueueItem
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		inserted, err := db.Enqueue(ctx, batch)
+		if err != nil {
+			return err
+		}
+		imported += inserted
+		skipped += len(batch) - inserted
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		item, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if gracefulexit.ErrImport.Has(err) || (err == nil && !item.FinishedAt.IsZero()) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return imported, skipped, err
+		}
+
+		batch = append(batch, gracefulexit.TransferQueueItem{
+			NodeID:          nodeID,
+			Path:            item.Path,
+			PieceNum:        item.PieceNum,
+			DurabilityRatio: item.DurabilityRatio,
+		})
+		if len(batch) >= exportQueueBatchSize {
+			if err := flush(); err != nil {
+				return imported, skipped, err
+			}
+		}
+	}
+
+	return imported, skipped, flush()
+}
+
+// CountTransferQueueItems returns the number of all, finished and failed graceful exit transfer queue entries for a node.
+//
+// The failed entries are the finished ones which were given up after their last request failed, like in ReconcileProgress,
+// so the queue is drained when total equals finished. Finished entries which have already been deleted aren't counted.
+func (db *gracefulexitDB) CountTransferQueueItems(ctx context.Context, nodeID storj.NodeID) (total, finished, failed int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = db.db.QueryRowContext(ctx, db.db.Rebind(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN finished_at IS NOT NULL THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN finished_at IS NOT NULL AND last_failed_at IS NOT NULL AND (requested_at IS NULL OR last_failed_at >= requested_at) THEN 1 ELSE 0 END), 0)
+		FROM graceful_exit_transfer_queue
+		WHERE node_id = ?`,
+	), nodeID.Bytes()).Scan(&total, &finished, &failed)
+	if err != nil {
+		return 0, 0, 0, Error.Wrap(err)
+	}
+
+	return total, finished, failed, nil
+}
+
+// EstimateQueueSize returns an estimate of the number of incomplete graceful exit transfer queue entries for a node.
+//
+// The count is taken without a transaction using the primary key index, so it is cheap, but
+// items enqueued or finished while the caller is working through the queue are not reflected.
+// It is intended for sizing exports and progress reporting, not for exact bookkeeping.
+func (db *gracefulexitDB) EstimateQueueSize(ctx context.Context, nodeID storj.NodeID) (count int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = db.db.QueryRowContext(ctx, db.db.Rebind(
+		`SELECT COUNT(*) FROM graceful_exit_transfer_queue WHERE node_id = ? AND finished_at IS NULL`,
+	), nodeID.Bytes()).Scan(&count)
+	if err != nil {
+		return 0, Error.Wrap(err)
+	}
+
+	return count, nil
+}
+
+// CompactQueue reclaims the storage of the graceful exit transfer queue entries deleted so far.
+//
+// On postgres the queue table is vacuumed, which makes the space of the deleted rows reusable without
+// locking out the exiting nodes, but usually doesn't return it to the operating system. Its indexes are
+// rebuilt with REINDEX afterwards, as vacuuming doesn't shrink them; this blocks writes to the queue
+// until it's done. On sqlite the whole database is rebuilt with VACUUM, as sqlite can't compact a single
+// table, which fails while other statements are running.
+func (db *gracefulexitDB) CompactQueue(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	switch t := db.db.Driver().(type) {
+	case *sqlite3.SQLiteDriver:
+		_, err = db.db.ExecContext(ctx, `VACUUM`)
+	case *pq.Driver:
+		_, err = db.db.ExecContext(ctx, `VACUUM graceful_exit_transfer_queue`)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+		_, err = db.db.ExecContext(ctx, `REINDEX TABLE graceful_exit_transfer_queue`)
+	default:
+		return Error.New("Unsupported database %t", t)
+	}
+	return Error.Wrap(err)
+}
+
+// QueueStorageSize returns the number of bytes the graceful exit transfer queue takes up.
+//
+// On postgres it's the size of the queue table including its indexes. On sqlite the size
+// of the table can't be told apart, so it's the size of the whole database.
+func (db *gracefulexitDB) QueueStorageSize(ctx context.Context) (size int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	switch t := db.db.Driver().(type) {
+	case *sqlite3.SQLiteDriver:
+		var pageCount, pageSize int64
+		if err := db.db.QueryRowContext(ctx, `PRAGMA page_count`).Scan(&pageCount); err != nil {
+			return 0, Error.Wrap(err)
+		}
+		if err := db.db.QueryRowContext(ctx, `PRAGMA page_size`).Scan(&pageSize); err != nil {
+			return 0, Error.Wrap(err)
+		}
+		return pageCount * pageSize, nil
+	case *pq.Driver:
+		err = db.db.QueryRowContext(ctx, `SELECT pg_total_relation_size('graceful_exit_transfer_queue')`).Scan(&size)
+		return size, Error.Wrap(err)
+	default:
+		return 0, Error.New("Unsupported database %t", t)
+	}
+}
+
+// ValidateExitComplete checks whether the graceful exit of the node may be marked as succeeded:
+// the transfer queue must not have incomplete entries and less than gracefulexit.MaxFailedPiecesPercentage
+// of the pieces may have failed to transfer. When the exit isn't complete, the reason is returned.
+func (db *gracefulexitDB) ValidateExitComplete(ctx context.Context, nodeID storj.NodeID) (complete bool, reason string, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	incomplete, err := db.EstimateQueueSize(ctx, nodeID)
+	if err != nil {
+		return false, "", err
+	}
+	if incomplete > 0 {
+		return false, fmt.Sprintf("%d transfer queue items are incomplete", incomplete), nil
+	}
+
+	progress, err := db.db.Get_GracefulExitProgress_By_NodeId(ctx, dbx.GracefulExitProgress_NodeId(nodeID.Bytes()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, "no graceful exit progress", nil
+		}
+		return false, "", Error.Wrap(err)
+	}
+
+	total := progress.PiecesTransferred + progress.PiecesFailed
+	if total > 0 && progress.PiecesFailed*100 >= total*gracefulexit.MaxFailedPiecesPercentage {
+		return false, fmt.Sprintf("%d of %d pieces failed to transfer, the limit is %d%%",
+			progress.PiecesFailed, total, gracefulexit.MaxFailedPiecesPercentage), nil
+	}
+
+	return true, "", nil
+}
+
+// DurabilityHistogram returns the number of incomplete graceful exit transfer queue entries for a node in each durability bucket.
+//
+// Buckets are upper bounds: an entry is counted in the smallest bucket that is greater than or equal to its durability ratio.
+// Entries with a durability ratio above the largest bucket are counted under math.Inf(1).
+func (db *gracefulexitDB) DurabilityHistogram(ctx context.Context, nodeID storj.NodeID, buckets []float64) (_ map[float64]int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	bounds := append([]float64{}, buckets...)
+	sort.Float64s(bounds)
+
+	histogram := make(map[float64]int64, len(bounds)+1)
+	for _, bound := range bounds {
+		histogram[bound] = 0
+	}
+	histogram[math.Inf(1)] = 0
+
+	rows, err := db.db.QueryContext(ctx, db.db.Rebind(
+		`SELECT durability_ratio FROM graceful_exit_transfer_queue WHERE node_id = ? AND finished_at IS NULL`,
+	), nodeID.Bytes())
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var durability float64
+		if err := rows.Scan(&durability); err != nil {
+			return nil, Error.Wrap(err)
+		}
+
+		i := sort.SearchFloat64s(bounds, durability)
+		if i < len(bounds) {
+			histogram[bounds[i]]++
+		} else {
+			histogram[math.Inf(1)]++
+		}
+	}
+
+	return histogram, Error.Wrap(rows.Err())
+}
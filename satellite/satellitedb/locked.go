@@ -7,6 +7,7 @@ package satellitedb
 
 import (
 	"context"
+	"io"
 	"sync"
 	"time"
 
@@ -171,6 +172,20 @@ func (m *lockedAPIKeys) Delete(ctx context.Context, id uuid.UUID) error {
 	return m.db.Delete(ctx, id)
 }
 
+// DeleteAllByProjectID deletes all api keys of the project and returns the number of deleted keys
+func (m *lockedAPIKeys) DeleteAllByProjectID(ctx context.Context, projectID uuid.UUID) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.DeleteAllByProjectID(ctx, projectID)
+}
+
+// DeleteExpired deletes the api keys which expired at or before asOf and returns the number of deleted keys
+func (m *lockedAPIKeys) DeleteExpired(ctx context.Context, asOf time.Time) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.DeleteExpired(ctx, asOf)
+}
+
 // Get retrieves APIKeyInfo with given ID
 func (m *lockedAPIKeys) Get(ctx context.Context, id uuid.UUID) (*console.APIKeyInfo, error) {
 	m.Lock()
@@ -185,6 +200,13 @@ func (m *lockedAPIKeys) GetByHead(ctx context.Context, head []byte) (*console.AP
 	return m.db.GetByHead(ctx, head)
 }
 
+// GetByHeadPrefix retrieves the APIKeyInfos whose head starts with prefix, without the secrets
+func (m *lockedAPIKeys) GetByHeadPrefix(ctx context.Context, prefix []byte) ([]console.APIKeyInfo, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.GetByHeadPrefix(ctx, prefix)
+}
+
 // GetPagedByProjectID is a method for querying API keys from the database by projectID and cursor
 func (m *lockedAPIKeys) GetPagedByProjectID(ctx context.Context, projectID uuid.UUID, cursor console.APIKeyCursor) (akp *console.APIKeyPage, err error) {
 	m.Lock()
@@ -192,13 +214,46 @@ func (m *lockedAPIKeys) GetPagedByProjectID(ctx context.Context, projectID uuid.
 	return m.db.GetPagedByProjectID(ctx, projectID, cursor)
 }
 
-// Update updates APIKeyInfo in store
+// ProjectStats returns the number of api keys for the project and the creation time of the most recent one
+func (m *lockedAPIKeys) ProjectStats(ctx context.Context, projectID uuid.UUID) (total int, lastCreated *time.Time, err error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.ProjectStats(ctx, projectID)
+}
+
+// RotateSecret replaces the head and secret of the api key, keeping its id, name, project and creation date
+func (m *lockedAPIKeys) RotateSecret(ctx context.Context, id uuid.UUID, newHead []byte, newSecret []byte) (*console.APIKeyInfo, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.RotateSecret(ctx, id, newHead, newSecret)
+}
+
+// StreamByProjectID calls fn for every API key of the project ordered by creation date, without the secrets
+func (m *lockedAPIKeys) StreamByProjectID(ctx context.Context, projectID uuid.UUID, fn func(console.APIKeyInfo) error) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.StreamByProjectID(ctx, projectID, func(a0 console.APIKeyInfo) error {
+		m.Unlock()
+		defer m.Lock()
+		return fn(a0)
+	})
+}
+
+// Update updates the name and the expiration of APIKeyInfo in store, the fields which aren't set are kept
 func (m *lockedAPIKeys) Update(ctx context.Context, key console.APIKeyInfo) error {
 	m.Lock()
 	defer m.Unlock()
 	return m.db.Update(ctx, key)
 }
 
+// UpdateLastUsed records that the api key was used at the given time, unless it was already recorded
+// as used less than APIKeyLastUsedResolution before, which keeps the writes for busy keys low
+func (m *lockedAPIKeys) UpdateLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.UpdateLastUsed(ctx, id, at)
+}
+
 // BucketUsage is a getter for accounting.BucketUsage repository
 func (m *lockedConsole) BucketUsage() accounting.BucketUsage {
 	m.Lock()
@@ -602,6 +657,27 @@ type lockedGracefulExit struct {
 	db gracefulexit.DB
 }
 
+// CompactQueue reclaims the storage of deleted graceful exit transfer queue entries, see the implementations for the backend differences.
+func (m *lockedGracefulExit) CompactQueue(ctx context.Context) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.CompactQueue(ctx)
+}
+
+// CountTransferQueueItems returns the number of all, finished and failed graceful exit transfer queue entries for a node, where the failed entries are finished too.
+func (m *lockedGracefulExit) CountTransferQueueItems(ctx context.Context, nodeID storj.NodeID) (total int64, finished int64, failed int64, err error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.CountTransferQueueItems(ctx, nodeID)
+}
+
+// DeleteAbandonedQueueItems deletes incomplete graceful exit transfer queue entries queued more than olderThan ago which haven't been requested.
+func (m *lockedGracefulExit) DeleteAbandonedQueueItems(ctx context.Context, olderThan time.Duration) (int, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.DeleteAbandonedQueueItems(ctx, olderThan)
+}
+
 // DeleteFinishedTransferQueueItem deletes finiahed graceful exit transfer queue entries.
 func (m *lockedGracefulExit) DeleteFinishedTransferQueueItems(ctx context.Context, nodeID storj.NodeID) error {
 	m.Lock()
@@ -623,13 +699,48 @@ func (m *lockedGracefulExit) DeleteTransferQueueItems(ctx context.Context, nodeI
 	return m.db.DeleteTransferQueueItems(ctx, nodeID)
 }
 
-// Enqueue batch inserts graceful exit transfer queue entries it does not exist.
-func (m *lockedGracefulExit) Enqueue(ctx context.Context, items []gracefulexit.TransferQueueItem) error {
+// DurabilityHistogram returns the number of incomplete graceful exit transfer queue entries for a node in each durability bucket.
+func (m *lockedGracefulExit) DurabilityHistogram(ctx context.Context, nodeID storj.NodeID, buckets []float64) (map[float64]int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.DurabilityHistogram(ctx, nodeID, buckets)
+}
+
+// Enqueue batch inserts graceful exit transfer queue entries it does not exist and returns the number of inserted entries.
+func (m *lockedGracefulExit) Enqueue(ctx context.Context, items []gracefulexit.TransferQueueItem) (inserted int, err error) {
 	m.Lock()
 	defer m.Unlock()
 	return m.db.Enqueue(ctx, items)
 }
 
+// EstimateQueueSize returns an estimate of the number of incomplete graceful exit transfer queue entries for a node.
+func (m *lockedGracefulExit) EstimateQueueSize(ctx context.Context, nodeID storj.NodeID) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.EstimateQueueSize(ctx, nodeID)
+}
+
+// ExportQueue writes the incomplete graceful exit transfer queue entries of a node to w in the format, see ExportHeader for the columns.
+func (m *lockedGracefulExit) ExportQueue(ctx context.Context, nodeID storj.NodeID, w io.Writer, format string) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.ExportQueue(ctx, nodeID, w, format)
+}
+
+// GetExitCompletion returns the finished and total pieces of the graceful exit of a node and whether its transfer queue is still being built.
+func (m *lockedGracefulExit) GetExitCompletion(ctx context.Context, nodeID storj.NodeID) (*gracefulexit.ExitCompletion, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.GetExitCompletion(ctx, nodeID)
+}
+
+// GetFailedItems gets incomplete graceful exit transfer queue entries which have failed fewer than maxFailures times and aren't requested again, ordered by the last failure ascending.
+func (m *lockedGracefulExit) GetFailedItems(ctx context.Context, nodeID storj.NodeID, maxFailures int, limit int) ([]*gracefulexit.TransferQueueItem, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.GetFailedItems(ctx, nodeID, maxFailures, limit)
+}
+
 // GetIncomplete gets incomplete graceful exit transfer queue entries ordered by the queued date ascending.
 func (m *lockedGracefulExit) GetIncomplete(ctx context.Context, nodeID storj.NodeID, limit int, offset int64) ([]*gracefulexit.TransferQueueItem, error) {
 	m.Lock()
@@ -637,6 +748,20 @@ func (m *lockedGracefulExit) GetIncomplete(ctx context.Context, nodeID storj.Nod
 	return m.db.GetIncomplete(ctx, nodeID, limit, offset)
 }
 
+// GetIncompleteByDurability gets incomplete graceful exit transfer queue entries ordered by the durability ratio ascending.
+func (m *lockedGracefulExit) GetIncompleteByDurability(ctx context.Context, nodeID storj.NodeID, limit int, offset int64) ([]*gracefulexit.TransferQueueItem, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.GetIncompleteByDurability(ctx, nodeID, limit, offset)
+}
+
+// GetIncompleteExcludingExhausted gets incomplete graceful exit transfer queue entries which have failed fewer than maxFailures times, ordered by the failure count ascending.
+func (m *lockedGracefulExit) GetIncompleteExcludingExhausted(ctx context.Context, nodeID storj.NodeID, maxFailures int, limit int) ([]*gracefulexit.TransferQueueItem, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.GetIncompleteExcludingExhausted(ctx, nodeID, maxFailures, limit)
+}
+
 // GetProgress gets a graceful exit progress entry.
 func (m *lockedGracefulExit) GetProgress(ctx context.Context, nodeID storj.NodeID) (*gracefulexit.Progress, error) {
 	m.Lock()
@@ -644,6 +769,13 @@ func (m *lockedGracefulExit) GetProgress(ctx context.Context, nodeID storj.NodeI
 	return m.db.GetProgress(ctx, nodeID)
 }
 
+// GetProgressBatch gets the graceful exit progress entries of the nodes, nodes without an entry are missing from the result.
+func (m *lockedGracefulExit) GetProgressBatch(ctx context.Context, nodeIDs []storj.NodeID) (map[storj.NodeID]*gracefulexit.Progress, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.GetProgressBatch(ctx, nodeIDs)
+}
+
 // GetTransferQueueItem gets a graceful exit transfer queue entry.
 func (m *lockedGracefulExit) GetTransferQueueItem(ctx context.Context, nodeID storj.NodeID, path []byte) (*gracefulexit.TransferQueueItem, error) {
 	m.Lock()
@@ -651,6 +783,27 @@ func (m *lockedGracefulExit) GetTransferQueueItem(ctx context.Context, nodeID st
 	return m.db.GetTransferQueueItem(ctx, nodeID, path)
 }
 
+// GetTransferQueueItems gets the graceful exit transfer queue entries of the paths, keyed by the hex encoded path.
+func (m *lockedGracefulExit) GetTransferQueueItems(ctx context.Context, nodeID storj.NodeID, paths [][]byte) (map[string]*gracefulexit.TransferQueueItem, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.GetTransferQueueItems(ctx, nodeID, paths)
+}
+
+// GetTransferredBytes returns the sum of the recorded transferred bytes of a node.
+func (m *lockedGracefulExit) GetTransferredBytes(ctx context.Context, nodeID storj.NodeID) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.GetTransferredBytes(ctx, nodeID)
+}
+
+// ImportQueue enqueues the graceful exit transfer queue entries read from r in the format, as written by ExportQueue, for a node.
+func (m *lockedGracefulExit) ImportQueue(ctx context.Context, nodeID storj.NodeID, r io.Reader, format string) (imported int, skipped int, err error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.ImportQueue(ctx, nodeID, r, format)
+}
+
 // IncrementProgress increments transfer stats for a node.
 func (m *lockedGracefulExit) IncrementProgress(ctx context.Context, nodeID storj.NodeID, bytes int64, successfulTransfers int64, failedTransfers int64) error {
 	m.Lock()
@@ -658,13 +811,69 @@ func (m *lockedGracefulExit) IncrementProgress(ctx context.Context, nodeID storj
 	return m.db.IncrementProgress(ctx, nodeID, bytes, successfulTransfers, failedTransfers)
 }
 
-// UpdateTransferQueueItem creates a graceful exit transfer queue entry.
+// PurgeQueueForFinishedExits deletes all graceful exit transfer queue entries for nodes that have finished exiting.
+func (m *lockedGracefulExit) PurgeQueueForFinishedExits(ctx context.Context, finished []storj.NodeID) (int, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.PurgeQueueForFinishedExits(ctx, finished)
+}
+
+// QueueStorageSize returns the number of bytes the graceful exit transfer queue takes up.
+func (m *lockedGracefulExit) QueueStorageSize(ctx context.Context) (int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.QueueStorageSize(ctx)
+}
+
+// ReconcileProgress recomputes the graceful exit progress of a node from its finished transfer queue entries and recorded transfers and rewrites it.
+func (m *lockedGracefulExit) ReconcileProgress(ctx context.Context, nodeID storj.NodeID) (*gracefulexit.Progress, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.ReconcileProgress(ctx, nodeID)
+}
+
+// RecordTransfer increments transfer stats for a node by a successful transfer and records the transferred bytes of the path.
+func (m *lockedGracefulExit) RecordTransfer(ctx context.Context, nodeID storj.NodeID, path []byte, bytes int64) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.RecordTransfer(ctx, nodeID, path, bytes)
+}
+
+// RequeueTransferItem clears the finished date and the failures of a graceful exit transfer queue entry and queues it again, so that it is transferred again.
+func (m *lockedGracefulExit) RequeueTransferItem(ctx context.Context, nodeID storj.NodeID, path []byte) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.RequeueTransferItem(ctx, nodeID, path)
+}
+
+// TransferredPerDay returns the recorded transferred bytes of a node for every UTC day between from and to inclusive, keyed by the start of the day.
+func (m *lockedGracefulExit) TransferredPerDay(ctx context.Context, nodeID storj.NodeID, from time.Time, to time.Time) (map[time.Time]int64, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.TransferredPerDay(ctx, nodeID, from, to)
+}
+
+// UpdateTransferQueueItem updates a graceful exit transfer queue entry, rejecting invalid transitions with ErrInvalidTransition.
 func (m *lockedGracefulExit) UpdateTransferQueueItem(ctx context.Context, item gracefulexit.TransferQueueItem) error {
 	m.Lock()
 	defer m.Unlock()
 	return m.db.UpdateTransferQueueItem(ctx, item)
 }
 
+// UpdateTransferQueueItemsBatch updates graceful exit transfer queue entries in a single transaction, either all of them or none.
+func (m *lockedGracefulExit) UpdateTransferQueueItemsBatch(ctx context.Context, items []gracefulexit.TransferQueueItem) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.UpdateTransferQueueItemsBatch(ctx, items)
+}
+
+// ValidateExitComplete checks whether the graceful exit of the node may be marked as succeeded and returns the reason when it may not.
+func (m *lockedGracefulExit) ValidateExitComplete(ctx context.Context, nodeID storj.NodeID) (complete bool, reason string, err error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.ValidateExitComplete(ctx, nodeID)
+}
+
 // Irreparable returns database for failed repairs
 func (m *locked) Irreparable() irreparable.DB {
 	m.Lock()
@@ -907,6 +1116,13 @@ func (m *lockedOverlayCache) UpdateCheckIn(ctx context.Context, node overlay.Nod
 	return m.db.UpdateCheckIn(ctx, node, config)
 }
 
+// UpdateExitLoopCompleted records when all the pieces of an exiting node were added to the graceful exit transfer queue.
+func (m *lockedOverlayCache) UpdateExitLoopCompleted(ctx context.Context, nodeID storj.NodeID, completedAt time.Time) (err error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.db.UpdateExitLoopCompleted(ctx, nodeID, completedAt)
+}
+
 // UpdateNodeInfo updates node dossier with info requested from the node itself like node type, email, wallet, capacity, and version.
 func (m *lockedOverlayCache) UpdateNodeInfo(ctx context.Context, node storj.NodeID, nodeInfo *pb.InfoResponse) (stats *overlay.NodeDossier, err error) {
 	m.Lock()
@@ -1277,6 +1277,52 @@ func (db *DB) PostgresMigration() *migrate.Migration {
 					`ALTER TABLE graceful_exit_transfer_queue ALTER COLUMN finished_at TYPE timestamp;`,
 				},
 			},
+			{
+				DB:          db.db,
+				Description: "Add graceful_exit_transferred table",
+				Version:     59,
+				Action: migrate.SQL{
+					`CREATE TABLE graceful_exit_transferred (
+						node_id bytea NOT NULL,
+						path bytea NOT NULL,
+						bytes bigint NOT NULL,
+						transferred_at timestamp NOT NULL,
+						PRIMARY KEY ( node_id, path, transferred_at )
+					);`,
+				},
+			},
+			{
+				DB:          db.db,
+				Description: "Add index on graceful_exit_transfer_queue for ordering by durability ratio",
+				Version:     60,
+				Action: migrate.SQL{
+					`CREATE INDEX graceful_exit_transfer_queue_durability_ratio ON graceful_exit_transfer_queue ( node_id, finished_at, durability_ratio );`,
+				},
+			},
+			{
+				DB:          db.db,
+				Description: "Add expires_at to api_keys",
+				Version:     61,
+				Action: migrate.SQL{
+					`ALTER TABLE api_keys ADD COLUMN expires_at timestamp with time zone;`,
+				},
+			},
+			{
+				DB:          db.db,
+				Description: "Add last_used_at to api_keys",
+				Version:     62,
+				Action: migrate.SQL{
+					`ALTER TABLE api_keys ADD COLUMN last_used_at timestamp with time zone;`,
+				},
+			},
+			{
+				DB:          db.db,
+				Description: "Add caveats to api_keys",
+				Version:     63,
+				Action: migrate.SQL{
+					`ALTER TABLE api_keys ADD COLUMN caveats bytea;`,
+				},
+			},
 		},
 	}
 }
@@ -903,6 +903,22 @@ func (cache *overlaycache) UpdateUptime(ctx context.Context, nodeID storj.NodeID
 	return getNodeStats(dbNode), Error.Wrap(tx.Commit())
 }
 
+// UpdateExitLoopCompleted records when all the pieces of an exiting node were added to the graceful exit transfer queue.
+func (cache *overlaycache) UpdateExitLoopCompleted(ctx context.Context, nodeID storj.NodeID, completedAt time.Time) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	dbNode, err := cache.db.Update_Node_By_Id(ctx, dbx.Node_Id(nodeID.Bytes()), dbx.Node_Update_Fields{
+		ExitLoopCompletedAt: dbx.Node_ExitLoopCompletedAt(completedAt.UTC()),
+	})
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if dbNode == nil {
+		return Error.New("unable to get node by ID: %v", nodeID)
+	}
+	return nil
+}
+
 // AllPieceCounts returns a map of node IDs to piece counts from the db.
 // NB: a valid, partial piece map can be returned even if node ID parsing error(s) are returned.
 func (cache *overlaycache) AllPieceCounts(ctx context.Context) (_ map[storj.NodeID]int, err error) {
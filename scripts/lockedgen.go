@@ -201,6 +201,7 @@ func (code *Code) PrintSignatureTuple(tuple *types.Tuple, needsNames bool) {
 }
 
 // PrintCall prints a call using the specified signature.
+// Callbacks are called without holding the lock, so that they can use the database too.
 func (code *Code) PrintCall(sig *types.Signature) {
 	code.Printf("(")
 	defer code.Printf(")")
@@ -210,10 +211,30 @@ func (code *Code) PrintCall(sig *types.Signature) {
 		if i != 0 {
 			code.Printf(", ")
 		}
+		if callback, ok := params.At(i).Type().(*types.Signature); ok {
+			code.PrintUnlockedCallback(params.At(i), i, callback)
+			continue
+		}
 		code.PrintName(params.At(i), i, true)
 	}
 }
 
+// PrintUnlockedCallback prints a func literal calling the callback v with the lock released.
+func (code *Code) PrintUnlockedCallback(v *types.Var, index int, sig *types.Signature) {
+	code.Printf("func")
+	code.PrintSignature(sig)
+	code.Printf(" {\n")
+	code.Printf("		m.Unlock(); defer m.Lock()\n")
+	code.Printf("		")
+	if sig.Results().Len() > 0 {
+		code.Printf("return ")
+	}
+	code.PrintName(v, index, true)
+	code.PrintCall(sig)
+	code.Printf("\n")
+	code.Printf("	}")
+}
+
 // PrintName prints an appropriate name from signature tuple.
 func (code *Code) PrintName(v *types.Var, index int, needsNames bool) bool {
 	name := v.Name()
@@ -65,17 +65,17 @@ func (blob *blobReader) StorageFormatVersion() storage.FormatVersion {
 // blobWriter implements writing blobs
 type blobWriter struct {
 	ref           storage.BlobRef
-	store         *Store
+	dir           *Dir
 	closed        bool
 	formatVersion storage.FormatVersion
 
 	*os.File
 }
 
-func newBlobWriter(ref storage.BlobRef, store *Store, formatVersion storage.FormatVersion, file *os.File) *blobWriter {
+func newBlobWriter(ref storage.BlobRef, dir *Dir, formatVersion storage.FormatVersion, file *os.File) *blobWriter {
 	return &blobWriter{
 		ref:           ref,
-		store:         store,
+		dir:           dir,
 		closed:        false,
 		formatVersion: formatVersion,
 		File:          file,
@@ -101,7 +101,7 @@ func (blob *blobWriter) Commit(ctx context.Context) (err error) {
 		return Error.New("already closed")
 	}
 	blob.closed = true
-	err = blob.store.dir.Commit(ctx, blob.File, blob.ref, blob.formatVersion)
+	err = blob.dir.Commit(ctx, blob.File, blob.ref, blob.formatVersion)
 	return Error.Wrap(err)
 }
 
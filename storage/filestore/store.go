@@ -6,6 +6,7 @@ package filestore
 import (
 	"context"
 	"os"
+	"sync"
 
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
@@ -25,6 +26,7 @@ var (
 
 // Store implements a blob store
 type Store struct {
+	mu  sync.RWMutex
 	dir *Dir
 	log *zap.Logger
 }
@@ -46,10 +48,26 @@ func NewAt(log *zap.Logger, path string) (*Store, error) {
 // Close closes the store.
 func (store *Store) Close() error { return nil }
 
+// Dir returns the directory the blobs are currently stored in.
+func (store *Store) Dir() *Dir {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.dir
+}
+
+// SwapDir repoints the store to dir and returns the previous directory. Blobs which are
+// being written while swapping are committed to the directory they were created in.
+func (store *Store) SwapDir(dir *Dir) (previous *Dir) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	previous, store.dir = store.dir, dir
+	return previous
+}
+
 // Open loads blob with the specified hash
 func (store *Store) Open(ctx context.Context, ref storage.BlobRef) (_ storage.BlobReader, err error) {
 	defer mon.Task()(&ctx)(&err)
-	file, formatVer, err := store.dir.Open(ctx, ref)
+	file, formatVer, err := store.Dir().Open(ctx, ref)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, err
@@ -63,7 +81,7 @@ func (store *Store) Open(ctx context.Context, ref storage.BlobRef) (_ storage.Bl
 // storage formats to find the blob.
 func (store *Store) OpenWithStorageFormat(ctx context.Context, blobRef storage.BlobRef, formatVer storage.FormatVersion) (_ storage.BlobReader, err error) {
 	defer mon.Task()(&ctx)(&err)
-	file, err := store.dir.OpenWithStorageFormat(ctx, blobRef, formatVer)
+	file, err := store.Dir().OpenWithStorageFormat(ctx, blobRef, formatVer)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, err
@@ -76,28 +94,28 @@ func (store *Store) OpenWithStorageFormat(ctx context.Context, blobRef storage.B
 // Stat looks up disk metadata on the blob file
 func (store *Store) Stat(ctx context.Context, ref storage.BlobRef) (_ storage.BlobInfo, err error) {
 	defer mon.Task()(&ctx)(&err)
-	info, err := store.dir.Stat(ctx, ref)
+	info, err := store.Dir().Stat(ctx, ref)
 	return info, Error.Wrap(err)
 }
 
 // StatWithStorageFormat looks up disk metadata on the blob file with the given storage format version
 func (store *Store) StatWithStorageFormat(ctx context.Context, ref storage.BlobRef, formatVer storage.FormatVersion) (_ storage.BlobInfo, err error) {
 	defer mon.Task()(&ctx)(&err)
-	info, err := store.dir.StatWithStorageFormat(ctx, ref, formatVer)
+	info, err := store.Dir().StatWithStorageFormat(ctx, ref, formatVer)
 	return info, Error.Wrap(err)
 }
 
 // Delete deletes blobs with the specified ref
 func (store *Store) Delete(ctx context.Context, ref storage.BlobRef) (err error) {
 	defer mon.Task()(&ctx)(&err)
-	err = store.dir.Delete(ctx, ref)
+	err = store.Dir().Delete(ctx, ref)
 	return Error.Wrap(err)
 }
 
 // GarbageCollect tries to delete any files that haven't yet been deleted
 func (store *Store) GarbageCollect(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(&err)
-	err = store.dir.GarbageCollect(ctx)
+	err = store.Dir().GarbageCollect(ctx)
 	return Error.Wrap(err)
 }
 
@@ -105,11 +123,12 @@ func (store *Store) GarbageCollect(ctx context.Context) (err error) {
 // optionally takes a size argument for performance improvements, -1 is unknown size
 func (store *Store) Create(ctx context.Context, ref storage.BlobRef, size int64) (_ storage.BlobWriter, err error) {
 	defer mon.Task()(&ctx)(&err)
-	file, err := store.dir.CreateTemporaryFile(ctx, size)
+	dir := store.Dir()
+	file, err := dir.CreateTemporaryFile(ctx, size)
 	if err != nil {
 		return nil, Error.Wrap(err)
 	}
-	return newBlobWriter(ref, store, MaxFormatVersionSupported, file), nil
+	return newBlobWriter(ref, dir, MaxFormatVersionSupported, file), nil
 }
 
 // SpaceUsed adds up the space used in all namespaces for blob storage
@@ -152,7 +171,7 @@ func (store *Store) SpaceUsedInNamespace(ctx context.Context, namespace []byte)
 
 // FreeSpace returns how much space left in underlying directory
 func (store *Store) FreeSpace() (int64, error) {
-	info, err := store.dir.Info()
+	info, err := store.Dir().Info()
 	if err != nil {
 		return 0, err
 	}
@@ -162,14 +181,14 @@ func (store *Store) FreeSpace() (int64, error) {
 // ListNamespaces finds all known namespace IDs in use in local storage. They are not
 // guaranteed to contain any blobs.
 func (store *Store) ListNamespaces(ctx context.Context) (ids [][]byte, err error) {
-	return store.dir.ListNamespaces(ctx)
+	return store.Dir().ListNamespaces(ctx)
 }
 
 // WalkNamespace executes walkFunc for each locally stored blob in the given namespace. If walkFunc
 // returns a non-nil error, WalkNamespace will stop iterating and return the error immediately. The
 // ctx parameter is intended specifically to allow canceling iteration early.
 func (store *Store) WalkNamespace(ctx context.Context, namespace []byte, walkFunc func(storage.BlobInfo) error) (err error) {
-	return store.dir.WalkNamespace(ctx, namespace, walkFunc)
+	return store.Dir().WalkNamespace(ctx, namespace, walkFunc)
 }
 
 // StoreForTest is a wrapper for Store that also allows writing new V0 blobs (in order to test
@@ -182,9 +201,10 @@ type StoreForTest struct {
 func (store *Store) TestCreateV0(ctx context.Context, ref storage.BlobRef) (_ storage.BlobWriter, err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	file, err := store.dir.CreateTemporaryFile(ctx, -1)
+	dir := store.Dir()
+	file, err := dir.CreateTemporaryFile(ctx, -1)
 	if err != nil {
 		return nil, Error.Wrap(err)
 	}
-	return newBlobWriter(ref, store, FormatV0, file), nil
+	return newBlobWriter(ref, dir, FormatV0, file), nil
 }
@@ -0,0 +1,75 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package analyze implements periodic refreshing of the query planner
+// statistics of the storage node databases.
+package analyze
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	monkit "gopkg.in/spacemonkeygo/monkit.v2"
+
+	"storj.io/storj/internal/sync2"
+)
+
+var mon = monkit.Package()
+
+// Config defines parameters for the analyze chore.
+type Config struct {
+	Interval time.Duration `help:"how frequently ANALYZE is run on the storage node databases, 0 disables it" default:"24h0m0s"`
+}
+
+// DB is the database that is analyzed.
+type DB interface {
+	// AnalyzeAll runs ANALYZE on all the databases.
+	AnalyzeAll(ctx context.Context) error
+}
+
+// Chore periodically runs ANALYZE on the storage node databases, so that
+// the query planner statistics don't go stale on busy nodes.
+//
+// architecture: Chore
+type Chore struct {
+	log    *zap.Logger
+	db     DB
+	config Config
+
+	Loop sync2.Cycle
+}
+
+// NewChore creates a new analyze chore.
+func NewChore(log *zap.Logger, db DB, config Config) *Chore {
+	return &Chore{
+		log:    log,
+		db:     db,
+		config: config,
+		Loop:   *sync2.NewCycle(config.Interval),
+	}
+}
+
+// Run runs the analyze chore.
+func (chore *Chore) Run(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if chore.config.Interval <= 0 {
+		chore.log.Debug("analyze chore is disabled")
+		return nil
+	}
+
+	return chore.Loop.Run(ctx, func(ctx context.Context) error {
+		err := chore.db.AnalyzeAll(ctx)
+		if err != nil {
+			chore.log.Error("error during analyzing databases: ", zap.Error(err))
+		}
+		return nil
+	})
+}
+
+// Close stops the analyze chore.
+func (chore *Chore) Close() (err error) {
+	chore.Loop.Close()
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package analyze_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/analyze"
+)
+
+type countingDB struct {
+	calls int64
+}
+
+func (db *countingDB) AnalyzeAll(ctx context.Context) error {
+	atomic.AddInt64(&db.calls, 1)
+	return nil
+}
+
+func TestChore(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	db := &countingDB{}
+	chore := analyze.NewChore(zaptest.NewLogger(t), db, analyze.Config{Interval: time.Hour})
+	defer ctx.Check(chore.Close)
+
+	ctx.Go(func() error {
+		return chore.Run(ctx)
+	})
+
+	chore.Loop.TriggerWait()
+	chore.Loop.TriggerWait()
+	require.True(t, atomic.LoadInt64(&db.calls) >= 2)
+}
+
+func TestChoreDisabled(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	db := &countingDB{}
+	chore := analyze.NewChore(zaptest.NewLogger(t), db, analyze.Config{Interval: 0})
+	defer ctx.Check(chore.Close)
+
+	require.NoError(t, chore.Run(ctx))
+	require.Zero(t, atomic.LoadInt64(&db.calls))
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package bandwidth
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/storj/internal/memory"
+	"storj.io/storj/pkg/pb"
+)
+
+var (
+	// ErrActionCaps is the error class for invalid action caps.
+	ErrActionCaps = errs.Class("bandwidth action caps")
+	// ErrActionCapExceeded is returned when the bandwidth used this month for an action reached its cap.
+	ErrActionCapExceeded = errs.Class("bandwidth action cap exceeded")
+)
+
+// ActionCaps are the monthly bandwidth caps of piece actions, the actions without a cap are only
+// limited by the total allocated bandwidth. It's configured as a comma separated list of
+// action:size pairs, e.g. GET_REPAIR:1TB,PUT_REPAIR:500GB.
+type ActionCaps map[pb.PieceAction]memory.Size
+
+// String returns the caps in the format accepted by Set, ordered by action. The sizes are
+// formatted as exact byte counts, so that parsing them again returns the same caps.
+func (caps ActionCaps) String() string {
+	var actions []pb.PieceAction
+	for action := range caps {
+		actions = append(actions, action)
+	}
+	sort.Slice(actions, func(i, k int) bool { return actions[i] < actions[k] })
+
+	var pairs []string
+	for _, action := range actions {
+		pairs = append(pairs, action.String()+":"+strconv.FormatInt(caps[action].Int64(), 10))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses a comma separated list of action:size pairs, see ActionCaps.
+func (caps *ActionCaps) Set(s string) error {
+	parsed := ActionCaps{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return ErrActionCaps.New("invalid cap %q, expected action:size", pair)
+		}
+
+		action, ok := pb.PieceAction_value[strings.ToUpper(strings.TrimSpace(parts[0]))]
+		if !ok {
+			return ErrActionCaps.New("unknown action %q", parts[0])
+		}
+
+		var size memory.Size
+		if err := size.Set(strings.TrimSpace(parts[1])); err != nil {
+			return ErrActionCaps.New("invalid size %q: %v", parts[1], err)
+		}
+		parsed[pb.PieceAction(action)] = size
+	}
+
+	*caps = parsed
+	return nil
+}
+
+// Type implements pflag.Value.
+func (ActionCaps) Type() string { return "bandwidth.ActionCaps" }
+
+// Check returns ErrActionCapExceeded when the bandwidth used this month for the action reached its cap.
+//
+// The usage comes from the cached month-to-date summary, so a cap may be exceeded by the traffic
+// of the requests which were accepted while the summary was refreshed.
+func (caps ActionCaps) Check(ctx context.Context, db DB, action pb.PieceAction) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	limit, ok := caps[action]
+	if !ok || limit <= 0 {
+		return nil
+	}
+
+	usage, err := db.CachedSummary(ctx)
+	if err != nil {
+		return err
+	}
+
+	used := usage.action(action)
+	if used >= limit.Int64() {
+		mon.Meter("bandwidth_action_cap_exceeded").Mark(1)
+		return ErrActionCapExceeded.New("%s used %s of %s this month", action, memory.Size(used), limit)
+	}
+	return nil
+}
+
+// action returns the bandwidth used for the action.
+func (usage *Usage) action(action pb.PieceAction) int64 {
+	switch action {
+	case pb.PieceAction_INVALID:
+		return usage.Invalid
+	case pb.PieceAction_PUT:
+		return usage.Put
+	case pb.PieceAction_GET:
+		return usage.Get
+	case pb.PieceAction_GET_AUDIT:
+		return usage.GetAudit
+	case pb.PieceAction_GET_REPAIR:
+		return usage.GetRepair
+	case pb.PieceAction_PUT_REPAIR:
+		return usage.PutRepair
+	case pb.PieceAction_DELETE:
+		return usage.Delete
+	default:
+		return usage.Unknown
+	}
+}
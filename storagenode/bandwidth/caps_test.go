@@ -0,0 +1,75 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package bandwidth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/internal/memory"
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/bandwidth"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestActionCapsSet(t *testing.T) {
+	var caps bandwidth.ActionCaps
+	require.NoError(t, caps.Set("GET_REPAIR:1TB, put_repair:500GB"))
+	require.Equal(t, bandwidth.ActionCaps{
+		pb.PieceAction_GET_REPAIR: memory.TB,
+		pb.PieceAction_PUT_REPAIR: 500 * memory.GB,
+	}, caps)
+
+	var parsed bandwidth.ActionCaps
+	require.NoError(t, parsed.Set(caps.String()))
+	require.Equal(t, caps, parsed)
+
+	caps = bandwidth.ActionCaps{pb.PieceAction_GET: memory.TB + 1}
+	require.NoError(t, parsed.Set(caps.String()))
+	require.Equal(t, caps, parsed)
+
+	require.NoError(t, caps.Set(""))
+	require.Empty(t, caps)
+
+	require.True(t, bandwidth.ErrActionCaps.Has(caps.Set("GET_REPAIR")))
+	require.True(t, bandwidth.ErrActionCaps.Has(caps.Set("UNKNOWN:1TB")))
+	require.True(t, bandwidth.ErrActionCaps.Has(caps.Set("GET_REPAIR:lots")))
+}
+
+func TestActionCapsCheck(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		caps := bandwidth.ActionCaps{
+			pb.PieceAction_GET_REPAIR: 1 * memory.KiB,
+			pb.PieceAction_GET:        1 * memory.MiB,
+		}
+
+		satelliteID := testrand.NodeID()
+		require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET_REPAIR, 512, time.Now()))
+		require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 512, time.Now()))
+
+		for _, action := range []pb.PieceAction{pb.PieceAction_GET_REPAIR, pb.PieceAction_GET, pb.PieceAction_PUT} {
+			require.NoError(t, caps.Check(ctx, db.Bandwidth(), action), action)
+		}
+
+		// reaching the repair cap doesn't affect the other actions
+		require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET_REPAIR, 512, time.Now()))
+
+		err := caps.Check(ctx, db.Bandwidth(), pb.PieceAction_GET_REPAIR)
+		require.True(t, bandwidth.ErrActionCapExceeded.Has(err))
+		require.NoError(t, caps.Check(ctx, db.Bandwidth(), pb.PieceAction_GET))
+		require.NoError(t, caps.Check(ctx, db.Bandwidth(), pb.PieceAction_PUT))
+
+		// actions without a cap are never rejected
+		require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_PUT, memory.GiB.Int64(), time.Now()))
+		require.NoError(t, caps.Check(ctx, db.Bandwidth(), pb.PieceAction_PUT))
+	})
+}
@@ -4,11 +4,16 @@
 package bandwidth_test
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/sync/errgroup"
 
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testidentity"
@@ -17,6 +22,7 @@ import (
 	"storj.io/storj/pkg/storj"
 	"storj.io/storj/storagenode"
 	"storj.io/storj/storagenode/bandwidth"
+	"storj.io/storj/storagenode/storagenodedb"
 	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
 )
 
@@ -329,6 +335,79 @@ func TestCachedBandwidthMonthRollover(t *testing.T) {
 	})
 }
 
+func TestCachedSummary(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	config := storagenodedbtest.Config(ctx.Dir("storage"))
+	config.BandwidthSummaryCacheInterval = time.Hour
+	db := storagenodedbtest.Open(t, ctx, zaptest.NewLogger(t), config)
+	defer ctx.Check(db.Close)
+
+	bandwidthdb := db.Bandwidth()
+	satelliteID := testrand.NodeID()
+	now := time.Now().UTC()
+
+	usage, err := bandwidthdb.CachedSummary(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), usage.Total())
+
+	// a fresh Add is reflected immediately
+	require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_GET, 100, now))
+
+	usage, err = bandwidthdb.CachedSummary(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), usage.Get)
+
+	// usage written around Add is not seen until the cache is refreshed
+	rawDB := db.RawDatabases()[storagenodedb.BandwidthDBName].GetDB()
+	_, err = rawDB.Exec(`INSERT INTO bandwidth_usage(satellite_id, action, amount, created_at) VALUES(?, ?, ?, ?)`,
+		satelliteID, pb.PieceAction_GET, 50, now)
+	require.NoError(t, err)
+
+	usage, err = bandwidthdb.CachedSummary(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), usage.Get)
+
+	// modifying the returned usage does not affect the cache
+	usage.Get = 0
+	usage, err = bandwidthdb.CachedSummary(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), usage.Get)
+
+	// Add updates the cache without refreshing it
+	require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_PUT, 10, now))
+
+	usage, err = bandwidthdb.CachedSummary(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), usage.Get)
+	require.Equal(t, int64(10), usage.Put)
+
+	// usage of another month isn't included
+	require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_PUT, 1000, now.AddDate(0, -1, 0)))
+
+	usage, err = bandwidthdb.CachedSummary(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), usage.Put)
+
+	// concurrent use is safe
+	var group errgroup.Group
+	for i := 0; i < 10; i++ {
+		group.Go(func() error {
+			if err := bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_PUT, 1, now); err != nil {
+				return err
+			}
+			_, err := bandwidthdb.CachedSummary(ctx)
+			return err
+		})
+	}
+	require.NoError(t, group.Wait())
+
+	usage, err = bandwidthdb.CachedSummary(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(20), usage.Put)
+}
+
 func TestBandwidthRollup(t *testing.T) {
 	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
 		ctx := testcontext.New(t)
@@ -461,3 +540,106 @@ func TestDB_Trivial(t *testing.T) {
 		}
 	})
 }
+
+func TestBandwidthExport(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		bandwidthdb := db.Bandwidth()
+
+		satelliteID := testrand.NodeID()
+		day1 := time.Date(2019, 10, 1, 10, 0, 0, 0, time.UTC)
+		day2 := day1.Add(24 * time.Hour)
+
+		require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_GET, 100, day1))
+		require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_PUT, 30, day1))
+		// move the usage so far to the rollups table
+		require.NoError(t, bandwidthdb.Rollup(ctx))
+		require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_GET, 50, day1.Add(time.Hour)))
+		require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_GET, 20, day2))
+
+		t.Run("csv", func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, bandwidthdb.Export(ctx, day1, day2, &buf, bandwidth.ExportCSV))
+
+			records, err := csv.NewReader(&buf).ReadAll()
+			require.NoError(t, err)
+			require.Equal(t, [][]string{
+				{"satellite_id", "action", "day", "bytes"},
+				{satelliteID.String(), "PUT", "2019-10-01", "30"},
+				{satelliteID.String(), "GET", "2019-10-01", "150"},
+				{satelliteID.String(), "GET", "2019-10-02", "20"},
+			}, records)
+		})
+
+		t.Run("json", func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, bandwidthdb.Export(ctx, day2, day2, &buf, bandwidth.ExportJSON))
+			require.Contains(t, buf.String(), satelliteID.String())
+
+			var rows []bandwidth.ExportRow
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &rows))
+			require.Equal(t, []bandwidth.ExportRow{
+				{SatelliteID: satelliteID, Action: "GET", Day: "2019-10-02", Bytes: 20},
+			}, rows)
+		})
+
+		t.Run("empty json", func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, bandwidthdb.Export(ctx, day2.Add(48*time.Hour), day2.Add(48*time.Hour), &buf, bandwidth.ExportJSON))
+
+			var rows []bandwidth.ExportRow
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &rows))
+			require.Empty(t, rows)
+		})
+
+		t.Run("unsupported format", func(t *testing.T) {
+			var buf bytes.Buffer
+			err := bandwidthdb.Export(ctx, day1, day2, &buf, "xml")
+			require.Error(t, err)
+			require.True(t, bandwidth.ErrExport.Has(err))
+		})
+	})
+}
+
+func TestBandwidthVerifyRollup(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		bandwidthdb := db.Bandwidth()
+
+		satelliteID := testrand.NodeID()
+		now := time.Now().UTC()
+		old := now.Truncate(time.Hour).Add(-3 * time.Hour)
+
+		require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_GET, 100, old))
+		require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_PUT, 50, old.Add(time.Minute)))
+		require.NoError(t, bandwidthdb.Add(ctx, testrand.NodeID(), pb.PieceAction_GET_AUDIT, 25, old.Add(30*time.Minute)))
+		// too recent to be rolled up
+		require.NoError(t, bandwidthdb.Add(ctx, satelliteID, pb.PieceAction_GET, 10, now))
+
+		rawSum, rollupSum, err := bandwidthdb.VerifyRollup(ctx, old, old)
+		require.NoError(t, err)
+		require.Equal(t, int64(175), rawSum)
+		require.Equal(t, int64(0), rollupSum)
+
+		rawTotal, rollupTotal, err := bandwidthdb.VerifyRollup(ctx, old, now)
+		require.NoError(t, err)
+		require.Equal(t, int64(185), rawTotal+rollupTotal)
+
+		require.NoError(t, bandwidthdb.Rollup(ctx))
+
+		// the old usage moved to the rollups without any loss
+		rawSum, rollupSum, err = bandwidthdb.VerifyRollup(ctx, old, old)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), rawSum)
+		require.Equal(t, int64(175), rollupSum)
+
+		rawTotal, rollupTotal, err = bandwidthdb.VerifyRollup(ctx, old, now)
+		require.NoError(t, err)
+		require.Equal(t, int64(10), rawTotal)
+		require.Equal(t, int64(175), rollupTotal)
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package bandwidth
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/storj/pkg/storj"
+)
+
+// ErrExport is the error class for bandwidth exports.
+var ErrExport = errs.Class("bandwidth export error")
+
+// Export formats supported by DB.Export.
+const (
+	// ExportCSV exports comma separated values with a header row.
+	ExportCSV = "csv"
+	// ExportJSON exports a JSON array of ExportRow objects.
+	ExportJSON = "json"
+)
+
+// ExportHeader are the columns of the exported bandwidth usage:
+//
+//	satellite_id - id of the satellite the bandwidth was used for
+//	action       - piece action name, e.g. GET, PUT, GET_AUDIT, GET_REPAIR, PUT_REPAIR, DELETE
+//	day          - UTC day the bandwidth was used on, formatted as 2006-01-02
+//	bytes        - amount of bytes used
+var ExportHeader = []string{"satellite_id", "action", "day", "bytes"}
+
+// ExportRow is the bandwidth used for a single satellite and action during a day.
+type ExportRow struct {
+	SatelliteID storj.NodeID `json:"satelliteId"`
+	Action      string       `json:"action"`
+	Day         string       `json:"day"`
+	Bytes       int64        `json:"bytes"`
+}
+
+// ExportWriter writes exported rows to the underlying writer as they are added.
+type ExportWriter interface {
+	// Write writes a single row.
+	Write(row ExportRow) error
+	// Close finishes the export, it doesn't close the underlying writer.
+	Close() error
+}
+
+// NewExportWriter returns an ExportWriter for the format.
+func NewExportWriter(w io.Writer, format string) (ExportWriter, error) {
+	switch format {
+	case ExportCSV:
+		writer := &csvExportWriter{csv: csv.NewWriter(w)}
+		return writer, writer.writeHeader()
+	case ExportJSON:
+		return &jsonExportWriter{w: w}, nil
+	default:
+		return nil, ErrExport.New("unsupported format %q", format)
+	}
+}
+
+// csvExportWriter writes rows as comma separated values.
+type csvExportWriter struct {
+	csv *csv.Writer
+}
+
+func (writer *csvExportWriter) writeHeader() error {
+	return ErrExport.Wrap(writer.csv.Write(ExportHeader))
+}
+
+// Write writes a single row.
+func (writer *csvExportWriter) Write(row ExportRow) error {
+	return ErrExport.Wrap(writer.csv.Write([]string{
+		row.SatelliteID.String(),
+		row.Action,
+		row.Day,
+		strconv.FormatInt(row.Bytes, 10),
+	}))
+}
+
+// Close flushes the buffered rows.
+func (writer *csvExportWriter) Close() error {
+	writer.csv.Flush()
+	return ErrExport.Wrap(writer.csv.Error())
+}
+
+// jsonExportWriter writes rows as a JSON array.
+type jsonExportWriter struct {
+	w       io.Writer
+	started bool
+}
+
+// Write writes a single row.
+func (writer *jsonExportWriter) Write(row ExportRow) error {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return ErrExport.Wrap(err)
+	}
+
+	separator := ",\n"
+	if !writer.started {
+		separator = "[\n"
+		writer.started = true
+	}
+
+	if _, err := io.WriteString(writer.w, separator); err != nil {
+		return ErrExport.Wrap(err)
+	}
+	_, err = writer.w.Write(data)
+	return ErrExport.Wrap(err)
+}
+
+// Close terminates the array.
+func (writer *jsonExportWriter) Close() error {
+	end := "\n]\n"
+	if !writer.started {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(writer.w, end)
+	return ErrExport.Wrap(err)
+}
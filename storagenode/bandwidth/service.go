@@ -12,30 +12,37 @@ import (
 	monkit "gopkg.in/spacemonkeygo/monkit.v2"
 
 	"storj.io/storj/internal/sync2"
+	"storj.io/storj/storagenode/maintenance"
 )
 
 var mon = monkit.Package()
 
 // Config defines parameters for storage node Collector.
 type Config struct {
-	Interval time.Duration `help:"how frequently bandwidth usage rollups are calculated" default:"1h0m0s"`
+	Interval             time.Duration `help:"how frequently bandwidth usage rollups are calculated" default:"1h0m0s"`
+	SummaryCacheInterval time.Duration `help:"how long the month-to-date bandwidth summary is cached for the dashboard" default:"5m0s"`
+	ThrottleThreshold    float64       `help:"fraction of the bandwidth cap used this month above which traffic should be throttled" default:"0.9"`
 }
 
 // Service implements
 //
 // architecture: Chore
 type Service struct {
-	log  *zap.Logger
-	db   DB
-	Loop sync2.Cycle
+	log         *zap.Logger
+	db          DB
+	maintenance maintenance.Status
+	config      Config
+	Loop        sync2.Cycle
 }
 
 // NewService creates a new bandwidth service.
-func NewService(log *zap.Logger, db DB, config Config) *Service {
+func NewService(log *zap.Logger, db DB, maintenance maintenance.Status, config Config) *Service {
 	return &Service{
-		log:  log,
-		db:   db,
-		Loop: *sync2.NewCycle(config.Interval),
+		log:         log,
+		db:          db,
+		maintenance: maintenance,
+		config:      config,
+		Loop:        *sync2.NewCycle(config.Interval),
 	}
 }
 
@@ -49,6 +56,11 @@ func (service *Service) Run(ctx context.Context) (err error) {
 func (service *Service) Rollup(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
+	if service.maintenance.Paused() {
+		service.log.Debug("maintenance mode enabled, deferring bandwidth usage rollups")
+		return nil
+	}
+
 	service.log.Info("Performing bandwidth usage rollups")
 	err = service.db.Rollup(ctx)
 	if err != nil {
@@ -57,6 +69,43 @@ func (service *Service) Rollup(ctx context.Context) (err error) {
 	return nil
 }
 
+// ShouldThrottle returns whether the bandwidth used this month exceeds Config.ThrottleThreshold of
+// the monthly cap and the fraction of the usual traffic which should still be served.
+//
+// The factor is 1 below the threshold, decreases linearly to 0 between the threshold and the cap
+// and is 0 once the cap is reached, i.e. the traffic should be rejected. A cap of 0 or less means
+// that there is no cap.
+func (service *Service) ShouldThrottle(ctx context.Context, cap int64) (throttle bool, factor float64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if cap <= 0 {
+		return false, 1, nil
+	}
+
+	used, err := service.db.MonthSummary(ctx)
+	if err != nil {
+		return false, 1, err
+	}
+
+	threshold := service.config.ThrottleThreshold
+	if threshold < 0 {
+		threshold = 0
+	}
+	if threshold > 1 {
+		threshold = 1
+	}
+
+	usage := float64(used) / float64(cap)
+	switch {
+	case usage >= 1:
+		return true, 0, nil
+	case usage <= threshold:
+		return false, 1, nil
+	default:
+		return true, (1 - usage) / (1 - threshold), nil
+	}
+}
+
 // Close stops the background process for rollups of bandwidth usage
 func (service *Service) Close() (err error) {
 	service.Loop.Close()
@@ -4,15 +4,20 @@
 package bandwidth_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testplanet"
 	"storj.io/storj/internal/teststorj"
 	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/bandwidth"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
 )
 
 // Simple test for ensuring the service runs Rollups in the Loop
@@ -42,3 +47,82 @@ func TestBandwidthService(t *testing.T) {
 		}
 	})
 }
+
+// countingRollups counts the rollups performed on the bandwidth DB.
+type countingRollups struct {
+	bandwidth.DB
+	rollups int
+}
+
+func (db *countingRollups) Rollup(ctx context.Context) error {
+	db.rollups++
+	return db.DB.Rollup(ctx)
+}
+
+func TestBandwidthServiceMaintenance(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		bandwidthdb := &countingRollups{DB: db.Bandwidth()}
+		service := bandwidth.NewService(zaptest.NewLogger(t), bandwidthdb, db, bandwidth.Config{Interval: time.Hour})
+
+		db.Pause()
+		require.True(t, db.Paused())
+
+		// the rollup is deferred while paused
+		require.NoError(t, service.Rollup(ctx))
+		require.Equal(t, 0, bandwidthdb.rollups)
+
+		db.Resume()
+		require.False(t, db.Paused())
+
+		require.NoError(t, service.Rollup(ctx))
+		require.Equal(t, 1, bandwidthdb.rollups)
+	})
+}
+
+// monthUsage reports a fixed bandwidth usage for the current month.
+type monthUsage struct {
+	bandwidth.DB
+	used int64
+}
+
+func (db *monthUsage) MonthSummary(ctx context.Context) (int64, error) {
+	return db.used, nil
+}
+
+func TestShouldThrottle(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	const cap = 1000
+
+	for _, tt := range []struct {
+		used     int64
+		cap      int64
+		throttle bool
+		factor   float64
+	}{
+		{used: 0, cap: cap, throttle: false, factor: 1},
+		{used: 500, cap: cap, throttle: false, factor: 1},
+		{used: 900, cap: cap, throttle: false, factor: 1},
+		{used: 925, cap: cap, throttle: true, factor: 0.75},
+		{used: 950, cap: cap, throttle: true, factor: 0.5},
+		{used: 1000, cap: cap, throttle: true, factor: 0},
+		{used: 2000, cap: cap, throttle: true, factor: 0},
+		// no cap
+		{used: 2000, cap: 0, throttle: false, factor: 1},
+	} {
+		db := &monthUsage{used: tt.used}
+		service := bandwidth.NewService(zaptest.NewLogger(t), db, nil, bandwidth.Config{
+			Interval:          time.Hour,
+			ThrottleThreshold: 0.9,
+		})
+
+		throttle, factor, err := service.ShouldThrottle(ctx, tt.cap)
+		require.NoError(t, err)
+		require.Equal(t, tt.throttle, throttle, "used %d of %d", tt.used, tt.cap)
+		require.InDelta(t, tt.factor, factor, 1e-9, "used %d of %d", tt.used, tt.cap)
+	}
+}
@@ -5,6 +5,7 @@ package bandwidth
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"storj.io/storj/pkg/pb"
@@ -18,6 +19,9 @@ type DB interface {
 	Add(ctx context.Context, satelliteID storj.NodeID, action pb.PieceAction, amount int64, created time.Time) error
 	// MonthSummary returns summary of the current months bandwidth usages
 	MonthSummary(ctx context.Context) (int64, error)
+	// CachedSummary returns the summary of the current months bandwidth usages by action.
+	// The summary is cached and refreshed periodically, Add invalidates it.
+	CachedSummary(ctx context.Context) (*Usage, error)
 	Rollup(ctx context.Context) (err error)
 	Summary(ctx context.Context, from, to time.Time) (*Usage, error)
 	// SatelliteSummary returns aggregated bandwidth usage for a particular satellite.
@@ -29,6 +33,12 @@ type DB interface {
 	// GetDailySatelliteRollups returns slice of daily bandwidth usage for provided time range,
 	// sorted in ascending order for a particular satellite.
 	GetDailySatelliteRollups(ctx context.Context, satelliteID storj.NodeID, from, to time.Time) ([]UsageRollup, error)
+	// Export writes the bandwidth usage per satellite, action and day for the provided time range
+	// to w in the format, which is either ExportCSV or ExportJSON. See ExportHeader for the columns.
+	Export(ctx context.Context, from, to time.Time, w io.Writer, format string) error
+	// VerifyRollup sums the raw and the rolled up bandwidth usage for the provided time range,
+	// rounded out to whole hours. Rollup moves usage from raw to rolled up, so their total must not change.
+	VerifyRollup(ctx context.Context, from, to time.Time) (rawSum int64, rollupSum int64, err error)
 }
 
 // Usage contains bandwidth usage information based on the type
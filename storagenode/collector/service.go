@@ -8,19 +8,35 @@ import (
 	"context"
 	"time"
 
+	"github.com/zeebo/errs"
 	"go.uber.org/zap"
 	monkit "gopkg.in/spacemonkeygo/monkit.v2"
 
 	"storj.io/storj/internal/sync2"
+	"storj.io/storj/storagenode/maintenance"
 	"storj.io/storj/storagenode/pieces"
 	"storj.io/storj/storagenode/piecestore"
 )
 
-var mon = monkit.Package()
+var (
+	// Error is the default error class for the collector.
+	Error = errs.Class("collector")
+
+	mon = monkit.Package()
+)
 
 // Config defines parameters for storage node Collector.
 type Config struct {
-	Interval time.Duration `help:"how frequently expired pieces are collected" default:"1h0m0s"`
+	Interval            time.Duration `help:"how frequently expired pieces are collected" default:"1h0m0s"`
+	ExpirationBatchSize int           `help:"how many expired pieces are fetched and deleted per batch" default:"1000"`
+}
+
+// Verify verifies whether configuration is consistent and acceptable.
+func (config Config) Verify() error {
+	if config.ExpirationBatchSize <= 0 {
+		return Error.New("expiration batch size must be positive, got %d", config.ExpirationBatchSize)
+	}
+	return nil
 }
 
 // Service implements collecting expired pieces on the storage node.
@@ -30,16 +46,20 @@ type Service struct {
 	log         *zap.Logger
 	pieces      *pieces.Store
 	usedSerials piecestore.UsedSerials
+	maintenance maintenance.Status
+	batchSize   int
 
 	Loop sync2.Cycle
 }
 
 // NewService creates a new collector service.
-func NewService(log *zap.Logger, pieces *pieces.Store, usedSerials piecestore.UsedSerials, config Config) *Service {
+func NewService(log *zap.Logger, pieces *pieces.Store, usedSerials piecestore.UsedSerials, maintenance maintenance.Status, config Config) *Service {
 	return &Service{
 		log:         log,
 		pieces:      pieces,
 		usedSerials: usedSerials,
+		maintenance: maintenance,
+		batchSize:   config.ExpirationBatchSize,
 		Loop:        *sync2.NewCycle(config.Interval),
 	}
 }
@@ -49,6 +69,11 @@ func (service *Service) Run(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	return service.Loop.Run(ctx, func(ctx context.Context) error {
+		if service.maintenance.Paused() {
+			service.log.Debug("maintenance mode enabled, deferring collection")
+			return nil
+		}
+
 		err := service.Collect(ctx, time.Now())
 		if err != nil {
 			service.log.Error("error during collecting pieces: ", zap.Error(err))
@@ -72,7 +97,6 @@ func (service *Service) Collect(ctx context.Context, now time.Time) (err error)
 	}
 
 	const maxBatches = 100
-	const batchSize = 1000
 
 	var count int64
 	defer func() {
@@ -82,7 +106,7 @@ func (service *Service) Collect(ctx context.Context, now time.Time) (err error)
 	}()
 
 	for k := 0; k < maxBatches; k++ {
-		infos, err := service.pieces.GetExpired(ctx, now, batchSize)
+		infos, err := service.pieces.GetExpired(ctx, now, int64(service.batchSize))
 		if err != nil {
 			return err
 		}
@@ -8,12 +8,19 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 
 	"storj.io/storj/internal/memory"
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testplanet"
 	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
 	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/collector"
+	"storj.io/storj/storagenode/maintenance"
+	"storj.io/storj/storagenode/pieces"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
 	"storj.io/storj/uplink"
 )
 
@@ -125,3 +132,55 @@ func TestCollector(t *testing.T) {
 		require.Equal(t, 0, serialsPresent)
 	})
 }
+
+func TestCollectorBatchSize(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		store := pieces.NewStore(zaptest.NewLogger(t), db.Pieces(), db.V0PieceInfo(), db.PieceExpirationDB(), db.PieceSpaceUsedDB())
+
+		satelliteID := testrand.NodeID()
+		now := time.Now()
+
+		const pieceCount = 5
+		for i := 0; i < pieceCount; i++ {
+			pieceID := testrand.PieceID()
+
+			writer, err := store.Writer(ctx, satelliteID, pieceID)
+			require.NoError(t, err)
+			_, err = writer.Write(testrand.Bytes(memory.KiB))
+			require.NoError(t, err)
+			require.NoError(t, writer.Commit(ctx, &pb.PieceHeader{}))
+
+			require.NoError(t, store.SetExpiration(ctx, satelliteID, pieceID, now.Add(-time.Hour)))
+		}
+
+		service := collector.NewService(zaptest.NewLogger(t), store, db.UsedSerials(), &maintenance.Mode{}, collector.Config{
+			Interval:            time.Hour,
+			ExpirationBatchSize: 2,
+		})
+
+		// a batch size smaller than the number of expired pieces still collects all of them
+		require.NoError(t, service.Collect(ctx, now))
+
+		expired, err := store.GetExpired(ctx, now, pieceCount)
+		require.NoError(t, err)
+		require.Empty(t, expired)
+
+		used, err := store.SpaceUsedForPieces(ctx)
+		require.NoError(t, err)
+		require.Zero(t, used)
+	})
+}
+
+func TestConfigVerify(t *testing.T) {
+	require.NoError(t, collector.Config{ExpirationBatchSize: 1}.Verify())
+
+	err := collector.Config{ExpirationBatchSize: 0}.Verify()
+	require.Error(t, err)
+	require.True(t, collector.Error.Has(err))
+
+	err = collector.Config{ExpirationBatchSize: -10}.Verify()
+	require.True(t, collector.Error.Has(err))
+}
@@ -39,6 +39,7 @@ type Service struct {
 	bandwidthDB    bandwidth.DB
 	reputationDB   reputation.DB
 	storageUsageDB storageusage.DB
+	contactDB      contact.DB
 	pieceStore     *pieces.Store
 	contact        *contact.Service
 
@@ -56,7 +57,7 @@ type Service struct {
 // NewService returns new instance of Service.
 func NewService(log *zap.Logger, bandwidth bandwidth.DB, pieceStore *pieces.Store, version *version.Service,
 	allocatedBandwidth, allocatedDiskSpace memory.Size, walletAddress string, versionInfo version.Info, trust *trust.Pool,
-	reputationDB reputation.DB, storageUsageDB storageusage.DB, pingStats *contact.PingStats, contact *contact.Service) (*Service, error) {
+	reputationDB reputation.DB, storageUsageDB storageusage.DB, contactDB contact.DB, pingStats *contact.PingStats, contact *contact.Service) (*Service, error) {
 	if log == nil {
 		return nil, errs.New("log can't be nil")
 	}
@@ -86,6 +87,7 @@ func NewService(log *zap.Logger, bandwidth bandwidth.DB, pieceStore *pieces.Stor
 		bandwidthDB:        bandwidth,
 		reputationDB:       reputationDB,
 		storageUsageDB:     storageUsageDB,
+		contactDB:          contactDB,
 		pieceStore:         pieceStore,
 		version:            version,
 		pingStats:          pingStats,
@@ -98,10 +100,11 @@ func NewService(log *zap.Logger, bandwidth bandwidth.DB, pieceStore *pieces.Stor
 	}, nil
 }
 
-// SatelliteInfo encapsulates satellite ID and disqualification.
+// SatelliteInfo encapsulates satellite ID, disqualification and the time of the last successful check-in.
 type SatelliteInfo struct {
 	ID           storj.NodeID `json:"id"`
 	Disqualified *time.Time   `json:"disqualified"`
+	LastContact  *time.Time   `json:"lastContact"`
 }
 
 // Dashboard encapsulates dashboard stale data.
@@ -120,6 +123,9 @@ type Dashboard struct {
 
 	Version  version.SemVer `json:"version"`
 	UpToDate bool           `json:"upToDate"`
+
+	// DeletionFailedPieces is the number of pieces stuck because deleting them failed.
+	DeletionFailedPieces int64 `json:"deletionFailedPieces"`
 }
 
 // GetDashboardData returns stale dashboard data.
@@ -140,12 +146,20 @@ func (s *Service) GetDashboardData(ctx context.Context) (_ *Dashboard, err error
 	}
 
 	for _, rep := range stats {
-		data.Satellites = append(data.Satellites,
-			SatelliteInfo{
-				ID:           rep.SatelliteID,
-				Disqualified: rep.Disqualified,
-			},
-		)
+		info := SatelliteInfo{
+			ID:           rep.SatelliteID,
+			Disqualified: rep.Disqualified,
+		}
+
+		lastContact, err := s.contactDB.GetLastContact(ctx, rep.SatelliteID)
+		if err != nil {
+			return nil, SNOServiceErr.Wrap(err)
+		}
+		if !lastContact.IsZero() {
+			info.LastContact = &lastContact
+		}
+
+		data.Satellites = append(data.Satellites, info)
 	}
 
 	spaceUsage, err := s.pieceStore.SpaceUsedForPieces(ctx)
@@ -153,7 +167,12 @@ func (s *Service) GetDashboardData(ctx context.Context) (_ *Dashboard, err error
 		return nil, SNOServiceErr.Wrap(err)
 	}
 
-	bandwidthUsage, err := s.bandwidthDB.Summary(ctx, time.Time{}, time.Now())
+	bandwidthUsage, err := s.bandwidthDB.CachedSummary(ctx)
+	if err != nil {
+		return nil, SNOServiceErr.Wrap(err)
+	}
+
+	data.DeletionFailedPieces, err = s.pieceStore.DeletionFailedCount(ctx)
 	if err != nil {
 		return nil, SNOServiceErr.Wrap(err)
 	}
@@ -25,6 +25,7 @@ type Chore struct {
 	log     *zap.Logger
 	service *Service
 	dialer  rpc.Dialer
+	db      DB
 
 	trust *trust.Pool
 
@@ -33,11 +34,12 @@ type Chore struct {
 }
 
 // NewChore creates a new contact chore
-func NewChore(log *zap.Logger, interval time.Duration, maxSleep time.Duration, trust *trust.Pool, dialer rpc.Dialer, service *Service) *Chore {
+func NewChore(log *zap.Logger, interval time.Duration, maxSleep time.Duration, trust *trust.Pool, dialer rpc.Dialer, service *Service, db DB) *Chore {
 	return &Chore{
 		log:     log,
 		service: service,
 		dialer:  dialer,
+		db:      db,
 
 		trust: trust,
 
@@ -87,8 +89,14 @@ func (chore *Chore) pingSatellites(ctx context.Context) (err error) {
 				Capacity: &self.Capacity,
 				Operator: &self.Operator,
 			})
+			if err != nil {
+				return err
+			}
 
-			return err
+			if err := chore.db.SetLastContact(ctx, satellite, time.Now()); err != nil {
+				chore.log.Error("failed to store last contact", zap.Stringer("Satellite ID", satellite), zap.Error(err))
+			}
+			return nil
 		})
 	}
 
@@ -4,17 +4,26 @@
 package contact_test
 
 import (
+	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 	"golang.org/x/sync/errgroup"
 
 	"storj.io/storj/internal/errs2"
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testplanet"
+	"storj.io/storj/internal/testrand"
 	"storj.io/storj/pkg/pb"
 	"storj.io/storj/pkg/rpc/rpcstatus"
+	"storj.io/storj/satellite/overlay"
 	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/contact"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
 )
 
 func TestStoragenodeContactEndpoint(t *testing.T) {
@@ -137,3 +146,159 @@ func TestLocalAndUpdateSelf(t *testing.T) {
 		_ = group.Wait()
 	})
 }
+
+func TestLocalIsDeepCopy(t *testing.T) {
+	disqualified := time.Now()
+	service := contact.NewService(zaptest.NewLogger(t), &overlay.NodeDossier{
+		Node: pb.Node{
+			Id:      testrand.NodeID(),
+			Address: &pb.NodeAddress{Address: "127.0.0.1:7777"},
+		},
+		Disqualified: &disqualified,
+	})
+
+	local := service.Local()
+	local.Address.Address = "changed"
+	*local.Disqualified = time.Time{}
+
+	again := service.Local()
+	require.Equal(t, "127.0.0.1:7777", again.Address.Address)
+	require.Equal(t, disqualified, *again.Disqualified)
+}
+
+func TestLocalConcurrentUpdate(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	service := contact.NewService(zaptest.NewLogger(t), &overlay.NodeDossier{
+		Node: pb.Node{
+			Id:      testrand.NodeID(),
+			Address: &pb.NodeAddress{Address: "127.0.0.1:7777"},
+		},
+	})
+
+	const iterations = 1000
+
+	ctx.Go(func() error {
+		for i := 0; i < iterations; i++ {
+			service.UpdateSelf(&pb.NodeCapacity{FreeDisk: int64(i)})
+		}
+		return nil
+	})
+
+	ctx.Go(func() error {
+		for i := 0; i < iterations; i++ {
+			local := service.Local()
+			local.Address.Address = "changed"
+			local.Capacity.FreeBandwidth = int64(i)
+		}
+		return nil
+	})
+
+	ctx.Wait()
+
+	local := service.Local()
+	require.Equal(t, "127.0.0.1:7777", local.Address.Address)
+	require.EqualValues(t, iterations-1, local.Capacity.FreeDisk)
+	require.Zero(t, local.Capacity.FreeBandwidth)
+}
+
+// capacitySource reports a free disk space which grows with every refresh
+// and twice that as the free bandwidth.
+type capacitySource struct {
+	disk int64
+	fail int32
+}
+
+func (source *capacitySource) FreeDisk(ctx context.Context) (int64, error) {
+	if atomic.LoadInt32(&source.fail) != 0 {
+		return 0, errors.New("space usage failed")
+	}
+	return atomic.AddInt64(&source.disk, 1), nil
+}
+
+func (source *capacitySource) FreeBandwidth(ctx context.Context) (int64, error) {
+	return 2 * atomic.LoadInt64(&source.disk), nil
+}
+
+func TestRefreshCapacityConcurrent(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	service := contact.NewService(zaptest.NewLogger(t), &overlay.NodeDossier{
+		Node: pb.Node{
+			Id:      testrand.NodeID(),
+			Address: &pb.NodeAddress{Address: "127.0.0.1:7777"},
+		},
+	})
+
+	source := &capacitySource{}
+
+	const refreshers = 4
+	const iterations = 250
+
+	for i := 0; i < refreshers; i++ {
+		ctx.Go(func() error {
+			for k := 0; k < iterations; k++ {
+				if err := service.RefreshCapacity(ctx, source, source); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	ctx.Go(func() error {
+		for k := 0; k < iterations; k++ {
+			// disk and bandwidth are always updated together
+			capacity := service.Local().Capacity
+			if capacity.FreeBandwidth != 2*capacity.FreeDisk {
+				return errors.New("inconsistent capacity")
+			}
+		}
+		return nil
+	})
+
+	ctx.Wait()
+
+	capacity := service.Local().Capacity
+	require.EqualValues(t, refreshers*iterations, capacity.FreeDisk)
+	require.EqualValues(t, 2*refreshers*iterations, capacity.FreeBandwidth)
+
+	// a failed refresh keeps the previous capacity
+	atomic.StoreInt32(&source.fail, 1)
+	require.Error(t, service.RefreshCapacity(ctx, source, source))
+	require.Equal(t, capacity, service.Local().Capacity)
+}
+
+func TestLastContactDB(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		contactDB := db.Contact()
+		satelliteID := testrand.NodeID()
+
+		lastContact, err := contactDB.GetLastContact(ctx, satelliteID)
+		require.NoError(t, err)
+		require.True(t, lastContact.IsZero())
+
+		first := time.Now().Add(-time.Hour).UTC()
+		require.NoError(t, contactDB.SetLastContact(ctx, satelliteID, first))
+
+		lastContact, err = contactDB.GetLastContact(ctx, satelliteID)
+		require.NoError(t, err)
+		require.True(t, first.Equal(lastContact))
+
+		second := time.Now().UTC()
+		require.NoError(t, contactDB.SetLastContact(ctx, satelliteID, second))
+
+		lastContact, err = contactDB.GetLastContact(ctx, satelliteID)
+		require.NoError(t, err)
+		require.True(t, second.Equal(lastContact))
+
+		lastContact, err = contactDB.GetLastContact(ctx, testrand.NodeID())
+		require.NoError(t, err)
+		require.True(t, lastContact.IsZero())
+	})
+}
@@ -0,0 +1,22 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package contact
+
+import (
+	"context"
+	"time"
+
+	"storj.io/storj/pkg/storj"
+)
+
+// DB persists the time of the last successful contact with each satellite
+//
+// architecture: Database
+type DB interface {
+	// SetLastContact stores the time of the last successful check-in with the satellite
+	SetLastContact(ctx context.Context, satelliteID storj.NodeID, at time.Time) error
+	// GetLastContact returns the time of the last successful check-in with the satellite,
+	// the zero time is returned when the satellite has never been contacted
+	GetLastContact(ctx context.Context, satelliteID storj.NodeID) (time.Time, error)
+}
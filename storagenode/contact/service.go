@@ -4,6 +4,7 @@
 package contact
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -30,10 +31,25 @@ type Config struct {
 	MaxSleep time.Duration `help:"maximum duration to wait before pinging satellites" releaseDefault:"45m" devDefault:"0s" hidden:"true"`
 }
 
+// SpaceUsage reports the disk space the node has left.
+type SpaceUsage interface {
+	// FreeDisk returns the allocated disk space which isn't used yet.
+	FreeDisk(ctx context.Context) (int64, error)
+}
+
+// BandwidthUsage reports the bandwidth the node has left.
+type BandwidthUsage interface {
+	// FreeBandwidth returns the allocated bandwidth which isn't used yet.
+	FreeBandwidth(ctx context.Context) (int64, error)
+}
+
 // Service is the contact service between storage nodes and satellites
 type Service struct {
 	log *zap.Logger
 
+	// refreshMu serializes capacity updates, so that an older capacity can't overwrite a newer one.
+	refreshMu sync.Mutex
+
 	mu   sync.Mutex
 	self *overlay.NodeDossier
 }
@@ -46,18 +62,85 @@ func NewService(log *zap.Logger, self *overlay.NodeDossier) *Service {
 	}
 }
 
-// Local returns the storagenode node-dossier
+// Local returns a copy of the storagenode node-dossier.
+//
+// The copy does not share any memory with the dossier held by the service,
+// so callers are free to read or modify it while UpdateSelf is running.
 func (service *Service) Local() overlay.NodeDossier {
 	service.mu.Lock()
 	defer service.mu.Unlock()
-	return *service.self
+	return copyDossier(service.self)
 }
 
 // UpdateSelf updates the local node with the capacity
 func (service *Service) UpdateSelf(capacity *pb.NodeCapacity) {
+	service.refreshMu.Lock()
+	defer service.refreshMu.Unlock()
+
+	service.updateCapacity(capacity)
+}
+
+// RefreshCapacity recomputes the free disk space and the remaining bandwidth and updates the local node with them.
+//
+// Concurrent refreshes and updates are serialized, so the capacity is always computed and set as a whole.
+// The capacity is left unchanged when either of them fails.
+func (service *Service) RefreshCapacity(ctx context.Context, space SpaceUsage, bandwidth BandwidthUsage) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	service.refreshMu.Lock()
+	defer service.refreshMu.Unlock()
+
+	freeDisk, err := space.FreeDisk(ctx)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	freeBandwidth, err := bandwidth.FreeBandwidth(ctx)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	service.updateCapacity(&pb.NodeCapacity{
+		FreeBandwidth: freeBandwidth,
+		FreeDisk:      freeDisk,
+	})
+	return nil
+}
+
+// updateCapacity sets the capacity of the local node.
+func (service *Service) updateCapacity(capacity *pb.NodeCapacity) {
 	service.mu.Lock()
 	defer service.mu.Unlock()
 	if capacity != nil {
 		service.self.Capacity = *capacity
 	}
 }
+
+// copyDossier returns a deep copy of the node dossier.
+func copyDossier(self *overlay.NodeDossier) overlay.NodeDossier {
+	dossier := *self
+
+	if self.Address != nil {
+		address := *self.Address
+		address.XXX_unrecognized = copyBytes(self.Address.XXX_unrecognized)
+		dossier.Address = &address
+	}
+	if self.Disqualified != nil {
+		disqualified := *self.Disqualified
+		dossier.Disqualified = &disqualified
+	}
+
+	dossier.Node.XXX_unrecognized = copyBytes(self.Node.XXX_unrecognized)
+	dossier.Operator.XXX_unrecognized = copyBytes(self.Operator.XXX_unrecognized)
+	dossier.Capacity.XXX_unrecognized = copyBytes(self.Capacity.XXX_unrecognized)
+	dossier.Version.XXX_unrecognized = copyBytes(self.Version.XXX_unrecognized)
+
+	return dossier
+}
+
+func copyBytes(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append([]byte{}, data...)
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package dbhealth implements periodic measuring of the write latency
+// of the storage node databases.
+package dbhealth
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	monkit "gopkg.in/spacemonkeygo/monkit.v2"
+
+	"storj.io/storj/internal/sync2"
+)
+
+var mon = monkit.Package()
+
+// Config defines parameters for the database health chore.
+type Config struct {
+	Interval time.Duration `help:"how frequently the write latency of the storage node databases is measured, 0 disables it" default:"5m0s"`
+}
+
+// DB is the database whose health is measured.
+type DB interface {
+	// WriteLatencies measures how long a trivial write takes on every database.
+	WriteLatencies(ctx context.Context) map[string]time.Duration
+}
+
+// Chore periodically measures the write latency of the storage node databases
+// and reports it to monkit, so that a degrading disk is noticed before it fails.
+//
+// architecture: Chore
+type Chore struct {
+	log    *zap.Logger
+	db     DB
+	config Config
+
+	Loop sync2.Cycle
+}
+
+// NewChore creates a new database health chore.
+func NewChore(log *zap.Logger, db DB, config Config) *Chore {
+	return &Chore{
+		log:    log,
+		db:     db,
+		config: config,
+		Loop:   *sync2.NewCycle(config.Interval),
+	}
+}
+
+// Run runs the database health chore.
+func (chore *Chore) Run(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if chore.config.Interval <= 0 {
+		chore.log.Debug("database health chore is disabled")
+		return nil
+	}
+
+	return chore.Loop.Run(ctx, func(ctx context.Context) error {
+		for dbName, latency := range chore.db.WriteLatencies(ctx) {
+			mon.FloatVal("db_write_latency_seconds_" + dbName).Observe(latency.Seconds())
+		}
+		return nil
+	})
+}
+
+// Close stops the database health chore.
+func (chore *Chore) Close() (err error) {
+	chore.Loop.Close()
+	return nil
+}
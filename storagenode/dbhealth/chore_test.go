@@ -0,0 +1,55 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package dbhealth_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/dbhealth"
+)
+
+type countingDB struct {
+	calls int64
+}
+
+func (db *countingDB) WriteLatencies(ctx context.Context) map[string]time.Duration {
+	atomic.AddInt64(&db.calls, 1)
+	return map[string]time.Duration{"test": time.Millisecond}
+}
+
+func TestChore(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	db := &countingDB{}
+	chore := dbhealth.NewChore(zaptest.NewLogger(t), db, dbhealth.Config{Interval: time.Hour})
+	defer ctx.Check(chore.Close)
+
+	ctx.Go(func() error {
+		return chore.Run(ctx)
+	})
+
+	chore.Loop.TriggerWait()
+	chore.Loop.TriggerWait()
+	require.True(t, atomic.LoadInt64(&db.calls) >= 2)
+}
+
+func TestChoreDisabled(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	db := &countingDB{}
+	chore := dbhealth.NewChore(zaptest.NewLogger(t), db, dbhealth.Config{Interval: 0})
+	defer ctx.Check(chore.Close)
+
+	require.NoError(t, chore.Run(ctx))
+	require.Zero(t, atomic.LoadInt64(&db.calls))
+}
@@ -0,0 +1,34 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package maintenance implements a flag for pausing background chores on the storage node.
+package maintenance
+
+import (
+	"sync/atomic"
+)
+
+// Status reports whether the storage node is in maintenance mode.
+//
+// Background chores consult it before writing to the databases and
+// defer their work to the next cycle while it's paused.
+type Status interface {
+	// Paused returns true when background writes should be deferred.
+	Paused() bool
+}
+
+// Mode is a maintenance mode flag which can be safely used concurrently.
+//
+// The zero value is not paused.
+type Mode struct {
+	paused int32
+}
+
+// Pause enables maintenance mode.
+func (mode *Mode) Pause() { atomic.StoreInt32(&mode.paused, 1) }
+
+// Resume disables maintenance mode.
+func (mode *Mode) Resume() { atomic.StoreInt32(&mode.paused, 0) }
+
+// Paused returns true when maintenance mode is enabled.
+func (mode *Mode) Paused() bool { return atomic.LoadInt32(&mode.paused) != 0 }
@@ -5,6 +5,8 @@ package monitor
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/zeebo/errs"
@@ -13,10 +15,10 @@ import (
 
 	"storj.io/storj/internal/memory"
 	"storj.io/storj/internal/sync2"
-	"storj.io/storj/pkg/pb"
 	"storj.io/storj/storagenode/bandwidth"
 	"storj.io/storj/storagenode/contact"
 	"storj.io/storj/storagenode/pieces"
+	"storj.io/storj/storagenode/satellites"
 )
 
 var (
@@ -28,9 +30,11 @@ var (
 
 // Config defines parameters for storage node disk and bandwidth usage monitoring.
 type Config struct {
-	Interval         time.Duration `help:"how frequently Kademlia bucket should be refreshed with node stats" default:"1h0m0s"`
-	MinimumDiskSpace memory.Size   `help:"how much disk space a node at minimum has to advertise" default:"500GB"`
-	MinimumBandwidth memory.Size   `help:"how much bandwidth a node at minimum has to advertise" default:"500GB"`
+	Interval          time.Duration `help:"how frequently Kademlia bucket should be refreshed with node stats" default:"1h0m0s"`
+	MinimumDiskSpace  memory.Size   `help:"how much disk space a node at minimum has to advertise" default:"500GB"`
+	MinimumBandwidth  memory.Size   `help:"how much bandwidth a node at minimum has to advertise" default:"500GB"`
+	MinimumFreeSpace  memory.Size   `help:"how much free space the filesystem has to keep, uploads are rejected below it to avoid filling the disk" default:"1GB"`
+	FreeSpaceInterval time.Duration `help:"how long the filesystem free space is cached for uploads before it's checked again" default:"1m0s"`
 }
 
 // Service which monitors disk usage and updates kademlia network as necessary.
@@ -41,21 +45,35 @@ type Service struct {
 	store              *pieces.Store
 	contact            *contact.Service
 	usageDB            bandwidth.DB
+	satellitesDB       satellites.DB
 	allocatedDiskSpace int64
 	allocatedBandwidth int64
 	Loop               sync2.Cycle
 	Config             Config
+
+	// lowFreeSpace is set while the filesystem free space is below Config.MinimumFreeSpace.
+	lowFreeSpace int32
+
+	// diskFree is the filesystem free space, checked at diskFreeAt and cached for Config.FreeSpaceInterval.
+	diskFreeMu sync.Mutex
+	diskFree   int64
+	diskFreeAt time.Time
+
+	// exits are the graceful exits in progress, refreshed on every loop iteration.
+	exitsMu sync.Mutex
+	exits   []satellites.ExitProgress
 }
 
 // TODO: should it be responsible for monitoring actual bandwidth as well?
 
 // NewService creates a new storage node monitoring service.
-func NewService(log *zap.Logger, store *pieces.Store, contact *contact.Service, usageDB bandwidth.DB, allocatedDiskSpace, allocatedBandwidth int64, interval time.Duration, config Config) *Service {
+func NewService(log *zap.Logger, store *pieces.Store, contact *contact.Service, usageDB bandwidth.DB, satellitesDB satellites.DB, allocatedDiskSpace, allocatedBandwidth int64, interval time.Duration, config Config) *Service {
 	return &Service{
 		log:                log,
 		store:              store,
 		contact:            contact,
 		usageDB:            usageDB,
+		satellitesDB:       satellitesDB,
 		allocatedDiskSpace: allocatedDiskSpace,
 		allocatedBandwidth: allocatedBandwidth,
 		Loop:               *sync2.NewCycle(interval),
@@ -125,6 +143,10 @@ func (service *Service) Run(ctx context.Context) (err error) {
 	}
 
 	return service.Loop.Run(ctx, func(ctx context.Context) error {
+		if err := service.refreshExits(ctx); err != nil {
+			service.log.Error("error during refreshing graceful exits: ", zap.Error(err))
+		}
+
 		err := service.updateNodeInformation(ctx)
 		if err != nil {
 			service.log.Error("error during updating node information: ", zap.Error(err))
@@ -141,23 +163,37 @@ func (service *Service) Close() (err error) {
 
 func (service *Service) updateNodeInformation(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(&err)
+	return Error.Wrap(service.contact.RefreshCapacity(ctx, service, service))
+}
+
+// FreeDisk returns the allocated disk space which isn't used by pieces
+// or freed by the transfers of an active graceful exit.
+func (service *Service) FreeDisk(ctx context.Context) (_ int64, err error) {
+	defer mon.Task()(&ctx)(&err)
 
 	usedSpace, err := service.usedSpace(ctx)
 	if err != nil {
-		return Error.Wrap(err)
+		return 0, Error.Wrap(err)
 	}
 
-	usedBandwidth, err := service.usedBandwidth(ctx)
+	exitedSpace, err := service.exitedSpace(ctx)
 	if err != nil {
-		return Error.Wrap(err)
+		return 0, Error.Wrap(err)
 	}
 
-	service.contact.UpdateSelf(&pb.NodeCapacity{
-		FreeBandwidth: service.allocatedBandwidth - usedBandwidth,
-		FreeDisk:      service.allocatedDiskSpace - usedSpace,
-	})
+	return service.allocatedDiskSpace - usedSpace - exitedSpace, nil
+}
 
-	return nil
+// FreeBandwidth returns the allocated bandwidth which isn't used this month.
+func (service *Service) FreeBandwidth(ctx context.Context) (_ int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	usedBandwidth, err := service.usedBandwidth(ctx)
+	if err != nil {
+		return 0, Error.Wrap(err)
+	}
+
+	return service.allocatedBandwidth - usedBandwidth, nil
 }
 
 func (service *Service) usedSpace(ctx context.Context) (_ int64, err error) {
@@ -169,6 +205,57 @@ func (service *Service) usedSpace(ctx context.Context) (_ int64, err error) {
 	return usedSpace, nil
 }
 
+// refreshExits reloads the graceful exits in progress.
+// Suspended exits are included, since they are going to be resumed.
+func (service *Service) refreshExits(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+	exits, err := service.satellitesDB.ListGracefulExits(ctx)
+	if err != nil {
+		return err
+	}
+
+	var inProgress []satellites.ExitProgress
+	for _, exit := range exits {
+		if exit.Status == satellites.Exiting || exit.Status == satellites.Suspended {
+			inProgress = append(inProgress, exit)
+		}
+	}
+
+	service.exitsMu.Lock()
+	service.exits = inProgress
+	service.exitsMu.Unlock()
+	return nil
+}
+
+// exitedSpace returns the bytes that have been transferred away during
+// graceful exits that are still in progress.
+//
+// Transferred pieces are deleted from the node, which lowers the used space
+// while the exit is running. That space isn't advertised until the exit has
+// finished, otherwise the node would take new uploads into space that is
+// only temporarily free and over-advertise its capacity mid-exit.
+// The freed space of an exit is its starting disk usage less the space the
+// satellite uses now, as tracked by the used space cache of the piece store.
+func (service *Service) exitedSpace(ctx context.Context) (_ int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	service.exitsMu.Lock()
+	exits := service.exits
+	service.exitsMu.Unlock()
+
+	var total int64
+	for _, exit := range exits {
+		used, err := service.store.SpaceUsedBySatellite(ctx, exit.SatelliteID)
+		if err != nil {
+			return 0, err
+		}
+		if freed := exit.StartingDiskUsage - used; freed > 0 {
+			total += freed
+		}
+	}
+	return total, nil
+}
+
 func (service *Service) usedBandwidth(ctx context.Context) (_ int64, err error) {
 	defer mon.Task()(&ctx)(&err)
 	usage, err := service.usageDB.MonthSummary(ctx)
@@ -178,15 +265,71 @@ func (service *Service) usedBandwidth(ctx context.Context) (_ int64, err error)
 	return usage, nil
 }
 
-// AvailableSpace returns available disk space for upload
+// AvailableSpace returns available disk space for upload.
+// Space freed by transfers of an active graceful exit is not available.
+// The space is limited so that the filesystem keeps at least Config.MinimumFreeSpace free,
+// no space is available once the free space is below it.
 func (service *Service) AvailableSpace(ctx context.Context) (_ int64, err error) {
 	defer mon.Task()(&ctx)(&err)
 	usedSpace, err := service.store.SpaceUsedForPieces(ctx)
 	if err != nil {
 		return 0, Error.Wrap(err)
 	}
+	exitedSpace, err := service.exitedSpace(ctx)
+	if err != nil {
+		return 0, Error.Wrap(err)
+	}
 	allocatedSpace := service.allocatedDiskSpace
-	return allocatedSpace - usedSpace, nil
+	available := allocatedSpace - usedSpace - exitedSpace
+
+	if service.Config.MinimumFreeSpace <= 0 {
+		return available, nil
+	}
+
+	diskFree, err := service.diskFreeSpace(ctx)
+	if err != nil {
+		return 0, Error.Wrap(err)
+	}
+
+	writable := diskFree - service.Config.MinimumFreeSpace.Int64()
+	if writable <= 0 {
+		if atomic.CompareAndSwapInt32(&service.lowFreeSpace, 0, 1) {
+			service.log.Error("Free disk space is below the configured minimum, rejecting uploads",
+				zap.Int64("free bytes", diskFree),
+				zap.Int64("minimum bytes", service.Config.MinimumFreeSpace.Int64()))
+		}
+		return 0, nil
+	}
+	if atomic.CompareAndSwapInt32(&service.lowFreeSpace, 1, 0) {
+		service.log.Info("Free disk space is above the configured minimum again, accepting uploads",
+			zap.Int64("free bytes", diskFree))
+	}
+
+	if writable < available {
+		available = writable
+	}
+	return available, nil
+}
+
+// diskFreeSpace returns the filesystem free space, which is checked at most once per Config.FreeSpaceInterval.
+func (service *Service) diskFreeSpace(ctx context.Context) (_ int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	service.diskFreeMu.Lock()
+	defer service.diskFreeMu.Unlock()
+
+	now := time.Now()
+	if !service.diskFreeAt.IsZero() && now.Sub(service.diskFreeAt) < service.Config.FreeSpaceInterval {
+		return service.diskFree, nil
+	}
+
+	storageStatus, err := service.store.StorageStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+	service.diskFree = storageStatus.DiskFree
+	service.diskFreeAt = now
+	return service.diskFree, nil
 }
 
 // AvailableBandwidth returns available bandwidth for upload/download
@@ -5,15 +5,23 @@ package monitor_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 
 	"storj.io/storj/internal/memory"
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testplanet"
 	"storj.io/storj/internal/testrand"
 	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storage"
+	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/monitor"
+	"storj.io/storj/storagenode/pieces"
+	"storj.io/storj/storagenode/satellites"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
 )
 
 func TestMonitor(t *testing.T) {
@@ -55,3 +63,96 @@ func TestMonitor(t *testing.T) {
 		assert.NotZero(t, nodeAssertions, "No storage node were verifed")
 	})
 }
+
+func TestAvailableSpaceDuringGracefulExit(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 1, UplinkCount: 0,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		satellite := planet.Satellites[0]
+		storageNode := planet.StorageNodes[0]
+		monitor := storageNode.Storage2.Monitor
+		satellitesDB := storageNode.DB.Satellites()
+
+		initial, err := monitor.AvailableSpace(ctx)
+		require.NoError(t, err)
+
+		// the node stores no pieces, so everything used at the start of the exit was transferred away
+		transferred := 10 * memory.KiB.Int64()
+		err = satellitesDB.InitiateGracefulExit(ctx, satellite.ID(), time.Now(), transferred)
+		require.NoError(t, err)
+
+		// the exits are reloaded by the monitor loop
+		monitor.Loop.TriggerWait()
+
+		// space freed by transfers is not advertised while the exit is active
+		available, err := monitor.AvailableSpace(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, initial-transferred, available)
+
+		// once the exit has finished the space is available again
+		err = satellitesDB.CompleteGracefulExit(ctx, satellite.ID(), time.Now(), satellites.ExitSucceeded, nil)
+		require.NoError(t, err)
+		monitor.Loop.TriggerWait()
+
+		available, err = monitor.AvailableSpace(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, initial, available)
+	})
+}
+
+// lowSpaceBlobs reports a fixed amount of free space on the filesystem.
+type lowSpaceBlobs struct {
+	storage.Blobs
+	free int64
+}
+
+func (blobs *lowSpaceBlobs) FreeSpace() (int64, error) { return blobs.free, nil }
+
+func TestMinimumFreeSpace(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		log := zaptest.NewLogger(t)
+
+		blobs := &lowSpaceBlobs{Blobs: db.Pieces(), free: 10 * memory.GB.Int64()}
+		store := pieces.NewStore(log, blobs, db.V0PieceInfo(), db.PieceExpirationDB(), db.PieceSpaceUsedDB())
+
+		service := monitor.NewService(log, store, nil, db.Bandwidth(), db.Satellites(),
+			memory.TB.Int64(), memory.TB.Int64(), time.Hour, monitor.Config{
+				MinimumFreeSpace:  memory.GB,
+				FreeSpaceInterval: time.Hour,
+			})
+
+		// the allocation is limited by the filesystem free space above the minimum
+		available, err := service.AvailableSpace(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 9*memory.GB.Int64(), available)
+
+		// the free space is cached within the interval
+		blobs.free = 5 * memory.GB.Int64()
+		available, err = service.AvailableSpace(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 9*memory.GB.Int64(), available)
+
+		// below the minimum nothing can be written
+		service.Config.FreeSpaceInterval = 0
+		blobs.free = memory.GB.Int64() - 1
+		available, err = service.AvailableSpace(ctx)
+		require.NoError(t, err)
+		require.Zero(t, available)
+
+		// the uploads resume when space is freed up
+		blobs.free = 2 * memory.TB.Int64()
+		available, err = service.AvailableSpace(ctx)
+		require.NoError(t, err)
+		require.Equal(t, memory.TB.Int64(), available)
+
+		// the guard can be disabled
+		blobs.free = 0
+		service.Config.MinimumFreeSpace = 0
+		available, err = service.AvailableSpace(ctx)
+		require.NoError(t, err)
+		require.Equal(t, memory.TB.Int64(), available)
+	})
+}
@@ -30,6 +30,9 @@ type Config struct {
 	MaxSleep       time.Duration `help:"maximum duration to wait before requesting data" releaseDefault:"300s" devDefault:"1s"`
 	ReputationSync time.Duration `help:"how often to sync reputation" releaseDefault:"4h" devDefault:"1m"`
 	StorageSync    time.Duration `help:"how often to sync storage" releaseDefault:"12h" devDefault:"2m"`
+
+	StorageUsageRetention      time.Duration `help:"how long storage usage samples are kept, the current month is always kept, 0 keeps them forever" default:"2160h"`
+	ReputationHistoryRetention time.Duration `help:"how long the history of reputation scores is kept, 0 keeps it forever" default:"2160h"`
 }
 
 // CacheStorage encapsulates cache DBs
@@ -49,21 +52,25 @@ type Cache struct {
 	service *Service
 	trust   *trust.Pool
 
-	maxSleep        time.Duration
-	reputationCycle sync2.Cycle
-	storageCycle    sync2.Cycle
+	maxSleep                   time.Duration
+	storageUsageRetention      time.Duration
+	reputationHistoryRetention time.Duration
+	reputationCycle            sync2.Cycle
+	storageCycle               sync2.Cycle
 }
 
 // NewCache creates new caching service instance
 func NewCache(log *zap.Logger, config Config, db CacheStorage, service *Service, trust *trust.Pool) *Cache {
 	return &Cache{
-		log:             log,
-		db:              db,
-		service:         service,
-		trust:           trust,
-		maxSleep:        config.MaxSleep,
-		reputationCycle: *sync2.NewCycle(config.ReputationSync),
-		storageCycle:    *sync2.NewCycle(config.StorageSync),
+		log:                        log,
+		db:                         db,
+		service:                    service,
+		trust:                      trust,
+		maxSleep:                   config.MaxSleep,
+		storageUsageRetention:      config.StorageUsageRetention,
+		reputationHistoryRetention: config.ReputationHistoryRetention,
+		reputationCycle:            *sync2.NewCycle(config.ReputationSync),
+		storageCycle:               *sync2.NewCycle(config.StorageSync),
 	}
 }
 
@@ -81,6 +88,10 @@ func (cache *Cache) Run(ctx context.Context) error {
 			cache.log.Error("Get stats query failed", zap.Error(err))
 		}
 
+		if err := cache.PruneReputationHistory(ctx); err != nil {
+			cache.log.Error("Pruning reputation history failed", zap.Error(err))
+		}
+
 		return nil
 	})
 	cache.storageCycle.Start(ctx, &group, func(ctx context.Context) error {
@@ -93,6 +104,10 @@ func (cache *Cache) Run(ctx context.Context) error {
 			cache.log.Error("Get disk space usage query failed", zap.Error(err))
 		}
 
+		if err := cache.PruneSpaceUsage(ctx); err != nil {
+			cache.log.Error("Pruning disk space usage failed", zap.Error(err))
+		}
+
 		return nil
 	})
 
@@ -141,6 +156,53 @@ func (cache *Cache) CacheSpaceUsage(ctx context.Context) (err error) {
 	})
 }
 
+// PruneReputationHistory deletes the reputation scores older than the configured retention.
+func (cache *Cache) PruneReputationHistory(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if cache.reputationHistoryRetention <= 0 {
+		return nil
+	}
+
+	deleted, err := cache.db.Reputation.DeleteHistoryBefore(ctx, time.Now().Add(-cache.reputationHistoryRetention))
+	if err != nil {
+		return NodeStatsCacheErr.Wrap(err)
+	}
+	if deleted > 0 {
+		cache.log.Debug("pruned reputation history", zap.Int("deleted", deleted))
+	}
+	return nil
+}
+
+// PruneSpaceUsage deletes the storage usage samples older than the configured retention.
+func (cache *Cache) PruneSpaceUsage(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if cache.storageUsageRetention <= 0 {
+		return nil
+	}
+
+	deleted, err := cache.db.StorageUsage.DeleteBefore(ctx, storageUsageCutoff(time.Now(), cache.storageUsageRetention))
+	if err != nil {
+		return NodeStatsCacheErr.Wrap(err)
+	}
+	if deleted > 0 {
+		cache.log.Debug("pruned storage usage samples", zap.Int("deleted", deleted))
+	}
+	return nil
+}
+
+// storageUsageCutoff returns the time before which storage usage samples are pruned. Only whole UTC days are
+// pruned, so that the daily totals which are kept stay complete, and the current month is never pruned,
+// because the dashboard graphs it.
+func storageUsageCutoff(now time.Time, retention time.Duration) time.Time {
+	cutoff, _ := date.DayBoundary(now.UTC().Add(-retention))
+	if monthStart, _ := date.MonthBoundary(now.UTC()); monthStart.Before(cutoff) {
+		return monthStart
+	}
+	return cutoff
+}
+
 // sleep for random interval in [0;maxSleep)
 // returns error if context was cancelled
 func (cache *Cache) sleep(ctx context.Context) error {
@@ -16,3 +16,38 @@ func TestCacheSleep32bitBug(t *testing.T) {
 	// Ensure that a large maxSleep doesn't roll over to negative values on 32 bit systems.
 	_ = (&Cache{maxSleep: 1 << 32}).sleep(ctx)
 }
+
+func TestStorageUsageCutoff(t *testing.T) {
+	day := 24 * time.Hour
+	for _, test := range []struct {
+		now       time.Time
+		retention time.Duration
+		expected  time.Time
+	}{
+		{ // whole days are kept
+			now:       time.Date(2019, 10, 20, 15, 30, 0, 0, time.UTC),
+			retention: 30 * day,
+			expected:  time.Date(2019, 9, 20, 0, 0, 0, 0, time.UTC),
+		},
+		{ // the cutoff on a day boundary
+			now:       time.Date(2019, 10, 20, 0, 0, 0, 0, time.UTC),
+			retention: 90 * day,
+			expected:  time.Date(2019, 7, 22, 0, 0, 0, 0, time.UTC),
+		},
+		{ // the current month is kept
+			now:       time.Date(2019, 10, 20, 15, 30, 0, 0, time.UTC),
+			retention: 5 * day,
+			expected:  time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{ // in UTC
+			now:       time.Date(2019, 10, 20, 1, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)),
+			retention: 30 * day,
+			expected:  time.Date(2019, 9, 19, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		cutoff := storageUsageCutoff(test.now, test.retention)
+		if !cutoff.Equal(test.expected) {
+			t.Errorf("cutoff for %v and %v: expected %v, got %v", test.now, test.retention, test.expected, cutoff)
+		}
+	}
+}
@@ -144,6 +144,8 @@ func fromSpaceUsageResponse(resp *pb.DailyStorageUsageResponse, satelliteID stor
 			SatelliteID:   satelliteID,
 			AtRestTotal:   pbUsage.AtRestTotal,
 			IntervalStart: pbUsage.Timestamp,
+			// satellite tallies storage usage daily
+			IntervalEnd: pbUsage.Timestamp.Add(24 * time.Hour),
 		})
 	}
 
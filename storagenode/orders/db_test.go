@@ -238,3 +238,137 @@ func TestDB_Trivial(t *testing.T) {
 		}
 	})
 }
+
+func TestDB_UpdateArchiveStatus(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		ordersdb := db.Orders()
+		satelliteID := testrand.NodeID()
+
+		serials := make([]storj.SerialNumber, 3)
+		for i := range serials {
+			serials[i] = testrand.SerialNumber()
+			err := ordersdb.Enqueue(ctx, &orders.Info{
+				Order: &pb.Order{},
+				Limit: &pb.OrderLimit{
+					SatelliteId:     satelliteID,
+					SerialNumber:    serials[i],
+					OrderExpiration: time.Now(),
+				},
+			})
+			require.NoError(t, err)
+
+			err = ordersdb.Archive(ctx, time.Now().UTC(), orders.ArchiveRequest{satelliteID, serials[i], orders.StatusAccepted})
+			require.NoError(t, err)
+		}
+
+		accepted, err := ordersdb.ListArchivedByStatus(ctx, orders.StatusAccepted, 10)
+		require.NoError(t, err)
+		require.Len(t, accepted, 3)
+
+		rejected, err := ordersdb.ListArchivedByStatus(ctx, orders.StatusRejected, 10)
+		require.NoError(t, err)
+		require.Len(t, rejected, 0)
+
+		// accepted -> rejected
+		updatedAt := time.Now().UTC()
+		err = ordersdb.UpdateArchiveStatus(ctx, satelliteID, serials[0], orders.StatusRejected, updatedAt)
+		require.NoError(t, err)
+
+		rejected, err = ordersdb.ListArchivedByStatus(ctx, orders.StatusRejected, 10)
+		require.NoError(t, err)
+		require.Len(t, rejected, 1)
+		require.Equal(t, serials[0], rejected[0].Limit.SerialNumber)
+		require.True(t, updatedAt.Equal(rejected[0].ArchivedAt))
+
+		accepted, err = ordersdb.ListArchivedByStatus(ctx, orders.StatusAccepted, 10)
+		require.NoError(t, err)
+		require.Len(t, accepted, 2)
+
+		// rejected -> accepted
+		err = ordersdb.UpdateArchiveStatus(ctx, satelliteID, serials[0], orders.StatusAccepted, time.Now().UTC())
+		require.NoError(t, err)
+
+		accepted, err = ordersdb.ListArchivedByStatus(ctx, orders.StatusAccepted, 10)
+		require.NoError(t, err)
+		require.Len(t, accepted, 3)
+
+		// limit is respected
+		accepted, err = ordersdb.ListArchivedByStatus(ctx, orders.StatusAccepted, 1)
+		require.NoError(t, err)
+		require.Len(t, accepted, 1)
+
+		// unknown order
+		err = ordersdb.UpdateArchiveStatus(ctx, satelliteID, testrand.SerialNumber(), orders.StatusRejected, time.Now().UTC())
+		require.Error(t, err)
+		require.True(t, orders.OrderNotFoundError.Has(err))
+	})
+}
+
+func TestDB_Expiration(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		ordersdb := db.Orders()
+
+		soonest, err := ordersdb.SoonestExpiration(ctx)
+		require.NoError(t, err)
+		require.True(t, soonest.IsZero())
+
+		satellite0, satellite1 := testrand.NodeID(), testrand.NodeID()
+		now := time.Now().UTC().Truncate(time.Second)
+
+		for _, order := range []struct {
+			satelliteID storj.NodeID
+			expiration  time.Time
+		}{
+			{satellite0, now.Add(-time.Hour)},
+			{satellite0, now.Add(30 * time.Minute)},
+			{satellite0, now.Add(90 * time.Minute)},
+			{satellite1, now.Add(20 * time.Minute)},
+			{satellite1, now.Add(48 * time.Hour)},
+		} {
+			err := ordersdb.Enqueue(ctx, &orders.Info{
+				Order: &pb.Order{},
+				Limit: &pb.OrderLimit{
+					SatelliteId:     order.satelliteID,
+					SerialNumber:    testrand.SerialNumber(),
+					OrderExpiration: order.expiration,
+				},
+			})
+			require.NoError(t, err)
+		}
+
+		soonest, err = ordersdb.SoonestExpiration(ctx)
+		require.NoError(t, err)
+		require.True(t, now.Add(-time.Hour).Equal(soonest), soonest)
+
+		// the expired order isn't counted
+		expiring, err := ordersdb.CountExpiringWithin(ctx, time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, map[storj.NodeID]int{satellite0: 1, satellite1: 1}, expiring)
+
+		expiring, err = ordersdb.CountExpiringWithin(ctx, 2*time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, map[storj.NodeID]int{satellite0: 2, satellite1: 1}, expiring)
+
+		expiring, err = ordersdb.CountExpiringWithin(ctx, 10*time.Minute)
+		require.NoError(t, err)
+		require.Empty(t, expiring)
+
+		// sent orders aren't unsent anymore
+		unsent, err := ordersdb.ListUnsentBySatellite(ctx)
+		require.NoError(t, err)
+		for _, info := range unsent[satellite1] {
+			err = ordersdb.Archive(ctx, now, orders.ArchiveRequest{satellite1, info.Limit.SerialNumber, orders.StatusAccepted})
+			require.NoError(t, err)
+		}
+
+		expiring, err = ordersdb.CountExpiringWithin(ctx, 72*time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, map[storj.NodeID]int{satellite0: 2}, expiring)
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"storj.io/storj/pkg/pb"
 	"storj.io/storj/pkg/rpc"
 	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storagenode/maintenance"
 	"storj.io/storj/storagenode/trust"
 )
 
@@ -71,11 +72,19 @@ type DB interface {
 	ListUnsent(ctx context.Context, limit int) ([]*Info, error)
 	// ListUnsentBySatellite returns orders that haven't been sent yet grouped by satellite.
 	ListUnsentBySatellite(ctx context.Context) (map[storj.NodeID][]*Info, error)
+	// SoonestExpiration returns the nearest order limit expiration of the unsent orders, or the zero time when there are none.
+	SoonestExpiration(ctx context.Context) (time.Time, error)
+	// CountExpiringWithin returns the number of unsent orders per satellite whose order limit expires within d from now.
+	CountExpiringWithin(ctx context.Context, d time.Duration) (map[storj.NodeID]int, error)
 
 	// Archive marks order as being handled.
 	Archive(ctx context.Context, archivedAt time.Time, requests ...ArchiveRequest) error
 	// ListArchived returns orders that have been sent.
 	ListArchived(ctx context.Context, limit int) ([]*ArchivedInfo, error)
+	// ListArchivedByStatus returns orders that have been sent and have the specified status.
+	ListArchivedByStatus(ctx context.Context, status Status, limit int) ([]*ArchivedInfo, error)
+	// UpdateArchiveStatus changes the status of an archived order.
+	UpdateArchiveStatus(ctx context.Context, satelliteID storj.NodeID, serial storj.SerialNumber, status Status, archivedAt time.Time) error
 	// CleanArchive deletes all entries older than ttl
 	CleanArchive(ctx context.Context, ttl time.Duration) (int, error)
 }
@@ -88,6 +97,7 @@ type Config struct {
 	SenderRequestTimeout time.Duration `help:"timeout for read/write operations during sending" default:"1h0m0s"`
 	CleanupInterval      time.Duration `help:"duration between archive cleanups" default:"24h0m0s"`
 	ArchiveTTL           time.Duration `help:"length of time to archive orders before deletion" default:"168h0m0s"` // 7 days
+	ExpirationWarning    time.Duration `help:"warn about unsent orders expiring within this duration, 0 disables the warning" default:"24h0m0s"`
 }
 
 // Service sends every interval unsent orders to the satellite.
@@ -97,22 +107,24 @@ type Service struct {
 	log    *zap.Logger
 	config Config
 
-	dialer rpc.Dialer
-	orders DB
-	trust  *trust.Pool
+	dialer      rpc.Dialer
+	orders      DB
+	maintenance maintenance.Status
+	trust       *trust.Pool
 
 	Sender  sync2.Cycle
 	Cleanup sync2.Cycle
 }
 
 // NewService creates an order service.
-func NewService(log *zap.Logger, dialer rpc.Dialer, orders DB, trust *trust.Pool, config Config) *Service {
+func NewService(log *zap.Logger, dialer rpc.Dialer, orders DB, maintenance maintenance.Status, trust *trust.Pool, config Config) *Service {
 	return &Service{
-		log:    log,
-		dialer: dialer,
-		orders: orders,
-		config: config,
-		trust:  trust,
+		log:         log,
+		dialer:      dialer,
+		orders:      orders,
+		maintenance: maintenance,
+		config:      config,
+		trust:       trust,
 
 		Sender:  *sync2.NewCycle(config.SenderInterval),
 		Cleanup: *sync2.NewCycle(config.CleanupInterval),
@@ -132,6 +144,11 @@ func (service *Service) Run(ctx context.Context) (err error) {
 
 func (service *Service) cleanArchive(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(&err)
+	if service.maintenance.Paused() {
+		service.log.Debug("maintenance mode enabled, deferring cleanup")
+		return nil
+	}
+
 	service.log.Debug("cleaning")
 
 	deleted, err := service.orders.CleanArchive(ctx, service.config.ArchiveTTL)
@@ -146,8 +163,15 @@ func (service *Service) cleanArchive(ctx context.Context) (err error) {
 
 func (service *Service) sendOrders(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(&err)
+	if service.maintenance.Paused() {
+		service.log.Debug("maintenance mode enabled, deferring sending")
+		return nil
+	}
+
 	service.log.Debug("sending")
 
+	service.warnExpiringOrders(ctx)
+
 	const batchSize = 1000
 
 	ordersBySatellite, err := service.orders.ListUnsentBySatellite(ctx)
@@ -190,6 +214,28 @@ func (service *Service) sendOrders(ctx context.Context) (err error) {
 	return nil
 }
 
+// warnExpiringOrders logs a warning for every satellite with unsent orders which expire soon,
+// as the expired orders can't be sent anymore and their revenue is lost.
+func (service *Service) warnExpiringOrders(ctx context.Context) {
+	defer mon.Task()(&ctx)(nil)
+	if service.config.ExpirationWarning <= 0 {
+		return
+	}
+
+	expiring, err := service.orders.CountExpiringWithin(ctx, service.config.ExpirationWarning)
+	if err != nil {
+		service.log.Error("counting expiring orders", zap.Error(err))
+		return
+	}
+
+	for satelliteID, count := range expiring {
+		service.log.Warn("unsent orders expire soon, check that orders are sent to the satellite",
+			zap.Stringer("satellite id", satelliteID),
+			zap.Int("count", count),
+			zap.Duration("within", service.config.ExpirationWarning))
+	}
+}
+
 // Settle uploads orders to the satellite.
 func (service *Service) Settle(ctx context.Context, satelliteID storj.NodeID, orders []*Info, requests chan ArchiveRequest) {
 	log := service.log.Named(satelliteID.String())
@@ -5,7 +5,9 @@ package storagenode
 
 import (
 	"context"
+	"math"
 	"net"
+	"time"
 
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
@@ -25,11 +27,13 @@ import (
 	"storj.io/storj/pkg/storj"
 	"storj.io/storj/satellite/overlay"
 	"storj.io/storj/storage"
+	"storj.io/storj/storagenode/analyze"
 	"storj.io/storj/storagenode/bandwidth"
 	"storj.io/storj/storagenode/collector"
 	"storj.io/storj/storagenode/console"
 	"storj.io/storj/storagenode/console/consoleserver"
 	"storj.io/storj/storagenode/contact"
+	"storj.io/storj/storagenode/dbhealth"
 	"storj.io/storj/storagenode/inspector"
 	"storj.io/storj/storagenode/monitor"
 	"storj.io/storj/storagenode/nodestats"
@@ -38,8 +42,10 @@ import (
 	"storj.io/storj/storagenode/piecestore"
 	"storj.io/storj/storagenode/reputation"
 	"storj.io/storj/storagenode/retain"
+	"storj.io/storj/storagenode/satellites"
 	"storj.io/storj/storagenode/storageusage"
 	"storj.io/storj/storagenode/trust"
+	"storj.io/storj/storagenode/vacuum"
 )
 
 var (
@@ -52,6 +58,20 @@ var (
 type DB interface {
 	// CreateTables initializes the database
 	CreateTables(ctx context.Context) error
+	// AnalyzeAll refreshes the query planner statistics of the databases
+	AnalyzeAll(ctx context.Context) error
+	// Vacuum returns the free pages of the databases to the file system
+	Vacuum(ctx context.Context) error
+	// WriteLatencies measures how long a trivial write takes on every database
+	WriteLatencies(ctx context.Context) map[string]time.Duration
+	// Pause enables maintenance mode, which defers the writes of background chores
+	Pause()
+	// Resume disables maintenance mode
+	Resume()
+	// Paused returns true when maintenance mode is enabled
+	Paused() bool
+	// MarkRunning marks the databases as used by a running node until the returned func is called
+	MarkRunning() (done func())
 	// Close closes the database
 	Close() error
 
@@ -65,11 +85,14 @@ type DB interface {
 	UsedSerials() piecestore.UsedSerials
 	Reputation() reputation.DB
 	StorageUsage() storageusage.DB
+	Satellites() satellites.DB
+	Contact() contact.DB
 }
 
 // Config is all the configuration parameters for a Storage Node
 type Config struct {
-	Identity identity.Config
+	Identity          identity.Config
+	MinimumDifficulty uint64 `help:"minimum difficulty of the node identity, the node doesn't start with an identity below it" default:"30"`
 
 	Server server.Config
 
@@ -80,6 +103,9 @@ type Config struct {
 	Storage   piecestore.OldConfig
 	Storage2  piecestore.Config
 	Collector collector.Config
+	Analyze   analyze.Config
+	DBHealth  dbhealth.Config
+	Vacuum    vacuum.Config
 
 	Retain retain.Config
 
@@ -94,7 +120,10 @@ type Config struct {
 
 // Verify verifies whether configuration is consistent and acceptable.
 func (config *Config) Verify(log *zap.Logger) error {
-	return config.Kademlia.Verify(log)
+	return errs.Combine(
+		config.Kademlia.Verify(log),
+		config.Collector.Verify(),
+	)
 }
 
 // Peer is the representation of a Storage Node.
@@ -130,6 +159,7 @@ type Peer struct {
 		BlobsCache    *pieces.BlobsUsageCache
 		CacheService  *pieces.CacheService
 		RetainService *retain.Service
+		UsedSerials   *piecestore.UsedSerialsCache
 		Endpoint      *piecestore.Endpoint
 		Inspector     *inspector.Endpoint
 		Monitor       *monitor.Service
@@ -137,6 +167,9 @@ type Peer struct {
 	}
 
 	Collector *collector.Service
+	Analyze   *analyze.Chore
+	DBHealth  *dbhealth.Chore
+	Vacuum    *vacuum.Chore
 
 	NodeStats struct {
 		Service *nodestats.Service
@@ -155,6 +188,14 @@ type Peer struct {
 
 // New creates a new Storage Node.
 func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB extensions.RevocationDB, config Config, versionInfo version.Info) (*Peer, error) {
+	// satellites reject identities below the network difficulty, so it's better to fail before starting
+	if config.MinimumDifficulty > math.MaxUint16 {
+		return nil, identity.Error.New("minimum difficulty %d is above %d", config.MinimumDifficulty, math.MaxUint16)
+	}
+	if err := identity.VerifyDifficulty(full.ID, uint16(config.MinimumDifficulty)); err != nil {
+		return nil, err
+	}
+
 	peer := &Peer{
 		Log:      log,
 		Identity: full,
@@ -222,7 +263,7 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB exten
 		}
 		peer.Contact.PingStats = new(contact.PingStats)
 		peer.Contact.Service = contact.NewService(peer.Log.Named("contact:service"), self)
-		peer.Contact.Chore = contact.NewChore(peer.Log.Named("contact:chore"), config.Contact.Interval, config.Contact.MaxSleep, peer.Storage2.Trust, peer.Dialer, peer.Contact.Service)
+		peer.Contact.Chore = contact.NewChore(peer.Log.Named("contact:chore"), config.Contact.Interval, config.Contact.MaxSleep, peer.Storage2.Trust, peer.Dialer, peer.Contact.Service, peer.DB.Contact())
 		peer.Contact.Endpoint = contact.NewEndpoint(peer.Log.Named("contact:endpoint"), peer.Contact.PingStats)
 		peer.Contact.KEndpoint = contact.NewKademliaEndpoint(peer.Log.Named("contact:nodes_service_endpoint"), peer.Contact.Service, peer.Storage2.Trust)
 		pb.RegisterContactServer(peer.Server.GRPC(), peer.Contact.Endpoint)
@@ -253,6 +294,7 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB exten
 			peer.Storage2.Store,
 			peer.Contact.Service,
 			peer.DB.Bandwidth(),
+			peer.DB.Satellites(),
 			config.Storage.AllocatedDiskSpace.Int64(),
 			config.Storage.AllocatedBandwidth.Int64(),
 			//TODO use config.Storage.Monitor.Interval, but for some reason is not set
@@ -266,6 +308,12 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB exten
 			config.Retain,
 		)
 
+		peer.Storage2.UsedSerials = piecestore.NewUsedSerialsCache(
+			peer.Log.Named("usedserials"),
+			peer.DB.UsedSerials(),
+			config.Storage2.UsedSerials,
+		)
+
 		peer.Storage2.Endpoint, err = piecestore.NewEndpoint(
 			peer.Log.Named("piecestore"),
 			signing.SignerFromFullIdentity(peer.Identity),
@@ -275,7 +323,7 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB exten
 			peer.Storage2.Store,
 			peer.DB.Orders(),
 			peer.DB.Bandwidth(),
-			peer.DB.UsedSerials(),
+			peer.Storage2.UsedSerials,
 			config.Storage2,
 		)
 		if err != nil {
@@ -300,6 +348,7 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB exten
 			log.Named("orders"),
 			dialer,
 			peer.DB.Orders(),
+			peer.DB,
 			peer.Storage2.Trust,
 			config.Storage2.Orders,
 		)
@@ -335,6 +384,7 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB exten
 			peer.Storage2.Trust,
 			peer.DB.Reputation(),
 			peer.DB.StorageUsage(),
+			peer.DB.Contact(),
 			peer.Contact.PingStats,
 			peer.Contact.Service)
 
@@ -370,9 +420,15 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB exten
 		pb.DRPCRegisterPieceStoreInspector(peer.Server.PrivateDRPC(), peer.Storage2.Inspector)
 	}
 
-	peer.Collector = collector.NewService(peer.Log.Named("collector"), peer.Storage2.Store, peer.DB.UsedSerials(), config.Collector)
+	peer.Collector = collector.NewService(peer.Log.Named("collector"), peer.Storage2.Store, peer.Storage2.UsedSerials, peer.DB, config.Collector)
+
+	peer.Analyze = analyze.NewChore(peer.Log.Named("analyze"), peer.DB, config.Analyze)
+
+	peer.DBHealth = dbhealth.NewChore(peer.Log.Named("dbhealth"), peer.DB, config.DBHealth)
 
-	peer.Bandwidth = bandwidth.NewService(peer.Log.Named("bandwidth"), peer.DB.Bandwidth(), config.Bandwidth)
+	peer.Vacuum = vacuum.NewChore(peer.Log.Named("vacuum"), peer.DB, peer.DB, peer.Storage2.Endpoint, config.Vacuum)
+
+	peer.Bandwidth = bandwidth.NewService(peer.Log.Named("bandwidth"), peer.DB.Bandwidth(), peer.DB, config.Bandwidth)
 
 	return peer, nil
 }
@@ -381,6 +437,12 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB, revocationDB exten
 func (peer *Peer) Run(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
+	defer peer.DB.MarkRunning()()
+
+	if err = peer.Storage2.UsedSerials.Init(ctx); err != nil {
+		return err
+	}
+
 	group, ctx := errgroup.WithContext(ctx)
 
 	group.Go(func() error {
@@ -392,6 +454,15 @@ func (peer *Peer) Run(ctx context.Context) (err error) {
 	group.Go(func() error {
 		return errs2.IgnoreCanceled(peer.Collector.Run(ctx))
 	})
+	group.Go(func() error {
+		return errs2.IgnoreCanceled(peer.Analyze.Run(ctx))
+	})
+	group.Go(func() error {
+		return errs2.IgnoreCanceled(peer.DBHealth.Run(ctx))
+	})
+	group.Go(func() error {
+		return errs2.IgnoreCanceled(peer.Vacuum.Run(ctx))
+	})
 	group.Go(func() error {
 		return errs2.IgnoreCanceled(peer.Storage2.Orders.Run(ctx))
 	})
@@ -458,6 +529,15 @@ func (peer *Peer) Close() error {
 	if peer.Storage2.CacheService != nil {
 		errlist.Add(peer.Storage2.CacheService.Close())
 	}
+	if peer.Analyze != nil {
+		errlist.Add(peer.Analyze.Close())
+	}
+	if peer.DBHealth != nil {
+		errlist.Add(peer.DBHealth.Close())
+	}
+	if peer.Vacuum != nil {
+		errlist.Add(peer.Vacuum.Close())
+	}
 	if peer.Collector != nil {
 		errlist.Add(peer.Collector.Close())
 	}
@@ -0,0 +1,37 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenode_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testidentity"
+	"storj.io/storj/internal/version"
+	"storj.io/storj/pkg/identity"
+	"storj.io/storj/storagenode"
+)
+
+func TestNewRejectsLowDifficulty(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	full, err := testidentity.NewTestIdentity(ctx)
+	require.NoError(t, err)
+	difficulty, err := full.ID.Difficulty()
+	require.NoError(t, err)
+
+	// the difficulty is checked before anything else is set up, so no databases are needed
+	for _, minimum := range []uint64{uint64(difficulty) + 1, 1 << 16} {
+		peer, err := storagenode.New(log, full, nil, nil, storagenode.Config{MinimumDifficulty: minimum}, version.Info{})
+		require.Error(t, err, minimum)
+		require.True(t, identity.Error.Has(err), minimum)
+		require.Nil(t, peer)
+	}
+}
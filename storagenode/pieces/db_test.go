@@ -4,6 +4,7 @@
 package pieces_test
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"storj.io/storj/internal/memory"
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testidentity"
 	"storj.io/storj/internal/testrand"
@@ -19,6 +21,7 @@ import (
 	"storj.io/storj/pkg/storj"
 	"storj.io/storj/storagenode"
 	"storj.io/storj/storagenode/pieces"
+	"storj.io/storj/storagenode/storagenodedb"
 	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
 )
 
@@ -141,10 +144,19 @@ func TestV0PieceInfo(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Len(t, expired, 3)
 
+		// no failed deletions yet
+		failed, err := pieceinfos.CountDeletionFailed(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, failed)
+
 		// mark info0 deletion as a failure
 		err = pieceinfos.DeleteFailed(ctx, info0.SatelliteID, info0.PieceID, exp)
 		assert.NoError(t, err)
 
+		failed, err = pieceinfos.CountDeletionFailed(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, failed)
+
 		// this shouldn't return info0
 		expired, err = pieceinfos.GetExpired(ctx, exp, 10)
 		assert.NoError(t, err)
@@ -212,3 +224,203 @@ func TestPieceinfo_Trivial(t *testing.T) {
 		}
 	})
 }
+
+func TestV0PieceInfo_EstimatePieceCount(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		pieceinfos := db.V0PieceInfo().(pieces.V0PieceInfoDBForTest)
+
+		count, err := pieceinfos.EstimatePieceCount(ctx)
+		require.NoError(t, err)
+		require.Zero(t, count)
+
+		satelliteID := testrand.NodeID()
+		for i := 0; i < 3; i++ {
+			err := pieceinfos.Add(ctx, &pieces.Info{
+				SatelliteID:     satelliteID,
+				PieceID:         testrand.PieceID(),
+				PieceCreation:   time.Now(),
+				OrderLimit:      &pb.OrderLimit{},
+				UplinkPieceHash: &pb.PieceHash{},
+			})
+			require.NoError(t, err)
+		}
+
+		// without statistics the count is exact
+		count, err = pieceinfos.EstimatePieceCount(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, count)
+
+		// with statistics the count comes from sqlite_stat1
+		rawDB := db.(*storagenodedb.DB).RawDatabases()[storagenodedb.PieceInfoDBName].GetDB()
+		_, err = rawDB.Exec("ANALYZE")
+		require.NoError(t, err)
+
+		count, err = pieceinfos.EstimatePieceCount(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, count)
+	})
+}
+
+func TestV0PieceInfo_BackfillExpirations(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		pieceinfos := db.V0PieceInfo().(pieces.V0PieceInfoDBForTest)
+		expirations := db.PieceExpirationDB()
+
+		satelliteID := testrand.NodeID()
+		expiresAt := time.Now().Add(-time.Hour).UTC()
+
+		add := func(pieceExpiration, orderLimitExpiration time.Time) storj.PieceID {
+			pieceID := testrand.PieceID()
+			err := pieceinfos.Add(ctx, &pieces.Info{
+				SatelliteID:     satelliteID,
+				PieceID:         pieceID,
+				PieceCreation:   time.Now(),
+				PieceExpiration: pieceExpiration,
+				OrderLimit:      &pb.OrderLimit{PieceExpiration: orderLimitExpiration},
+				UplinkPieceHash: &pb.PieceHash{},
+			})
+			require.NoError(t, err)
+			return pieceID
+		}
+
+		// legacy pieces which lost their expiration
+		lost1 := add(time.Time{}, expiresAt)
+		lost2 := add(time.Time{}, expiresAt)
+		// pieces which are already collected from pieceinfo_
+		add(expiresAt, expiresAt)
+		// pieces which never expire
+		add(time.Time{}, time.Time{})
+
+		// nothing is collected from the piece expirations yet
+		expired, err := expirations.GetExpired(ctx, time.Now(), 10)
+		require.NoError(t, err)
+		require.Empty(t, expired)
+
+		inserted, err := pieceinfos.BackfillExpirations(ctx, expirations)
+		require.NoError(t, err)
+		require.Equal(t, 2, inserted)
+
+		expired, err = expirations.GetExpired(ctx, time.Now(), 10)
+		require.NoError(t, err)
+		var pieceIDs []storj.PieceID
+		for _, info := range expired {
+			require.Equal(t, satelliteID, info.SatelliteID)
+			pieceIDs = append(pieceIDs, info.PieceID)
+		}
+		require.ElementsMatch(t, []storj.PieceID{lost1, lost2}, pieceIDs)
+
+		// backfilling again doesn't insert duplicates
+		inserted, err = pieceinfos.BackfillExpirations(ctx, expirations)
+		require.NoError(t, err)
+		require.Zero(t, inserted)
+	})
+}
+
+func TestV0PieceInfo_SizeHistogram(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		pieceinfos := db.V0PieceInfo().(pieces.V0PieceInfoDBForTest)
+
+		buckets := []int64{
+			64 * memory.KiB.Int64(),
+			4 * memory.KiB.Int64(),
+			memory.MiB.Int64(),
+		}
+
+		histogram, err := pieceinfos.SizeHistogram(ctx, buckets)
+		require.NoError(t, err)
+		require.Equal(t, map[int64]int64{
+			4 * memory.KiB.Int64():  0,
+			64 * memory.KiB.Int64(): 0,
+			memory.MiB.Int64():      0,
+			math.MaxInt64:           0,
+		}, histogram)
+
+		satelliteID := testrand.NodeID()
+		for _, size := range []memory.Size{
+			100 * memory.B,
+			4 * memory.KiB, // bounds are inclusive
+			5 * memory.KiB,
+			64 * memory.KiB,
+			100 * memory.KiB,
+			memory.MiB,
+			2 * memory.MiB,
+			10 * memory.MiB,
+		} {
+			err := pieceinfos.Add(ctx, &pieces.Info{
+				SatelliteID:     satelliteID,
+				PieceID:         testrand.PieceID(),
+				PieceSize:       size.Int64(),
+				PieceCreation:   time.Now(),
+				OrderLimit:      &pb.OrderLimit{},
+				UplinkPieceHash: &pb.PieceHash{},
+			})
+			require.NoError(t, err)
+		}
+
+		histogram, err = pieceinfos.SizeHistogram(ctx, buckets)
+		require.NoError(t, err)
+		require.Equal(t, map[int64]int64{
+			4 * memory.KiB.Int64():  2,
+			64 * memory.KiB.Int64(): 2,
+			memory.MiB.Int64():      2,
+			math.MaxInt64:           2,
+		}, histogram)
+
+		// without buckets all pieces are counted together
+		histogram, err = pieceinfos.SizeHistogram(ctx, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[int64]int64{math.MaxInt64: 8}, histogram)
+	})
+}
+
+func TestV0PieceInfo_FindDuplicatePieceIDs(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		pieceinfos := db.V0PieceInfo().(pieces.V0PieceInfoDBForTest)
+
+		duplicates, err := pieceinfos.FindDuplicatePieceIDs(ctx)
+		require.NoError(t, err)
+		require.Empty(t, duplicates)
+
+		add := func(satelliteID storj.NodeID, pieceID storj.PieceID) {
+			err := pieceinfos.Add(ctx, &pieces.Info{
+				SatelliteID:     satelliteID,
+				PieceID:         pieceID,
+				PieceCreation:   time.Now(),
+				OrderLimit:      &pb.OrderLimit{},
+				UplinkPieceHash: &pb.PieceHash{},
+			})
+			require.NoError(t, err)
+		}
+
+		satellite0, satellite1 := testrand.NodeID(), testrand.NodeID()
+
+		// different pieces of the same satellite aren't duplicates
+		add(satellite0, testrand.PieceID())
+		add(satellite0, testrand.PieceID())
+		add(satellite1, testrand.PieceID())
+
+		duplicates, err = pieceinfos.FindDuplicatePieceIDs(ctx)
+		require.NoError(t, err)
+		require.Empty(t, duplicates)
+
+		duplicate := testrand.PieceID()
+		add(satellite0, duplicate)
+		add(satellite1, duplicate)
+
+		duplicates, err = pieceinfos.FindDuplicatePieceIDs(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []storj.PieceID{duplicate}, duplicates)
+	})
+}
@@ -65,10 +65,18 @@ func TestPieceExpirationDB(t *testing.T) {
 
 		deleteFailedAt := expireAt.Add(2 * time.Microsecond)
 
+		failed, err := expireDB.CountExpirationDeletionFailed(ctx)
+		require.NoError(t, err)
+		require.Zero(t, failed)
+
 		// DeleteFailed normal usage
 		err = expireDB.DeleteFailed(ctx, satelliteID, pieceID, deleteFailedAt)
 		require.NoError(t, err)
 
+		failed, err = expireDB.CountExpirationDeletionFailed(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 1, failed)
+
 		// GetExpired filters out rows with deletion_failed_at = t
 		expiredPieceIDs, err = expireDB.GetExpired(ctx, deleteFailedAt, 1000)
 		require.NoError(t, err)
@@ -87,5 +95,56 @@ func TestPieceExpirationDB(t *testing.T) {
 		expiredPieceIDs, err = expireDB.GetExpired(ctx, expireAt.Add(365*24*time.Hour), 1000)
 		require.NoError(t, err)
 		require.Len(t, expiredPieceIDs, 0)
+
+		failed, err = expireDB.CountExpirationDeletionFailed(ctx)
+		require.NoError(t, err)
+		require.Zero(t, failed)
+	})
+}
+
+func TestListDeletionFailed(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		expireDB := db.PieceExpirationDB()
+
+		failures, err := expireDB.ListDeletionFailed(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, failures)
+
+		expireAt := time.Now().Add(-time.Hour)
+
+		// a piece which is still to be deleted
+		require.NoError(t, expireDB.SetExpiration(ctx, testrand.NodeID(), testrand.PieceID(), expireAt))
+
+		var expected []pieces.DeletionFailure
+		for i := 0; i < 3; i++ {
+			failure := pieces.DeletionFailure{
+				SatelliteID:      testrand.NodeID(),
+				PieceID:          testrand.PieceID(),
+				DeletionFailedAt: expireAt.Add(time.Duration(i) * time.Minute).UTC(),
+			}
+			require.NoError(t, expireDB.SetExpiration(ctx, failure.SatelliteID, failure.PieceID, expireAt))
+			require.NoError(t, expireDB.DeleteFailed(ctx, failure.SatelliteID, failure.PieceID, failure.DeletionFailedAt))
+
+			// latest failure first
+			expected = append([]pieces.DeletionFailure{failure}, expected...)
+		}
+
+		failures, err = expireDB.ListDeletionFailed(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, failures, len(expected))
+		for i := range expected {
+			assert.Equal(t, expected[i].SatelliteID, failures[i].SatelliteID)
+			assert.Equal(t, expected[i].PieceID, failures[i].PieceID)
+			assert.True(t, expected[i].DeletionFailedAt.Equal(failures[i].DeletionFailedAt), failures[i].DeletionFailedAt)
+		}
+
+		failures, err = expireDB.ListDeletionFailed(ctx, 2)
+		require.NoError(t, err)
+		require.Len(t, failures, 2)
+		assert.Equal(t, expected[0].PieceID, failures[0].PieceID)
+		assert.Equal(t, expected[1].PieceID, failures[1].PieceID)
 	})
 }
@@ -53,6 +53,13 @@ type ExpiredInfo struct {
 	InPieceInfo bool
 }
 
+// DeletionFailure describes an expired piece which failed to be deleted from the disk.
+type DeletionFailure struct {
+	SatelliteID      storj.NodeID
+	PieceID          storj.PieceID
+	DeletionFailedAt time.Time
+}
+
 // PieceExpirationDB stores information about pieces with expiration dates.
 //
 // architecture: Database
@@ -66,6 +73,10 @@ type PieceExpirationDB interface {
 	// DeleteFailed marks an expiration record as having experienced a failure in deleting the
 	// piece from the disk
 	DeleteFailed(ctx context.Context, satelliteID storj.NodeID, pieceID storj.PieceID, failedAt time.Time) error
+	// CountExpirationDeletionFailed returns the number of expiration records whose piece failed to be deleted
+	CountExpirationDeletionFailed(ctx context.Context) (int64, error)
+	// ListDeletionFailed returns at most limit expiration records whose piece failed to be deleted, latest failure first
+	ListDeletionFailed(ctx context.Context, limit int) ([]DeletionFailure, error)
 }
 
 // V0PieceInfoDB stores meta information about pieces stored with storage format V0 (where
@@ -89,6 +100,18 @@ type V0PieceInfoDB interface {
 	// immediately. The ctx parameter is intended specifically to allow canceling iteration
 	// early.
 	WalkSatelliteV0Pieces(ctx context.Context, blobStore storage.Blobs, satellite storj.NodeID, walkFunc func(StoredPieceAccess) error) error
+	// EstimatePieceCount returns an approximate number of pieces stored with storage format V0.
+	EstimatePieceCount(ctx context.Context) (int64, error)
+	// CountDeletionFailed returns the number of pieces stored with storage format V0 that failed to be deleted
+	CountDeletionFailed(ctx context.Context) (int64, error)
+	// BackfillExpirations inserts the expirations of pieces stored with storage format V0, which are
+	// only known from their order limits, into the piece expirations and returns how many were inserted.
+	BackfillExpirations(ctx context.Context, into PieceExpirationDB) (int, error)
+	// SizeHistogram returns the number of pieces stored with storage format V0 in each piece size bucket.
+	// Pieces larger than the largest bucket are counted under math.MaxInt64.
+	SizeHistogram(ctx context.Context, buckets []int64) (map[int64]int64, error)
+	// FindDuplicatePieceIDs returns the ids of pieces stored with storage format V0 for more than one satellite.
+	FindDuplicatePieceIDs(ctx context.Context) ([]storj.PieceID, error)
 }
 
 // V0PieceInfoDBForTest is like V0PieceInfoDB, but adds on the Add() method so
@@ -342,6 +365,29 @@ func (store *Store) DeleteFailed(ctx context.Context, expired ExpiredInfo, when
 	return store.expirationInfo.DeleteFailed(ctx, expired.SatelliteID, expired.PieceID, when)
 }
 
+// DeletionFailedCount returns the number of pieces, across all satellites, that are stuck
+// because deleting them from the disk failed.
+func (store *Store) DeletionFailedCount(ctx context.Context) (_ int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var total int64
+	if store.v0PieceInfo != nil {
+		count, err := store.v0PieceInfo.CountDeletionFailed(ctx)
+		if err != nil {
+			return 0, Error.Wrap(err)
+		}
+		total += count
+	}
+	if store.expirationInfo != nil {
+		count, err := store.expirationInfo.CountExpirationDeletionFailed(ctx)
+		if err != nil {
+			return 0, Error.Wrap(err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
 // SpaceUsedForPieces returns *an approximation of* the disk space used by all local pieces (both
 // V0 and later). This is an approximation because changes may be being applied to the filestore as
 // this information is collected, and because it is possible that various errors in directory
@@ -403,7 +449,7 @@ func (store *Store) SpaceUsedBySatellite(ctx context.Context, satelliteID storj.
 	err := store.WalkSatellitePieces(ctx, satelliteID, func(access StoredPieceAccess) error {
 		contentSize, statErr := access.ContentSize(ctx)
 		if statErr != nil {
-			store.log.Error("failed to stat", zap.Error(statErr), zap.String("pieceID", access.PieceID().String()), zap.String("satellite", satelliteID.String()))
+			store.log.Error("failed to stat", zap.Error(statErr), zap.Stringer("Piece ID", access.PieceID()), zap.Stringer("SatelliteID", satelliteID))
 			// keep iterating; we want a best effort total here.
 			return nil
 		}
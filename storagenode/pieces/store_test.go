@@ -304,6 +304,19 @@ func TestGetExpired(t *testing.T) {
 		assert.Equal(t, testPieces[0].PieceID, expired[1].PieceID)
 		assert.Equal(t, testPieces[0].SatelliteID, expired[1].SatelliteID)
 		assert.True(t, expired[1].InPieceInfo)
+
+		// failed deletions are counted across both tables
+		failed, err := store.DeletionFailedCount(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, failed)
+
+		for _, info := range expired {
+			require.NoError(t, store.DeleteFailed(ctx, info, now))
+		}
+
+		failed, err = store.DeletionFailedCount(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, failed)
 	})
 }
 
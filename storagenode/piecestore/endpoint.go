@@ -48,6 +48,13 @@ var _ pb.PiecestoreServer = (*Endpoint)(nil)
 // OldConfig contains everything necessary for a server
 type OldConfig struct {
 	Path                   string         `help:"path to store data in" default:"$CONFDIR/storage"`
+	DatabaseDriver         string         `help:"driver of the databases, sqlite3 stores them in files next to the pieces, postgres in schemas of the database at storage.database-url" default:"sqlite3"`
+	DatabaseURL            string         `help:"connection string of the postgres database used by the postgres database driver" default:""`
+	DatabasePrefix         string         `help:"prefix for the names of the database files, or of the postgres schemas" default:""`
+	AllowDegradedDatabases bool           `help:"start the node even when non-critical databases, e.g. the storage usage or reputation caches, fail to open" default:"false"`
+	WALAutocheckpoint      int            `help:"number of pages in the database write-ahead logs which trigger an automatic checkpoint" default:"1000"`
+	VersionHistoryTail     int            `help:"how many of the latest rows of the database version tables are kept on startup" default:"1"`
+	UsedSerialsSalt        string         `help:"salt of the hashes stored instead of the used serial numbers, the serial numbers are stored as they are when it's empty" default:""`
 	WhitelistedSatellites  storj.NodeURLs `help:"a comma-separated list of approved satellite node urls" devDefault:"" releaseDefault:"12EayRS2V1kEsWESU9QMRseFhdxYxKicsiFmxrsLZHeLUtdps3S@mars.tardigrade.io:7777,118UWpMCHzs6CvSgWd9BfFVjw5K9pZbJjkfZJexMtSkmKxvvAW@satellite.stefan-benten.de:7777,121RTSDpyNZVcEU84Ticf2L1ntiuUimbWgfATz21tuvgk3vzoA6@saturn.tardigrade.io:7777,12L9ZFwhzVpuEKMUNUqkaTLGzwY9G24tbiigLiXpmZWKwmcNDDs@jupiter.tardigrade.io:7777"`
 	AllocatedDiskSpace     memory.Size    `user:"true" help:"total allocated disk space in bytes" default:"1TB"`
 	AllocatedBandwidth     memory.Size    `user:"true" help:"total allocated bandwidth in bytes" default:"2TB"`
@@ -63,8 +70,12 @@ type Config struct {
 
 	RetainTimeBuffer time.Duration `help:"allows for small differences in the satellite and storagenode clocks" default:"1h0m0s"`
 
-	Monitor monitor.Config
-	Orders  orders.Config
+	ActionCaps    bandwidth.ActionCaps `help:"monthly bandwidth caps of piece actions, e.g. GET_REPAIR:1TB,PUT_REPAIR:500GB; requests for an action are rejected once its cap is reached" default:""`
+	SatelliteCaps SatelliteCaps        `help:"disk space caps of satellites, e.g. 12EayRS2V1kEsWESU9QMRseFhdxYxKicsiFmxrsLZHeLUtdps3S:1TB; uploads from a satellite are rejected once its pieces use its cap" default:""`
+
+	Monitor     monitor.Config
+	Orders      orders.Config
+	UsedSerials UsedSerialsCacheConfig
 }
 
 // Endpoint implements uploading, downloading and deleting for a storage node..
@@ -115,6 +126,49 @@ func NewEndpoint(log *zap.Logger, signer signing.Signer, trust *trust.Pool, moni
 
 var monLiveRequests = mon.TaskNamed("live-request")
 
+// checkActionCap rejects the request with ResourceExhausted when the monthly bandwidth cap of the action was reached,
+// so that e.g. repair traffic can be capped while customer traffic is still served.
+func (endpoint *Endpoint) checkActionCap(ctx context.Context, action pb.PieceAction) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = endpoint.config.ActionCaps.Check(ctx, endpoint.usage, action)
+	switch {
+	case bandwidth.ErrActionCapExceeded.Has(err):
+		endpoint.log.Info("request rejected, bandwidth cap reached", zap.Stringer("Action", action), zap.Error(err))
+		return rpcstatus.Error(rpcstatus.ResourceExhausted, err.Error())
+	case err != nil:
+		return rpcstatus.Error(rpcstatus.Internal, err.Error())
+	}
+	return nil
+}
+
+// checkSatelliteCap rejects the upload with ResourceExhausted when the pieces of the satellite use its disk space cap,
+// so that a single satellite can't fill the whole allocation.
+func (endpoint *Endpoint) checkSatelliteCap(ctx context.Context, satelliteID storj.NodeID) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = endpoint.config.SatelliteCaps.Check(ctx, endpoint.store, satelliteID)
+	switch {
+	case ErrSatelliteCapExceeded.Has(err):
+		endpoint.log.Info("upload rejected, satellite cap reached", zap.Stringer("SatelliteID", satelliteID), zap.Error(err))
+		return rpcstatus.Error(rpcstatus.ResourceExhausted, err.Error())
+	case err != nil:
+		return rpcstatus.Error(rpcstatus.Internal, err.Error())
+	}
+	return nil
+}
+
+// SatelliteOverCap returns the satellites whose uploads are rejected, because their pieces use their disk space cap.
+func (endpoint *Endpoint) SatelliteOverCap(ctx context.Context) (_ []storj.NodeID, err error) {
+	defer mon.Task()(&ctx)(&err)
+	return endpoint.config.SatelliteCaps.OverCap(ctx, endpoint.store)
+}
+
+// LiveRequests returns the number of requests which are currently being handled.
+func (endpoint *Endpoint) LiveRequests() int {
+	return int(atomic.LoadInt32(&endpoint.liveRequests))
+}
+
 // Delete handles deleting a piece on piece store.
 func (endpoint *Endpoint) Delete(ctx context.Context, delete *pb.PieceDeleteRequest) (_ *pb.PieceDeleteResponse, err error) {
 	defer monLiveRequests(&ctx)(&err)
@@ -205,6 +259,14 @@ func (endpoint *Endpoint) doUpload(stream uploadStream) (err error) {
 		return err
 	}
 
+	if err := endpoint.checkActionCap(ctx, limit.Action); err != nil {
+		return err
+	}
+
+	if err := endpoint.checkSatelliteCap(ctx, limit.SatelliteId); err != nil {
+		return err
+	}
+
 	var pieceWriter *pieces.Writer
 	defer func() {
 		endTime := time.Now().UTC()
@@ -407,6 +469,10 @@ func (endpoint *Endpoint) doDownload(stream downloadStream) (err error) {
 		return Error.Wrap(err) // TODO: report rpc status unauthorized or bad request
 	}
 
+	if err := endpoint.checkActionCap(ctx, limit.Action); err != nil {
+		return err
+	}
+
 	var pieceReader *pieces.Reader
 	defer func() {
 		endTime := time.Now().UTC()
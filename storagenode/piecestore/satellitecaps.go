@@ -0,0 +1,123 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package piecestore
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/storj/internal/memory"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storagenode/pieces"
+)
+
+var (
+	// ErrSatelliteCaps is the error class for invalid satellite caps.
+	ErrSatelliteCaps = errs.Class("satellite caps")
+	// ErrSatelliteCapExceeded is returned when the pieces of a satellite use at least its cap.
+	ErrSatelliteCapExceeded = errs.Class("satellite cap exceeded")
+)
+
+// SatelliteCaps limit the disk space the pieces of a satellite may use, the satellites without a cap are
+// only limited by the allocated disk space. It's configured as a comma separated list of
+// satelliteID:size pairs, e.g. 12EayRS2V1kEsWESU9QMRseFhdxYxKicsiFmxrsLZHeLUtdps3S:1TB.
+type SatelliteCaps map[storj.NodeID]memory.Size
+
+// String returns the caps in the format accepted by Set, ordered by satellite id. The sizes are
+// formatted as exact byte counts, so that parsing them again returns the same caps.
+func (caps SatelliteCaps) String() string {
+	var satellites []storj.NodeID
+	for satelliteID := range caps {
+		satellites = append(satellites, satelliteID)
+	}
+	sort.Slice(satellites, func(i, k int) bool { return satellites[i].Less(satellites[k]) })
+
+	var pairs []string
+	for _, satelliteID := range satellites {
+		pairs = append(pairs, satelliteID.String()+":"+strconv.FormatInt(caps[satelliteID].Int64(), 10))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses a comma separated list of satelliteID:size pairs, see SatelliteCaps.
+func (caps *SatelliteCaps) Set(s string) error {
+	parsed := SatelliteCaps{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return ErrSatelliteCaps.New("invalid cap %q, expected satelliteID:size", pair)
+		}
+
+		satelliteID, err := storj.NodeIDFromString(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return ErrSatelliteCaps.New("invalid satellite id %q: %v", parts[0], err)
+		}
+
+		var size memory.Size
+		if err := size.Set(strings.TrimSpace(parts[1])); err != nil {
+			return ErrSatelliteCaps.New("invalid size %q: %v", parts[1], err)
+		}
+		parsed[satelliteID] = size
+	}
+
+	*caps = parsed
+	return nil
+}
+
+// Type implements pflag.Value.
+func (SatelliteCaps) Type() string { return "piecestore.SatelliteCaps" }
+
+// Check returns ErrSatelliteCapExceeded when the pieces of the satellite use at least its cap.
+//
+// The space used comes from the space used cache of the store, so a cap may be exceeded by the
+// uploads which are still in progress.
+func (caps SatelliteCaps) Check(ctx context.Context, store *pieces.Store, satelliteID storj.NodeID) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	limit, ok := caps[satelliteID]
+	if !ok || limit <= 0 {
+		return nil
+	}
+
+	used, err := store.SpaceUsedBySatellite(ctx, satelliteID)
+	if err != nil {
+		return err
+	}
+
+	if used >= limit.Int64() {
+		mon.Meter("satellite_cap_exceeded").Mark(1)
+		return ErrSatelliteCapExceeded.New("%s uses %s of %s", satelliteID, memory.Size(used), limit)
+	}
+	return nil
+}
+
+// OverCap returns the satellites whose pieces use at least their cap, sorted by id, e.g. for
+// reporting which satellites the uploads are rejected from.
+//
+// The space used comes from the space used cache of the store, like for Check.
+func (caps SatelliteCaps) OverCap(ctx context.Context, store *pieces.Store) (_ []storj.NodeID, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var over []storj.NodeID
+	for satelliteID := range caps {
+		err := caps.Check(ctx, store, satelliteID)
+		switch {
+		case ErrSatelliteCapExceeded.Has(err):
+			over = append(over, satelliteID)
+		case err != nil:
+			return nil, err
+		}
+	}
+	sort.Slice(over, func(i, k int) bool { return over[i].Less(over[k]) })
+	return over, nil
+}
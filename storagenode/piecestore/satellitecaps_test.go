@@ -0,0 +1,72 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package piecestore_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/memory"
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/pieces"
+	"storj.io/storj/storagenode/piecestore"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestSatelliteCapsSet(t *testing.T) {
+	first, second := testrand.NodeID(), testrand.NodeID()
+
+	var caps piecestore.SatelliteCaps
+	require.NoError(t, caps.Set(first.String()+":1TB, "+second.String()+":500GB"))
+	require.Equal(t, piecestore.SatelliteCaps{
+		first:  memory.TB,
+		second: 500 * memory.GB,
+	}, caps)
+
+	var parsed piecestore.SatelliteCaps
+	require.NoError(t, parsed.Set(caps.String()))
+	require.Equal(t, caps, parsed)
+
+	require.NoError(t, caps.Set(""))
+	require.Empty(t, caps)
+
+	require.True(t, piecestore.ErrSatelliteCaps.Has(caps.Set(first.String())))
+	require.True(t, piecestore.ErrSatelliteCaps.Has(caps.Set("unknown:1TB")))
+	require.True(t, piecestore.ErrSatelliteCaps.Has(caps.Set(first.String()+":lots")))
+}
+
+func TestSatelliteCapsCheck(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		over, under, uncapped := testrand.NodeID(), testrand.NodeID(), testrand.NodeID()
+
+		blobs := pieces.NewBlobsUsageCacheTest(db.Pieces(), 0, map[storj.NodeID]int64{
+			over:     memory.KiB.Int64(),
+			under:    memory.KiB.Int64() - 1,
+			uncapped: memory.GiB.Int64(),
+		})
+		store := pieces.NewStore(zaptest.NewLogger(t), blobs, db.V0PieceInfo(), db.PieceExpirationDB(), db.PieceSpaceUsedDB())
+
+		caps := piecestore.SatelliteCaps{
+			over:  memory.KiB,
+			under: memory.KiB,
+		}
+
+		err := caps.Check(ctx, store, over)
+		require.True(t, piecestore.ErrSatelliteCapExceeded.Has(err))
+		require.NoError(t, caps.Check(ctx, store, under))
+		require.NoError(t, caps.Check(ctx, store, uncapped))
+
+		overCap, err := caps.OverCap(ctx, store)
+		require.NoError(t, err)
+		require.Equal(t, []storj.NodeID{over}, overCap)
+	})
+}
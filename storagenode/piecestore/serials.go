@@ -7,9 +7,14 @@ import (
 	"context"
 	"time"
 
+	"github.com/zeebo/errs"
+
 	"storj.io/storj/pkg/storj"
 )
 
+// ErrUsedSerialsHashed is returned by IterateAll when the serial numbers are stored hashed and can't be listed.
+var ErrUsedSerialsHashed = errs.Class("used serials are hashed")
+
 // SerialNumberFn is callback from IterateAll
 type SerialNumberFn func(satelliteID storj.NodeID, serialNumber storj.SerialNumber, expiration time.Time)
 
@@ -20,6 +25,8 @@ type SerialNumberFn func(satelliteID storj.NodeID, serialNumber storj.SerialNumb
 type UsedSerials interface {
 	// Add adds a serial to the database.
 	Add(ctx context.Context, satelliteID storj.NodeID, serialNumber storj.SerialNumber, expiration time.Time) error
+	// Exists checks whether the serial has been used.
+	Exists(ctx context.Context, satelliteID storj.NodeID, serialNumber storj.SerialNumber) (bool, error)
 	// DeleteExpired deletes expired serial numbers
 	DeleteExpired(ctx context.Context, now time.Time) error
 
@@ -27,3 +34,17 @@ type UsedSerials interface {
 	// Note, this will lock the database and should only be used during startup.
 	IterateAll(ctx context.Context, fn SerialNumberFn) error
 }
+
+// SerialKeyFn is callback from IterateAllKeys
+type SerialKeyFn func(satelliteID storj.NodeID, key []byte)
+
+// HashedUsedSerials is implemented by the UsedSerials databases which may store the serial numbers hashed.
+// The in-memory filter of UsedSerialsCache is keyed on the stored values then, which can be listed even
+// when the serial numbers can't.
+type HashedUsedSerials interface {
+	// SerialKey returns the value stored for the serial number of the satellite.
+	SerialKey(satelliteID storj.NodeID, serialNumber storj.SerialNumber) []byte
+	// IterateAllKeys iterates the values stored for all serials.
+	// Note, this will lock the database and should only be used during startup.
+	IterateAllKeys(ctx context.Context, fn SerialKeyFn) error
+}
@@ -4,18 +4,24 @@
 package piecestore_test
 
 import (
+	"context"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
 
+	"storj.io/storj/internal/memory"
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testidentity"
 	"storj.io/storj/internal/testrand"
 	"storj.io/storj/pkg/storj"
 	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/piecestore"
 	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
 )
 
@@ -125,3 +131,235 @@ func TestUsedSerials_Trivial(t *testing.T) {
 		}
 	})
 }
+
+// countingSerials counts the calls that reach the database.
+type countingSerials struct {
+	piecestore.UsedSerials
+	exists  int64
+	adds    int64
+	iterate int64
+}
+
+func (db *countingSerials) Add(ctx context.Context, satelliteID storj.NodeID, serialNumber storj.SerialNumber, expiration time.Time) error {
+	atomic.AddInt64(&db.adds, 1)
+	return db.UsedSerials.Add(ctx, satelliteID, serialNumber, expiration)
+}
+
+func (db *countingSerials) Exists(ctx context.Context, satelliteID storj.NodeID, serialNumber storj.SerialNumber) (bool, error) {
+	atomic.AddInt64(&db.exists, 1)
+	return db.UsedSerials.Exists(ctx, satelliteID, serialNumber)
+}
+
+func (db *countingSerials) IterateAll(ctx context.Context, fn piecestore.SerialNumberFn) error {
+	atomic.AddInt64(&db.iterate, 1)
+	return db.UsedSerials.IterateAll(ctx, fn)
+}
+
+func TestUsedSerialsCache(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		now := time.Now()
+		satelliteID := testrand.NodeID()
+		stored, expiring, added := testrand.SerialNumber(), testrand.SerialNumber(), testrand.SerialNumber()
+
+		require.NoError(t, db.UsedSerials().Add(ctx, satelliteID, stored, now.Add(time.Hour)))
+		require.NoError(t, db.UsedSerials().Add(ctx, satelliteID, expiring, now.Add(time.Minute)))
+
+		counting := &countingSerials{UsedSerials: db.UsedSerials()}
+		cache := piecestore.NewUsedSerialsCache(zaptest.NewLogger(t), counting, piecestore.UsedSerialsCacheConfig{
+			ExpectedSerials:   1000,
+			FalsePositiveRate: 0.0001,
+			MaxMemory:         memory.MB,
+		})
+
+		// before Init every check goes to the database
+		exists, err := cache.Exists(ctx, satelliteID, testrand.SerialNumber())
+		require.NoError(t, err)
+		assert.False(t, exists)
+		assert.EqualValues(t, 1, atomic.LoadInt64(&counting.exists))
+
+		require.NoError(t, cache.Init(ctx))
+		atomic.StoreInt64(&counting.exists, 0)
+
+		// serials seeded from the database are found
+		for _, serial := range []storj.SerialNumber{stored, expiring} {
+			exists, err := cache.Exists(ctx, satelliteID, serial)
+			require.NoError(t, err)
+			assert.True(t, exists)
+		}
+
+		// serials added through the cache are found
+		require.NoError(t, cache.Add(ctx, satelliteID, added, now.Add(time.Hour)))
+		exists, err = cache.Exists(ctx, satelliteID, added)
+		require.NoError(t, err)
+		assert.True(t, exists)
+		assert.EqualValues(t, 3, atomic.LoadInt64(&counting.exists))
+
+		// unknown serials are answered without the database, modulo false positives
+		atomic.StoreInt64(&counting.exists, 0)
+		for i := 0; i < 100; i++ {
+			exists, err := cache.Exists(ctx, satelliteID, testrand.SerialNumber())
+			require.NoError(t, err)
+			assert.False(t, exists)
+		}
+		assert.True(t, atomic.LoadInt64(&counting.exists) < 5)
+
+		// deleting expired serials doesn't rebuild a filter that isn't full
+		require.NoError(t, cache.DeleteExpired(ctx, now.Add(10*time.Minute)))
+		assert.EqualValues(t, 1, atomic.LoadInt64(&counting.iterate))
+		for serial, expected := range map[storj.SerialNumber]bool{stored: true, added: true, expiring: false} {
+			exists, err := cache.Exists(ctx, satelliteID, serial)
+			require.NoError(t, err)
+			assert.Equal(t, expected, exists)
+		}
+	})
+}
+
+func TestUsedSerialsCacheRebuild(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		now := time.Now()
+		satelliteID := testrand.NodeID()
+
+		counting := &countingSerials{UsedSerials: db.UsedSerials()}
+		cache := piecestore.NewUsedSerialsCache(zaptest.NewLogger(t), counting, piecestore.UsedSerialsCacheConfig{
+			ExpectedSerials:   10,
+			FalsePositiveRate: 0.0001,
+			MaxMemory:         memory.MB,
+		})
+		require.NoError(t, cache.Init(ctx))
+		assert.EqualValues(t, 1, atomic.LoadInt64(&counting.iterate))
+
+		var expiring []storj.SerialNumber
+		for i := 0; i < 9; i++ {
+			serial := testrand.SerialNumber()
+			require.NoError(t, cache.Add(ctx, satelliteID, serial, now.Add(time.Minute)))
+			expiring = append(expiring, serial)
+		}
+		require.NoError(t, cache.DeleteExpired(ctx, now.Add(10*time.Minute)))
+		assert.EqualValues(t, 1, atomic.LoadInt64(&counting.iterate))
+
+		// the deleted serials are still in the filter and checked against the database
+		atomic.StoreInt64(&counting.exists, 0)
+		for _, serial := range expiring {
+			exists, err := cache.Exists(ctx, satelliteID, serial)
+			require.NoError(t, err)
+			assert.False(t, exists)
+		}
+		assert.EqualValues(t, len(expiring), atomic.LoadInt64(&counting.exists))
+
+		// filling the filter rebuilds it on the next deletion
+		stored := testrand.SerialNumber()
+		require.NoError(t, cache.Add(ctx, satelliteID, stored, now.Add(time.Hour)))
+		require.NoError(t, cache.DeleteExpired(ctx, now.Add(10*time.Minute)))
+		assert.EqualValues(t, 2, atomic.LoadInt64(&counting.iterate))
+
+		exists, err := cache.Exists(ctx, satelliteID, stored)
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		atomic.StoreInt64(&counting.exists, 0)
+		for _, serial := range expiring {
+			exists, err := cache.Exists(ctx, satelliteID, serial)
+			require.NoError(t, err)
+			assert.False(t, exists)
+		}
+		assert.True(t, atomic.LoadInt64(&counting.exists) < 2)
+	})
+}
+
+// countingHashedSerials counts the Exists calls that reach a database which stores the serial numbers hashed.
+type countingHashedSerials struct {
+	*countingSerials
+	piecestore.HashedUsedSerials
+}
+
+func TestUsedSerialsCacheHashed(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	config := storagenodedbtest.Config(ctx.Dir("storage"))
+	config.UsedSerialsSalt = []byte("salt")
+	db := storagenodedbtest.Open(t, ctx, zaptest.NewLogger(t), config)
+	defer ctx.Check(db.Close)
+
+	satelliteID := testrand.NodeID()
+	stored := testrand.SerialNumber()
+	require.NoError(t, db.UsedSerials().Add(ctx, satelliteID, stored, time.Now().Add(time.Hour)))
+
+	counting := &countingHashedSerials{
+		countingSerials:   &countingSerials{UsedSerials: db.UsedSerials()},
+		HashedUsedSerials: db.UsedSerials().(piecestore.HashedUsedSerials),
+	}
+	cache := piecestore.NewUsedSerialsCache(zaptest.NewLogger(t), counting, piecestore.UsedSerialsCacheConfig{
+		ExpectedSerials:   1000,
+		FalsePositiveRate: 0.0001,
+		MaxMemory:         memory.MB,
+	})
+	require.NoError(t, cache.Init(ctx))
+
+	// the filter is seeded from the hashes
+	exists, err := cache.Exists(ctx, satelliteID, stored)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.EqualValues(t, 1, atomic.LoadInt64(&counting.exists))
+
+	// unknown serials are still answered without the database, modulo false positives
+	atomic.StoreInt64(&counting.exists, 0)
+	for i := 0; i < 100; i++ {
+		exists, err := cache.Exists(ctx, satelliteID, testrand.SerialNumber())
+		require.NoError(t, err)
+		assert.False(t, exists)
+	}
+	assert.True(t, atomic.LoadInt64(&counting.exists) < 5)
+}
+
+// BenchmarkUsedSerialsVerify measures the used serial checks done when verifying an order limit.
+func BenchmarkUsedSerialsVerify(b *testing.B) {
+	ctx := testcontext.New(b)
+	defer ctx.Cleanup()
+
+	db := storagenodedbtest.Open(b, ctx, zap.NewNop(), storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	satelliteID := testrand.NodeID()
+	expiration := time.Now().Add(time.Hour)
+	for i := 0; i < 1000; i++ {
+		require.NoError(b, db.UsedSerials().Add(ctx, satelliteID, testrand.SerialNumber(), expiration))
+	}
+
+	counting := &countingSerials{UsedSerials: db.UsedSerials()}
+	cache := piecestore.NewUsedSerialsCache(zap.NewNop(), counting, piecestore.UsedSerialsCacheConfig{
+		ExpectedSerials:   100000,
+		FalsePositiveRate: 0.01,
+		MaxMemory:         memory.MB,
+	})
+	require.NoError(b, cache.Init(ctx))
+
+	for _, bench := range []struct {
+		name    string
+		serials piecestore.UsedSerials
+	}{
+		{"Database", counting},
+		{"Cache", cache},
+	} {
+		bench := bench
+		b.Run(bench.name, func(b *testing.B) {
+			atomic.StoreInt64(&counting.exists, 0)
+			atomic.StoreInt64(&counting.adds, 0)
+			for i := 0; i < b.N; i++ {
+				serial := testrand.SerialNumber()
+				exists, err := bench.serials.Exists(ctx, satelliteID, serial)
+				require.NoError(b, err)
+				require.False(b, exists)
+				require.NoError(b, bench.serials.Add(ctx, satelliteID, serial, expiration))
+			}
+			queries := atomic.LoadInt64(&counting.exists) + atomic.LoadInt64(&counting.adds)
+			b.ReportMetric(float64(queries)/float64(b.N), "queries/op")
+		})
+	}
+}
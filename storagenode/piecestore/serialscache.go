@@ -0,0 +1,204 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package piecestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"storj.io/storj/internal/memory"
+	"storj.io/storj/pkg/bloomfilter"
+	"storj.io/storj/pkg/storj"
+)
+
+// UsedSerialsCacheConfig defines parameters for the in-memory used serials filter.
+type UsedSerialsCacheConfig struct {
+	ExpectedSerials   int         `help:"number of used serials the in-memory filter is sized for" default:"100000"`
+	FalsePositiveRate float64     `help:"false positive rate of the in-memory used serials filter" default:"0.01"`
+	MaxMemory         memory.Size `help:"maximum memory used by the in-memory used serials filter" default:"2MB"`
+}
+
+// UsedSerialsCache keeps a bloom filter of used serials in front of the
+// UsedSerials database.
+//
+// Exists only hits the database when the filter reports that the serial may
+// be present, checks for serials that were never added are answered from
+// memory. The filter is seeded by Init. Serials deleted by DeleteExpired stay in
+// the filter, which only costs a database check for them, so the filter is
+// rebuilt from the database only after as many serials as it's sized for have
+// been added since the last build. This keeps the full iteration of the
+// database rare, instead of running it on every collection. When the
+// database implements HashedUsedSerials, the filter is keyed on the values it
+// stores, so that it works with hashed serial numbers too.
+//
+// Add always goes to the database, which rejects a used serial by itself.
+//
+// architecture: Database
+type UsedSerialsCache struct {
+	log    *zap.Logger
+	db     UsedSerials
+	config UsedSerialsCacheConfig
+
+	rebuildMu sync.Mutex
+
+	mu         sync.Mutex
+	filter     *bloomfilter.Filter
+	added      int
+	rebuilding bool
+	pending    []storj.PieceID
+}
+
+var _ UsedSerials = (*UsedSerialsCache)(nil)
+
+// NewUsedSerialsCache creates a new used serials cache. The cache answers
+// every request from the database until Init has been called.
+func NewUsedSerialsCache(log *zap.Logger, db UsedSerials, config UsedSerialsCacheConfig) *UsedSerialsCache {
+	return &UsedSerialsCache{
+		log:    log,
+		db:     db,
+		config: config,
+	}
+}
+
+// Init seeds the filter with the serials stored in the database.
+func (cache *UsedSerialsCache) Init(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+	return cache.rebuild(ctx)
+}
+
+// Add adds a serial to the database.
+func (cache *UsedSerialsCache) Add(ctx context.Context, satelliteID storj.NodeID, serialNumber storj.SerialNumber, expiration time.Time) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// add to the filter before the database, a failed insert only causes a false positive
+	key := cache.key(satelliteID, serialNumber)
+	cache.mu.Lock()
+	if cache.filter != nil {
+		cache.filter.Add(key)
+		cache.added++
+	}
+	if cache.rebuilding {
+		cache.pending = append(cache.pending, key)
+	}
+	cache.mu.Unlock()
+
+	return cache.db.Add(ctx, satelliteID, serialNumber, expiration)
+}
+
+// Exists checks whether the serial has been used.
+func (cache *UsedSerialsCache) Exists(ctx context.Context, satelliteID storj.NodeID, serialNumber storj.SerialNumber) (_ bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	cache.mu.Lock()
+	mayContain := cache.filter == nil || cache.filter.Contains(cache.key(satelliteID, serialNumber))
+	cache.mu.Unlock()
+
+	if !mayContain {
+		mon.Meter("used_serials_cache_skipped").Mark(1)
+		return false, nil
+	}
+
+	return cache.db.Exists(ctx, satelliteID, serialNumber)
+}
+
+// DeleteExpired deletes expired serial numbers and rebuilds the filter when it's full.
+func (cache *UsedSerialsCache) DeleteExpired(ctx context.Context, now time.Time) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = cache.db.DeleteExpired(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	cache.mu.Lock()
+	full := cache.filter != nil && cache.added >= cache.config.ExpectedSerials
+	cache.mu.Unlock()
+	if !full {
+		return nil
+	}
+
+	if err := cache.rebuild(ctx); err != nil {
+		// the old filter is kept, it's still correct, just less selective
+		cache.log.Warn("unable to rebuild used serials filter", zap.Error(err))
+	}
+	return nil
+}
+
+// IterateAll iterates all serials.
+// Note, this will lock the database and should only be used during startup.
+func (cache *UsedSerialsCache) IterateAll(ctx context.Context, fn SerialNumberFn) (err error) {
+	defer mon.Task()(&ctx)(&err)
+	return cache.db.IterateAll(ctx, fn)
+}
+
+// rebuild creates a new filter from the serials stored in the database.
+func (cache *UsedSerialsCache) rebuild(ctx context.Context) (err error) {
+	if cache.config.ExpectedSerials <= 0 || cache.config.FalsePositiveRate <= 0 || cache.config.FalsePositiveRate >= 1 {
+		// filter is disabled, all requests go to the database
+		return nil
+	}
+
+	cache.rebuildMu.Lock()
+	defer cache.rebuildMu.Unlock()
+
+	// track serials added while iterating, they may be missed by the iteration
+	cache.mu.Lock()
+	cache.rebuilding = true
+	cache.pending = nil
+	cache.mu.Unlock()
+
+	defer func() {
+		cache.mu.Lock()
+		cache.rebuilding = false
+		cache.pending = nil
+		cache.mu.Unlock()
+	}()
+
+	filter := bloomfilter.NewOptimalMaxSize(cache.config.ExpectedSerials, cache.config.FalsePositiveRate, cache.config.MaxMemory)
+	if hashed, ok := cache.db.(HashedUsedSerials); ok {
+		err = hashed.IterateAllKeys(ctx, func(satelliteID storj.NodeID, key []byte) {
+			filter.Add(serialKey(satelliteID, key))
+		})
+	} else {
+		err = cache.db.IterateAll(ctx, func(satelliteID storj.NodeID, serialNumber storj.SerialNumber, expiration time.Time) {
+			filter.Add(serialKey(satelliteID, serialNumber.Bytes()))
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	cache.mu.Lock()
+	for _, key := range cache.pending {
+		filter.Add(key)
+	}
+	cache.filter = filter
+	cache.added = len(cache.pending)
+	cache.mu.Unlock()
+
+	return nil
+}
+
+// key returns the filter key for the serial number of a satellite.
+func (cache *UsedSerialsCache) key(satelliteID storj.NodeID, serialNumber storj.SerialNumber) storj.PieceID {
+	if hashed, ok := cache.db.(HashedUsedSerials); ok {
+		return serialKey(satelliteID, hashed.SerialKey(satelliteID, serialNumber))
+	}
+	return serialKey(satelliteID, serialNumber.Bytes())
+}
+
+// serialKey derives the filter key from the value stored for a serial number of a satellite.
+func serialKey(satelliteID storj.NodeID, stored []byte) storj.PieceID {
+	hash := sha256.New()
+	_, _ = hash.Write(satelliteID.Bytes())
+	_, _ = hash.Write(stored)
+
+	var key storj.PieceID
+	copy(key[:], hash.Sum(nil))
+	return key
+}
@@ -72,6 +72,15 @@ func (endpoint *Endpoint) verifyOrderLimit(ctx context.Context, limit *pb.OrderL
 		serialExpiration = graceExpiration
 	}
 
+	// the used serials cache answers this from memory for serials that weren't used
+	used, err := endpoint.usedSerials.Exists(ctx, limit.SatelliteId, limit.SerialNumber)
+	if err != nil {
+		return rpcstatus.Errorf(rpcstatus.Internal, "unable to check serial number: %+v", err)
+	}
+	if used {
+		return rpcstatus.Error(rpcstatus.Unauthenticated, "serial number is already used")
+	}
+
 	if err := endpoint.usedSerials.Add(ctx, limit.SatelliteId, limit.SerialNumber, serialExpiration); err != nil {
 		return rpcstatus.Errorf(rpcstatus.Unauthenticated, "serial number is already used: %+v", err)
 	}
@@ -5,6 +5,7 @@ package reputation
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"storj.io/storj/pkg/storj"
@@ -20,6 +21,13 @@ type DB interface {
 	Get(ctx context.Context, satelliteID storj.NodeID) (*Stats, error)
 	// All retrieves all stats from DB
 	All(ctx context.Context) ([]Stats, error)
+	// RecentChange returns how the reputation scores of the satellite changed since the provided time
+	RecentChange(ctx context.Context, satelliteID storj.NodeID, since time.Time) (*Change, error)
+	// DeleteHistoryBefore deletes the recorded scores older than cutoff, except for the latest of each satellite,
+	// and returns the number of deleted records
+	DeleteHistoryBefore(ctx context.Context, cutoff time.Time) (int, error)
+	// ExportHistory writes the recorded scores of the satellite to w as JSON lines, oldest first
+	ExportHistory(ctx context.Context, satelliteID storj.NodeID, w io.Writer) error
 }
 
 // Stats consist of reputation metrics
@@ -43,3 +51,26 @@ type Metric struct {
 	Beta  float64 `json:"beta"`
 	Score float64 `json:"score"`
 }
+
+// Change is the difference between the current reputation scores and the scores
+// recorded at an earlier point in time.
+type Change struct {
+	SatelliteID storj.NodeID
+
+	// Since is the time of the scores the change is computed against.
+	// It's zero when there is no earlier record.
+	Since time.Time
+
+	Uptime float64
+	Audit  float64
+}
+
+// HistoryRecord is the reputation scores of a satellite recorded at a point in time.
+type HistoryRecord struct {
+	SatelliteID storj.NodeID `json:"satelliteID"`
+
+	UptimeScore float64 `json:"uptimeScore"`
+	AuditScore  float64 `json:"auditScore"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
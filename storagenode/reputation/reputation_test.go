@@ -4,6 +4,9 @@
 package reputation_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"testing"
 	"time"
 
@@ -12,6 +15,7 @@ import (
 
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/storj"
 	"storj.io/storj/storagenode"
 	"storj.io/storj/storagenode/reputation"
 	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
@@ -64,6 +68,34 @@ func TestReputationDBGetInsert(t *testing.T) {
 	})
 }
 
+func TestReputationDBNotDisqualified(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		reputationDB := db.Reputation()
+
+		// stats without a disqualification are stored with a NULL disqualified column
+		stats := reputation.Stats{
+			SatelliteID: testrand.NodeID(),
+			Uptime:      reputation.Metric{Score: 1},
+			Audit:       reputation.Metric{Score: 1},
+			UpdatedAt:   time.Now().UTC(),
+		}
+		require.NoError(t, reputationDB.Store(ctx, stats))
+
+		res, err := reputationDB.Get(ctx, stats.SatelliteID)
+		require.NoError(t, err)
+		assert.Nil(t, res.Disqualified)
+		assert.Equal(t, stats.UpdatedAt, res.UpdatedAt)
+
+		all, err := reputationDB.All(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Nil(t, all[0].Disqualified)
+	})
+}
+
 func TestReputationDBGetAll(t *testing.T) {
 	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
 		ctx := testcontext.New(t)
@@ -120,6 +152,175 @@ func TestReputationDBGetAll(t *testing.T) {
 	})
 }
 
+func TestReputationDBRecentChange(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		reputationDB := db.Reputation()
+		satelliteID := testrand.NodeID()
+		now := time.Now().UTC()
+
+		scores := []struct {
+			updatedAt     time.Time
+			uptime, audit float64
+		}{
+			{now.Add(-48 * time.Hour), 1, 0.9},
+			{now.Add(-24 * time.Hour), 1, 0.8},
+			{now, 0.95, 0.85},
+		}
+		for _, score := range scores {
+			err := reputationDB.Store(ctx, reputation.Stats{
+				SatelliteID: satelliteID,
+				Uptime:      reputation.Metric{Score: score.uptime},
+				Audit:       reputation.Metric{Score: score.audit},
+				UpdatedAt:   score.updatedAt,
+			})
+			require.NoError(t, err)
+		}
+
+		t.Run("since yesterday", func(t *testing.T) {
+			change, err := reputationDB.RecentChange(ctx, satelliteID, now.Add(-time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, satelliteID, change.SatelliteID)
+			assert.Equal(t, scores[1].updatedAt, change.Since)
+			assert.InDelta(t, -0.05, change.Uptime, 1e-9)
+			assert.InDelta(t, 0.05, change.Audit, 1e-9)
+		})
+
+		t.Run("before the earliest record", func(t *testing.T) {
+			change, err := reputationDB.RecentChange(ctx, satelliteID, now.Add(-72*time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, scores[0].updatedAt, change.Since)
+			assert.InDelta(t, -0.05, change.Uptime, 1e-9)
+			assert.InDelta(t, -0.05, change.Audit, 1e-9)
+		})
+
+		t.Run("unknown satellite", func(t *testing.T) {
+			change, err := reputationDB.RecentChange(ctx, testrand.NodeID(), now)
+			require.NoError(t, err)
+			assert.True(t, change.Since.IsZero())
+			assert.Zero(t, change.Uptime)
+			assert.Zero(t, change.Audit)
+		})
+	})
+}
+
+func TestReputationDBDeleteHistoryBefore(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		reputationDB := db.Reputation()
+		first, second := testrand.NodeID(), testrand.NodeID()
+		cutoff := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+
+		store := func(satelliteID storj.NodeID, audit float64, updatedAt time.Time) {
+			require.NoError(t, reputationDB.Store(ctx, reputation.Stats{
+				SatelliteID: satelliteID,
+				Audit:       reputation.Metric{Score: audit},
+				UpdatedAt:   updatedAt,
+			}))
+		}
+		store(first, 0.7, cutoff.Add(-48*time.Hour))
+		store(first, 0.8, cutoff.Add(-time.Second))
+		store(first, 0.9, cutoff)
+		store(first, 1, cutoff.Add(time.Hour))
+		// the only record of a satellite is kept, however old it is
+		store(second, 0.5, cutoff.Add(-48*time.Hour))
+
+		// the latest record before the cutoff and the records from the cutoff are kept
+		deleted, err := reputationDB.DeleteHistoryBefore(ctx, cutoff)
+		require.NoError(t, err)
+		assert.Equal(t, 1, deleted)
+
+		change, err := reputationDB.RecentChange(ctx, first, cutoff.Add(-time.Millisecond))
+		require.NoError(t, err)
+		assert.Equal(t, cutoff.Add(-time.Second), change.Since)
+		assert.InDelta(t, 0.2, change.Audit, 1e-9)
+
+		change, err = reputationDB.RecentChange(ctx, second, cutoff)
+		require.NoError(t, err)
+		assert.Equal(t, cutoff.Add(-48*time.Hour), change.Since)
+
+		deleted, err = reputationDB.DeleteHistoryBefore(ctx, cutoff)
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+
+		// moving the cutoff past the latest record keeps only that one
+		deleted, err = reputationDB.DeleteHistoryBefore(ctx, cutoff.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+
+		change, err = reputationDB.RecentChange(ctx, first, cutoff.Add(-72*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, cutoff.Add(time.Hour), change.Since)
+		assert.Zero(t, change.Audit)
+	})
+}
+
+func TestReputationDBExportHistory(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		reputationDB := db.Reputation()
+		satelliteID := testrand.NodeID()
+		now := time.Now().UTC()
+
+		var recorded []reputation.HistoryRecord
+		for i := 0; i < 5; i++ {
+			recorded = append(recorded, reputation.HistoryRecord{
+				SatelliteID: satelliteID,
+				UptimeScore: 1 - 0.01*float64(i),
+				AuditScore:  0.5 + 0.1*float64(i),
+				UpdatedAt:   now.Add(time.Duration(i-5) * time.Hour),
+			})
+		}
+		// store them out of order, the export is ordered by time
+		for _, i := range []int{3, 0, 4, 1, 2} {
+			require.NoError(t, reputationDB.Store(ctx, reputation.Stats{
+				SatelliteID: satelliteID,
+				Uptime:      reputation.Metric{Score: recorded[i].UptimeScore},
+				Audit:       reputation.Metric{Score: recorded[i].AuditScore},
+				UpdatedAt:   recorded[i].UpdatedAt,
+			}))
+		}
+		// the scores of other satellites aren't exported
+		require.NoError(t, reputationDB.Store(ctx, reputation.Stats{
+			SatelliteID: testrand.NodeID(),
+			UpdatedAt:   now,
+		}))
+
+		var exported bytes.Buffer
+		require.NoError(t, reputationDB.ExportHistory(ctx, satelliteID, &exported))
+
+		var records []reputation.HistoryRecord
+		decoder := json.NewDecoder(&exported)
+		for {
+			var record reputation.HistoryRecord
+			err := decoder.Decode(&record)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			records = append(records, record)
+		}
+
+		require.Len(t, records, len(recorded))
+		for i, record := range records {
+			assert.Equal(t, recorded[i].SatelliteID, record.SatelliteID)
+			assert.Equal(t, recorded[i].UptimeScore, record.UptimeScore)
+			assert.Equal(t, recorded[i].AuditScore, record.AuditScore)
+			assert.True(t, recorded[i].UpdatedAt.Equal(record.UpdatedAt))
+		}
+
+		exported.Reset()
+		require.NoError(t, reputationDB.ExportHistory(ctx, testrand.NodeID(), &exported))
+		assert.Zero(t, exported.Len())
+	})
+}
+
 // compareReputationMetric compares two reputation metrics and asserts that they are equal
 func compareReputationMetric(t *testing.T, a, b *reputation.Metric) {
 	assert.Equal(t, a.SuccessCount, b.SuccessCount)
@@ -0,0 +1,86 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package satellites
+
+import (
+	"context"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/storj/pkg/storj"
+)
+
+// ErrExitStatus is returned when a graceful exit isn't in the status required for the change.
+var ErrExitStatus = errs.Class("graceful exit status")
+
+// Status refers to the state of the relationship with a satellite.
+type Status = int
+
+const (
+	// Normal status reflects a lack of graceful exit
+	Normal = 0
+	// Exiting reflects an ongoing graceful exit
+	Exiting = 1
+	// ExitSucceeded reflects a graceful exit that succeeded
+	ExitSucceeded = 2
+	// ExitFailed reflects a graceful exit that failed
+	ExitFailed = 3
+	// Suspended reflects an ongoing graceful exit which is temporarily halted
+	Suspended = 4
+)
+
+// ExitProgress contains the status of a graceful exit
+type ExitProgress struct {
+	SatelliteID       storj.NodeID
+	InitiatedAt       *time.Time
+	FinishedAt        *time.Time
+	StartingDiskUsage int64
+	BytesDeleted      int64
+	CompletionReceipt []byte
+	Status            Status
+}
+
+// SatelliteExitStatus combines the relationship status of a satellite with the progress of its graceful exit.
+type SatelliteExitStatus struct {
+	SatelliteID storj.NodeID
+	AddedAt     time.Time
+	Status      Status
+	// Progress is nil when no graceful exit was initiated for the satellite
+	Progress *ExitProgress
+}
+
+// ExitRequest is a request from a satellite for the node to begin a graceful exit.
+type ExitRequest struct {
+	SatelliteID storj.NodeID
+	RequestedAt time.Time
+}
+
+// DB works with satellite database
+//
+// architecture: Database
+type DB interface {
+	// InitiateGracefulExit updates the database to reflect the beginning of a graceful exit
+	InitiateGracefulExit(ctx context.Context, satelliteID storj.NodeID, initiatedAt time.Time, startingDiskUsage int64) error
+	// UpdateGracefulExit increments the total bytes deleted during a graceful exit
+	UpdateGracefulExit(ctx context.Context, satelliteID storj.NodeID, bytesDeleted int64) error
+	// SuspendGracefulExit temporarily halts an ongoing graceful exit, keeping its progress
+	SuspendGracefulExit(ctx context.Context, satelliteID storj.NodeID) error
+	// ResumeGracefulExit continues a suspended graceful exit
+	ResumeGracefulExit(ctx context.Context, satelliteID storj.NodeID) error
+	// CompleteGracefulExit updates the database when a graceful exit is completed or failed
+	CompleteGracefulExit(ctx context.Context, satelliteID storj.NodeID, finishedAt time.Time, exitStatus Status, completionReceipt []byte) error
+	// ListGracefulExits lists all graceful exit records
+	ListGracefulExits(ctx context.Context) ([]ExitProgress, error)
+	// SatelliteExitStatuses lists all known satellites with their status and graceful exit progress
+	SatelliteExitStatuses(ctx context.Context) ([]SatelliteExitStatus, error)
+
+	// RecordExitRequest persists a request from the satellite to begin a graceful exit.
+	// Recording a request for the same satellite again keeps the original request time.
+	RecordExitRequest(ctx context.Context, satelliteID storj.NodeID, requestedAt time.Time) error
+	// PendingExitRequests lists exit requests for satellites where the graceful exit hasn't been initiated yet
+	PendingExitRequests(ctx context.Context) ([]ExitRequest, error)
+	// DeleteExitRequest removes the exit request of the satellite, e.g. when the operator dismisses it
+	DeleteExitRequest(ctx context.Context, satelliteID storj.NodeID) error
+}
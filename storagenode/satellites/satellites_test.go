@@ -0,0 +1,218 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package satellites_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/satellites"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestGracefulExit(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		satellitesDB := db.Satellites()
+		satelliteID := testrand.NodeID()
+		initiatedAt := time.Now().UTC()
+
+		exits, err := satellitesDB.ListGracefulExits(ctx)
+		require.NoError(t, err)
+		require.Empty(t, exits)
+
+		err = satellitesDB.InitiateGracefulExit(ctx, satelliteID, initiatedAt, 1000)
+		require.NoError(t, err)
+
+		err = satellitesDB.UpdateGracefulExit(ctx, satelliteID, 100)
+		require.NoError(t, err)
+		err = satellitesDB.UpdateGracefulExit(ctx, satelliteID, 200)
+		require.NoError(t, err)
+
+		exits, err = satellitesDB.ListGracefulExits(ctx)
+		require.NoError(t, err)
+		require.Len(t, exits, 1)
+		require.Equal(t, satelliteID, exits[0].SatelliteID)
+		require.Equal(t, satellites.Exiting, exits[0].Status)
+		require.Equal(t, int64(1000), exits[0].StartingDiskUsage)
+		require.Equal(t, int64(300), exits[0].BytesDeleted)
+		require.NotNil(t, exits[0].InitiatedAt)
+		require.True(t, initiatedAt.Equal(*exits[0].InitiatedAt))
+		require.Nil(t, exits[0].FinishedAt)
+
+		finishedAt := initiatedAt.Add(time.Hour)
+		receipt := testrand.Bytes(32)
+		err = satellitesDB.CompleteGracefulExit(ctx, satelliteID, finishedAt, satellites.ExitSucceeded, receipt)
+		require.NoError(t, err)
+
+		exits, err = satellitesDB.ListGracefulExits(ctx)
+		require.NoError(t, err)
+		require.Len(t, exits, 1)
+		require.Equal(t, satellites.ExitSucceeded, exits[0].Status)
+		require.NotNil(t, exits[0].FinishedAt)
+		require.True(t, finishedAt.Equal(*exits[0].FinishedAt))
+		require.Equal(t, receipt, exits[0].CompletionReceipt)
+	})
+}
+
+func TestSuspendGracefulExit(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		satellitesDB := db.Satellites()
+		satelliteID := testrand.NodeID()
+
+		// only ongoing exits can be suspended
+		err := satellitesDB.SuspendGracefulExit(ctx, satelliteID)
+		require.True(t, satellites.ErrExitStatus.Has(err))
+
+		require.NoError(t, satellitesDB.InitiateGracefulExit(ctx, satelliteID, time.Now(), 1000))
+		require.NoError(t, satellitesDB.UpdateGracefulExit(ctx, satelliteID, 100))
+
+		// only suspended exits can be resumed
+		err = satellitesDB.ResumeGracefulExit(ctx, satelliteID)
+		require.True(t, satellites.ErrExitStatus.Has(err))
+
+		require.NoError(t, satellitesDB.SuspendGracefulExit(ctx, satelliteID))
+
+		err = satellitesDB.SuspendGracefulExit(ctx, satelliteID)
+		require.True(t, satellites.ErrExitStatus.Has(err))
+
+		// the progress is kept while suspended
+		require.NoError(t, satellitesDB.UpdateGracefulExit(ctx, satelliteID, 50))
+		exits, err := satellitesDB.ListGracefulExits(ctx)
+		require.NoError(t, err)
+		require.Len(t, exits, 1)
+		require.Equal(t, satellites.Suspended, exits[0].Status)
+		require.Equal(t, int64(1000), exits[0].StartingDiskUsage)
+		require.Equal(t, int64(150), exits[0].BytesDeleted)
+
+		require.NoError(t, satellitesDB.ResumeGracefulExit(ctx, satelliteID))
+
+		exits, err = satellitesDB.ListGracefulExits(ctx)
+		require.NoError(t, err)
+		require.Len(t, exits, 1)
+		require.Equal(t, satellites.Exiting, exits[0].Status)
+		require.Equal(t, int64(150), exits[0].BytesDeleted)
+
+		// finished exits can't be suspended
+		require.NoError(t, satellitesDB.CompleteGracefulExit(ctx, satelliteID, time.Now(), satellites.ExitSucceeded, nil))
+		err = satellitesDB.SuspendGracefulExit(ctx, satelliteID)
+		require.True(t, satellites.ErrExitStatus.Has(err))
+	})
+}
+
+func TestExitRequests(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		satellitesDB := db.Satellites()
+		satellite0, satellite1 := testrand.NodeID(), testrand.NodeID()
+		requestedAt := time.Now().UTC()
+
+		requests, err := satellitesDB.PendingExitRequests(ctx)
+		require.NoError(t, err)
+		require.Empty(t, requests)
+
+		require.NoError(t, satellitesDB.RecordExitRequest(ctx, satellite0, requestedAt))
+		require.NoError(t, satellitesDB.RecordExitRequest(ctx, satellite1, requestedAt.Add(time.Minute)))
+
+		// recording again keeps the original request
+		require.NoError(t, satellitesDB.RecordExitRequest(ctx, satellite0, requestedAt.Add(time.Hour)))
+
+		requests, err = satellitesDB.PendingExitRequests(ctx)
+		require.NoError(t, err)
+		require.Len(t, requests, 2)
+		require.Equal(t, satellite0, requests[0].SatelliteID)
+		require.True(t, requestedAt.Equal(requests[0].RequestedAt))
+		require.Equal(t, satellite1, requests[1].SatelliteID)
+
+		// initiating the exit resolves the request
+		require.NoError(t, satellitesDB.InitiateGracefulExit(ctx, satellite0, requestedAt.Add(time.Hour), 100))
+
+		requests, err = satellitesDB.PendingExitRequests(ctx)
+		require.NoError(t, err)
+		require.Len(t, requests, 1)
+		require.Equal(t, satellite1, requests[0].SatelliteID)
+
+		// dismissing the request removes it
+		require.NoError(t, satellitesDB.DeleteExitRequest(ctx, satellite1))
+
+		requests, err = satellitesDB.PendingExitRequests(ctx)
+		require.NoError(t, err)
+		require.Empty(t, requests)
+	})
+}
+
+func TestSatelliteExitStatuses(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		satellitesDB := db.Satellites()
+
+		statuses, err := satellitesDB.SatelliteExitStatuses(ctx)
+		require.NoError(t, err)
+		require.Empty(t, statuses)
+
+		now := time.Now().UTC()
+
+		// a satellite without a graceful exit
+		normal := testrand.NodeID()
+		rawDB := db.(*storagenodedb.DB).RawDatabases()[storagenodedb.SatellitesDBName].GetDB()
+		_, err = rawDB.Exec(`INSERT INTO satellites (node_id, address, added_at, status) VALUES (?, ?, ?, ?)`,
+			normal, "127.0.0.1:7777", now.Add(-time.Hour), satellites.Normal)
+		require.NoError(t, err)
+
+		exiting := testrand.NodeID()
+		require.NoError(t, satellitesDB.InitiateGracefulExit(ctx, exiting, now, 1000))
+		require.NoError(t, satellitesDB.UpdateGracefulExit(ctx, exiting, 100))
+
+		succeeded := testrand.NodeID()
+		require.NoError(t, satellitesDB.InitiateGracefulExit(ctx, succeeded, now.Add(time.Minute), 2000))
+		require.NoError(t, satellitesDB.CompleteGracefulExit(ctx, succeeded, now.Add(time.Hour), satellites.ExitSucceeded, []byte("receipt")))
+
+		failed := testrand.NodeID()
+		require.NoError(t, satellitesDB.InitiateGracefulExit(ctx, failed, now.Add(2*time.Minute), 3000))
+		require.NoError(t, satellitesDB.CompleteGracefulExit(ctx, failed, now.Add(time.Hour), satellites.ExitFailed, nil))
+
+		statuses, err = satellitesDB.SatelliteExitStatuses(ctx)
+		require.NoError(t, err)
+		require.Len(t, statuses, 4)
+
+		require.Equal(t, normal, statuses[0].SatelliteID)
+		require.Equal(t, satellites.Normal, statuses[0].Status)
+		require.Nil(t, statuses[0].Progress)
+
+		require.Equal(t, exiting, statuses[1].SatelliteID)
+		require.Equal(t, satellites.Exiting, statuses[1].Status)
+		require.NotNil(t, statuses[1].Progress)
+		require.Equal(t, exiting, statuses[1].Progress.SatelliteID)
+		require.Equal(t, satellites.Exiting, statuses[1].Progress.Status)
+		require.Equal(t, int64(1000), statuses[1].Progress.StartingDiskUsage)
+		require.Equal(t, int64(100), statuses[1].Progress.BytesDeleted)
+		require.Nil(t, statuses[1].Progress.FinishedAt)
+
+		require.Equal(t, succeeded, statuses[2].SatelliteID)
+		require.Equal(t, satellites.ExitSucceeded, statuses[2].Status)
+		require.NotNil(t, statuses[2].Progress)
+		require.NotNil(t, statuses[2].Progress.FinishedAt)
+		require.Equal(t, []byte("receipt"), statuses[2].Progress.CompletionReceipt)
+
+		require.Equal(t, failed, statuses[3].SatelliteID)
+		require.Equal(t, satellites.ExitFailed, statuses[3].Status)
+		require.NotNil(t, statuses[3].Progress)
+		require.Equal(t, int64(3000), statuses[3].Progress.StartingDiskUsage)
+	})
+}
@@ -0,0 +1,128 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/zeebo/errs"
+
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storagenode/orders"
+)
+
+// BackfillBandwidthFromOrders reconstructs the bandwidth usage rollups from the settled orders in
+// the order archive, e.g. after bandwidth.db was lost or corrupted, and returns the number of
+// inserted rollups.
+//
+// The reconstruction is approximate: only the orders accepted by the satellites are counted, the
+// amounts are the ones signed by the uplinks and they are attributed to the hour the order limit
+// was created. Unsent and rejected orders, and the orders already removed from the archive, are
+// missing. Rollups which already exist are kept as they are, and the hours from the oldest usage
+// which isn't rolled up yet are skipped, because Rollup adds that usage to them later. So the
+// backfill doesn't count any usage twice and can be repeated.
+func (db *DB) BackfillBandwidthFromOrders(ctx context.Context) (inserted int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	type rollupKey struct {
+		intervalStart time.Time
+		satelliteID   storj.NodeID
+		action        pb.PieceAction
+	}
+	rollups := make(map[rollupKey]int64)
+
+	rows, err := db.ordersDB.QueryContext(ctx, `
+		SELECT satellite_id, order_limit_serialized, order_serialized, archived_at
+		FROM order_archive_
+		WHERE status = ?
+	`, int(orders.StatusAccepted))
+	if err != nil {
+		return 0, ErrOrders.Wrap(err)
+	}
+	err = func() (err error) {
+		defer func() { err = errs.Combine(err, rows.Close()) }()
+
+		for rows.Next() {
+			var satelliteID storj.NodeID
+			var limitSerialized, orderSerialized []byte
+			var archivedAt time.Time
+			if err := rows.Scan(&satelliteID, &limitSerialized, &orderSerialized, &archivedAt); err != nil {
+				return err
+			}
+
+			var limit pb.OrderLimit
+			if err := proto.Unmarshal(limitSerialized, &limit); err != nil {
+				return err
+			}
+			var order pb.Order
+			if err := proto.Unmarshal(orderSerialized, &order); err != nil {
+				return err
+			}
+
+			usedAt := limit.OrderCreation
+			if usedAt.IsZero() {
+				usedAt = archivedAt
+			}
+			key := rollupKey{
+				intervalStart: usedAt.UTC().Truncate(time.Hour),
+				satelliteID:   satelliteID,
+				action:        limit.Action,
+			}
+			rollups[key] += order.Amount
+		}
+		return rows.Err()
+	}()
+	if err != nil {
+		return 0, ErrOrders.Wrap(err)
+	}
+
+	tx, err := db.bandwidthDB.Begin()
+	if err != nil {
+		return 0, ErrBandwidth.Wrap(err)
+	}
+
+	query := `
+		INSERT INTO bandwidth_usage_rollups (interval_start, satellite_id, action, amount)
+		SELECT ?, ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM bandwidth_usage WHERE datetime(created_at) < datetime(?, '+1 hour')
+		)
+		ON CONFLICT(interval_start, satellite_id, action) DO NOTHING
+	`
+	if db.bandwidthDB.isPostgres() {
+		// postgres doesn't infer the types of the selected arguments from the inserted columns
+		query = `
+			INSERT INTO bandwidth_usage_rollups (interval_start, satellite_id, action, amount)
+			SELECT ?::TIMESTAMPTZ, ?::BYTEA, ?::BIGINT, ?::BIGINT
+			WHERE NOT EXISTS (
+				SELECT 1 FROM bandwidth_usage WHERE created_at < ?::TIMESTAMPTZ + INTERVAL '1 hour'
+			)
+			ON CONFLICT(interval_start, satellite_id, action) DO NOTHING
+		`
+	}
+
+	for key, amount := range rollups {
+		// the interval start is formatted like the rollups of bandwidthDB.Rollup, so that they conflict
+		intervalStart := key.intervalStart.Format("2006-01-02 15:04:05")
+		result, err := tx.ExecContext(ctx, query, intervalStart, key.satelliteID, key.action, amount, intervalStart)
+		if err != nil {
+			return 0, ErrBandwidth.Wrap(errs.Combine(err, tx.Rollback()))
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, ErrBandwidth.Wrap(errs.Combine(err, tx.Rollback()))
+		}
+		inserted += int(affected)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, ErrBandwidth.Wrap(err)
+	}
+
+	db.bandwidthDB.resetCaches()
+	return inserted, nil
+}
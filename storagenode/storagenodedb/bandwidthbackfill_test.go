@@ -0,0 +1,130 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/orders"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestBackfillBandwidthFromOrders(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		satelliteID := testrand.NodeID()
+		created := time.Now().UTC().Truncate(time.Hour).Add(-48 * time.Hour)
+
+		archive := func(action pb.PieceAction, amount int64, createdAt time.Time, status orders.Status) {
+			serialNumber := testrand.SerialNumber()
+			require.NoError(t, db.Orders().Enqueue(ctx, &orders.Info{
+				Limit: &pb.OrderLimit{
+					SerialNumber:    serialNumber,
+					SatelliteId:     satelliteID,
+					Action:          action,
+					OrderCreation:   createdAt,
+					OrderExpiration: createdAt.Add(time.Hour),
+				},
+				Order: &pb.Order{
+					SerialNumber: serialNumber,
+					Amount:       amount,
+				},
+			}))
+			require.NoError(t, db.Orders().Archive(ctx, time.Now(), orders.ArchiveRequest{
+				Satellite: satelliteID,
+				Serial:    serialNumber,
+				Status:    status,
+			}))
+		}
+
+		// two rollups of the same hour and one of the next hour
+		archive(pb.PieceAction_GET, 100, created.Add(time.Minute), orders.StatusAccepted)
+		archive(pb.PieceAction_GET, 200, created.Add(30*time.Minute), orders.StatusAccepted)
+		archive(pb.PieceAction_PUT, 300, created.Add(10*time.Minute), orders.StatusAccepted)
+		archive(pb.PieceAction_GET, 400, created.Add(time.Hour), orders.StatusAccepted)
+		// rejected orders aren't counted
+		archive(pb.PieceAction_GET, 1000, created, orders.StatusRejected)
+
+		// the next hour has usage which isn't rolled up yet
+		require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 50, created.Add(time.Hour+time.Minute)))
+
+		inserted, err := db.(*storagenodedb.DB).BackfillBandwidthFromOrders(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, inserted)
+
+		usage, err := db.Bandwidth().Summary(ctx, created.Add(-time.Hour), created.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.EqualValues(t, 350, usage.Get)
+		require.EqualValues(t, 300, usage.Put)
+
+		// existing rollups, including the ones of the rolled up usage, aren't counted twice
+		require.NoError(t, db.Bandwidth().Rollup(ctx))
+
+		inserted, err = db.(*storagenodedb.DB).BackfillBandwidthFromOrders(ctx)
+		require.NoError(t, err)
+		require.Zero(t, inserted)
+
+		usage, err = db.Bandwidth().Summary(ctx, created.Add(-time.Hour), created.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.EqualValues(t, 350, usage.Get)
+	})
+}
+
+func TestBackfillBandwidthMonthSummary(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		satelliteID := testrand.NodeID()
+		now := time.Now().UTC()
+		beginningOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		// the month usage is cached by Add
+		require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 50, beginningOfMonth))
+		require.NoError(t, db.Bandwidth().Rollup(ctx))
+
+		monthSummary, err := db.Bandwidth().MonthSummary(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 50, monthSummary)
+
+		serialNumber := testrand.SerialNumber()
+		require.NoError(t, db.Orders().Enqueue(ctx, &orders.Info{
+			Limit: &pb.OrderLimit{
+				SerialNumber:    serialNumber,
+				SatelliteId:     satelliteID,
+				Action:          pb.PieceAction_PUT,
+				OrderCreation:   beginningOfMonth,
+				OrderExpiration: beginningOfMonth.Add(time.Hour),
+			},
+			Order: &pb.Order{
+				SerialNumber: serialNumber,
+				Amount:       100,
+			},
+		}))
+		require.NoError(t, db.Orders().Archive(ctx, time.Now(), orders.ArchiveRequest{
+			Satellite: satelliteID,
+			Serial:    serialNumber,
+			Status:    orders.StatusAccepted,
+		}))
+
+		inserted, err := db.(*storagenodedb.DB).BackfillBandwidthFromOrders(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, inserted)
+
+		// the backfilled usage of the current month is included right away
+		monthSummary, err = db.Bandwidth().MonthSummary(ctx)
+		require.NoError(t, err)
+		require.EqualValues(t, 150, monthSummary)
+	})
+}
@@ -6,6 +6,7 @@ package storagenodedb
 import (
 	"context"
 	"database/sql"
+	"io"
 	"sync"
 	"time"
 
@@ -30,6 +31,11 @@ type bandwidthDB struct {
 	usedMu    sync.RWMutex
 	usedSince time.Time
 
+	summaryCacheInterval time.Duration
+	summaryMu            sync.Mutex
+	summary              *bandwidth.Usage
+	summaryAt            time.Time
+
 	migratableDB
 }
 
@@ -41,6 +47,8 @@ func (db *bandwidthDB) Add(ctx context.Context, satelliteID storj.NodeID, action
 			bandwidth_usage(satellite_id, action, amount, created_at)
 		VALUES(?, ?, ?, ?)`, satelliteID, action, amount, created.UTC())
 	if err == nil {
+		db.includeInSummary(action, amount, created.UTC())
+
 		db.usedMu.Lock()
 		defer db.usedMu.Unlock()
 
@@ -78,6 +86,58 @@ func (db *bandwidthDB) MonthSummary(ctx context.Context) (_ int64, err error) {
 	return usage.Total(), nil
 }
 
+// CachedSummary returns summary of the current months bandwidth usages.
+// The summary is recomputed when it's older than the cache interval or when the month
+// changed, Add updates it in between.
+func (db *bandwidthDB) CachedSummary(ctx context.Context) (_ *bandwidth.Usage, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	db.summaryMu.Lock()
+	defer db.summaryMu.Unlock()
+
+	now := time.Now().UTC()
+	beginningOfMonth := getBeginningOfMonth(now)
+	if db.summary != nil && !db.summaryAt.Before(beginningOfMonth) && now.Sub(db.summaryAt) < db.summaryCacheInterval {
+		usage := *db.summary
+		return &usage, nil
+	}
+
+	summary, err := db.Summary(ctx, beginningOfMonth, now)
+	if err != nil {
+		return nil, err
+	}
+	db.summary, db.summaryAt = summary, now
+
+	usage := *summary
+	return &usage, nil
+}
+
+// includeInSummary adds usage created at the given time to the cached summary, when it's of the summarized month.
+func (db *bandwidthDB) includeInSummary(action pb.PieceAction, amount int64, created time.Time) {
+	db.summaryMu.Lock()
+	defer db.summaryMu.Unlock()
+
+	if db.summary != nil && getBeginningOfMonth(created).Equal(getBeginningOfMonth(db.summaryAt)) {
+		db.summary.Include(action, amount)
+	}
+}
+
+// invalidateSummary drops the cached summary, so that the next CachedSummary recomputes it.
+func (db *bandwidthDB) invalidateSummary() {
+	db.summaryMu.Lock()
+	db.summary = nil
+	db.summaryMu.Unlock()
+}
+
+// resetCaches drops the cached month usage and summary, so that both are recomputed from the database.
+func (db *bandwidthDB) resetCaches() {
+	db.usedMu.Lock()
+	db.usedSpace, db.usedSince = 0, time.Time{}
+	db.usedMu.Unlock()
+
+	db.invalidateSummary()
+}
+
 // Summary returns summary of bandwidth usages
 func (db *bandwidthDB) Summary(ctx context.Context, from, to time.Time) (_ *bandwidth.Usage, err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -90,14 +150,14 @@ func (db *bandwidthDB) Summary(ctx context.Context, from, to time.Time) (_ *band
 		SELECT action, sum(a) amount from(
 				SELECT action, sum(amount) a
 				FROM bandwidth_usage
-				WHERE datetime(?) <= datetime(created_at) AND datetime(created_at) <= datetime(?)
+				WHERE `+db.inRange("created_at")+`
 				GROUP BY action
 				UNION ALL
 				SELECT action, sum(amount) a
 				FROM bandwidth_usage_rollups
-				WHERE datetime(?) <= datetime(interval_start) AND datetime(interval_start) <= datetime(?)
+				WHERE `+db.inRange("interval_start")+`
 				GROUP BY action
-		) GROUP BY action;
+		) AS usage GROUP BY action;
 		`, from, to, from, to)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -129,16 +189,16 @@ func (db *bandwidthDB) SatelliteSummary(ctx context.Context, satelliteID storj.N
 	query := `SELECT action, sum(a) amount from(
 			SELECT action, sum(amount) a
 				FROM bandwidth_usage
-				WHERE datetime(?) <= datetime(created_at) AND datetime(created_at) <= datetime(?)
+				WHERE ` + db.inRange("created_at") + `
 				AND satellite_id = ?
 				GROUP BY action
 			UNION ALL
 			SELECT action, sum(amount) a
 				FROM bandwidth_usage_rollups
-				WHERE datetime(?) <= datetime(interval_start) AND datetime(interval_start) <= datetime(?)
+				WHERE ` + db.inRange("interval_start") + `
 				AND satellite_id = ?
 				GROUP BY action
-		) GROUP BY action;`
+		) AS usage GROUP BY action;`
 
 	rows, err := db.QueryContext(ctx, query, from, to, satelliteID, from, to, satelliteID)
 	if err != nil {
@@ -177,14 +237,14 @@ func (db *bandwidthDB) SummaryBySatellite(ctx context.Context, from, to time.Tim
 	SELECT satellite_id, action, sum(a) amount from(
 			SELECT satellite_id, action, sum(amount) a
 			FROM bandwidth_usage
-			WHERE datetime(?) <= datetime(created_at) AND datetime(created_at) <= datetime(?)
+			WHERE `+db.inRange("created_at")+`
 			GROUP BY satellite_id, action
 			UNION ALL
 			SELECT satellite_id, action, sum(amount) a
 			FROM bandwidth_usage_rollups
-			WHERE datetime(?) <= datetime(interval_start) AND datetime(interval_start) <= datetime(?)
+			WHERE `+db.inRange("interval_start")+`
 			GROUP BY satellite_id, action
-		) GROUP BY satellite_id, action;
+		) AS usage GROUP BY satellite_id, action;
 		`, from, to, from, to)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -238,27 +298,27 @@ func (db *bandwidthDB) Rollup(ctx context.Context) (err error) {
 		}
 	}()
 
-	result, err := tx.Exec(`
+	createdHour := "datetime(strftime('%Y-%m-%dT%H:00:00', created_at))"
+	if db.isPostgres() {
+		createdHour = "date_trunc('hour', created_at)"
+	}
+
+	// postgres runs a single statement per query with arguments
+	_, err = tx.Exec(`
 		INSERT INTO bandwidth_usage_rollups (interval_start, satellite_id,  action, amount)
-		SELECT datetime(strftime('%Y-%m-%dT%H:00:00', created_at)) created_hr, satellite_id, action, SUM(amount)
+		SELECT `+createdHour+` created_hr, satellite_id, action, SUM(amount)
 			FROM bandwidth_usage
-		WHERE datetime(created_at) < datetime(?)
+		WHERE `+db.datetime("created_at")+` < `+db.datetime("?")+`
 		GROUP BY created_hr, satellite_id, action
 		ON CONFLICT(interval_start, satellite_id,  action)
-		DO UPDATE SET amount = bandwidth_usage_rollups.amount + excluded.amount;
-
-		DELETE FROM bandwidth_usage WHERE datetime(created_at) < datetime(?);
-	`, hour, hour)
+		DO UPDATE SET amount = bandwidth_usage_rollups.amount + excluded.amount
+	`, hour)
 	if err != nil {
 		return ErrBandwidth.Wrap(err)
 	}
 
-	_, err = result.RowsAffected()
-	if err != nil {
-		return ErrBandwidth.Wrap(err)
-	}
-
-	return nil
+	_, err = tx.Exec(`DELETE FROM bandwidth_usage WHERE `+db.datetime("created_at")+` < `+db.datetime("?"), hour)
+	return ErrBandwidth.Wrap(err)
 }
 
 // GetDailyRollups returns slice of daily bandwidth usage rollups for provided time range,
@@ -269,9 +329,9 @@ func (db *bandwidthDB) GetDailyRollups(ctx context.Context, from, to time.Time)
 	since, _ := date.DayBoundary(from.UTC())
 	_, before := date.DayBoundary(to.UTC())
 
-	return db.getDailyUsageRollups(ctx,
-		"WHERE DATETIME(?) <= DATETIME(interval_start) AND DATETIME(interval_start) <= DATETIME(?)",
-		since, before)
+	return db.getDailyUsageRollups(ctx, func(column string) string {
+		return "WHERE " + db.inRange(column)
+	}, since, before)
 }
 
 // GetDailySatelliteRollups returns slice of daily bandwidth usage for provided time range,
@@ -282,28 +342,124 @@ func (db *bandwidthDB) GetDailySatelliteRollups(ctx context.Context, satelliteID
 	since, _ := date.DayBoundary(from.UTC())
 	_, before := date.DayBoundary(to.UTC())
 
-	return db.getDailyUsageRollups(ctx,
-		"WHERE satellite_id = ? AND DATETIME(?) <= DATETIME(interval_start) AND DATETIME(interval_start) <= DATETIME(?)",
-		satelliteID, since, before)
+	return db.getDailyUsageRollups(ctx, func(column string) string {
+		return "WHERE satellite_id = ? AND " + db.inRange(column)
+	}, satelliteID, since, before)
+}
+
+// Export writes the bandwidth usage per satellite, action and day for the provided time range to w.
+// The rows are written as they are read from the database.
+func (db *bandwidthDB) Export(ctx context.Context, from, to time.Time, w io.Writer, format string) (err error) {
+	defer mon.Task()(&ctx, from, to)(&err)
+
+	writer, err := bandwidth.NewExportWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	since, _ := date.DayBoundary(from.UTC())
+	_, before := date.DayBoundary(to.UTC())
+
+	// the day is read as text
+	day := func(column string) string {
+		if db.isPostgres() {
+			return "to_char(" + column + ", 'YYYY-MM-DD')"
+		}
+		return "DATE(" + column + ")"
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT satellite_id, action, day, SUM(amount) FROM (
+			SELECT satellite_id, action, amount, `+day("created_at")+` AS day
+				FROM bandwidth_usage
+				WHERE `+db.inRange("created_at")+`
+			UNION ALL
+			SELECT satellite_id, action, amount, `+day("interval_start")+` AS day
+				FROM bandwidth_usage_rollups
+				WHERE `+db.inRange("interval_start")+`
+		) AS usage GROUP BY day, satellite_id, action
+		ORDER BY day, satellite_id, action`,
+		since, before, since, before)
+	if err != nil {
+		return ErrBandwidth.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, ErrBandwidth.Wrap(rows.Close())) }()
+
+	for rows.Next() {
+		var row bandwidth.ExportRow
+		var action int32
+
+		if err := rows.Scan(&row.SatelliteID, &action, &row.Day, &row.Bytes); err != nil {
+			return ErrBandwidth.Wrap(err)
+		}
+		row.Action = pb.PieceAction(action).String()
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return ErrBandwidth.Wrap(err)
+	}
+
+	return writer.Close()
+}
+
+// VerifyRollup sums the raw and the rolled up bandwidth usage for the provided time range independently,
+// so that they can be compared. The range is rounded out to whole hours, because rollups are hourly,
+// and both sums are read in the same transaction.
+func (db *bandwidthDB) VerifyRollup(ctx context.Context, from, to time.Time) (rawSum int64, rollupSum int64, err error) {
+	defer mon.Task()(&ctx, from, to)(&err)
+
+	since := from.UTC().Truncate(time.Hour)
+	before := to.UTC().Truncate(time.Hour).Add(time.Hour)
+
+	err = db.ReadTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(amount), 0)
+			FROM bandwidth_usage
+			WHERE `+db.datetime("?")+` <= `+db.datetime("created_at")+` AND `+db.datetime("created_at")+` < `+db.datetime("?"),
+			since, before).Scan(&rawSum)
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(amount), 0)
+			FROM bandwidth_usage_rollups
+			WHERE `+db.datetime("?")+` <= `+db.datetime("interval_start")+` AND `+db.datetime("interval_start")+` < `+db.datetime("?"),
+			since, before).Scan(&rollupSum)
+	})
+	if err != nil {
+		return 0, 0, ErrBandwidth.Wrap(err)
+	}
+
+	return rawSum, rollupSum, nil
 }
 
 // getDailyUsageRollups returns slice of grouped by date bandwidth usage rollups
-// sorted in ascending order and applied condition if any.
-func (db *bandwidthDB) getDailyUsageRollups(ctx context.Context, cond string, args ...interface{}) (_ []bandwidth.UsageRollup, err error) {
+// sorted in ascending order and applied condition if any. cond returns the condition
+// for the timestamp column of each table.
+func (db *bandwidthDB) getDailyUsageRollups(ctx context.Context, cond func(column string) string, args ...interface{}) (_ []bandwidth.UsageRollup, err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	query := `SELECT action, sum(a) as amount, DATETIME(DATE(interval_start)) as date FROM (
+	date := "DATETIME(DATE(interval_start))"
+	if db.isPostgres() {
+		date = "date_trunc('day', interval_start)"
+	}
+
+	query := `SELECT action, sum(a) as amount, ` + date + ` as interval_date FROM (
 			SELECT action, sum(amount) as a, created_at AS interval_start
 				FROM bandwidth_usage
-				` + cond + `
+				` + cond("created_at") + `
 				GROUP BY interval_start, action
 			UNION ALL
 			SELECT action, sum(amount) as a, interval_start
 				FROM bandwidth_usage_rollups
-				` + cond + `
+				` + cond("interval_start") + `
 				GROUP BY interval_start, action
-		) GROUP BY date, action
-		ORDER BY interval_start`
+		) AS usage GROUP BY interval_date, action
+		ORDER BY interval_date`
 
 	// duplicate args as they are used twice
 	args = append(args, args...)
@@ -366,6 +522,11 @@ func (db *bandwidthDB) getDailyUsageRollups(ctx context.Context, cond string, ar
 	return usageRollups, nil
 }
 
+// inRange returns the condition that the timestamps of column are within the range given by the next two arguments.
+func (db *bandwidthDB) inRange(column string) string {
+	return db.datetime("?") + " <= " + db.datetime(column) + " AND " + db.datetime(column) + " <= " + db.datetime("?")
+}
+
 func getBeginningOfMonth(now time.Time) time.Time {
 	y, m, _ := now.Date()
 	return time.Date(y, m, 1, 0, 0, 0, 0, time.Now().UTC().Location())
@@ -0,0 +1,172 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/storj/storage"
+	"storj.io/storj/storage/filestore"
+)
+
+// SwapBlobDir repoints the pieces blob store to newDir, e.g. after the blobs were copied to a
+// larger disk, without restarting the node. The blobs in both directories are counted first and
+// the store keeps using the current directory when the counts don't match.
+//
+// Uploads which are in progress while swapping are committed to the previous directory, so the
+// node shouldn't accept uploads until the swap is done.
+func (db *DB) SwapBlobDir(ctx context.Context, newDir string) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	current := db.pieces.Dir()
+	if filepath.Clean(newDir) == filepath.Clean(current.Path()) {
+		return ErrDatabase.New("%s is already the blob directory", newDir)
+	}
+
+	dir, err := filestore.NewDir(newDir)
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+
+	expected, err := countBlobs(ctx, current)
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+	actual, err := countBlobs(ctx, dir)
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+	if actual != expected {
+		return ErrDatabase.New("piece count mismatch: %s has %d pieces, %s has %d pieces",
+			current.Path(), expected, dir.Path(), actual)
+	}
+
+	db.pieces.SwapDir(dir)
+	db.log.Info("swapped blob directory",
+		zap.String("from", current.Path()),
+		zap.String("to", dir.Path()),
+		zap.Int64("pieces", actual))
+	return nil
+}
+
+// MigratePieces copies every blob of the pieces blob store to newPath and switches the store over
+// to it with SwapBlobDir, e.g. to move the pieces to a larger disk. The blobs keep their storage
+// format version and size, so the space used accounting stays valid.
+//
+// Blobs which were already copied are skipped, so an interrupted migration can be resumed by
+// calling it again. The current directory is left intact, it's up to the operator to delete it.
+// The node shouldn't accept uploads or delete pieces while migrating, otherwise the piece counts
+// don't match and the store keeps using the current directory.
+func (db *DB) MigratePieces(ctx context.Context, newPath string) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	current := db.pieces.Dir()
+	if filepath.Clean(newPath) == filepath.Clean(current.Path()) {
+		return ErrDatabase.New("%s is already the blob directory", newPath)
+	}
+
+	dest, err := filestore.NewDir(newPath)
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+
+	namespaces, err := current.ListNamespaces(ctx)
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+
+	var copied, skipped int64
+	for _, namespace := range namespaces {
+		err = current.WalkNamespace(ctx, namespace, func(info storage.BlobInfo) error {
+			done, err := copyBlob(ctx, dest, info)
+			if err != nil {
+				return err
+			}
+			if done {
+				copied++
+			} else {
+				skipped++
+			}
+			return nil
+		})
+		if err != nil {
+			return ErrDatabase.Wrap(err)
+		}
+	}
+
+	db.log.Info("copied blobs",
+		zap.String("to", dest.Path()),
+		zap.Int64("copied", copied),
+		zap.Int64("skipped", skipped))
+
+	return db.SwapBlobDir(ctx, newPath)
+}
+
+// copyBlob copies the blob to dest with the same storage format version, unless dest already
+// has it with the same size. It returns whether the blob was copied.
+func copyBlob(ctx context.Context, dest *filestore.Dir, info storage.BlobInfo) (copied bool, err error) {
+	source, err := info.Stat(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	existing, err := dest.StatWithStorageFormat(ctx, info.BlobRef(), info.StorageFormatVersion())
+	switch {
+	case err == nil:
+		stat, err := existing.Stat(ctx)
+		if err != nil {
+			return false, err
+		}
+		if stat.Size() == source.Size() {
+			return false, nil
+		}
+	case !os.IsNotExist(err):
+		return false, err
+	}
+
+	path, err := info.FullPath(ctx)
+	if err != nil {
+		return false, err
+	}
+	reader, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { err = errs.Combine(err, reader.Close()) }()
+
+	file, err := dest.CreateTemporaryFile(ctx, source.Size())
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(file, reader); err != nil {
+		return false, errs.Combine(err, file.Close(), os.Remove(file.Name()))
+	}
+
+	// Commit syncs the file and moves it to its place
+	return true, dest.Commit(ctx, file, info.BlobRef(), info.StorageFormatVersion())
+}
+
+// countBlobs counts the blobs of all namespaces in dir.
+func countBlobs(ctx context.Context, dir *filestore.Dir) (count int64, err error) {
+	namespaces, err := dir.ListNamespaces(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, namespace := range namespaces {
+		err = dir.WalkNamespace(ctx, namespace, func(storage.BlobInfo) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
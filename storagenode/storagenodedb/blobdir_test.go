@@ -0,0 +1,157 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/storage"
+	"storj.io/storj/storage/filestore"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestSwapBlobDir(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	storageDir := ctx.Dir("storage")
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(storageDir))
+	defer ctx.Check(db.Close)
+
+	writeBlob := func(blobs storage.Blobs, ref storage.BlobRef, data []byte) {
+		writer, err := blobs.Create(ctx, ref, -1)
+		require.NoError(t, err)
+		_, err = writer.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, writer.Commit(ctx))
+	}
+
+	satelliteID := testrand.NodeID()
+	refs := map[string][]byte{}
+	for i := 0; i < 3; i++ {
+		ref := storage.BlobRef{Namespace: satelliteID.Bytes(), Key: testrand.PieceID().Bytes()}
+		refs[string(ref.Key)] = testrand.BytesInt(100)
+		writeBlob(db.Pieces(), ref, refs[string(ref.Key)])
+	}
+
+	// copy all but one blob to the new directory
+	newDir := ctx.Dir("newdisk")
+	copied, err := filestore.NewAt(log, newDir)
+	require.NoError(t, err)
+
+	var missing storage.BlobRef
+	for key, data := range refs {
+		ref := storage.BlobRef{Namespace: satelliteID.Bytes(), Key: []byte(key)}
+		if missing.Key == nil {
+			missing = ref
+			continue
+		}
+		writeBlob(copied, ref, data)
+	}
+
+	// the store keeps the current directory when the pieces don't match
+	err = db.SwapBlobDir(ctx, newDir)
+	require.Error(t, err)
+	require.Equal(t, storageDir, db.Pieces().(*filestore.Store).Dir().Path())
+	_, err = db.Pieces().Stat(ctx, missing)
+	require.NoError(t, err)
+
+	// swapping to the current directory is rejected
+	require.Error(t, db.SwapBlobDir(ctx, storageDir))
+
+	writeBlob(copied, missing, refs[string(missing.Key)])
+	require.NoError(t, db.SwapBlobDir(ctx, newDir))
+	require.Equal(t, newDir, db.Pieces().(*filestore.Store).Dir().Path())
+
+	for key, data := range refs {
+		reader, err := db.Pieces().Open(ctx, storage.BlobRef{Namespace: satelliteID.Bytes(), Key: []byte(key)})
+		require.NoError(t, err)
+		read, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		require.Equal(t, data, read)
+	}
+
+	// new blobs are written to the new directory
+	ref := storage.BlobRef{Namespace: satelliteID.Bytes(), Key: testrand.PieceID().Bytes()}
+	writeBlob(db.Pieces(), ref, testrand.BytesInt(100))
+	_, err = copied.Stat(ctx, ref)
+	require.NoError(t, err)
+}
+
+func TestMigratePieces(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	storageDir := ctx.Dir("storage")
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(storageDir))
+	defer ctx.Check(db.Close)
+
+	writeBlob := func(writer storage.BlobWriter, err error, data []byte) {
+		require.NoError(t, err)
+		_, err = writer.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, writer.Commit(ctx))
+	}
+
+	satelliteID := testrand.NodeID()
+	blobs := map[string][]byte{}
+	var refs []storage.BlobRef
+	for i := 0; i < 3; i++ {
+		ref := storage.BlobRef{Namespace: satelliteID.Bytes(), Key: testrand.PieceID().Bytes()}
+		blobs[string(ref.Key)] = testrand.BytesInt(100 + i)
+		writer, err := db.Pieces().Create(ctx, ref, -1)
+		writeBlob(writer, err, blobs[string(ref.Key)])
+		refs = append(refs, ref)
+	}
+
+	v0 := storage.BlobRef{Namespace: satelliteID.Bytes(), Key: testrand.PieceID().Bytes()}
+	blobs[string(v0.Key)] = testrand.BytesInt(50)
+	writer, err := db.Pieces().(*filestore.Store).TestCreateV0(ctx, v0)
+	writeBlob(writer, err, blobs[string(v0.Key)])
+
+	// an interrupted migration already copied a blob
+	newDir := ctx.Dir("newdisk")
+	copied, err := filestore.NewAt(log, newDir)
+	require.NoError(t, err)
+	writer, err = copied.Create(ctx, refs[0], -1)
+	writeBlob(writer, err, blobs[string(refs[0].Key)])
+
+	require.NoError(t, db.MigratePieces(ctx, newDir))
+	require.Equal(t, newDir, db.Pieces().(*filestore.Store).Dir().Path())
+
+	for key, data := range blobs {
+		reader, err := db.Pieces().Open(ctx, storage.BlobRef{Namespace: satelliteID.Bytes(), Key: []byte(key)})
+		require.NoError(t, err)
+		read, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		require.Equal(t, data, read)
+	}
+
+	// the storage format version is kept
+	_, err = db.Pieces().StatWithStorageFormat(ctx, v0, filestore.FormatV0)
+	require.NoError(t, err)
+
+	// the source is left intact
+	source, err := filestore.NewAt(log, storageDir)
+	require.NoError(t, err)
+	for _, ref := range append(refs, v0) {
+		_, err = source.Stat(ctx, ref)
+		require.NoError(t, err)
+	}
+
+	// migrating to the current directory is rejected
+	require.Error(t, db.MigratePieces(ctx, newDir))
+}
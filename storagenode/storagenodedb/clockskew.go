@@ -0,0 +1,57 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// timestampColumn is a column which is set to the current time when a row is written.
+type timestampColumn struct {
+	dbName string
+	table  string
+	column string
+}
+
+// timestampColumns are the columns checked by DetectClockSkew.
+var timestampColumns = []timestampColumn{
+	{BandwidthDBName, "bandwidth_usage", "created_at"},
+	{OrdersDBName, "order_archive_", "archived_at"},
+	{ReputationDBName, "reputation", "updated_at"},
+	{SatellitesDBName, "satellite_last_contact", "last_contact"},
+}
+
+// DetectClockSkew compares the newest timestamps written to the databases with the current time.
+// A positive skew means rows were written in the future, i.e. the clock has been set back since,
+// which makes expiration, used serial and order limit deadlines happen too early.
+// Degraded databases are not checked.
+func (db *DB) DetectClockSkew(ctx context.Context) (skew time.Duration, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	now := time.Now()
+	for _, column := range timestampColumns {
+		if _, degraded := db.degraded[column.dbName]; degraded {
+			continue
+		}
+
+		var newest time.Time
+		err := db.rawDatabaseFromName(column.dbName).QueryRowContext(ctx,
+			`SELECT `+column.column+` FROM `+column.table+` ORDER BY `+column.column+` DESC LIMIT 1`,
+		).Scan(&newest)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return 0, ErrDatabase.Wrap(err)
+		}
+
+		if ahead := newest.Sub(now); ahead > skew {
+			skew = ahead
+		}
+	}
+
+	return skew, nil
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestDetectClockSkew(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	// empty databases have no skew
+	skew, err := db.DetectClockSkew(ctx)
+	require.NoError(t, err)
+	require.Zero(t, skew)
+
+	satelliteID := testrand.NodeID()
+
+	// rows written in the past don't indicate skew
+	require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 100, time.Now().Add(-time.Hour)))
+	skew, err = db.DetectClockSkew(ctx)
+	require.NoError(t, err)
+	require.Zero(t, skew)
+
+	// future dated rows are detected, the largest skew wins
+	require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_PUT, 100, time.Now().Add(2*time.Hour)))
+	require.NoError(t, db.Contact().SetLastContact(ctx, satelliteID, time.Now().Add(24*time.Hour)))
+
+	skew, err = db.DetectClockSkew(ctx)
+	require.NoError(t, err)
+	require.True(t, skew > 23*time.Hour && skew <= 24*time.Hour, skew)
+}
@@ -7,8 +7,13 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3" // used indirectly.
 	"github.com/zeebo/errs"
@@ -22,10 +27,13 @@ import (
 	"storj.io/storj/storage/filestore"
 	"storj.io/storj/storagenode"
 	"storj.io/storj/storagenode/bandwidth"
+	"storj.io/storj/storagenode/contact"
+	"storj.io/storj/storagenode/maintenance"
 	"storj.io/storj/storagenode/orders"
 	"storj.io/storj/storagenode/pieces"
 	"storj.io/storj/storagenode/piecestore"
 	"storj.io/storj/storagenode/reputation"
+	"storj.io/storj/storagenode/satellites"
 	"storj.io/storj/storagenode/storageusage"
 )
 
@@ -37,6 +45,9 @@ var (
 
 	// ErrDatabase represents errors from the databases.
 	ErrDatabase = errs.Class("storage node database error")
+
+	// ErrUnsupported is the error class of the features which the database driver doesn't support.
+	ErrUnsupported = errs.Class("unsupported by the database driver")
 )
 
 var _ storagenode.DB = (*DB)(nil)
@@ -45,6 +56,7 @@ var _ storagenode.DB = (*DB)(nil)
 type SQLDB interface {
 	Configure(sqlDB *sql.DB)
 	GetDB() *sql.DB
+	ReadTx(ctx context.Context, fn func(*sql.Tx) error) error
 }
 
 // Config configures storage node database
@@ -55,18 +67,56 @@ type Config struct {
 	Info2   string
 
 	Pieces string
+
+	// Driver is the database driver, either DriverSQLite3, the default, or DriverPostgres.
+	Driver string
+
+	// DSN is the connection string of the postgres database, it's only used by DriverPostgres.
+	DSN string
+
+	// DatabasePrefix is prepended to the names of the database files, or of the postgres schemas.
+	DatabasePrefix string
+
+	// AllowDegraded lets the node start when non-critical databases fail to open.
+	AllowDegraded bool
+
+	// BandwidthSummaryCacheInterval is how long the month-to-date bandwidth summary is cached.
+	BandwidthSummaryCacheInterval time.Duration
+
+	// VersionHistoryTail is how many of the latest rows CompactVersionHistory keeps in the versions tables.
+	// The latest row is always kept.
+	VersionHistoryTail int
+
+	// WALAutocheckpoint is the number of pages in the write-ahead log which trigger an automatic checkpoint.
+	// Zero keeps the sqlite default of 1000 pages.
+	WALAutocheckpoint int
+
+	// ReadOnly opens the existing databases for reading only, e.g. for reporting on a running node.
+	// Missing databases aren't created, writes fail and the tables aren't created.
+	ReadOnly bool
+
+	// UsedSerialsSalt makes the used serials database store salted hashes instead of the serial numbers,
+	// so that the serial numbers can't be read from the disk. The serial numbers can't be listed then.
+	// The serial numbers stored before are hashed by CreateTables.
+	UsedSerialsSalt []byte
 }
 
 // DB contains access to different database tables
 type DB struct {
 	log *zap.Logger
 
-	pieces interface {
-		storage.Blobs
-		Close() error
-	}
+	pieces *filestore.Store
+
+	driver string
+	dsn    string
 
-	dbDirectory string
+	dbDirectory    string
+	databasePrefix string
+	allowDegraded  bool
+	readOnly       bool
+
+	versionHistoryTail int
+	walAutocheckpoint  int
 
 	deprecatedInfoDB  *deprecatedInfoDB
 	v0PieceInfoDB     *v0PieceInfoDB
@@ -80,10 +130,37 @@ type DB struct {
 	satellitesDB      *satellitesDB
 
 	sqlDatabases map[string]SQLDB
+	degraded     map[string]error
+
+	// queryDBs are the read-only connections used by Query, keyed by the database name.
+	queryMu  sync.Mutex
+	queryDBs map[string]*sql.DB
+
+	maintenance maintenance.Mode
+
+	// running counts the nodes using the databases, ResetAll refuses to run meanwhile.
+	running int32
 }
 
 // New creates a new master database for storage node
 func New(log *zap.Logger, config Config) (*DB, error) {
+	if config.WALAutocheckpoint < 0 {
+		return nil, ErrDatabase.New("invalid wal autocheckpoint %d: must not be negative", config.WALAutocheckpoint)
+	}
+
+	driver := config.Driver
+	switch driver {
+	case "":
+		driver = DriverSQLite3
+	case DriverSQLite3:
+	case DriverPostgres:
+		if config.DSN == "" {
+			return nil, ErrDatabase.New("the %s driver needs a DSN", DriverPostgres)
+		}
+	default:
+		return nil, ErrDatabase.New("unknown database driver %q", config.Driver)
+	}
+
 	piecesDir, err := filestore.NewDir(config.Pieces)
 	if err != nil {
 		return nil, err
@@ -92,20 +169,29 @@ func New(log *zap.Logger, config Config) (*DB, error) {
 
 	deprecatedInfoDB := &deprecatedInfoDB{}
 	v0PieceInfoDB := &v0PieceInfoDB{}
-	bandwidthDB := &bandwidthDB{}
+	bandwidthDB := &bandwidthDB{summaryCacheInterval: config.BandwidthSummaryCacheInterval}
 	ordersDB := &ordersDB{}
 	pieceExpirationDB := &pieceExpirationDB{}
 	pieceSpaceUsedDB := &pieceSpaceUsedDB{}
 	reputationDB := &reputationDB{}
 	storageUsageDB := &storageUsageDB{}
-	usedSerialsDB := &usedSerialsDB{}
+	usedSerialsDB := &usedSerialsDB{salt: config.UsedSerialsSalt}
 	satellitesDB := &satellitesDB{}
 
 	db := &DB{
 		log:    log,
 		pieces: pieces,
 
-		dbDirectory: filepath.Dir(config.Info2),
+		driver: driver,
+		dsn:    config.DSN,
+
+		dbDirectory:    filepath.Dir(config.Info2),
+		databasePrefix: config.DatabasePrefix,
+		allowDegraded:  config.AllowDegraded,
+		readOnly:       config.ReadOnly,
+
+		versionHistoryTail: config.VersionHistoryTail,
+		walAutocheckpoint:  config.WALAutocheckpoint,
 
 		deprecatedInfoDB:  deprecatedInfoDB,
 		v0PieceInfoDB:     v0PieceInfoDB,
@@ -130,85 +216,92 @@ func New(log *zap.Logger, config Config) (*DB, error) {
 			UsedSerialsDBName:     usedSerialsDB,
 			SatellitesDBName:      satellitesDB,
 		},
+		degraded: map[string]error{},
 	}
 
 	err = db.openDatabases()
 	if err != nil {
 		return nil, err
 	}
+
 	return db, nil
 }
 
-// openDatabases opens all the SQLite3 storage node databases and returns if any fails to open successfully.
+// nonCriticalDatabases are the databases which only hold cached or informational data,
+// so the node is able to work without them.
+var nonCriticalDatabases = map[string]bool{
+	ReputationDBName:   true,
+	StorageUsageDBName: true,
+}
+
+// openDatabases opens all the storage node databases and returns if any fails to open successfully.
+// When degraded mode is allowed, failing non-critical databases are only logged and marked as degraded.
 func (db *DB) openDatabases() error {
 	// These objects have a Configure method to allow setting the underlining SQLDB connection
-	// that each uses internally to do data access to the SQLite3 databases.
+	// that each uses internally to do data access to the databases.
 	// The reason it was done this way was because there's some outside consumers that are
 	// taking a reference to the business object.
-	err := db.openDatabase(DeprecatedInfoDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
-	}
-
-	err = db.openDatabase(BandwidthDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
-	}
-
-	err = db.openDatabase(OrdersDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
-	}
-
-	err = db.openDatabase(PieceExpirationDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
-	}
-
-	err = db.openDatabase(PieceInfoDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
-	}
-
-	err = db.openDatabase(PieceSpaceUsedDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
-	}
-
-	err = db.openDatabase(ReputationDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
-	}
-
-	err = db.openDatabase(StorageUsageDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
-	}
+	for _, dbName := range []string{
+		DeprecatedInfoDBName,
+		BandwidthDBName,
+		OrdersDBName,
+		PieceExpirationDBName,
+		PieceInfoDBName,
+		PieceSpaceUsedDBName,
+		ReputationDBName,
+		StorageUsageDBName,
+		UsedSerialsDBName,
+		SatellitesDBName,
+	} {
+		err := db.openDatabase(dbName)
+		if err == nil {
+			continue
+		}
+		if !db.allowDegraded || !nonCriticalDatabases[dbName] {
+			return errs.Combine(err, db.closeDatabases())
+		}
 
-	err = db.openDatabase(UsedSerialsDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
+		db.log.Error("failed to open database, continuing in degraded mode", zap.String("database", dbName), zap.Error(err))
+		db.degraded[dbName] = err
 	}
+	return nil
+}
 
-	err = db.openDatabase(SatellitesDBName)
-	if err != nil {
-		return errs.Combine(err, db.closeDatabases())
+// DegradedDatabases returns the names of the non-critical databases which failed to open.
+func (db *DB) DegradedDatabases() []string {
+	var names []string
+	for dbName := range db.degraded {
+		names = append(names, dbName)
 	}
-	return nil
+	sort.Strings(names)
+	return names
 }
 
 func (db *DB) rawDatabaseFromName(dbName string) *sql.DB {
 	return db.sqlDatabases[dbName].GetDB()
 }
 
-// openDatabase opens or creates a database at the specified path.
+// openDatabase opens or creates a database at the specified path, or in its postgres schema.
 func (db *DB) openDatabase(dbName string) error {
+	if db.isPostgres() {
+		return db.openPostgresDatabase(dbName)
+	}
+
 	path := db.filepathFromDBName(dbName)
-	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+	dsn := "file:" + path + "?_journal=WAL&_busy_timeout=10000"
+	if db.readOnly {
+		// sqlite needs write access to the shared memory index of the write-ahead log to read a database
+		// which is in use, so the database is opened for writing and the changes are refused by query_only.
+		// The journal mode is given, as the driver would switch it to the default otherwise.
+		if _, err := os.Stat(path); err != nil {
+			return ErrDatabase.New("%s: %v", dbName, err)
+		}
+		dsn = "file:" + path + "?_journal=WAL&_query_only=true&_busy_timeout=10000"
+	} else if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
 		return ErrDatabase.Wrap(err)
 	}
 
-	sqlDB, err := sql.Open("sqlite3", "file:"+path+"?_journal=WAL&_busy_timeout=10000")
+	sqlDB, err := sql.Open(sqliteDriverName(db.walAutocheckpoint), dsn)
 	if err != nil {
 		return ErrDatabase.Wrap(err)
 	}
@@ -218,13 +311,19 @@ func (db *DB) openDatabase(dbName string) error {
 
 	dbutil.Configure(sqlDB, mon)
 
+	// sqlite3 opens the file lazily, so make sure it's readable
+	var tables int
+	if err := sqlDB.QueryRow(`SELECT count(*) FROM sqlite_master`).Scan(&tables); err != nil {
+		return ErrDatabase.New("%s: %v", dbName, err)
+	}
+
 	db.log.Debug(fmt.Sprintf("opened database %s", dbName))
 	return nil
 }
 
 // filenameFromDBName returns a constructed filename for the specified database name.
 func (db *DB) filenameFromDBName(dbName string) string {
-	return dbName + ".db"
+	return db.databasePrefix + dbName + ".db"
 }
 
 func (db *DB) filepathFromDBName(dbName string) string {
@@ -232,9 +331,72 @@ func (db *DB) filepathFromDBName(dbName string) string {
 }
 
 // CreateTables creates any necessary tables.
+// The steps of degraded databases are skipped.
+// When the used serials are hashed, the serial numbers stored as they are before are hashed too.
 func (db *DB) CreateTables(ctx context.Context) error {
+	if db.readOnly {
+		return ErrDatabase.New("can't create tables of read-only databases")
+	}
+
+	if err := db.healthyMigration(ctx).Run(db.log.Named("migration")); err != nil {
+		return err
+	}
+
+	if _, degraded := db.degraded[UsedSerialsDBName]; degraded || !db.usedSerialsDB.hashed() {
+		return nil
+	}
+	hashed, err := db.usedSerialsDB.hashExisting(ctx)
+	if err != nil {
+		return err
+	}
+	if hashed > 0 {
+		db.log.Info("hashed the used serial numbers", zap.Int("count", hashed))
+	}
+	return nil
+}
+
+// MigrationPlan returns the migration steps which CreateTables would apply, in order, without
+// changing the databases. Operators should look out for the steps deleting files, e.g. versions 13, 14 and 30.
+// The steps of degraded databases are left out.
+func (db *DB) MigrationPlan(ctx context.Context) (_ []*migrate.Step, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	return db.healthyMigration(ctx).Plan(db.log.Named("migration"))
+}
+
+// Rollback undoes the applied migration steps after toVersion, so that an older release can open the databases.
+// It fails without undoing anything when one of the steps can't be undone, e.g. because it deleted data.
+// The steps of degraded databases are skipped.
+func (db *DB) Rollback(ctx context.Context, toVersion int) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	return db.healthyMigration(ctx).Rollback(db.log.Named("migration"), toVersion)
+}
+
+// healthyMigration returns the migration without the steps of degraded databases.
+func (db *DB) healthyMigration(ctx context.Context) *migrate.Migration {
 	migration := db.Migration(ctx)
-	return migration.Run(db.log.Named("migration"))
+	if len(db.degraded) > 0 {
+		var steps []*migrate.Step
+		for _, step := range migration.Steps {
+			if db.isDegraded(step.DB) {
+				continue
+			}
+			steps = append(steps, step)
+		}
+		migration.Steps = steps
+	}
+	return migration
+}
+
+// isDegraded returns whether the migration target belongs to a degraded database.
+func (db *DB) isDegraded(target migrate.DB) bool {
+	for dbName := range db.degraded {
+		if mdb, ok := db.sqlDatabases[dbName].(migrate.DB); ok && mdb == target {
+			return true
+		}
+	}
+	return false
 }
 
 // Close closes any resources.
@@ -246,6 +408,7 @@ func (db *DB) Close() error {
 func (db *DB) closeDatabases() error {
 	var errlist errs.Group
 
+	errlist.Add(db.closeQueryDatabases())
 	for k := range db.sqlDatabases {
 		errlist.Add(db.closeDatabase(k))
 	}
@@ -258,6 +421,9 @@ func (db *DB) closeDatabase(dbName string) (err error) {
 	if !ok {
 		return ErrDatabase.New("no database with name %s found. database was never opened or already closed.", dbName)
 	}
+	if mdb.GetDB() == nil {
+		return nil
+	}
 	return ErrDatabase.Wrap(mdb.GetDB().Close())
 }
 
@@ -291,11 +457,21 @@ func (db *DB) PieceSpaceUsedDB() pieces.PieceSpaceUsedDB {
 	return db.pieceSpaceUsedDB
 }
 
+// Contact returns the instance of the Contact database.
+func (db *DB) Contact() contact.DB {
+	return db.satellitesDB
+}
+
 // Reputation returns the instance of the Reputation database.
 func (db *DB) Reputation() reputation.DB {
 	return db.reputationDB
 }
 
+// Satellites returns the instance of the Satellites database.
+func (db *DB) Satellites() satellites.DB {
+	return db.satellitesDB
+}
+
 // StorageUsage returns the instance of the StorageUsage database.
 func (db *DB) StorageUsage() storageusage.DB {
 	return db.storageUsageDB
@@ -306,6 +482,164 @@ func (db *DB) UsedSerials() piecestore.UsedSerials {
 	return db.usedSerialsDB
 }
 
+// ReadTx runs fn inside a read transaction on the specified database, see migratableDB.ReadTx.
+// Each database is a separate SQLite file, the snapshot does not span multiple databases.
+func (db *DB) ReadTx(ctx context.Context, dbName string, fn func(*sql.Tx) error) error {
+	mdb, ok := db.sqlDatabases[dbName]
+	if !ok {
+		return ErrDatabase.New("no database with name %s found", dbName)
+	}
+	return mdb.ReadTx(ctx, fn)
+}
+
+// Pause enables maintenance mode. While paused, background chores defer their writes.
+func (db *DB) Pause() { db.maintenance.Pause() }
+
+// Resume disables maintenance mode.
+func (db *DB) Resume() { db.maintenance.Resume() }
+
+// MarkRunning marks the databases as used by a running node until the returned func is called.
+func (db *DB) MarkRunning() (done func()) {
+	atomic.AddInt32(&db.running, 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { atomic.AddInt32(&db.running, -1) })
+	}
+}
+
+// Paused returns true when maintenance mode is enabled.
+func (db *DB) Paused() bool { return db.maintenance.Paused() }
+
+// AnalyzeAll runs ANALYZE on all the databases to refresh the query planner statistics.
+// In-memory databases are skipped, postgres databases are analyzed by its autovacuum.
+func (db *DB) AnalyzeAll(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if db.isPostgres() {
+		return nil
+	}
+
+	var errlist errs.Group
+	for dbName, mdb := range db.sqlDatabases {
+		inMemory, err := isInMemory(ctx, mdb.GetDB())
+		if err != nil {
+			errlist.Add(ErrDatabase.New("%s: %v", dbName, err))
+			continue
+		}
+		if inMemory {
+			continue
+		}
+
+		if _, err := mdb.GetDB().ExecContext(ctx, "ANALYZE"); err != nil {
+			errlist.Add(ErrDatabase.New("%s: %v", dbName, err))
+		}
+	}
+	return errlist.Err()
+}
+
+// isInMemory returns whether the main database of sqlDB has no backing file.
+// postgres databases are never in memory.
+func isInMemory(ctx context.Context, sqlDB *sql.DB) (_ bool, err error) {
+	if isPostgresDB(sqlDB) {
+		return false, nil
+	}
+
+	rows, err := sqlDB.QueryContext(ctx, "PRAGMA database_list")
+	if err != nil {
+		return false, err
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var seq int
+		var name, file string
+		if err := rows.Scan(&seq, &name, &file); err != nil {
+			return false, err
+		}
+		if name == "main" {
+			return file == "", nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// isInMemory returns whether the database with the specified name has no backing file, e.g. in tests.
+// The migration steps touching the file system are skipped for such databases. When it can't be
+// determined, the database is assumed to have a file.
+func (db *DB) isInMemory(ctx context.Context, dbName string) bool {
+	inMemory, err := isInMemory(ctx, db.rawDatabaseFromName(dbName))
+	if err != nil {
+		db.log.Warn("failed to check whether the database is in memory", zap.String("database", dbName), zap.Error(err))
+		return false
+	}
+	return inMemory
+}
+
+// Backup copies the database with the specified name to destPath.
+// progress, when not nil, is called with the number of copied and total pages.
+// postgres databases can't be backed up this way, pg_dump backs up their schemas.
+func (db *DB) Backup(ctx context.Context, dbName string, destPath string, progress func(done, total int)) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if db.isPostgres() {
+		return ErrUnsupported.New("backing up postgres databases")
+	}
+
+	mdb, ok := db.sqlDatabases[dbName]
+	if !ok {
+		return ErrDatabase.New("no database with name %s found", dbName)
+	}
+
+	destDB, err := sql.Open("sqlite3", "file:"+destPath+"?_journal=WAL&_busy_timeout=10000")
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, ErrDatabase.Wrap(destDB.Close())) }()
+
+	return ErrDatabase.Wrap(sqliteutil.Backup(ctx, mdb.GetDB(), destDB, progress))
+}
+
+// BackupAll copies every database into destDir, using the same filenames as the originals,
+// while the node keeps running. Each copy is a consistent snapshot of its database, but the
+// snapshots are taken one after another, so they don't span multiple databases.
+// Degraded databases are skipped, a failing copy doesn't stop the others from being copied.
+func (db *DB) BackupAll(ctx context.Context, destDir string) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if db.isPostgres() {
+		return ErrUnsupported.New("backing up postgres databases")
+	}
+
+	if err := os.MkdirAll(destDir, 0700); err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+
+	var dbNames []string
+	for dbName := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+		dbNames = append(dbNames, dbName)
+	}
+	sort.Strings(dbNames)
+
+	var errlist errs.Group
+	for i, dbName := range dbNames {
+		destPath := filepath.Join(destDir, db.filenameFromDBName(dbName))
+		db.log.Info("backing up database", zap.String("database", dbName), zap.String("destination", destPath),
+			zap.Int("database number", i+1), zap.Int("databases", len(dbNames)))
+
+		err := db.Backup(ctx, dbName, destPath, func(done, total int) {
+			db.log.Debug("backup progress", zap.String("database", dbName), zap.Int("pages copied", done), zap.Int("pages", total))
+		})
+		if err != nil {
+			db.log.Error("failed to back up database", zap.String("database", dbName), zap.Error(err))
+			errlist.Add(ErrDatabase.New("%s: %v", dbName, err))
+		}
+	}
+	return errlist.Err()
+}
+
 // RawDatabases are required for testing purposes
 func (db *DB) RawDatabases() map[string]SQLDB {
 	return db.sqlDatabases
@@ -355,8 +689,59 @@ func (db *DB) migrateToDB(ctx context.Context, dbName string, tablesToKeep ...st
 	return nil
 }
 
+// removeDirEntries removes at most limit entries of the directory, and the directory itself once it's empty.
+// It returns the number of removed entries and whether the directory is gone. Errors are only logged
+// and give up on the directory, so that they can't prevent the node from starting up.
+func removeDirEntries(log *zap.Logger, dir string, limit int) (removed int, done bool) {
+	openDir, err := os.Open(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Sugar().Debug(err)
+		}
+		return 0, true
+	}
+
+	names, err := openDir.Readdirnames(limit)
+	if closeErr := openDir.Close(); closeErr != nil {
+		log.Sugar().Debug(closeErr)
+	}
+	if len(names) == 0 {
+		if err != nil && err != io.EOF {
+			log.Sugar().Debug(err)
+		}
+		if err := os.Remove(dir); err != nil {
+			log.Sugar().Debug(err)
+		}
+		return 0, true
+	}
+
+	for _, name := range names {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			log.Sugar().Debug(err)
+			return removed, true
+		}
+		removed++
+	}
+	return removed, false
+}
+
 // Migration returns table migrations.
 func (db *DB) Migration(ctx context.Context) *migrate.Migration {
+	if db.isPostgres() {
+		return db.postgresMigration(ctx)
+	}
+	return db.sqliteMigration(ctx)
+}
+
+// sqliteMigration returns the table migrations of sqlite.
+func (db *DB) sqliteMigration(ctx context.Context) *migrate.Migration {
+	trashDirs := []string{
+		filepath.Join(db.dbDirectory, "blob/ukfu6bhbboxilvt7jrwlqk7y2tapb5d2r2tsmj2sjxvw5qaaaaaa"), // us-central1
+		filepath.Join(db.dbDirectory, "blob/v4weeab67sbgvnbwd5z7tweqsqqun7qox2agpbxy44mqqaaaaaaa"), // europe-west1
+		filepath.Join(db.dbDirectory, "blob/qstuylguhrn2ozjv4h2c6xpxykd622gtgurhql2k7k75wqaaaaaa"), // asia-east1
+		filepath.Join(db.dbDirectory, "blob/abforhuxbzyd35blusvrifvdwmfx4hmocsva4vmpp3rgqaaaaaaa"), // "tothemoon (stefan)"
+	}
+
 	return &migrate.Migration{
 		Table: VersionTable,
 		Steps: []*migrate.Step{
@@ -554,6 +939,7 @@ func (db *DB) Migration(ctx context.Context) *migrate.Migration {
 				DB:          db.deprecatedInfoDB,
 				Description: "Clear Tables from Alpha data",
 				Version:     12,
+				Rollback:    migrate.Irreversible("the alpha data was deleted"),
 				Action: migrate.SQL{
 					`DROP TABLE pieceinfo`,
 					`DROP TABLE used_serial`,
@@ -603,6 +989,9 @@ func (db *DB) Migration(ctx context.Context) *migrate.Migration {
 				Description: "Free Storagenodes from trash data",
 				Version:     13,
 				Action: migrate.Func(func(log *zap.Logger, mgdb migrate.DB, tx *sql.Tx) error {
+					if db.isInMemory(ctx, DeprecatedInfoDBName) {
+						return nil
+					}
 					err := os.RemoveAll(filepath.Join(db.dbDirectory, "blob/ukfu6bhbboxilvt7jrwlqk7y2tapb5d2r2tsmj2sjxvw5qaaaaaa")) // us-central1
 					if err != nil {
 						log.Sugar().Debug(err)
@@ -628,6 +1017,9 @@ func (db *DB) Migration(ctx context.Context) *migrate.Migration {
 				Description: "Free Storagenodes from orphaned tmp data",
 				Version:     14,
 				Action: migrate.Func(func(log *zap.Logger, mgdb migrate.DB, tx *sql.Tx) error {
+					if db.isInMemory(ctx, DeprecatedInfoDBName) {
+						return nil
+					}
 					err := os.RemoveAll(filepath.Join(db.dbDirectory, "tmp"))
 					if err != nil {
 						log.Sugar().Debug(err)
@@ -769,6 +1161,9 @@ func (db *DB) Migration(ctx context.Context) *migrate.Migration {
 				Description: "Vacuum info db",
 				Version:     22,
 				Action: migrate.Func(func(log *zap.Logger, _ migrate.DB, tx *sql.Tx) error {
+					if db.isInMemory(ctx, DeprecatedInfoDBName) {
+						return nil
+					}
 					_, err := db.deprecatedInfoDB.GetDB().Exec("VACUUM;")
 					return err
 				}),
@@ -778,6 +1173,11 @@ func (db *DB) Migration(ctx context.Context) *migrate.Migration {
 				Description: "Split into multiple sqlite databases",
 				Version:     23,
 				Action: migrate.Func(func(log *zap.Logger, _ migrate.DB, tx *sql.Tx) error {
+					// In-memory databases can't be reopened from files, so the tables are kept where they are.
+					if db.isInMemory(ctx, DeprecatedInfoDBName) {
+						return nil
+					}
+
 					// Migrate all the tables to new database files.
 					if err := db.migrateToDB(ctx, BandwidthDBName, "bandwidth_usage", "bandwidth_usage_rollups"); err != nil {
 						return ErrDatabase.Wrap(err)
@@ -821,6 +1221,11 @@ func (db *DB) Migration(ctx context.Context) *migrate.Migration {
 					// may have successfully dropped and we would experience unrecoverable data loss.
 					// This way if step 22 completes it never gets replayed even if a drop table or
 					// VACUUM call fails.
+					//
+					// In-memory databases kept the tables in step 23, so they must not be dropped.
+					if db.isInMemory(ctx, DeprecatedInfoDBName) {
+						return nil
+					}
 					if err := sqliteutil.KeepTables(ctx, db.rawDatabaseFromName(DeprecatedInfoDBName), VersionTable); err != nil {
 						return ErrDatabase.Wrap(err)
 					}
@@ -838,6 +1243,110 @@ func (db *DB) Migration(ctx context.Context) *migrate.Migration {
 					return nil
 				}),
 			},
+			{
+				DB:          db.ordersDB,
+				Description: "Add indexes on order_archive_ archived_at and status",
+				Version:     25,
+				Action: migrate.SQL{
+					`CREATE INDEX idx_order_archive_archived_at ON order_archive_(archived_at)`,
+					`CREATE INDEX idx_order_archive_status ON order_archive_(status)`,
+				},
+				Rollback: migrate.SQL{
+					`DROP INDEX idx_order_archive_archived_at`,
+					`DROP INDEX idx_order_archive_status`,
+				},
+			},
+			{
+				DB:          db.satellitesDB,
+				Description: "Create satellite_exit_requests table",
+				Version:     26,
+				Action: migrate.SQL{
+					`CREATE TABLE satellite_exit_requests (
+						satellite_id BLOB NOT NULL,
+						requested_at TIMESTAMP NOT NULL,
+						PRIMARY KEY (satellite_id)
+					)`,
+				},
+				Rollback: migrate.SQL{
+					`DROP TABLE satellite_exit_requests`,
+				},
+			},
+			{
+				DB:          db.reputationDB,
+				Description: "Create reputation_history table",
+				Version:     27,
+				Action: migrate.SQL{
+					`CREATE TABLE reputation_history (
+						satellite_id BLOB NOT NULL,
+						uptime_reputation_score REAL NOT NULL,
+						audit_reputation_score REAL NOT NULL,
+						updated_at TIMESTAMP NOT NULL,
+						PRIMARY KEY (satellite_id, updated_at)
+					)`,
+				},
+				Rollback: migrate.SQL{
+					`DROP TABLE reputation_history`,
+				},
+			},
+			{
+				DB:          db.storageUsageDB,
+				Description: "Add interval_end to storage_usage",
+				Version:     28,
+				Action: migrate.SQL{
+					`ALTER TABLE storage_usage ADD COLUMN interval_end TIMESTAMP`,
+				},
+				// sqlite can't drop columns, so the table is rebuilt without it
+				Rollback: migrate.SQL{
+					`CREATE TABLE storage_usage_old (
+						satellite_id BLOB NOT NULL,
+						at_rest_total REAL NOT NUll,
+						interval_start TIMESTAMP NOT NULL,
+						PRIMARY KEY (satellite_id, interval_start)
+					)`,
+					`INSERT INTO storage_usage_old SELECT satellite_id, at_rest_total, interval_start FROM storage_usage`,
+					`DROP TABLE storage_usage`,
+					`ALTER TABLE storage_usage_old RENAME TO storage_usage`,
+				},
+			},
+			{
+				DB:          db.satellitesDB,
+				Description: "Create satellite_last_contact table",
+				Version:     29,
+				Action: migrate.SQL{
+					`CREATE TABLE satellite_last_contact (
+						satellite_id BLOB NOT NULL,
+						last_contact TIMESTAMP NOT NULL,
+						PRIMARY KEY (satellite_id)
+					)`,
+				},
+				Rollback: migrate.SQL{
+					`DROP TABLE satellite_last_contact`,
+				},
+			},
+			{
+				DB:          db.deprecatedInfoDB,
+				Description: "Free Storagenodes from the trash data left over by version 13 in chunks",
+				Version:     30,
+				Action: migrate.Batch{
+					Size: 1000,
+					Fn: func(log *zap.Logger, mgdb migrate.DB, tx *sql.Tx, limit int) (removed int, err error) {
+						if db.isInMemory(ctx, DeprecatedInfoDBName) {
+							return 0, nil
+						}
+						for len(trashDirs) > 0 && removed < limit {
+							count, done := removeDirEntries(log, trashDirs[0], limit-removed)
+							removed += count
+							if done {
+								trashDirs = trashDirs[1:]
+							}
+						}
+						// To prevent the node from starting up, we just log errors and return nil
+						return removed, nil
+					},
+				},
+				// the removed trash isn't used by any version, so there is nothing to restore
+				Rollback: migrate.SQL{},
+			},
 		},
 	}
 }
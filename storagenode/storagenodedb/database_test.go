@@ -0,0 +1,289 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestReadTxConsistency(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	satelliteID := testrand.NodeID()
+	done := make(chan struct{})
+
+	ctx.Go(func() error {
+		for {
+			select {
+			case <-done:
+				return nil
+			default:
+			}
+			err := db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 1, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+	})
+
+	for i := 0; i < 10; i++ {
+		err := db.ReadTx(ctx, storagenodedb.BandwidthDBName, func(tx *sql.Tx) error {
+			var first, second int64
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM bandwidth_usage`).Scan(&first); err != nil {
+				return err
+			}
+
+			// give the writer a chance to insert more rows
+			time.Sleep(10 * time.Millisecond)
+
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM bandwidth_usage`).Scan(&second); err != nil {
+				return err
+			}
+
+			require.Equal(t, first, second)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	close(done)
+	ctx.Wait()
+
+	err := db.ReadTx(ctx, "unknown", func(tx *sql.Tx) error { return nil })
+	require.Error(t, err)
+}
+
+func TestAnalyzeAll(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	err := db.UsedSerials().Add(ctx, testrand.NodeID(), testrand.SerialNumber(), time.Now())
+	require.NoError(t, err)
+
+	require.NoError(t, db.AnalyzeAll(ctx))
+
+	rawDB := db.RawDatabases()[storagenodedb.UsedSerialsDBName].GetDB()
+
+	var count int
+	err = rawDB.QueryRow(`SELECT COUNT(*) FROM sqlite_stat1 WHERE tbl = 'used_serial_'`).Scan(&count)
+	require.NoError(t, err)
+	require.NotZero(t, count)
+}
+
+func TestBackupAll(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	satelliteID, serialNumber := testrand.NodeID(), testrand.SerialNumber()
+	err := db.UsedSerials().Add(ctx, satelliteID, serialNumber, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	destDir := ctx.Dir("backup")
+	require.NoError(t, db.BackupAll(ctx, destDir))
+
+	for dbName := range db.RawDatabases() {
+		_, err := os.Stat(filepath.Join(destDir, dbName+".db"))
+		require.NoError(t, err, dbName)
+	}
+
+	backupDB, err := sql.Open("sqlite3", "file:"+filepath.Join(destDir, storagenodedb.UsedSerialsDBName+".db"))
+	require.NoError(t, err)
+	defer ctx.Check(backupDB.Close)
+
+	var count int
+	err = backupDB.QueryRow(`SELECT COUNT(*) FROM used_serial_ WHERE satellite_id = ?`, satelliteID).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	satelliteID, serialNumber := testrand.NodeID(), testrand.SerialNumber()
+	require.NoError(t, db.UsedSerials().Add(ctx, satelliteID, serialNumber, time.Now().Add(time.Hour)))
+	require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 1000, time.Now()))
+
+	restoreDir := ctx.Dir("restore")
+	for dbName := range db.RawDatabases() {
+		var calls, lastDone, lastTotal int
+		err := db.Backup(ctx, dbName, filepath.Join(restoreDir, dbName+".db"), func(done, total int) {
+			calls++
+			lastDone, lastTotal = done, total
+		})
+		require.NoError(t, err, dbName)
+		require.NotZero(t, calls, dbName)
+		require.Equal(t, lastTotal, lastDone, dbName)
+	}
+
+	restored := storagenodedbtest.Open(t, ctx, log.Named("restored"), storagenodedbtest.Config(restoreDir))
+	defer ctx.Check(restored.Close)
+
+	exists, err := restored.UsedSerials().Exists(ctx, satelliteID, serialNumber)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	usage, err := restored.Bandwidth().Summary(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), usage.Get)
+
+	problems, err := restored.IntegrityCheck(ctx)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+}
+
+func TestDatabasePrefix(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+	storageDir := ctx.Dir("storage")
+
+	openDB := func(prefix string) *storagenodedb.DB {
+		config := storagenodedbtest.Config(storageDir)
+		config.DatabasePrefix = prefix
+		return storagenodedbtest.Open(t, ctx, log.Named(prefix), config)
+	}
+
+	first := openDB("first_")
+	defer ctx.Check(first.Close)
+	second := openDB("second_")
+	defer ctx.Check(second.Close)
+
+	for _, name := range []string{"first_", "second_"} {
+		_, err := os.Stat(filepath.Join(storageDir, name+storagenodedb.BandwidthDBName+".db"))
+		require.NoError(t, err)
+	}
+
+	satelliteID := testrand.NodeID()
+	require.NoError(t, first.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 100, time.Now()))
+
+	usage, err := first.Bandwidth().Summary(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(100), usage.Total())
+
+	usage, err = second.Bandwidth().Summary(ctx, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), usage.Total())
+}
+
+func TestDegradedDatabases(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+	storageDir := ctx.Dir("storage")
+
+	config := storagenodedbtest.Config(storageDir)
+
+	corrupt := func(dbName string) {
+		garbage := testrand.BytesInt(4096)
+		err := ioutil.WriteFile(filepath.Join(storageDir, dbName+".db"), garbage, 0644)
+		require.NoError(t, err)
+	}
+
+	// a corrupt non-critical database blocks the startup unless degraded mode is allowed
+	corrupt(storagenodedb.StorageUsageDBName)
+
+	_, err := storagenodedb.New(log, config)
+	require.Error(t, err)
+
+	config.AllowDegraded = true
+	db, err := storagenodedb.New(log, config)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{storagenodedb.StorageUsageDBName}, db.DegradedDatabases())
+	require.NoError(t, db.CreateTables(ctx))
+
+	// the critical databases keep working
+	satelliteID := testrand.NodeID()
+	require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 100, time.Now()))
+
+	// the degraded database is unavailable
+	_, err = db.StorageUsage().GetDailyTotal(ctx, time.Now().Add(-time.Hour), time.Now())
+	require.Error(t, err)
+	require.NoError(t, db.Close())
+
+	// a corrupt critical database always blocks the startup
+	corrupt(storagenodedb.OrdersDBName)
+
+	_, err = storagenodedb.New(log, config)
+	require.Error(t, err)
+}
+
+func TestReadOnly(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	config := storagenodedbtest.Config(ctx.Dir("storage"))
+
+	db := storagenodedbtest.Open(t, ctx, log, config)
+	defer ctx.Check(db.Close)
+
+	satelliteID := testrand.NodeID()
+	now := time.Now()
+	require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 1024, now))
+
+	// the databases of the running node are opened for reading
+	config.ReadOnly = true
+	readOnly, err := storagenodedb.New(log.Named("read-only"), config)
+	require.NoError(t, err)
+	defer ctx.Check(readOnly.Close)
+
+	usage, err := readOnly.Bandwidth().Summary(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.EqualValues(t, 1024, usage.Get)
+
+	require.Error(t, readOnly.CreateTables(ctx))
+	require.Error(t, readOnly.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 1024, now))
+
+	// the running node can still write
+	require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_GET, 1024, now))
+
+	usage, err = readOnly.Bandwidth().Summary(ctx, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	require.EqualValues(t, 2048, usage.Get)
+
+	// missing databases aren't created
+	config.Info2 = filepath.Join(ctx.Dir("missing"), "info.db")
+	_, err = storagenodedb.New(log.Named("missing"), config)
+	require.Error(t, err)
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DiskUsage returns the size in bytes of every database, keyed by the database filename,
+// e.g. "bandwidth.db". The size is the page count times the page size of the main database,
+// so it includes the free pages but not the write-ahead log. Degraded databases are left out.
+func (db *DB) DiskUsage(ctx context.Context) (_ map[string]int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	usage := make(map[string]int64)
+	for dbName, mdb := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+
+		size, err := databaseSize(ctx, mdb.GetDB())
+		if err != nil {
+			return nil, ErrDatabase.New("%s: %v", dbName, err)
+		}
+		usage[db.filenameFromDBName(dbName)] = size
+	}
+	return usage, nil
+}
+
+// Stats implements monkit.StatSource, it reports the size in bytes and the number of free
+// pages of every database, e.g. "bandwidth_size_bytes" and "bandwidth_free_pages". postgres
+// doesn't report free pages. Databases which can't be queried, e.g. after closing, are left out. It isn't registered
+// by New, as several nodes may run in a process, the process running the node chains it.
+func (db *DB) Stats(cb func(name string, val float64)) {
+	ctx := context.Background()
+	for dbName, mdb := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+
+		size, err := databaseSize(ctx, mdb.GetDB())
+		if err != nil {
+			continue
+		}
+		if db.isPostgres() {
+			cb(dbName+"_size_bytes", float64(size))
+			continue
+		}
+		free, err := freePages(ctx, mdb.GetDB())
+		if err != nil {
+			continue
+		}
+
+		cb(dbName+"_size_bytes", float64(size))
+		cb(dbName+"_free_pages", float64(free))
+	}
+}
+
+// freePages returns the number of unused pages of the main database of sqlDB.
+func freePages(ctx context.Context, sqlDB *sql.DB) (free int64, err error) {
+	err = sqlDB.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&free)
+	return free, err
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestDiskUsage(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	before, err := db.DiskUsage(ctx)
+	require.NoError(t, err)
+	require.Contains(t, before, "bandwidth.db")
+	require.Contains(t, before, "orders.db")
+	require.Contains(t, before, "used_serial.db")
+
+	now := time.Now()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, db.Bandwidth().Add(ctx, testrand.NodeID(), pb.PieceAction_GET, 1024, now))
+	}
+
+	after, err := db.DiskUsage(ctx)
+	require.NoError(t, err)
+	require.True(t, after["bandwidth.db"] > before["bandwidth.db"], after["bandwidth.db"])
+	require.Equal(t, before["orders.db"], after["orders.db"])
+
+	stats := map[string]float64{}
+	db.Stats(func(name string, val float64) { stats[name] = val })
+	require.Equal(t, float64(after["bandwidth.db"]), stats["bandwidth_size_bytes"])
+	require.Contains(t, stats, "bandwidth_free_pages")
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+)
+
+// expectedIndex is an index which exists once all the migrations have been applied.
+type expectedIndex struct {
+	dbName string
+	name   string
+	create string
+}
+
+// expectedIndexes must be kept in sync with the indexes created by the migrations.
+var expectedIndexes = []expectedIndex{
+	{UsedSerialsDBName, "pk_used_serial_", `CREATE UNIQUE INDEX IF NOT EXISTS pk_used_serial_ ON used_serial_(satellite_id, serial_number)`},
+	{UsedSerialsDBName, "idx_used_serial_", `CREATE INDEX IF NOT EXISTS idx_used_serial_ ON used_serial_(expiration)`},
+	{PieceSpaceUsedDBName, "idx_piece_space_used_satellite_id", `CREATE UNIQUE INDEX IF NOT EXISTS idx_piece_space_used_satellite_id ON piece_space_used(satellite_id)`},
+	{PieceInfoDBName, "pk_pieceinfo_", `CREATE UNIQUE INDEX IF NOT EXISTS pk_pieceinfo_ ON pieceinfo_(satellite_id, piece_id)`},
+	{PieceInfoDBName, "idx_pieceinfo__expiration", `CREATE INDEX IF NOT EXISTS idx_pieceinfo__expiration ON pieceinfo_(piece_expiration) WHERE piece_expiration IS NOT NULL`},
+	{PieceExpirationDBName, "idx_piece_expirations_piece_expiration", `CREATE INDEX IF NOT EXISTS idx_piece_expirations_piece_expiration ON piece_expirations(piece_expiration)`},
+	{PieceExpirationDBName, "idx_piece_expirations_deletion_failed_at", `CREATE INDEX IF NOT EXISTS idx_piece_expirations_deletion_failed_at ON piece_expirations(deletion_failed_at)`},
+	{OrdersDBName, "idx_orders", `CREATE UNIQUE INDEX IF NOT EXISTS idx_orders ON unsent_order(satellite_id, serial_number)`},
+	{OrdersDBName, "idx_order_archive_archived_at", `CREATE INDEX IF NOT EXISTS idx_order_archive_archived_at ON order_archive_(archived_at)`},
+	{OrdersDBName, "idx_order_archive_status", `CREATE INDEX IF NOT EXISTS idx_order_archive_status ON order_archive_(status)`},
+	{BandwidthDBName, "idx_bandwidth_usage_satellite", `CREATE INDEX IF NOT EXISTS idx_bandwidth_usage_satellite ON bandwidth_usage(satellite_id)`},
+	{BandwidthDBName, "idx_bandwidth_usage_created", `CREATE INDEX IF NOT EXISTS idx_bandwidth_usage_created ON bandwidth_usage(created_at)`},
+}
+
+// VerifyIndexes returns the names of the indexes which should exist after the migrations,
+// but are missing from the databases, e.g. because of a partially applied migration.
+func (db *DB) VerifyIndexes(ctx context.Context) (missing []string, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	for _, index := range expectedIndexes {
+		exists, err := db.indexExists(ctx, index)
+		if err != nil {
+			return nil, ErrDatabase.Wrap(err)
+		}
+		if !exists {
+			missing = append(missing, index.name)
+		}
+	}
+
+	return missing, nil
+}
+
+// RebuildMissingIndexes recreates the indexes reported missing by VerifyIndexes.
+// It returns the names of the rebuilt indexes.
+func (db *DB) RebuildMissingIndexes(ctx context.Context) (rebuilt []string, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var group errs.Group
+	for _, index := range expectedIndexes {
+		exists, err := db.indexExists(ctx, index)
+		if err != nil {
+			group.Add(err)
+			continue
+		}
+		if exists {
+			continue
+		}
+
+		db.log.Info("rebuilding missing index", zap.String("database", index.dbName), zap.String("index", index.name))
+		_, err = db.rawDatabaseFromName(index.dbName).ExecContext(ctx, index.create)
+		if err != nil {
+			group.Add(errs.New("%s: %v", index.name, err))
+			continue
+		}
+		rebuilt = append(rebuilt, index.name)
+	}
+
+	return rebuilt, ErrDatabase.Wrap(group.Err())
+}
+
+// indexExists checks whether the index exists in its database.
+func (db *DB) indexExists(ctx context.Context, index expectedIndex) (bool, error) {
+	query := `SELECT name FROM sqlite_master WHERE type = 'index' AND name = ?`
+	if db.isPostgres() {
+		query = `SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND indexname = ?`
+	}
+
+	var name string
+	err := db.rawDatabaseFromName(index.dbName).QueryRowContext(ctx, query, index.name).Scan(&name)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errs.New("%s: %v", index.name, err)
+	}
+	return true, nil
+}
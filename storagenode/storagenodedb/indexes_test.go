@@ -0,0 +1,60 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestVerifyIndexes(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	missing, err := db.VerifyIndexes(ctx)
+	require.NoError(t, err)
+	require.Empty(t, missing)
+
+	// every index created by the migrations is verified
+	for dbName, rawDB := range db.RawDatabases() {
+		rows, err := rawDB.GetDB().QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL`)
+		require.NoError(t, err)
+
+		var indexes []string
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			indexes = append(indexes, name)
+		}
+		require.NoError(t, rows.Err())
+		require.NoError(t, rows.Close())
+
+		for _, index := range indexes {
+			_, err := rawDB.GetDB().ExecContext(ctx, "DROP INDEX "+index)
+			require.NoError(t, err)
+
+			missing, err := db.VerifyIndexes(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{index}, missing, dbName)
+
+			rebuilt, err := db.RebuildMissingIndexes(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{index}, rebuilt)
+
+			missing, err = db.VerifyIndexes(ctx)
+			require.NoError(t, err)
+			require.Empty(t, missing)
+		}
+	}
+}
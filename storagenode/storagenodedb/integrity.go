@@ -0,0 +1,85 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+// ErrIntegrity is the error class for the problems found by IntegrityCheck.
+var ErrIntegrity = errs.Class("integrity check")
+
+// IntegrityCheck runs PRAGMA integrity_check and PRAGMA foreign_key_check on every database.
+// The problems found are returned keyed by the database filename, databases without problems
+// are left out. The returned error is only set when the checks couldn't be run.
+//
+// The checks only read, but they read the whole database, so they are best run while the node is idle.
+// Degraded databases are skipped. postgres databases can't be checked, it fails with ErrUnsupported then.
+func (db *DB) IntegrityCheck(ctx context.Context) (_ map[string]error, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if db.isPostgres() {
+		return nil, ErrUnsupported.New("checking the integrity of postgres databases")
+	}
+
+	problems := make(map[string]error)
+	for dbName, mdb := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+
+		found, err := checkIntegrity(ctx, mdb.GetDB())
+		if err != nil {
+			return nil, ErrDatabase.New("%s: %v", dbName, err)
+		}
+		if len(found) > 0 {
+			problems[db.filenameFromDBName(dbName)] = ErrIntegrity.New("%s", strings.Join(found, "; "))
+		}
+	}
+	return problems, nil
+}
+
+// checkIntegrity returns the problems reported by the integrity and foreign key checks.
+func checkIntegrity(ctx context.Context, db queryer) (problems []string, err error) {
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			return nil, errs.Combine(err, rows.Close())
+		}
+		if message != "ok" {
+			problems = append(problems, message)
+		}
+	}
+	if err := errs.Combine(rows.Err(), rows.Close()); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var table, parent string
+		var rowid, fkid *int64
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return nil, err
+		}
+		if rowid != nil {
+			problems = append(problems, fmt.Sprintf("row %d of %s references a missing row of %s", *rowid, table, parent))
+		} else {
+			problems = append(problems, fmt.Sprintf("a row of %s references a missing row of %s", table, parent))
+		}
+	}
+	return problems, rows.Err()
+}
@@ -0,0 +1,44 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestIntegrityCheck(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	problems, err := db.IntegrityCheck(ctx)
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	// foreign keys aren't enforced, so a dangling reference can be inserted
+	rawDB := db.RawDatabases()[storagenodedb.SatellitesDBName].GetDB()
+	_, err = rawDB.Exec(`
+		CREATE TABLE test_parent (id INTEGER PRIMARY KEY);
+		CREATE TABLE test_child (parent_id INTEGER REFERENCES test_parent(id));
+		INSERT INTO test_child (parent_id) VALUES (1);
+	`)
+	require.NoError(t, err)
+
+	problems, err = db.IntegrityCheck(ctx)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	require.Error(t, problems[storagenodedb.SatellitesDBName+".db"])
+	require.True(t, storagenodedb.ErrIntegrity.Has(problems[storagenodedb.SatellitesDBName+".db"]))
+}
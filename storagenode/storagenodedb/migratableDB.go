@@ -4,7 +4,10 @@
 package storagenodedb
 
 import (
+	"context"
 	"database/sql"
+
+	"github.com/zeebo/errs"
 )
 
 // migratableDB fulfills the migrate.DB interface and the SQLDB interface
@@ -35,3 +38,41 @@ func (db *migratableDB) Configure(sqlDB *sql.DB) {
 func (db *migratableDB) GetDB() *sql.DB {
 	return db.DB
 }
+
+// isPostgres returns whether the database is stored in postgres.
+func (db *migratableDB) isPostgres() bool {
+	return isPostgresDB(db.DB)
+}
+
+// datetime returns the SQL expression to compare and group the timestamps of expr by.
+// sqlite stores the timestamps as text in different formats, which its datetime function normalizes,
+// postgres stores them as timestamps.
+func (db *migratableDB) datetime(expr string) string {
+	if db.isPostgres() {
+		return expr
+	}
+	return "datetime(" + expr + ")"
+}
+
+// ReadTx runs fn inside a deferred transaction to get a consistent view of the database.
+//
+// With the WAL journal a deferred transaction takes its snapshot at the first read
+// and keeps it until the transaction ends, so every query made by fn sees the same
+// state even while other connections keep writing. postgres needs a repeatable read
+// transaction for the same. The transaction is always rolled back, fn must not be used for writes.
+func (db *migratableDB) ReadTx(ctx context.Context, fn func(*sql.Tx) error) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var opts *sql.TxOptions
+	if db.isPostgres() {
+		opts = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, ErrDatabase.Wrap(tx.Rollback())) }()
+
+	return fn(tx)
+}
@@ -4,9 +4,13 @@
 package storagenodedb_test
 
 import (
+	"database/sql"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/zeebo/errs"
@@ -14,8 +18,10 @@ import (
 
 	"storj.io/storj/internal/dbutil/dbschema"
 	"storj.io/storj/internal/dbutil/sqliteutil"
+	"storj.io/storj/internal/migrate"
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
 	"storj.io/storj/storagenode/storagenodedb/testdata"
 )
 
@@ -140,3 +146,186 @@ func TestMigrate(t *testing.T) {
 		}
 	}
 }
+
+func TestOrderArchiveQueryPlan(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	ordersDB := db.RawDatabases()[storagenodedb.OrdersDBName].GetDB()
+
+	for _, test := range []struct {
+		query string
+		args  []interface{}
+		index string
+	}{
+		{`DELETE FROM order_archive_ WHERE archived_at <= ?`, []interface{}{time.Now()}, "idx_order_archive_archived_at"},
+		{`SELECT * FROM order_archive_ WHERE status = ? LIMIT ?`, []interface{}{1, 10}, "idx_order_archive_status"},
+	} {
+		rows, err := ordersDB.Query("EXPLAIN QUERY PLAN "+test.query, test.args...)
+		require.NoError(t, err, test.query)
+
+		var plan []string
+		for rows.Next() {
+			var id, parent, notused int
+			var detail string
+			require.NoError(t, rows.Scan(&id, &parent, &notused, &detail))
+			plan = append(plan, detail)
+		}
+		require.NoError(t, rows.Err())
+		require.NoError(t, rows.Close())
+
+		require.Contains(t, strings.Join(plan, "\n"), "USING INDEX "+test.index, test.query)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db, err := storagenodedb.New(log, storagenodedbtest.Config(ctx.Dir("storage")))
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	requireSchemas := func(version int) {
+		expected, ok := testdata.States.FindVersion(version)
+		require.True(t, ok)
+
+		multiDBSnapshot, err := testdata.LoadMultiDBSnapshot(expected)
+		require.NoError(t, err)
+
+		schemas, err := getSchemas(db.RawDatabases())
+		require.NoError(t, err)
+
+		for dbName, dbSnapshot := range multiDBSnapshot.DBSnapshots {
+			schema := schemas[dbName]
+			if len(schema.Tables) == 0 {
+				schema.Tables = nil
+			}
+			if len(schema.Indexes) == 0 {
+				schema.Indexes = nil
+			}
+			require.Equal(t, dbSnapshot.Schema, schema, fmt.Sprintf("v%d %s", version, dbName))
+		}
+	}
+
+	require.NoError(t, db.CreateTables(ctx))
+	latest := db.Migration(ctx).Steps[len(db.Migration(ctx).Steps)-1].Version
+
+	// steps without a rollback and irreversible ones stop the rollback before anything is undone
+	err = db.Rollback(ctx, 23)
+	require.True(t, migrate.ErrIrreversible.Has(err))
+	err = db.Rollback(ctx, 11)
+	require.True(t, migrate.ErrIrreversible.Has(err))
+	requireSchemas(latest)
+
+	require.NoError(t, db.Rollback(ctx, 24))
+	requireSchemas(24)
+
+	// rolling back again is a no-op
+	require.NoError(t, db.Rollback(ctx, 24))
+	requireSchemas(24)
+
+	// the undone steps are applied again
+	require.NoError(t, db.CreateTables(ctx))
+	requireSchemas(latest)
+}
+
+func TestMigrationPlan(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db, err := storagenodedb.New(log, storagenodedbtest.Config(ctx.Dir("storage")))
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	// every step is pending on a new node and planning doesn't apply any
+	plan, err := db.MigrationPlan(ctx)
+	require.NoError(t, err)
+	require.Len(t, plan, len(db.Migration(ctx).Steps))
+
+	plan, err = db.MigrationPlan(ctx)
+	require.NoError(t, err)
+	require.Len(t, plan, len(db.Migration(ctx).Steps))
+
+	require.NoError(t, db.Migration(ctx).TargetVersion(20).Run(log))
+
+	plan, err = db.MigrationPlan(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, plan)
+	for _, step := range plan {
+		require.True(t, step.Version > 20, step.Version)
+	}
+
+	require.NoError(t, db.CreateTables(ctx))
+
+	plan, err = db.MigrationPlan(ctx)
+	require.NoError(t, err)
+	require.Empty(t, plan)
+}
+
+func TestMigrationVersions(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db, err := storagenodedb.New(log, storagenodedbtest.Config(ctx.Dir("storage")))
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	// every step has its own version and there are no gaps
+	migration := db.Migration(ctx)
+	for i, step := range migration.Steps {
+		require.Equal(t, i, step.Version, step.Description)
+	}
+}
+
+func TestMigrateInMemory(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	storageDir := ctx.Dir("storage")
+	db, err := storagenodedb.New(log, storagenodedbtest.Config(storageDir))
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	// all the databases share a single in-memory database, like before the split
+	memDB, err := sql.Open("sqlite3", "file:"+t.Name()+"?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer ctx.Check(memDB.Close)
+
+	for _, mdb := range db.RawDatabases() {
+		require.NoError(t, mdb.GetDB().Close())
+		mdb.Configure(memDB)
+	}
+
+	// a directory removed by the migration when the databases have files
+	tmpDir := filepath.Join(storageDir, "tmp")
+	require.NoError(t, os.MkdirAll(tmpDir, 0700))
+
+	migration := db.Migration(ctx)
+	require.NoError(t, migration.Run(log))
+
+	var version int
+	require.NoError(t, memDB.QueryRow(`SELECT MAX(version) FROM versions`).Scan(&version))
+	require.Equal(t, migration.Steps[len(migration.Steps)-1].Version, version)
+
+	// the split tables are kept in the shared database
+	var tables int
+	require.NoError(t, memDB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('bandwidth_usage', 'satellite_last_contact')`).Scan(&tables))
+	require.Equal(t, 2, tables)
+
+	_, err = os.Stat(tmpDir)
+	require.NoError(t, err)
+}
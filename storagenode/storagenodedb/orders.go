@@ -169,6 +169,56 @@ func (db *ordersDB) ListUnsentBySatellite(ctx context.Context) (_ map[storj.Node
 	return infos, ErrOrders.Wrap(rows.Err())
 }
 
+// SoonestExpiration returns the nearest order limit expiration of the unsent orders,
+// or the zero time when there are none.
+func (db *ordersDB) SoonestExpiration(ctx context.Context) (_ time.Time, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var expiration time.Time
+	err = db.QueryRow(`
+		SELECT order_limit_expiration
+		FROM unsent_order
+		ORDER BY order_limit_expiration ASC
+		LIMIT 1
+	`).Scan(&expiration)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, ErrOrders.Wrap(err)
+	}
+	return expiration, nil
+}
+
+// CountExpiringWithin returns the number of unsent orders per satellite whose order limit
+// expires within d from now. Satellites without such orders are missing from the result.
+func (db *ordersDB) CountExpiringWithin(ctx context.Context, d time.Duration) (_ map[storj.NodeID]int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	now := time.Now().UTC()
+	rows, err := db.Query(`
+		SELECT satellite_id, COUNT(*)
+		FROM unsent_order
+		WHERE order_limit_expiration > ? AND order_limit_expiration <= ?
+		GROUP BY satellite_id
+	`, now, now.Add(d))
+	if err != nil {
+		return nil, ErrOrders.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	counts := make(map[storj.NodeID]int)
+	for rows.Next() {
+		var satelliteID storj.NodeID
+		var count int
+		if err := rows.Scan(&satelliteID, &count); err != nil {
+			return nil, ErrOrders.Wrap(err)
+		}
+		counts[satelliteID] = count
+	}
+	return counts, ErrOrders.Wrap(rows.Err())
+}
+
 // Archive marks order as being handled.
 //
 // If any of the request contains an order which doesn't exist the method will
@@ -217,7 +267,14 @@ func (db *ordersDB) Archive(ctx context.Context, archivedAt time.Time, requests
 func (db *ordersDB) archiveOne(ctx context.Context, txn *sql.Tx, archivedAt time.Time, req orders.ArchiveRequest) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	result, err := txn.Exec(`
+	// postgres doesn't infer the types of the selected arguments from the inserted columns
+	values := "?, ?"
+	if db.isPostgres() {
+		values = "?::BIGINT, ?::TIMESTAMPTZ"
+	}
+
+	// postgres runs a single statement per query with arguments
+	_, err = txn.Exec(`
 		INSERT INTO order_archive_ (
 			satellite_id, serial_number,
 			order_limit_serialized, order_serialized,
@@ -227,13 +284,18 @@ func (db *ordersDB) archiveOne(ctx context.Context, txn *sql.Tx, archivedAt time
 			satellite_id, serial_number,
 			order_limit_serialized, order_serialized,
 			uplink_cert_id,
-			?, ?
+			`+values+`
 		FROM unsent_order
-		WHERE satellite_id = ? AND serial_number = ?;
+		WHERE satellite_id = ? AND serial_number = ?
+	`, int(req.Status), archivedAt, req.Satellite, req.Serial)
+	if err != nil {
+		return ErrOrders.Wrap(err)
+	}
 
+	result, err := txn.Exec(`
 		DELETE FROM unsent_order
-		WHERE satellite_id = ? AND serial_number = ?;
-	`, int(req.Status), archivedAt, req.Satellite, req.Serial, req.Satellite, req.Serial)
+		WHERE satellite_id = ? AND serial_number = ?
+	`, req.Satellite, req.Serial)
 	if err != nil {
 		return ErrOrders.Wrap(err)
 	}
@@ -266,6 +328,32 @@ func (db *ordersDB) ListArchived(ctx context.Context, limit int) (_ []*orders.Ar
 		}
 		return nil, ErrOrders.Wrap(err)
 	}
+
+	return scanArchived(rows)
+}
+
+// ListArchivedByStatus returns orders that have been sent and have the specified status.
+func (db *ordersDB) ListArchivedByStatus(ctx context.Context, status orders.Status, limit int) (_ []*orders.ArchivedInfo, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.Query(`
+		SELECT order_limit_serialized, order_serialized, status, archived_at
+		FROM order_archive_
+		WHERE status = ?
+		LIMIT ?
+	`, int(status), limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, ErrOrders.Wrap(err)
+	}
+
+	return scanArchived(rows)
+}
+
+// scanArchived reads archived orders from rows and closes them.
+func scanArchived(rows *sql.Rows) (_ []*orders.ArchivedInfo, err error) {
 	defer func() { err = errs.Combine(err, rows.Close()) }()
 
 	var infos []*orders.ArchivedInfo
@@ -304,6 +392,37 @@ func (db *ordersDB) ListArchived(ctx context.Context, limit int) (_ []*orders.Ar
 	return infos, ErrOrders.Wrap(rows.Err())
 }
 
+// UpdateArchiveStatus changes the status of an archived order.
+//
+// It's used to correct the status when the satellite response differs from
+// the one recorded when the order was archived, e.g. after a retried send.
+// It returns an error of the class orders.OrderNotFoundError when there is no
+// archived order for the satellite and serial number.
+func (db *ordersDB) UpdateArchiveStatus(ctx context.Context, satelliteID storj.NodeID, serial storj.SerialNumber, status orders.Status, archivedAt time.Time) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := db.Exec(`
+		UPDATE order_archive_
+		SET status = ?, archived_at = ?
+		WHERE satellite_id = ? AND serial_number = ?
+	`, int(status), archivedAt.UTC(), satelliteID, serial)
+	if err != nil {
+		return ErrOrders.Wrap(err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return ErrOrders.Wrap(err)
+	}
+	if count == 0 {
+		return orders.OrderNotFoundError.New("satellite: %s, serial number: %s",
+			satelliteID.String(), serial.String(),
+		)
+	}
+
+	return nil
+}
+
 // CleanArchive deletes all entries older than ttl
 func (db *ordersDB) CleanArchive(ctx context.Context, ttl time.Duration) (_ int, err error) {
 	defer mon.Task()(&ctx)(&err)
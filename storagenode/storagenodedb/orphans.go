@@ -0,0 +1,80 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storage"
+	"storj.io/storj/storage/filestore"
+)
+
+// FindOrphanedBlobs walks the blobs of the satellite and calls fn for each blob which has no
+// database record, e.g. because the node crashed between writing the blob and the record.
+//
+// Only blobs stored with storage format V0 are described by the pieceinfo_ table. Blobs stored
+// with newer formats keep their metadata in the piece header and only have a piece_expirations
+// record when they expire, so they are never reported. The walk stops when ctx is canceled or
+// fn returns an error.
+func (db *DB) FindOrphanedBlobs(ctx context.Context, satelliteID storj.NodeID, fn func(storage.BlobRef) error) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	known, err := db.v0PieceInfoDB.pieceIDsOwnedBy(ctx, satelliteID)
+	if err != nil {
+		return err
+	}
+
+	return db.pieces.WalkNamespace(ctx, satelliteID.Bytes(), func(info storage.BlobInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.StorageFormatVersion() != filestore.FormatV0 {
+			return nil
+		}
+
+		ref := info.BlobRef()
+		pieceID, err := storj.PieceIDFromBytes(ref.Key)
+		if err != nil {
+			return ErrDatabase.Wrap(err)
+		}
+		if _, ok := known[pieceID]; ok {
+			return nil
+		}
+		return fn(ref)
+	})
+}
+
+// DeleteOrphanedBlobs deletes the blobs of the satellite found by FindOrphanedBlobs
+// and returns how many were deleted.
+func (db *DB) DeleteOrphanedBlobs(ctx context.Context, satelliteID storj.NodeID) (deleted int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// collect the blobs first, so that the blob directories are not modified while walking them
+	var orphans []storage.BlobRef
+	err = db.FindOrphanedBlobs(ctx, satelliteID, func(ref storage.BlobRef) error {
+		orphans = append(orphans, ref)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var errlist errs.Group
+	for _, ref := range orphans {
+		if err := ctx.Err(); err != nil {
+			errlist.Add(err)
+			break
+		}
+		if err := db.pieces.Delete(ctx, ref); err != nil {
+			errlist.Add(err)
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, ErrDatabase.Wrap(errlist.Err())
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storage"
+	"storj.io/storj/storage/filestore"
+	"storj.io/storj/storagenode/pieces"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestOrphanedBlobs(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	satelliteID := testrand.NodeID()
+
+	writeBlob := func(pieceID storj.PieceID, v0 bool) storage.BlobRef {
+		ref := storage.BlobRef{Namespace: satelliteID.Bytes(), Key: pieceID.Bytes()}
+
+		var writer storage.BlobWriter
+		var err error
+		if v0 {
+			writer, err = db.Pieces().(*filestore.Store).TestCreateV0(ctx, ref)
+		} else {
+			writer, err = db.Pieces().Create(ctx, ref, -1)
+		}
+		require.NoError(t, err)
+
+		_, err = writer.Write(testrand.BytesInt(100))
+		require.NoError(t, err)
+		require.NoError(t, writer.Commit(ctx))
+		return ref
+	}
+
+	// a V0 blob with a pieceinfo record
+	tracked := testrand.PieceID()
+	writeBlob(tracked, true)
+	require.NoError(t, db.V0PieceInfo().(pieces.V0PieceInfoDBForTest).Add(ctx, &pieces.Info{
+		SatelliteID:     satelliteID,
+		PieceID:         tracked,
+		PieceSize:       100,
+		PieceCreation:   time.Now(),
+		UplinkPieceHash: &pb.PieceHash{},
+		OrderLimit:      &pb.OrderLimit{},
+	}))
+
+	// V1 blobs keep their metadata in the header
+	v1 := writeBlob(testrand.PieceID(), false)
+
+	// V0 blobs without a pieceinfo record
+	orphans := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		ref := writeBlob(testrand.PieceID(), true)
+		orphans[string(ref.Key)] = true
+	}
+
+	found := map[string]bool{}
+	err := db.FindOrphanedBlobs(ctx, satelliteID, func(ref storage.BlobRef) error {
+		found[string(ref.Key)] = true
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, orphans, found)
+
+	// other satellites have no orphans
+	err = db.FindOrphanedBlobs(ctx, testrand.NodeID(), func(ref storage.BlobRef) error {
+		return errs.New("unexpected orphan %v", ref)
+	})
+	require.NoError(t, err)
+
+	// the walk stops when the context is canceled
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = db.FindOrphanedBlobs(canceled, satelliteID, func(ref storage.BlobRef) error { return nil })
+	require.Error(t, err)
+
+	deleted, err := db.DeleteOrphanedBlobs(ctx, satelliteID)
+	require.NoError(t, err)
+	require.Equal(t, 3, deleted)
+
+	err = db.FindOrphanedBlobs(ctx, satelliteID, func(ref storage.BlobRef) error {
+		return errs.New("unexpected orphan %v", ref)
+	})
+	require.NoError(t, err)
+
+	// the tracked blobs are kept
+	_, err = db.Pieces().Stat(ctx, storage.BlobRef{Namespace: satelliteID.Bytes(), Key: tracked.Bytes()})
+	require.NoError(t, err)
+	_, err = db.Pieces().Stat(ctx, v1)
+	require.NoError(t, err)
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/storj/storage"
+	"storj.io/storj/storage/filestore"
+)
+
+// VerifyPieceCounts returns the number of pieces recorded in the pieceinfo_ and piece_expirations tables
+// and the number of blobs on disk they describe, a quick health check for orphaned blobs or missing records
+// which doesn't read the blobs. The counts don't match when there are either, see FindOrphanedBlobs.
+//
+// Blobs stored with storage format V0 are recorded in pieceinfo_. Blobs stored with newer formats keep
+// their metadata in the piece header and only have a piece_expirations record when they expire, so only
+// the ones with a record are counted; an orphaned blob without an expiration can't be told apart from a
+// piece which never expires. The blob walk stops when ctx is canceled.
+func (db *DB) VerifyPieceCounts(ctx context.Context) (dbCount int64, blobCount int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = db.v0PieceInfoDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM pieceinfo_`).Scan(&dbCount)
+	if err != nil {
+		return 0, 0, ErrDatabase.Wrap(err)
+	}
+
+	expiring, err := db.expiringPieces(ctx)
+	if err != nil {
+		return 0, 0, ErrDatabase.Wrap(err)
+	}
+	dbCount += int64(len(expiring))
+
+	dir := db.pieces.Dir()
+	namespaces, err := dir.ListNamespaces(ctx)
+	if err != nil {
+		return 0, 0, ErrDatabase.Wrap(err)
+	}
+	for _, namespace := range namespaces {
+		err = dir.WalkNamespace(ctx, namespace, func(info storage.BlobInfo) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if info.StorageFormatVersion() == filestore.FormatV0 {
+				blobCount++
+				return nil
+			}
+			ref := info.BlobRef()
+			if _, ok := expiring[string(ref.Namespace)+string(ref.Key)]; ok {
+				blobCount++
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, ErrDatabase.Wrap(err)
+		}
+	}
+
+	if dbCount != blobCount {
+		mon.Meter("piece_count_mismatch").Mark(1)
+	}
+	return dbCount, blobCount, nil
+}
+
+// expiringPieces returns the satellite and piece ids of the piece_expirations records, concatenated.
+func (db *DB) expiringPieces(ctx context.Context) (_ map[string]struct{}, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.pieceExpirationDB.QueryContext(ctx, `SELECT satellite_id, piece_id FROM piece_expirations`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	expiring := make(map[string]struct{})
+	for rows.Next() {
+		var satelliteID, pieceID []byte
+		if err := rows.Scan(&satelliteID, &pieceID); err != nil {
+			return nil, err
+		}
+		expiring[string(satelliteID)+string(pieceID)] = struct{}{}
+	}
+	return expiring, rows.Err()
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storage"
+	"storj.io/storj/storage/filestore"
+	"storj.io/storj/storagenode/pieces"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestVerifyPieceCounts(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	satelliteID := testrand.NodeID()
+
+	writeBlob := func(pieceID storj.PieceID, v0 bool) {
+		ref := storage.BlobRef{Namespace: satelliteID.Bytes(), Key: pieceID.Bytes()}
+
+		var writer storage.BlobWriter
+		var err error
+		if v0 {
+			writer, err = db.Pieces().(*filestore.Store).TestCreateV0(ctx, ref)
+		} else {
+			writer, err = db.Pieces().Create(ctx, ref, -1)
+		}
+		require.NoError(t, err)
+
+		_, err = writer.Write(testrand.BytesInt(100))
+		require.NoError(t, err)
+		require.NoError(t, writer.Commit(ctx))
+	}
+	addRecord := func(pieceID storj.PieceID) {
+		require.NoError(t, db.V0PieceInfo().(pieces.V0PieceInfoDBForTest).Add(ctx, &pieces.Info{
+			SatelliteID:     satelliteID,
+			PieceID:         pieceID,
+			PieceSize:       100,
+			PieceCreation:   time.Now(),
+			UplinkPieceHash: &pb.PieceHash{},
+			OrderLimit:      &pb.OrderLimit{},
+		}))
+	}
+
+	dbCount, blobCount, err := db.VerifyPieceCounts(ctx)
+	require.NoError(t, err)
+	require.Zero(t, dbCount)
+	require.Zero(t, blobCount)
+
+	for i := 0; i < 3; i++ {
+		pieceID := testrand.PieceID()
+		writeBlob(pieceID, true)
+		addRecord(pieceID)
+	}
+	// V1 blobs are recorded when they expire
+	expiring := testrand.PieceID()
+	writeBlob(expiring, false)
+	require.NoError(t, db.PieceExpirationDB().SetExpiration(ctx, satelliteID, expiring, time.Now().Add(time.Hour)))
+	// V1 blobs which don't expire have no record to match
+	writeBlob(testrand.PieceID(), false)
+
+	dbCount, blobCount, err = db.VerifyPieceCounts(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, dbCount)
+	require.EqualValues(t, 4, blobCount)
+
+	// an expiration record without a V1 blob
+	require.NoError(t, db.PieceExpirationDB().SetExpiration(ctx, satelliteID, testrand.PieceID(), time.Now().Add(time.Hour)))
+
+	dbCount, blobCount, err = db.VerifyPieceCounts(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, dbCount)
+	require.EqualValues(t, 4, blobCount)
+
+	// three orphaned blobs and a record without a blob
+	writeBlob(testrand.PieceID(), true)
+	writeBlob(testrand.PieceID(), true)
+	writeBlob(testrand.PieceID(), true)
+	addRecord(testrand.PieceID())
+
+	dbCount, blobCount, err = db.VerifyPieceCounts(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 6, dbCount)
+	require.EqualValues(t, 7, blobCount)
+
+	// the walk stops when the context is canceled
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = db.VerifyPieceCounts(canceled)
+	require.Error(t, err)
+}
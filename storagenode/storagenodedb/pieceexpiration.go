@@ -97,3 +97,43 @@ func (db *pieceExpirationDB) DeleteFailed(ctx context.Context, satelliteID storj
 	`, when.UTC(), satelliteID, pieceID)
 	return ErrPieceExpiration.Wrap(err)
 }
+
+// CountExpirationDeletionFailed returns the number of expiration records whose piece failed to be deleted
+func (db *pieceExpirationDB) CountExpirationDeletionFailed(ctx context.Context) (count int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+			FROM piece_expirations
+			WHERE deletion_failed_at IS NOT NULL
+	`).Scan(&count)
+	return count, ErrPieceExpiration.Wrap(err)
+}
+
+// ListDeletionFailed returns at most limit expiration records whose piece failed to be deleted,
+// ordered by the time of the failure descending
+func (db *pieceExpirationDB) ListDeletionFailed(ctx context.Context, limit int) (failures []pieces.DeletionFailure, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT satellite_id, piece_id, deletion_failed_at
+			FROM piece_expirations
+			WHERE deletion_failed_at IS NOT NULL
+			ORDER BY deletion_failed_at DESC
+			LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, ErrPieceExpiration.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var failure pieces.DeletionFailure
+		err = rows.Scan(&failure.SatelliteID, &failure.PieceID, &failure.DeletionFailedAt)
+		if err != nil {
+			return nil, ErrPieceExpiration.Wrap(err)
+		}
+		failures = append(failures, failure)
+	}
+	return failures, ErrPieceExpiration.Wrap(rows.Err())
+}
@@ -5,12 +5,19 @@ package storagenodedb
 
 import (
 	"context"
+	"database/sql"
+	"math"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/zeebo/errs"
 
+	"storj.io/storj/internal/dbutil/pgutil"
+	"storj.io/storj/internal/dbutil/sqliteutil"
 	"storj.io/storj/pkg/pb"
 	"storj.io/storj/pkg/storj"
 	"storj.io/storj/storage"
@@ -84,6 +91,29 @@ func (db *v0PieceInfoDB) getAllPiecesOwnedBy(ctx context.Context, blobStore stor
 	return pieceInfos, nil
 }
 
+// pieceIDsOwnedBy returns the ids of all the pieces of the satellite.
+func (db *v0PieceInfoDB) pieceIDsOwnedBy(ctx context.Context, satelliteID storj.NodeID) (_ map[storj.PieceID]struct{}, err error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT piece_id
+		FROM pieceinfo_
+		WHERE satellite_id = ?
+	`, satelliteID)
+	if err != nil {
+		return nil, ErrPieceInfo.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	pieceIDs := map[storj.PieceID]struct{}{}
+	for rows.Next() {
+		var pieceID storj.PieceID
+		if err := rows.Scan(&pieceID); err != nil {
+			return nil, ErrPieceInfo.Wrap(err)
+		}
+		pieceIDs[pieceID] = struct{}{}
+	}
+	return pieceIDs, ErrPieceInfo.Wrap(rows.Err())
+}
+
 // WalkSatelliteV0Pieces executes walkFunc for each locally stored piece, stored with storage
 // format V0 in the namespace of the given satellite. If walkFunc returns a non-nil error,
 // WalkSatelliteV0Pieces will stop iterating and return the error immediately. The ctx parameter
@@ -206,6 +236,217 @@ func (db *v0PieceInfoDB) GetExpired(ctx context.Context, expiredAt time.Time, li
 	return infos, nil
 }
 
+// BackfillExpirations inserts the expirations of the pieces, which lost their expiration in
+// the table migrations, into the piece expirations. The expiration is decoded from the order limit
+// stored with the piece. Pieces which already have a piece expiration record are skipped.
+// It returns the number of inserted records.
+func (db *v0PieceInfoDB) BackfillExpirations(ctx context.Context, into pieces.PieceExpirationDB) (inserted int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	type missingExpiration struct {
+		satelliteID storj.NodeID
+		pieceID     storj.PieceID
+		orderLimit  []byte
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT satellite_id, piece_id, order_limit
+		FROM pieceinfo_
+		WHERE piece_expiration IS NULL
+	`)
+	if err != nil {
+		return 0, ErrPieceInfo.Wrap(err)
+	}
+
+	var missing []missingExpiration
+	for rows.Next() {
+		var piece missingExpiration
+		if err := rows.Scan(&piece.satelliteID, &piece.pieceID, &piece.orderLimit); err != nil {
+			return 0, ErrPieceInfo.Wrap(errs.Combine(err, rows.Close()))
+		}
+		missing = append(missing, piece)
+	}
+	if err := errs.Combine(rows.Err(), rows.Close()); err != nil {
+		return 0, ErrPieceInfo.Wrap(err)
+	}
+
+	// the rows are closed before writing, the piece expirations may be in a different database
+	for _, piece := range missing {
+		if err := ctx.Err(); err != nil {
+			return inserted, err
+		}
+
+		var orderLimit pb.OrderLimit
+		if err := proto.Unmarshal(piece.orderLimit, &orderLimit); err != nil {
+			return inserted, ErrPieceInfo.Wrap(err)
+		}
+		if orderLimit.PieceExpiration.IsZero() {
+			continue
+		}
+
+		err := into.SetExpiration(ctx, piece.satelliteID, piece.pieceID, orderLimit.PieceExpiration)
+		if sqliteutil.IsConstraintError(err) || pgutil.IsConstraintError(err) {
+			continue
+		}
+		if err != nil {
+			return inserted, err
+		}
+		inserted++
+	}
+
+	return inserted, nil
+}
+
+// EstimatePieceCount returns an approximate number of pieces stored with storage format V0.
+//
+// When the database has been analyzed, the row count recorded in sqlite_stat1, or in pg_class
+// for postgres, is used, which avoids scanning the table but may be stale by however many pieces
+// were added or deleted since the last ANALYZE. Otherwise it falls back to an exact COUNT over
+// the primary key index.
+func (db *v0PieceInfoDB) EstimatePieceCount(ctx context.Context) (count int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	analyzedCount := db.sqliteAnalyzedCount
+	if db.isPostgres() {
+		analyzedCount = db.postgresAnalyzedCount
+	}
+	count, ok, err := analyzedCount(ctx)
+	if err != nil {
+		return 0, ErrPieceInfo.Wrap(err)
+	}
+	if ok {
+		return count, nil
+	}
+
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pieceinfo_`).Scan(&count)
+	if err != nil {
+		return 0, ErrPieceInfo.Wrap(err)
+	}
+	return count, nil
+}
+
+// sqliteAnalyzedCount returns the number of rows of the primary key index recorded by ANALYZE,
+// ok is false when the database hasn't been analyzed.
+func (db *v0PieceInfoDB) sqliteAnalyzedCount(ctx context.Context) (count int64, ok bool, err error) {
+	var hasStats bool
+	err = db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_stat1')
+	`).Scan(&hasStats)
+	if err != nil || !hasStats {
+		return 0, false, err
+	}
+
+	var stat string
+	err = db.QueryRowContext(ctx, `
+		SELECT stat FROM sqlite_stat1 WHERE tbl = 'pieceinfo_' AND idx = 'pk_pieceinfo_'
+	`).Scan(&stat)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	// the first number in the stat column is the approximate number of rows in the index
+	fields := strings.Fields(stat)
+	if len(fields) > 0 {
+		count, err = strconv.ParseInt(fields[0], 10, 64)
+		if err == nil {
+			return count, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// postgresAnalyzedCount returns the number of rows of the table estimated by ANALYZE or VACUUM,
+// ok is false when the table hasn't been analyzed or is empty.
+func (db *v0PieceInfoDB) postgresAnalyzedCount(ctx context.Context) (count int64, ok bool, err error) {
+	var estimate float64
+	err = db.QueryRowContext(ctx, `SELECT reltuples FROM pg_class WHERE oid = 'pieceinfo_'::regclass`).Scan(&estimate)
+	if err != nil || estimate <= 0 {
+		return 0, false, err
+	}
+	return int64(estimate), true, nil
+}
+
+// CountDeletionFailed returns the number of pieces stored with storage format V0 that failed to be deleted.
+func (db *v0PieceInfoDB) CountDeletionFailed(ctx context.Context) (count int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM pieceinfo_
+		WHERE deletion_failed_at IS NOT NULL
+	`).Scan(&count)
+	return count, ErrPieceInfo.Wrap(err)
+}
+
+// SizeHistogram returns the number of pieces stored with storage format V0 in each piece size bucket.
+//
+// Buckets are upper bounds: a piece is counted in the smallest bucket that is greater than or equal to its size.
+// Pieces larger than the largest bucket are counted under math.MaxInt64.
+func (db *v0PieceInfoDB) SizeHistogram(ctx context.Context, buckets []int64) (_ map[int64]int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	bounds := append([]int64{}, buckets...)
+	sort.Slice(bounds, func(i, k int) bool { return bounds[i] < bounds[k] })
+
+	histogram := make(map[int64]int64, len(bounds)+1)
+	for _, bound := range bounds {
+		histogram[bound] = 0
+	}
+	histogram[math.MaxInt64] = 0
+
+	rows, err := db.QueryContext(ctx, `SELECT piece_size FROM pieceinfo_`)
+	if err != nil {
+		return nil, ErrPieceInfo.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var size int64
+		if err := rows.Scan(&size); err != nil {
+			return nil, ErrPieceInfo.Wrap(err)
+		}
+
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] >= size })
+		if i < len(bounds) {
+			histogram[bounds[i]]++
+		} else {
+			histogram[math.MaxInt64]++
+		}
+	}
+
+	return histogram, ErrPieceInfo.Wrap(rows.Err())
+}
+
+// FindDuplicatePieceIDs returns the ids of pieces stored with storage format V0 for more than one satellite.
+// Such pieces are valid, since pieces are keyed by satellite and piece id, but they are worth looking into.
+func (db *v0PieceInfoDB) FindDuplicatePieceIDs(ctx context.Context) (pieceIDs []storj.PieceID, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT piece_id
+		FROM pieceinfo_
+		GROUP BY piece_id
+		HAVING COUNT(DISTINCT satellite_id) > 1
+		ORDER BY piece_id
+	`)
+	if err != nil {
+		return nil, ErrPieceInfo.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var pieceID storj.PieceID
+		if err := rows.Scan(&pieceID); err != nil {
+			return nil, ErrPieceInfo.Wrap(err)
+		}
+		pieceIDs = append(pieceIDs, pieceID)
+	}
+	return pieceIDs, ErrPieceInfo.Wrap(rows.Err())
+}
+
 type v0StoredPieceAccess struct {
 	blobStore      storage.Blobs
 	satellite      storj.NodeID
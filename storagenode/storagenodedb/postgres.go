@@ -0,0 +1,433 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/storj/internal/dbutil"
+	"storj.io/storj/internal/dbutil/pgutil"
+	"storj.io/storj/internal/migrate"
+)
+
+const (
+	// DriverSQLite3 stores every database in its own sqlite3 file, it's the default.
+	DriverSQLite3 = "sqlite3"
+	// DriverPostgres stores every database in its own schema of a postgres database.
+	DriverPostgres = "postgres"
+)
+
+// postgresDriverName is the name of the driver of the postgres connections.
+const postgresDriverName = "postgres_storagenode"
+
+var registerPostgresDriver sync.Once
+
+// postgresDriver wraps the pq driver to rebind the sqlite placeholders of the queries,
+// so that both drivers share the queries which don't depend on the dialect.
+type postgresDriver struct {
+	pq.Driver
+}
+
+// pqConn are the interfaces of the pq connections used by postgresConn.
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ExecerContext
+	driver.QueryerContext
+}
+
+// Open opens a new connection to the database.
+func (d *postgresDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	pgConn, ok := conn.(pqConn)
+	if !ok {
+		return nil, errs.Combine(errs.New("unsupported postgres connection %T", conn), conn.Close())
+	}
+	return postgresConn{pgConn}, nil
+}
+
+// postgresConn is a pq connection which rebinds the placeholders of the queries.
+type postgresConn struct {
+	pqConn
+}
+
+// Prepare prepares the query.
+func (conn postgresConn) Prepare(query string) (driver.Stmt, error) {
+	return conn.pqConn.Prepare(rebindPostgres(query))
+}
+
+// ExecContext executes the query without preparing it.
+func (conn postgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return conn.pqConn.ExecContext(ctx, rebindPostgres(query), args)
+}
+
+// QueryContext runs the query without preparing it.
+func (conn postgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return conn.pqConn.QueryContext(ctx, rebindPostgres(query), args)
+}
+
+// rebindPostgres replaces the ? placeholders of the query with the numbered placeholders of postgres.
+// Question marks in string literals and quoted identifiers are kept.
+func rebindPostgres(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var rebound strings.Builder
+	var quote byte
+	placeholder := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			placeholder++
+			rebound.WriteString("$" + strconv.Itoa(placeholder))
+			continue
+		}
+		rebound.WriteByte(c)
+	}
+	return rebound.String()
+}
+
+// isPostgresDB returns whether sqlDB is connected to postgres.
+func isPostgresDB(sqlDB *sql.DB) bool {
+	_, ok := sqlDB.Driver().(*postgresDriver)
+	return ok
+}
+
+// isPostgres returns whether the databases are stored in postgres.
+func (db *DB) isPostgres() bool {
+	return db.driver == DriverPostgres
+}
+
+// postgresSchema returns the name of the postgres schema of the database.
+func (db *DB) postgresSchema(dbName string) string {
+	return db.databasePrefix + dbName
+}
+
+// openPostgresDatabase opens the schema of the database, creating it unless the databases are read-only.
+func (db *DB) openPostgresDatabase(dbName string) error {
+	schema := db.postgresSchema(dbName)
+	sqlDB, err := openPostgres(db.dsn, schema, db.readOnly)
+	if err != nil {
+		return ErrDatabase.New("%s: %v", dbName, err)
+	}
+
+	mDB := db.sqlDatabases[dbName]
+	mDB.Configure(sqlDB)
+
+	dbutil.Configure(sqlDB, mon)
+
+	if db.readOnly {
+		var exists bool
+		err := sqlDB.QueryRow(`SELECT EXISTS (SELECT 1 FROM information_schema.schemata WHERE schema_name = ?)`, schema).Scan(&exists)
+		if err != nil {
+			return ErrDatabase.New("%s: %v", dbName, err)
+		}
+		if !exists {
+			return ErrDatabase.New("%s: schema %s doesn't exist", dbName, schema)
+		}
+	} else if err := pgutil.CreateSchema(sqlDB, schema); err != nil {
+		return ErrDatabase.New("%s: %v", dbName, err)
+	}
+
+	db.log.Debug("opened database " + dbName)
+	return nil
+}
+
+// openPostgres opens the connections to the schema of the postgres database at dsn. The timestamps
+// are read in UTC like the sqlite ones. When readOnly is set, the transactions are read-only by default.
+func openPostgres(dsn, schema string, readOnly bool) (*sql.DB, error) {
+	registerPostgresDriver.Do(func() {
+		sql.Register(postgresDriverName, &postgresDriver{})
+	})
+
+	params := url.Values{}
+	params.Set("search_path", pgutil.QuoteSchema(schema))
+	params.Set("TimeZone", "UTC")
+	if readOnly {
+		params.Set("default_transaction_read_only", "on")
+	}
+	return sql.Open(postgresDriverName, connstrWithParams(dsn, params))
+}
+
+// connstrWithParams adds the params to a postgres connection string, either in the URL or the key=value format.
+func connstrWithParams(connstr string, params url.Values) string {
+	if strings.HasPrefix(connstr, "postgres://") || strings.HasPrefix(connstr, "postgresql://") {
+		if strings.Contains(connstr, "?") {
+			return connstr + "&" + params.Encode()
+		}
+		return connstr + "?" + params.Encode()
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	escape := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	for _, key := range keys {
+		connstr += " " + key + "='" + escape.Replace(params.Get(key)) + "'"
+	}
+	return connstr
+}
+
+// postgresBaseVersion is the schema version which the postgres migration starts from. The first step of
+// every database creates its tables as they are at this version, the later steps are translated from sqlite.
+const postgresBaseVersion = 30
+
+// postgresDialect translates the column types of the sqlite statements to postgres.
+// sqlite integers have 64 bits and its timestamps are always stored in UTC.
+var postgresDialect = []struct {
+	sqlite   *regexp.Regexp
+	postgres string
+}{
+	{regexp.MustCompile(`(?i)\bINTEGER\s+PRIMARY\s+KEY\s+AUTOINCREMENT\b`), "BIGSERIAL PRIMARY KEY"},
+	{regexp.MustCompile(`(?i)\bBLOB\b`), "BYTEA"},
+	{regexp.MustCompile(`(?i)\bINTEGER\b`), "BIGINT"},
+	{regexp.MustCompile(`(?i)\bREAL\b`), "DOUBLE PRECISION"},
+	{regexp.MustCompile(`(?i)\bTIMESTAMP\b`), "TIMESTAMP WITH TIME ZONE"},
+}
+
+// postgresSQL translates the sqlite statements of a migration step to postgres.
+func postgresSQL(statements migrate.SQL) migrate.SQL {
+	translated := make(migrate.SQL, 0, len(statements))
+	for _, statement := range statements {
+		for _, rule := range postgresDialect {
+			statement = rule.sqlite.ReplaceAllString(statement, rule.postgres)
+		}
+		translated = append(translated, statement)
+	}
+	return translated
+}
+
+// postgresStep returns the step with its sqlite statements translated to postgres.
+// The actions running go code are kept as they are.
+func postgresStep(step *migrate.Step) *migrate.Step {
+	translated := *step
+	if action, ok := step.Action.(migrate.SQL); ok {
+		translated.Action = postgresSQL(action)
+	}
+	if rollback, ok := step.Rollback.(migrate.SQL); ok {
+		translated.Rollback = postgresSQL(rollback)
+	}
+	return &translated
+}
+
+// postgresMigration returns the table migrations of postgres. The steps of the sqlite migration up to
+// postgresBaseVersion are replaced by a single step which creates the tables, the later ones are translated.
+func (db *DB) postgresMigration(ctx context.Context) *migrate.Migration {
+	migration := &migrate.Migration{
+		Table: VersionTable,
+		Steps: []*migrate.Step{db.postgresBaseStep()},
+	}
+	for _, step := range db.sqliteMigration(ctx).Steps {
+		if step.Version > postgresBaseVersion {
+			migration.Steps = append(migration.Steps, postgresStep(step))
+		}
+	}
+	return migration
+}
+
+// postgresBaseStep returns the step which creates the tables of all the databases as they are at
+// postgresBaseVersion. The versions are unique, so the step runs in the transaction of the info
+// database, which switches to the schema of every database in turn to create its tables.
+func (db *DB) postgresBaseStep() *migrate.Step {
+	return &migrate.Step{
+		DB:          db.deprecatedInfoDB,
+		Description: "Create the tables of all the databases",
+		Version:     postgresBaseVersion,
+		Action: migrate.Func(func(log *zap.Logger, mgdb migrate.DB, tx *sql.Tx) error {
+			for _, schema := range postgresBaseSchemas {
+				_, err := tx.Exec(`SET LOCAL search_path TO ` + pgutil.QuoteSchema(db.postgresSchema(schema.dbName)))
+				if err != nil {
+					return ErrDatabase.Wrap(err)
+				}
+				for _, statement := range postgresSQL(schema.statements) {
+					if _, err := tx.Exec(statement); err != nil {
+						return ErrDatabase.New("%s: %v", schema.dbName, err)
+					}
+				}
+			}
+
+			// the version is recorded in the info database
+			_, err := tx.Exec(`SET LOCAL search_path TO ` + pgutil.QuoteSchema(db.postgresSchema(DeprecatedInfoDBName)))
+			return ErrDatabase.Wrap(err)
+		}),
+		Rollback: migrate.Irreversible("the postgres databases start at version 30"),
+	}
+}
+
+// postgresBaseSchemas are the tables of the databases at postgresBaseVersion, in the sqlite dialect.
+// The foreign keys to the certificate table of the info database are left out, as it's in another schema.
+var postgresBaseSchemas = []struct {
+	dbName     string
+	statements migrate.SQL
+}{
+	{UsedSerialsDBName, migrate.SQL{
+		`CREATE TABLE used_serial_ (
+			satellite_id  BLOB NOT NULL,
+			serial_number BLOB NOT NULL,
+			expiration    TIMESTAMP NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX pk_used_serial_ ON used_serial_(satellite_id, serial_number)`,
+		`CREATE INDEX idx_used_serial_ ON used_serial_(expiration)`,
+	}},
+	{StorageUsageDBName, migrate.SQL{
+		`CREATE TABLE storage_usage (
+			satellite_id BLOB NOT NULL,
+			at_rest_total REAL NOT NULL,
+			interval_start TIMESTAMP NOT NULL,
+			interval_end TIMESTAMP,
+			PRIMARY KEY (satellite_id, interval_start)
+		)`,
+	}},
+	{ReputationDBName, migrate.SQL{
+		`CREATE TABLE reputation (
+			satellite_id BLOB NOT NULL,
+			uptime_success_count INTEGER NOT NULL,
+			uptime_total_count INTEGER NOT NULL,
+			uptime_reputation_alpha REAL NOT NULL,
+			uptime_reputation_beta REAL NOT NULL,
+			uptime_reputation_score REAL NOT NULL,
+			audit_success_count INTEGER NOT NULL,
+			audit_total_count INTEGER NOT NULL,
+			audit_reputation_alpha REAL NOT NULL,
+			audit_reputation_beta REAL NOT NULL,
+			audit_reputation_score REAL NOT NULL,
+			disqualified TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (satellite_id)
+		)`,
+		`CREATE TABLE reputation_history (
+			satellite_id BLOB NOT NULL,
+			uptime_reputation_score REAL NOT NULL,
+			audit_reputation_score REAL NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (satellite_id, updated_at)
+		)`,
+	}},
+	{PieceSpaceUsedDBName, migrate.SQL{
+		`CREATE TABLE piece_space_used (
+			total INTEGER NOT NULL,
+			satellite_id BLOB
+		)`,
+		`CREATE UNIQUE INDEX idx_piece_space_used_satellite_id ON piece_space_used(satellite_id)`,
+	}},
+	{PieceInfoDBName, migrate.SQL{
+		`CREATE TABLE pieceinfo_ (
+			satellite_id     BLOB      NOT NULL,
+			piece_id         BLOB      NOT NULL,
+			piece_size       BIGINT    NOT NULL,
+			piece_expiration TIMESTAMP,
+			order_limit       BLOB    NOT NULL,
+			uplink_piece_hash BLOB    NOT NULL,
+			uplink_cert_id    INTEGER NOT NULL,
+			deletion_failed_at TIMESTAMP,
+			piece_creation TIMESTAMP NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX pk_pieceinfo_ ON pieceinfo_(satellite_id, piece_id)`,
+		`CREATE INDEX idx_pieceinfo__expiration ON pieceinfo_(piece_expiration) WHERE piece_expiration IS NOT NULL`,
+	}},
+	{PieceExpirationDBName, migrate.SQL{
+		`CREATE TABLE piece_expirations (
+			satellite_id       BLOB      NOT NULL,
+			piece_id           BLOB      NOT NULL,
+			piece_expiration   TIMESTAMP NOT NULL,
+			deletion_failed_at TIMESTAMP,
+			PRIMARY KEY ( satellite_id, piece_id )
+		)`,
+		`CREATE INDEX idx_piece_expirations_piece_expiration ON piece_expirations(piece_expiration)`,
+		`CREATE INDEX idx_piece_expirations_deletion_failed_at ON piece_expirations(deletion_failed_at)`,
+	}},
+	{OrdersDBName, migrate.SQL{
+		`CREATE TABLE unsent_order (
+			satellite_id  BLOB NOT NULL,
+			serial_number BLOB NOT NULL,
+			order_limit_serialized BLOB      NOT NULL,
+			order_serialized       BLOB      NOT NULL,
+			order_limit_expiration TIMESTAMP NOT NULL,
+			uplink_cert_id INTEGER NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX idx_orders ON unsent_order(satellite_id, serial_number)`,
+		`CREATE TABLE order_archive_ (
+			satellite_id  BLOB NOT NULL,
+			serial_number BLOB NOT NULL,
+			order_limit_serialized BLOB NOT NULL,
+			order_serialized       BLOB NOT NULL,
+			uplink_cert_id INTEGER NOT NULL,
+			status      INTEGER   NOT NULL,
+			archived_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX idx_order_archive_archived_at ON order_archive_(archived_at)`,
+		`CREATE INDEX idx_order_archive_status ON order_archive_(status)`,
+	}},
+	{BandwidthDBName, migrate.SQL{
+		`CREATE TABLE bandwidth_usage (
+			satellite_id  BLOB    NOT NULL,
+			action        INTEGER NOT NULL,
+			amount        BIGINT  NOT NULL,
+			created_at    TIMESTAMP NOT NULL
+		)`,
+		`CREATE INDEX idx_bandwidth_usage_satellite ON bandwidth_usage(satellite_id)`,
+		`CREATE INDEX idx_bandwidth_usage_created ON bandwidth_usage(created_at)`,
+		`CREATE TABLE bandwidth_usage_rollups (
+			interval_start TIMESTAMP NOT NULL,
+			satellite_id   BLOB    NOT NULL,
+			action         INTEGER NOT NULL,
+			amount         BIGINT  NOT NULL,
+			PRIMARY KEY ( interval_start, satellite_id, action )
+		)`,
+	}},
+	{SatellitesDBName, migrate.SQL{
+		`CREATE TABLE satellites (
+			node_id BLOB NOT NULL,
+			address TEXT NOT NULL,
+			added_at TIMESTAMP NOT NULL,
+			status INTEGER NOT NULL,
+			PRIMARY KEY (node_id)
+		)`,
+		`CREATE TABLE satellite_exit_progress (
+			satellite_id BLOB NOT NULL,
+			initiated_at TIMESTAMP,
+			finished_at TIMESTAMP,
+			starting_disk_usage INTEGER NOT NULL,
+			bytes_deleted INTEGER NOT NULL,
+			completion_receipt BLOB,
+			PRIMARY KEY (satellite_id)
+		)`,
+		`CREATE TABLE satellite_exit_requests (
+			satellite_id BLOB NOT NULL,
+			requested_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (satellite_id)
+		)`,
+		`CREATE TABLE satellite_last_contact (
+			satellite_id BLOB NOT NULL,
+			last_contact TIMESTAMP NOT NULL,
+			PRIMARY KEY (satellite_id)
+		)`,
+	}},
+}
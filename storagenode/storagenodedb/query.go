@@ -0,0 +1,127 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/zeebo/errs"
+)
+
+// ErrQueryRejected is the error class for the statements rejected by Query.
+var ErrQueryRejected = errs.Class("query rejected")
+
+// queryDriverName is the name of the sqlite3 driver of the connections used by Query.
+const queryDriverName = "sqlite3_storagenode_query"
+
+// pgErrReadOnlyTransaction is the postgres error code of the writes in a read-only transaction.
+const pgErrReadOnlyTransaction = "25006"
+
+// sqliteRecursive is the authorizer action of recursive common table expressions, which the driver doesn't export.
+const sqliteRecursive = 33
+
+var registerQueryDriver sync.Once
+
+// queryAuthorizer allows the statements which only read, everything else is denied when it's prepared.
+// Pragmas can be read but not set, so that query_only can't be turned off.
+func queryAuthorizer(action int, arg1, arg2, arg3 string) int {
+	switch action {
+	case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_READ, sqlite3.SQLITE_FUNCTION, sqliteRecursive:
+		return sqlite3.SQLITE_OK
+	case sqlite3.SQLITE_PRAGMA:
+		if arg2 == "" {
+			return sqlite3.SQLITE_OK
+		}
+	}
+	return sqlite3.SQLITE_DENY
+}
+
+// Query runs a read-only query on the named database for diagnostics, e.g. by support staff.
+// The query runs on a separate connection which is opened with query_only, so that sqlite refuses to change
+// the database, and the statements which don't only read are rejected with ErrQueryRejected when they're
+// prepared, before anything runs. The caller must close the returned rows.
+//
+// On postgres the transactions of the separate connections are read-only by default and the statements which
+// write are rejected with ErrQueryRejected when they run. As a query can change the default, untrusted queries
+// need a DSN with a role which can only read.
+func (db *DB) Query(ctx context.Context, dbName string, query string, args ...interface{}) (_ *sql.Rows, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if _, ok := db.sqlDatabases[dbName]; !ok {
+		return nil, ErrDatabase.New("no database with name %s found", dbName)
+	}
+	if _, degraded := db.degraded[dbName]; degraded {
+		return nil, ErrDatabase.New("database %s is degraded", dbName)
+	}
+
+	queryDB, err := db.queryDatabase(dbName)
+	if err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+
+	rows, err := queryDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.Code == sqlite3.ErrAuth {
+			return nil, ErrQueryRejected.Wrap(err)
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == pgErrReadOnlyTransaction {
+			return nil, ErrQueryRejected.Wrap(err)
+		}
+		return nil, ErrDatabase.Wrap(err)
+	}
+	return rows, nil
+}
+
+// queryDatabase returns the read-only connections of the named database used by Query, opening them on first use.
+func (db *DB) queryDatabase(dbName string) (*sql.DB, error) {
+	registerQueryDriver.Do(func() {
+		sql.Register(queryDriverName, &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				conn.RegisterAuthorizer(queryAuthorizer)
+				return nil
+			},
+		})
+	})
+
+	db.queryMu.Lock()
+	defer db.queryMu.Unlock()
+
+	if queryDB, ok := db.queryDBs[dbName]; ok {
+		return queryDB, nil
+	}
+
+	var queryDB *sql.DB
+	var err error
+	if db.isPostgres() {
+		queryDB, err = openPostgres(db.dsn, db.postgresSchema(dbName), true)
+	} else {
+		// see openDatabase about opening a database in use for reading only
+		queryDB, err = sql.Open(queryDriverName, "file:"+db.filepathFromDBName(dbName)+"?_journal=WAL&_query_only=true&_busy_timeout=10000")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if db.queryDBs == nil {
+		db.queryDBs = make(map[string]*sql.DB)
+	}
+	db.queryDBs[dbName] = queryDB
+	return queryDB, nil
+}
+
+// closeQueryDatabases closes the connections opened by Query.
+func (db *DB) closeQueryDatabases() error {
+	db.queryMu.Lock()
+	defer db.queryMu.Unlock()
+
+	var errlist errs.Group
+	for dbName, queryDB := range db.queryDBs {
+		errlist.Add(queryDB.Close())
+		delete(db.queryDBs, dbName)
+	}
+	return errlist.Err()
+}
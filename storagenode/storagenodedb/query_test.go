@@ -0,0 +1,76 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestQuery(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	satelliteID, serialNumber := testrand.NodeID(), testrand.SerialNumber()
+	require.NoError(t, db.UsedSerials().Add(ctx, satelliteID, serialNumber, time.Now().Add(time.Hour)))
+
+	countSerials := func() (count int) {
+		rows, err := db.Query(ctx, storagenodedb.UsedSerialsDBName, `
+			-- diagnostics; with a comment
+			SELECT count(*) FROM used_serial_ WHERE satellite_id = ?`, satelliteID)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, rows.Close()) }()
+
+		require.True(t, rows.Next())
+		require.NoError(t, rows.Scan(&count))
+		return count
+	}
+	require.Equal(t, 1, countSerials())
+
+	rows, err := db.Query(ctx, storagenodedb.UsedSerialsDBName, `explain query plan select * from used_serial_;`)
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	for _, query := range []string{
+		`DELETE FROM used_serial_`,
+		`  /* select */ UPDATE used_serial_ SET expiration = 0`,
+		`SELECT 1; DELETE FROM used_serial_`,
+		`WITH serials AS (SELECT 1) DELETE FROM used_serial_`,
+		`DROP TABLE used_serial_`,
+		`PRAGMA journal_mode = DELETE`,
+		`PRAGMA query_only = 0`,
+		`ATTACH DATABASE 'other.db' AS other`,
+		`BEGIN`,
+	} {
+		_, err := db.Query(ctx, storagenodedb.UsedSerialsDBName, query)
+		require.True(t, storagenodedb.ErrQueryRejected.Has(err), query)
+	}
+	require.Equal(t, 1, countSerials())
+
+	// pragmas can be read
+	rows, err = db.Query(ctx, storagenodedb.UsedSerialsDBName, `PRAGMA user_version`)
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	// the changes are refused by the database, not by parsing the statement
+	rows, err = db.Query(ctx, storagenodedb.UsedSerialsDBName, `SELECT ';DELETE FROM used_serial_'`)
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	_, err = db.Query(ctx, "unknown", `SELECT 1`)
+	require.Error(t, err)
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"sort"
+
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storagenode/satellites"
+)
+
+// ReclaimableSatellites returns the satellites whose data can be deleted, because the node was
+// disqualified by them or successfully finished a graceful exit from them, sorted by id.
+//
+// A satellite with an ongoing graceful exit, even a suspended one, is never returned, as the exit
+// still needs the data, even when the satellite has disqualified the node in the meantime.
+// When the reputation database is degraded only the finished exits are taken into account.
+func (db *DB) ReclaimableSatellites(ctx context.Context) (_ []storj.NodeID, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	statuses, err := db.satellitesDB.SatelliteExitStatuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reclaimable := make(map[storj.NodeID]bool)
+	exiting := make(map[storj.NodeID]bool)
+	for _, status := range statuses {
+		switch status.Status {
+		case satellites.ExitSucceeded:
+			reclaimable[status.SatelliteID] = true
+		case satellites.Exiting, satellites.Suspended:
+			exiting[status.SatelliteID] = true
+		}
+	}
+
+	if _, degraded := db.degraded[ReputationDBName]; !degraded {
+		stats, err := db.reputationDB.All(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, stat := range stats {
+			if stat.Disqualified != nil && !exiting[stat.SatelliteID] {
+				reclaimable[stat.SatelliteID] = true
+			}
+		}
+	}
+
+	satelliteIDs := make([]storj.NodeID, 0, len(reclaimable))
+	for satelliteID := range reclaimable {
+		satelliteIDs = append(satelliteIDs, satelliteID)
+	}
+	sort.Slice(satelliteIDs, func(i, k int) bool {
+		return satelliteIDs[i].Less(satelliteIDs[k])
+	})
+	return satelliteIDs, nil
+}
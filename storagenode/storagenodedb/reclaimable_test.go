@@ -0,0 +1,78 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storagenode"
+	"storj.io/storj/storagenode/reputation"
+	"storj.io/storj/storagenode/satellites"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestReclaimableSatellites(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		now := time.Now()
+
+		disqualify := func(satelliteID storj.NodeID) {
+			require.NoError(t, db.Reputation().Store(ctx, reputation.Stats{
+				SatelliteID:  satelliteID,
+				Disqualified: &now,
+				UpdatedAt:    now,
+			}))
+		}
+		exit := func(satelliteID storj.NodeID, status satellites.Status) {
+			require.NoError(t, db.Satellites().InitiateGracefulExit(ctx, satelliteID, now, 100))
+			switch status {
+			case satellites.Suspended:
+				require.NoError(t, db.Satellites().SuspendGracefulExit(ctx, satelliteID))
+			case satellites.ExitSucceeded, satellites.ExitFailed:
+				require.NoError(t, db.Satellites().CompleteGracefulExit(ctx, satelliteID, now, status, nil))
+			}
+		}
+
+		reclaimable, err := db.(*storagenodedb.DB).ReclaimableSatellites(ctx)
+		require.NoError(t, err)
+		require.Empty(t, reclaimable)
+
+		healthy := testrand.NodeID()
+		require.NoError(t, db.Reputation().Store(ctx, reputation.Stats{SatelliteID: healthy, UpdatedAt: now}))
+
+		disqualified := testrand.NodeID()
+		disqualify(disqualified)
+
+		exited := testrand.NodeID()
+		exit(exited, satellites.ExitSucceeded)
+
+		exitFailed := testrand.NodeID()
+		exit(exitFailed, satellites.ExitFailed)
+
+		exitFailedDisqualified := testrand.NodeID()
+		exit(exitFailedDisqualified, satellites.ExitFailed)
+		disqualify(exitFailedDisqualified)
+
+		exitingDisqualified := testrand.NodeID()
+		exit(exitingDisqualified, satellites.Exiting)
+		disqualify(exitingDisqualified)
+
+		suspendedDisqualified := testrand.NodeID()
+		exit(suspendedDisqualified, satellites.Suspended)
+		disqualify(suspendedDisqualified)
+
+		reclaimable, err = db.(*storagenodedb.DB).ReclaimableSatellites(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []storj.NodeID{disqualified, exited, exitFailedDisqualified}, reclaimable)
+	})
+}
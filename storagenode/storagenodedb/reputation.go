@@ -6,6 +6,9 @@ package storagenodedb
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"io"
+	"time"
 
 	"github.com/zeebo/errs"
 
@@ -28,7 +31,7 @@ type reputationDB struct {
 func (db *reputationDB) Store(ctx context.Context, stats reputation.Stats) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	query := `INSERT OR REPLACE INTO reputation (
+	query := `INSERT INTO reputation (
 			satellite_id, 
 			uptime_success_count,
 			uptime_total_count,
@@ -42,7 +45,20 @@ func (db *reputationDB) Store(ctx context.Context, stats reputation.Stats) (err
 			audit_reputation_score,
 			disqualified,
 			updated_at
-		) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)`
+		) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)
+		ON CONFLICT (satellite_id) DO UPDATE SET
+			uptime_success_count = excluded.uptime_success_count,
+			uptime_total_count = excluded.uptime_total_count,
+			uptime_reputation_alpha = excluded.uptime_reputation_alpha,
+			uptime_reputation_beta = excluded.uptime_reputation_beta,
+			uptime_reputation_score = excluded.uptime_reputation_score,
+			audit_success_count = excluded.audit_success_count,
+			audit_total_count = excluded.audit_total_count,
+			audit_reputation_alpha = excluded.audit_reputation_alpha,
+			audit_reputation_beta = excluded.audit_reputation_beta,
+			audit_reputation_score = excluded.audit_reputation_score,
+			disqualified = excluded.disqualified,
+			updated_at = excluded.updated_at`
 
 	// ensure we insert utc
 	if stats.Disqualified != nil {
@@ -50,7 +66,19 @@ func (db *reputationDB) Store(ctx context.Context, stats reputation.Stats) (err
 		stats.Disqualified = &utc
 	}
 
-	_, err = db.ExecContext(ctx, query,
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ErrReputation.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, tx.Rollback())
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	_, err = tx.ExecContext(ctx, query,
 		stats.SatelliteID,
 		stats.Uptime.SuccessCount,
 		stats.Uptime.TotalCount,
@@ -65,6 +93,25 @@ func (db *reputationDB) Store(ctx context.Context, stats reputation.Stats) (err
 		stats.Disqualified,
 		stats.UpdatedAt.UTC(),
 	)
+	if err != nil {
+		return ErrReputation.Wrap(err)
+	}
+
+	// keep the scores, so that the change over time can be shown
+	_, err = tx.ExecContext(ctx, `INSERT INTO reputation_history (
+			satellite_id,
+			uptime_reputation_score,
+			audit_reputation_score,
+			updated_at
+		) VALUES(?,?,?,?)
+		ON CONFLICT (satellite_id, updated_at) DO UPDATE SET
+			uptime_reputation_score = excluded.uptime_reputation_score,
+			audit_reputation_score = excluded.audit_reputation_score`,
+		stats.SatelliteID,
+		stats.Uptime.Score,
+		stats.Audit.Score,
+		stats.UpdatedAt.UTC(),
+	)
 
 	return ErrReputation.Wrap(err)
 }
@@ -77,6 +124,7 @@ func (db *reputationDB) Get(ctx context.Context, satelliteID storj.NodeID) (_ *r
 		SatelliteID: satelliteID,
 	}
 
+	var disqualified sql.NullTime
 	row := db.QueryRowContext(ctx,
 		`SELECT uptime_success_count,
 			uptime_total_count,
@@ -105,13 +153,14 @@ func (db *reputationDB) Get(ctx context.Context, satelliteID storj.NodeID) (_ *r
 		&stats.Audit.Alpha,
 		&stats.Audit.Beta,
 		&stats.Audit.Score,
-		&stats.Disqualified,
+		&disqualified,
 		&stats.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
 		err = nil
 	}
+	stats.Disqualified = nullTimeToPtr(disqualified)
 
 	return &stats, ErrReputation.Wrap(err)
 }
@@ -137,7 +186,7 @@ func (db *reputationDB) All(ctx context.Context) (_ []reputation.Stats, err erro
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, ErrReputation.Wrap(err)
 	}
 
 	defer func() { err = errs.Combine(err, rows.Close()) }()
@@ -145,6 +194,7 @@ func (db *reputationDB) All(ctx context.Context) (_ []reputation.Stats, err erro
 	var statsList []reputation.Stats
 	for rows.Next() {
 		var stats reputation.Stats
+		var disqualified sql.NullTime
 
 		err := rows.Scan(&stats.SatelliteID,
 			&stats.Uptime.SuccessCount,
@@ -157,16 +207,139 @@ func (db *reputationDB) All(ctx context.Context) (_ []reputation.Stats, err erro
 			&stats.Audit.Alpha,
 			&stats.Audit.Beta,
 			&stats.Audit.Score,
-			&stats.Disqualified,
+			&disqualified,
 			&stats.UpdatedAt,
 		)
 
 		if err != nil {
 			return nil, ErrReputation.Wrap(err)
 		}
+		stats.Disqualified = nullTimeToPtr(disqualified)
 
 		statsList = append(statsList, stats)
 	}
 
-	return statsList, nil
+	return statsList, ErrReputation.Wrap(rows.Err())
+}
+
+// nullTimeToPtr converts a nullable timestamp column to a pointer, which is nil for NULL.
+func nullTimeToPtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// RecentChange returns how the reputation scores of the satellite changed since the provided time.
+// The change is computed against the latest scores recorded at or before since,
+// or against the earliest recorded scores when there are none that old.
+func (db *reputationDB) RecentChange(ctx context.Context, satelliteID storj.NodeID, since time.Time) (_ *reputation.Change, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	change := &reputation.Change{
+		SatelliteID: satelliteID,
+	}
+
+	var currentUptime, currentAudit float64
+	err = db.QueryRowContext(ctx,
+		`SELECT uptime_reputation_score, audit_reputation_score
+		FROM reputation WHERE satellite_id = ?`,
+		satelliteID,
+	).Scan(&currentUptime, &currentAudit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return change, nil
+		}
+		return nil, ErrReputation.Wrap(err)
+	}
+
+	var previousUptime, previousAudit float64
+	err = db.QueryRowContext(ctx,
+		`SELECT uptime_reputation_score, audit_reputation_score, updated_at
+		FROM reputation_history
+		WHERE satellite_id = ? AND updated_at <= ?
+		ORDER BY updated_at DESC
+		LIMIT 1`,
+		satelliteID, since.UTC(),
+	).Scan(&previousUptime, &previousAudit, &change.Since)
+	if err == sql.ErrNoRows {
+		err = db.QueryRowContext(ctx,
+			`SELECT uptime_reputation_score, audit_reputation_score, updated_at
+			FROM reputation_history
+			WHERE satellite_id = ?
+			ORDER BY updated_at ASC
+			LIMIT 1`,
+			satelliteID,
+		).Scan(&previousUptime, &previousAudit, &change.Since)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return change, nil
+		}
+		return nil, ErrReputation.Wrap(err)
+	}
+
+	change.Uptime = currentUptime - previousUptime
+	change.Audit = currentAudit - previousAudit
+
+	return change, nil
+}
+
+// DeleteHistoryBefore deletes the recorded scores older than cutoff and returns the number of deleted records.
+// The latest scores recorded before cutoff are kept for every satellite, so that RecentChange still computes
+// the change since any time after cutoff against the scores which were current at that time.
+func (db *reputationDB) DeleteHistoryBefore(ctx context.Context, cutoff time.Time) (_ int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := db.ExecContext(ctx, `
+		DELETE FROM reputation_history
+		WHERE updated_at < (
+			SELECT MAX(latest.updated_at)
+			FROM reputation_history AS latest
+			WHERE latest.satellite_id = reputation_history.satellite_id
+				AND latest.updated_at < ?
+		)`, cutoff.UTC())
+	if err != nil {
+		return 0, ErrReputation.Wrap(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, ErrReputation.Wrap(err)
+	}
+	return int(deleted), nil
+}
+
+// ExportHistory writes the recorded scores of the satellite to w as JSON lines, oldest first.
+// The records are written as they are read, so the history isn't held in memory.
+func (db *reputationDB) ExportHistory(ctx context.Context, satelliteID storj.NodeID, w io.Writer) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT uptime_reputation_score, audit_reputation_score, updated_at
+		FROM reputation_history
+		WHERE satellite_id = ?
+		ORDER BY updated_at`,
+		satelliteID,
+	)
+	if err != nil {
+		return ErrReputation.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	encoder := json.NewEncoder(w)
+	for rows.Next() {
+		record := reputation.HistoryRecord{
+			SatelliteID: satelliteID,
+		}
+		err := rows.Scan(&record.UptimeScore, &record.AuditScore, &record.UpdatedAt)
+		if err != nil {
+			return ErrReputation.Wrap(err)
+		}
+		if err := encoder.Encode(record); err != nil {
+			return ErrReputation.Wrap(err)
+		}
+	}
+
+	return ErrReputation.Wrap(rows.Err())
 }
@@ -0,0 +1,154 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync/atomic"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/storj/storage"
+)
+
+// ResetAllConfirmation has to be passed to ResetAll to confirm that all node data should be deleted.
+const ResetAllConfirmation = "delete all node data"
+
+// ResetAll deletes all data of the node, so that it can be set up again: the blobs are deleted,
+// all tables are emptied within a transaction per database, the space used is reset to zero and
+// the cached bandwidth usage is dropped. The schemas and the versions tables are kept, so no
+// migration has to run again.
+//
+// ResetAll only runs while the node is stopped, because a running node keeps its own space used
+// cache, which it would write back to the database. It fails without changing anything unless
+// confirmation is ResetAllConfirmation, while a node is running with the databases, or when
+// a database is degraded. When it fails part way, calling it again finishes the reset.
+func (db *DB) ResetAll(ctx context.Context, confirmation string) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if confirmation != ResetAllConfirmation {
+		return ErrDatabase.New("reset not confirmed, confirm with %q", ResetAllConfirmation)
+	}
+	if atomic.LoadInt32(&db.running) > 0 {
+		return ErrDatabase.New("can't reset the databases of a running node")
+	}
+	if degraded := db.DegradedDatabases(); len(degraded) > 0 {
+		return ErrDatabase.New("can't reset degraded databases: %s", strings.Join(degraded, ", "))
+	}
+
+	if err := db.deleteAllBlobs(ctx); err != nil {
+		return err
+	}
+
+	for dbName := range db.sqlDatabases {
+		if err := db.truncateTables(ctx, dbName); err != nil {
+			return err
+		}
+	}
+
+	db.bandwidthDB.resetCaches()
+
+	return ErrDatabase.Wrap(db.pieceSpaceUsedDB.Init(ctx))
+}
+
+// deleteAllBlobs deletes the blobs of all namespaces.
+func (db *DB) deleteAllBlobs(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	namespaces, err := db.pieces.ListNamespaces(ctx)
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+
+	for _, namespace := range namespaces {
+		// collect the blobs first, so that the blob directories are not modified while walking them
+		var refs []storage.BlobRef
+		err := db.pieces.WalkNamespace(ctx, namespace, func(info storage.BlobInfo) error {
+			refs = append(refs, info.BlobRef())
+			return ctx.Err()
+		})
+		if err != nil {
+			return ErrDatabase.Wrap(err)
+		}
+
+		for _, ref := range refs {
+			if err := db.pieces.Delete(ctx, ref); err != nil {
+				return ErrDatabase.Wrap(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// queryTableNames returns the names of all the tables of the database, except for the internal sqlite ones.
+// The tables of a postgres database are the ones in its schema.
+func queryTableNames(ctx context.Context, db queryer, postgres bool) (_ []string, err error) {
+	query := `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+	`
+	if postgres {
+		query = `
+			SELECT table_name FROM information_schema.tables
+			WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+		`
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// truncateTables deletes the rows of all the tables of the database, except for the versions table.
+func (db *DB) truncateTables(ctx context.Context, dbName string) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	tx, err := db.rawDatabaseFromName(dbName).BeginTx(ctx, nil)
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, tx.Rollback())
+			return
+		}
+		err = ErrDatabase.Wrap(tx.Commit())
+	}()
+
+	tables, err := queryTableNames(ctx, tx, db.isPostgres())
+	if err != nil {
+		return ErrDatabase.New("%s: %v", dbName, err)
+	}
+
+	for _, table := range tables {
+		if table == VersionTable {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "`+table+`"`); err != nil {
+			return ErrDatabase.New("%s: %v", dbName, err)
+		}
+	}
+	return nil
+}
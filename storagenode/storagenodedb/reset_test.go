@@ -0,0 +1,107 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/memory"
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storage"
+	"storj.io/storj/storagenode/pieces"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestResetAll(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+	require.NoError(t, db.PieceSpaceUsedDB().Init(ctx))
+
+	schema, err := db.SchemaReport(ctx)
+	require.NoError(t, err)
+
+	satelliteID := testrand.NodeID()
+	store := pieces.NewStore(log, db.Pieces(), db.V0PieceInfo(), db.PieceExpirationDB(), db.PieceSpaceUsedDB())
+
+	writer, err := store.Writer(ctx, satelliteID, testrand.PieceID())
+	require.NoError(t, err)
+	_, err = writer.Write(testrand.Bytes(memory.KiB))
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit(ctx, &pb.PieceHeader{}))
+
+	require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_PUT, 1024, time.Now()))
+	require.NoError(t, db.PieceSpaceUsedDB().UpdateTotal(ctx, 1024))
+	require.NoError(t, db.Contact().SetLastContact(ctx, satelliteID, time.Now()))
+
+	// fill the bandwidth caches
+	monthSummary, err := db.Bandwidth().MonthSummary(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1024, monthSummary)
+	cachedSummary, err := db.Bandwidth().CachedSummary(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1024, cachedSummary.Total())
+
+	// nothing is deleted without confirmation
+	require.Error(t, db.ResetAll(ctx, "yes"))
+
+	// nor while a node is running
+	done := db.MarkRunning()
+	require.Error(t, db.ResetAll(ctx, storagenodedb.ResetAllConfirmation))
+	done()
+
+	used, err := db.Pieces().SpaceUsed(ctx)
+	require.NoError(t, err)
+	require.NotZero(t, used)
+
+	require.NoError(t, db.ResetAll(ctx, storagenodedb.ResetAllConfirmation))
+
+	// the node is empty
+	namespaces, err := db.Pieces().ListNamespaces(ctx)
+	require.NoError(t, err)
+	for _, namespace := range namespaces {
+		err := db.Pieces().WalkNamespace(ctx, namespace, func(info storage.BlobInfo) error {
+			return errs.New("blob %x wasn't deleted", info.BlobRef().Key)
+		})
+		require.NoError(t, err)
+	}
+
+	usage, err := db.Bandwidth().Summary(ctx, time.Time{}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, usage.Total())
+
+	monthSummary, err = db.Bandwidth().MonthSummary(ctx)
+	require.NoError(t, err)
+	require.Zero(t, monthSummary)
+	cachedSummary, err = db.Bandwidth().CachedSummary(ctx)
+	require.NoError(t, err)
+	require.Zero(t, cachedSummary.Total())
+
+	total, err := db.PieceSpaceUsedDB().GetTotal(ctx)
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	lastContact, err := db.Contact().GetLastContact(ctx, satelliteID)
+	require.NoError(t, err)
+	require.True(t, lastContact.IsZero())
+
+	// the schema is intact
+	after, err := db.SchemaReport(ctx)
+	require.NoError(t, err)
+	require.Equal(t, schema, after)
+	require.NoError(t, db.CreateTables(ctx))
+	require.NoError(t, db.Bandwidth().Add(ctx, satelliteID, pb.PieceAction_PUT, 1024, time.Now()))
+}
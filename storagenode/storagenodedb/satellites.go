@@ -4,7 +4,14 @@
 package storagenodedb
 
 import (
+	"context"
+	"database/sql"
+	"time"
+
 	"github.com/zeebo/errs"
+
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storagenode/satellites"
 )
 
 // ErrSatellitesDB represents errors from the satellites database.
@@ -13,7 +20,279 @@ var ErrSatellitesDB = errs.Class("satellitesdb error")
 // SatellitesDBName represents the database name.
 const SatellitesDBName = "satellites"
 
-// reputation works with node reputation DB
+// satellitesDB works with the satellites and graceful exit progress DB
 type satellitesDB struct {
 	migratableDB
 }
+
+// InitiateGracefulExit updates the database to reflect the beginning of a graceful exit
+func (db *satellitesDB) InitiateGracefulExit(ctx context.Context, satelliteID storj.NodeID, initiatedAt time.Time, startingDiskUsage int64) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ErrSatellitesDB.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, tx.Rollback())
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// keep the address and the time the satellite was added when it's already known
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO satellites (node_id, address, added_at, status)
+		VALUES (?, '', ?, ?)
+		ON CONFLICT (node_id) DO UPDATE SET status = excluded.status
+	`, satelliteID, initiatedAt.UTC(), satellites.Exiting)
+	if err != nil {
+		return ErrSatellitesDB.Wrap(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO satellite_exit_progress (satellite_id, initiated_at, starting_disk_usage, bytes_deleted)
+		VALUES (?, ?, ?, 0)
+	`, satelliteID, initiatedAt.UTC(), startingDiskUsage)
+	return ErrSatellitesDB.Wrap(err)
+}
+
+// UpdateGracefulExit increments the total bytes deleted during a graceful exit
+func (db *satellitesDB) UpdateGracefulExit(ctx context.Context, satelliteID storj.NodeID, bytesDeleted int64) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	_, err = db.ExecContext(ctx, `
+		UPDATE satellite_exit_progress
+		SET bytes_deleted = bytes_deleted + ?
+		WHERE satellite_id = ?
+	`, bytesDeleted, satelliteID)
+	return ErrSatellitesDB.Wrap(err)
+}
+
+// SuspendGracefulExit temporarily halts an ongoing graceful exit, keeping its progress
+func (db *satellitesDB) SuspendGracefulExit(ctx context.Context, satelliteID storj.NodeID) (err error) {
+	defer mon.Task()(&ctx)(&err)
+	return db.changeExitStatus(ctx, satelliteID, satellites.Exiting, satellites.Suspended)
+}
+
+// ResumeGracefulExit continues a suspended graceful exit
+func (db *satellitesDB) ResumeGracefulExit(ctx context.Context, satelliteID storj.NodeID) (err error) {
+	defer mon.Task()(&ctx)(&err)
+	return db.changeExitStatus(ctx, satelliteID, satellites.Suspended, satellites.Exiting)
+}
+
+// changeExitStatus changes the status of the satellite from one graceful exit status to another.
+// It fails with satellites.ErrExitStatus when the satellite doesn't have the from status.
+func (db *satellitesDB) changeExitStatus(ctx context.Context, satelliteID storj.NodeID, from, to satellites.Status) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := db.ExecContext(ctx, `
+		UPDATE satellites SET status = ? WHERE node_id = ? AND status = ?
+	`, to, satelliteID, from)
+	if err != nil {
+		return ErrSatellitesDB.Wrap(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return ErrSatellitesDB.Wrap(err)
+	}
+	if affected == 0 {
+		return satellites.ErrExitStatus.New("satellite %v doesn't have status %d", satelliteID, from)
+	}
+	return nil
+}
+
+// CompleteGracefulExit updates the database when a graceful exit is completed or failed
+func (db *satellitesDB) CompleteGracefulExit(ctx context.Context, satelliteID storj.NodeID, finishedAt time.Time, exitStatus satellites.Status, completionReceipt []byte) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return ErrSatellitesDB.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, tx.Rollback())
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE satellites SET status = ? WHERE node_id = ?
+	`, exitStatus, satelliteID)
+	if err != nil {
+		return ErrSatellitesDB.Wrap(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE satellite_exit_progress
+		SET finished_at = ?, completion_receipt = ?
+		WHERE satellite_id = ?
+	`, finishedAt.UTC(), completionReceipt, satelliteID)
+	return ErrSatellitesDB.Wrap(err)
+}
+
+// ListGracefulExits lists all graceful exit records
+func (db *satellitesDB) ListGracefulExits(ctx context.Context) (exitList []satellites.ExitProgress, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT satellite_id, initiated_at, finished_at, starting_disk_usage, bytes_deleted, completion_receipt, status
+		FROM satellite_exit_progress
+		INNER JOIN satellites ON satellite_exit_progress.satellite_id = satellites.node_id
+	`)
+	if err != nil {
+		return nil, ErrSatellitesDB.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var progress satellites.ExitProgress
+		err := rows.Scan(
+			&progress.SatelliteID,
+			&progress.InitiatedAt,
+			&progress.FinishedAt,
+			&progress.StartingDiskUsage,
+			&progress.BytesDeleted,
+			&progress.CompletionReceipt,
+			&progress.Status,
+		)
+		if err != nil {
+			return nil, ErrSatellitesDB.Wrap(err)
+		}
+		exitList = append(exitList, progress)
+	}
+
+	return exitList, ErrSatellitesDB.Wrap(rows.Err())
+}
+
+// SatelliteExitStatuses lists all known satellites with their status and graceful exit progress
+func (db *satellitesDB) SatelliteExitStatuses(ctx context.Context) (statuses []satellites.SatelliteExitStatus, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT satellites.node_id, satellites.added_at, satellites.status,
+			satellite_exit_progress.satellite_id, initiated_at, finished_at,
+			starting_disk_usage, bytes_deleted, completion_receipt
+		FROM satellites
+		LEFT JOIN satellite_exit_progress ON satellites.node_id = satellite_exit_progress.satellite_id
+		ORDER BY satellites.added_at, satellites.node_id
+	`)
+	if err != nil {
+		return nil, ErrSatellitesDB.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var status satellites.SatelliteExitStatus
+		var progressSatelliteID *storj.NodeID
+		var progress satellites.ExitProgress
+		var startingDiskUsage, bytesDeleted *int64
+		err := rows.Scan(
+			&status.SatelliteID,
+			&status.AddedAt,
+			&status.Status,
+			&progressSatelliteID,
+			&progress.InitiatedAt,
+			&progress.FinishedAt,
+			&startingDiskUsage,
+			&bytesDeleted,
+			&progress.CompletionReceipt,
+		)
+		if err != nil {
+			return nil, ErrSatellitesDB.Wrap(err)
+		}
+
+		if progressSatelliteID != nil {
+			progress.SatelliteID = *progressSatelliteID
+			progress.Status = status.Status
+			if startingDiskUsage != nil {
+				progress.StartingDiskUsage = *startingDiskUsage
+			}
+			if bytesDeleted != nil {
+				progress.BytesDeleted = *bytesDeleted
+			}
+			status.Progress = &progress
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, ErrSatellitesDB.Wrap(rows.Err())
+}
+
+// RecordExitRequest persists a request from the satellite to begin a graceful exit
+func (db *satellitesDB) RecordExitRequest(ctx context.Context, satelliteID storj.NodeID, requestedAt time.Time) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO satellite_exit_requests (satellite_id, requested_at)
+		VALUES (?, ?)
+		ON CONFLICT (satellite_id) DO NOTHING
+	`, satelliteID, requestedAt.UTC())
+	return ErrSatellitesDB.Wrap(err)
+}
+
+// PendingExitRequests lists exit requests for satellites where the graceful exit hasn't been initiated yet
+func (db *satellitesDB) PendingExitRequests(ctx context.Context) (requests []satellites.ExitRequest, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT satellite_id, requested_at
+		FROM satellite_exit_requests
+		WHERE satellite_id NOT IN (SELECT satellite_id FROM satellite_exit_progress)
+		ORDER BY requested_at
+	`)
+	if err != nil {
+		return nil, ErrSatellitesDB.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var request satellites.ExitRequest
+		if err := rows.Scan(&request.SatelliteID, &request.RequestedAt); err != nil {
+			return nil, ErrSatellitesDB.Wrap(err)
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, ErrSatellitesDB.Wrap(rows.Err())
+}
+
+// DeleteExitRequest removes the exit request of the satellite
+func (db *satellitesDB) DeleteExitRequest(ctx context.Context, satelliteID storj.NodeID) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	_, err = db.ExecContext(ctx, `
+		DELETE FROM satellite_exit_requests WHERE satellite_id = ?
+	`, satelliteID)
+	return ErrSatellitesDB.Wrap(err)
+}
+
+// SetLastContact stores the time of the last successful check-in with the satellite
+func (db *satellitesDB) SetLastContact(ctx context.Context, satelliteID storj.NodeID, at time.Time) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO satellite_last_contact (satellite_id, last_contact)
+		VALUES (?, ?)
+		ON CONFLICT (satellite_id) DO UPDATE SET last_contact = excluded.last_contact
+	`, satelliteID, at.UTC())
+	return ErrSatellitesDB.Wrap(err)
+}
+
+// GetLastContact returns the time of the last successful check-in with the satellite
+func (db *satellitesDB) GetLastContact(ctx context.Context, satelliteID storj.NodeID) (_ time.Time, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var lastContact time.Time
+	err = db.QueryRowContext(ctx, `
+		SELECT last_contact FROM satellite_last_contact WHERE satellite_id = ?
+	`, satelliteID).Scan(&lastContact)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return lastContact, ErrSatellitesDB.Wrap(err)
+}
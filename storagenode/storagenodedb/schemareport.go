@@ -0,0 +1,77 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaReport returns the schema version of every database, keyed by database filename.
+// Every database records the migration steps applied to it in its own versions table,
+// so the reported version is the latest step which touched that database. Databases which
+// were split off the info database and haven't been migrated since report the version of
+// the info database. Databases without any applied step report -1 and degraded databases
+// are left out.
+func (db *DB) SchemaReport(ctx context.Context) (_ map[string]int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	infoVersion, err := db.schemaVersion(ctx, DeprecatedInfoDBName)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make(map[string]int, len(db.sqlDatabases))
+	for dbName := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+
+		version, err := db.schemaVersion(ctx, dbName)
+		if err != nil {
+			return nil, err
+		}
+		if version < 0 {
+			version = infoVersion
+		}
+		report[db.filenameFromDBName(dbName)] = version
+	}
+	return report, nil
+}
+
+// schemaVersion returns the latest version recorded in the versions table of the database,
+// or -1 when no migration has been applied to it yet.
+func (db *DB) schemaVersion(ctx context.Context, dbName string) (_ int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	exists, err := db.hasVersionTable(ctx, dbName)
+	if err != nil || !exists {
+		return -1, err
+	}
+
+	var version sql.NullInt64
+	err = db.rawDatabaseFromName(dbName).QueryRowContext(ctx, `SELECT MAX(version) FROM `+VersionTable).Scan(&version)
+	if err != nil {
+		return -1, ErrDatabase.New("%s: %v", dbName, err)
+	}
+	if !version.Valid {
+		return -1, nil
+	}
+	return int(version.Int64), nil
+}
+
+// hasVersionTable returns whether the database has a versions table.
+func (db *DB) hasVersionTable(ctx context.Context, dbName string) (bool, error) {
+	query := `SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if db.isPostgres() {
+		query = `SELECT count(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	}
+
+	var tables int
+	err := db.rawDatabaseFromName(dbName).QueryRowContext(ctx, query, VersionTable).Scan(&tables)
+	if err != nil {
+		return false, ErrDatabase.New("%s: %v", dbName, err)
+	}
+	return tables > 0, nil
+}
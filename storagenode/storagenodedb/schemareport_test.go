@@ -0,0 +1,59 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/migrate"
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestSchemaReport(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db, err := storagenodedb.New(log, storagenodedbtest.Config(ctx.Dir("storage")))
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	// nothing has been migrated yet
+	report, err := db.SchemaReport(ctx)
+	require.NoError(t, err)
+	require.Len(t, report, len(db.RawDatabases()))
+	for filename, version := range report {
+		require.Equal(t, -1, version, filename)
+	}
+
+	require.NoError(t, db.CreateTables(ctx))
+
+	// every database reports the latest step which was applied to it,
+	// the ones without own steps since the split report the info database version
+	versions := map[migrate.DB]int{}
+	for _, step := range db.Migration(ctx).Steps {
+		versions[step.DB] = step.Version
+	}
+	rawDatabases := db.RawDatabases()
+	infoVersion := versions[rawDatabases[storagenodedb.DeprecatedInfoDBName].(migrate.DB)]
+
+	expected := map[string]int{}
+	for dbName, rawDB := range rawDatabases {
+		version, ok := versions[rawDB.(migrate.DB)]
+		if !ok {
+			version = infoVersion
+		}
+		expected[dbName+".db"] = version
+	}
+
+	report, err = db.SchemaReport(ctx)
+	require.NoError(t, err)
+	require.Equal(t, expected, report)
+}
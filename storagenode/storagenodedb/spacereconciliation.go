@@ -0,0 +1,60 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// SpaceReconciliation returns the different views of the space used by pieces side by side,
+// to diagnose discrepancies between the dashboard and the disk:
+//
+//   - cached is the total recorded in the piece_space_used cache, it excludes piece headers,
+//   - actualBlob is the size of all blobs found by walking the blob store,
+//   - fsUsed is the size of all files in the blob store directories, including temporary
+//     and garbage files that are not blobs.
+//
+// It walks the whole blob store twice, so it may take a long time on large nodes; the
+// walk stops when ctx is canceled.
+func (db *DB) SpaceReconciliation(ctx context.Context) (cached int64, actualBlob int64, fsUsed int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	cached, err = db.pieceSpaceUsedDB.GetTotal(ctx)
+	if err != nil {
+		return 0, 0, 0, ErrDatabase.Wrap(err)
+	}
+
+	actualBlob, err = db.pieces.SpaceUsed(ctx)
+	if err != nil {
+		return 0, 0, 0, ErrDatabase.Wrap(err)
+	}
+
+	// the pieces directory may also contain the databases, only look at the blob store layout
+	piecesDir := db.pieces.Dir().Path()
+	for _, subdir := range []string{"blobs", "temp", "garbage"} {
+		err = filepath.Walk(filepath.Join(piecesDir, subdir), func(path string, info os.FileInfo, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				if os.IsNotExist(err) {
+					// removed while walking or never created
+					return nil
+				}
+				return err
+			}
+			if info.Mode().IsRegular() {
+				fsUsed += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, 0, ErrDatabase.Wrap(err)
+		}
+	}
+
+	return cached, actualBlob, fsUsed, nil
+}
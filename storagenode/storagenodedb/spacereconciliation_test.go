@@ -0,0 +1,59 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/storage"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestSpaceReconciliation(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	storageDir := ctx.Dir("storage")
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(storageDir))
+	defer ctx.Check(db.Close)
+	require.NoError(t, db.PieceSpaceUsedDB().Init(ctx))
+	require.NoError(t, db.PieceSpaceUsedDB().UpdateTotal(ctx, 100))
+
+	data := testrand.Bytes(1024)
+	writer, err := db.Pieces().Create(ctx, storage.BlobRef{
+		Namespace: testrand.NodeID().Bytes(),
+		Key:       testrand.PieceID().Bytes(),
+	}, int64(len(data)))
+	require.NoError(t, err)
+	_, err = writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Commit(ctx))
+
+	// leftover files that are not blobs only show up in the filesystem usage
+	garbageDir := filepath.Join(storageDir, "garbage")
+	require.NoError(t, os.MkdirAll(garbageDir, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(garbageDir, "leftover"), make([]byte, 512), 0600))
+
+	cached, actualBlob, fsUsed, err := db.SpaceReconciliation(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), cached)
+	require.Equal(t, int64(len(data)), actualBlob)
+	require.Equal(t, int64(len(data)+512), fsUsed)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, _, err = db.SpaceReconciliation(canceled)
+	require.Error(t, err)
+}
@@ -6,11 +6,17 @@ package storagenodedbtest
 // This package should be referenced only in test files!
 
 import (
+	"context"
+	"database/sql"
 	"path/filepath"
 	"testing"
 
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 
+	"storj.io/storj/internal/dbutil/pgutil"
+	"storj.io/storj/internal/dbutil/pgutil/pgtest"
 	"storj.io/storj/internal/testcontext"
 	"storj.io/storj/storagenode"
 	"storj.io/storj/storagenode/storagenodedb"
@@ -26,25 +32,88 @@ func Run(t *testing.T, test func(t *testing.T, db storagenode.DB)) {
 
 		log := zaptest.NewLogger(t)
 
-		storageDir := ctx.Dir("storage")
-		cfg := storagenodedb.Config{
-			Storage: storageDir,
-			Info:    filepath.Join(storageDir, "piecestore.db"),
-			Info2:   filepath.Join(storageDir, "info.db"),
-			Pieces:  storageDir,
-		}
-
-		db, err := storagenodedb.New(log, cfg)
-		if err != nil {
-			t.Fatal(err)
-		}
+		db := Open(t, ctx, log, Config(ctx.Dir("storage")))
 		defer ctx.Check(db.Close)
 
-		err = db.CreateTables(ctx)
-		if err != nil {
-			t.Fatal(err)
+		test(t, db)
+	})
+
+	t.Run("Postgres", func(t *testing.T) {
+		t.Parallel()
+		if *pgtest.ConnStr == "" {
+			t.Skipf("postgres flag missing, example:\n-postgres-test-db=%s", pgtest.DefaultConnStr)
 		}
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		log := zaptest.NewLogger(t)
+
+		config := Config(ctx.Dir("storage"))
+		config.Driver = storagenodedb.DriverPostgres
+		config.DSN = *pgtest.ConnStr
+		config.DatabasePrefix = "storagenode_" + pgutil.CreateRandomTestingSchemaName(8) + "_"
+		defer ctx.Check(func() error { return dropSchemas(*pgtest.ConnStr, config.DatabasePrefix) })
+
+		db := Open(t, ctx, log, config)
+		defer ctx.Check(db.Close)
 
 		test(t, db)
 	})
 }
+
+// dropSchemas drops the postgres schemas of the databases with the prefix.
+func dropSchemas(connstr, prefix string) (err error) {
+	db, err := sql.Open("postgres", connstr)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errs.Combine(err, db.Close()) }()
+
+	rows, err := db.Query(`SELECT schema_name FROM information_schema.schemata WHERE substr(schema_name, 1, $1) = $2`, len(prefix), prefix)
+	if err != nil {
+		return err
+	}
+	var schemas []string
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return errs.Combine(err, rows.Close())
+		}
+		schemas = append(schemas, schema)
+	}
+	if err := errs.Combine(rows.Err(), rows.Close()); err != nil {
+		return err
+	}
+
+	var group errs.Group
+	for _, schema := range schemas {
+		group.Add(pgutil.DropSchema(db, schema))
+	}
+	return group.Err()
+}
+
+// Config returns the configuration of the databases and pieces stored in dir, as used by Run.
+func Config(dir string) storagenodedb.Config {
+	return storagenodedb.Config{
+		Storage: dir,
+		Info:    filepath.Join(dir, "piecestore.db"),
+		Info2:   filepath.Join(dir, "info.db"),
+		Pieces:  dir,
+	}
+}
+
+// Open opens the databases of the config and creates their tables, for the tests which
+// need a custom config or the methods of storagenodedb.DB. The caller closes the databases.
+func Open(t testing.TB, ctx context.Context, log *zap.Logger, config storagenodedb.Config) *storagenodedb.DB {
+	db, err := storagenodedb.New(log, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.CreateTables(ctx)
+	if err != nil {
+		t.Fatal(errs.Combine(err, db.Close()))
+	}
+
+	return db
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedbtest
+
+// This package should be referenced only in test files!
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/internal/dbutil/dbschema"
+	"storj.io/storj/internal/dbutil/sqliteutil"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/testdata"
+)
+
+// ExpectedSchemas returns the schemas of the databases after all the migrations have run,
+// keyed by database name. The versions tables are not part of the schemas.
+func ExpectedSchemas() (map[string]*dbschema.Schema, error) {
+	latest := testdata.States.List[len(testdata.States.List)-1]
+
+	snapshot, err := testdata.LoadMultiDBSnapshot(latest)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[string]*dbschema.Schema, len(snapshot.DBSnapshots))
+	for dbName, dbSnapshot := range snapshot.DBSnapshots {
+		schemas[dbName] = normalizeSchema(dbSnapshot.Schema)
+	}
+	return schemas, nil
+}
+
+// AssertSchema checks that every database of db has the schema which the migrations produce.
+// It's meant for tests of code which sets up or modifies the storage node databases itself.
+func AssertSchema(t testing.TB, db *storagenodedb.DB) {
+	expected, err := ExpectedSchemas()
+	require.NoError(t, err)
+
+	rawDBs := db.RawDatabases()
+	require.Len(t, rawDBs, len(expected))
+
+	for dbName, rawDB := range rawDBs {
+		expectedSchema, ok := expected[dbName]
+		require.True(t, ok, "unexpected database %q", dbName)
+
+		schema, err := sqliteutil.QuerySchema(rawDB.GetDB())
+		require.NoError(t, err, dbName)
+
+		require.Equal(t, expectedSchema, normalizeSchema(schema), dbName)
+	}
+}
+
+// normalizeSchema drops the versions table and replaces empty tables and indexes with nil,
+// which is semantically the same, so that schemas can be compared.
+func normalizeSchema(schema *dbschema.Schema) *dbschema.Schema {
+	schema.DropTable(storagenodedb.VersionTable)
+	if len(schema.Tables) == 0 {
+		schema.Tables = nil
+	}
+	if len(schema.Indexes) == 0 {
+		schema.Indexes = nil
+	}
+	return schema
+}
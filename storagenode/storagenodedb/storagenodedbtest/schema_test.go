@@ -0,0 +1,32 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedbtest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestAssertSchema(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+	storagenodedbtest.AssertSchema(t, db)
+
+	// modifying the returned schemas must not affect later assertions
+	expected, err := storagenodedbtest.ExpectedSchemas()
+	require.NoError(t, err)
+	expected[storagenodedb.BandwidthDBName].DropTable("bandwidth_usage")
+	storagenodedbtest.AssertSchema(t, db)
+}
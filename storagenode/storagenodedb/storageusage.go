@@ -6,8 +6,10 @@ package storagenodedb
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
+	"github.com/mattn/go-sqlite3"
 	"github.com/zeebo/errs"
 
 	"storj.io/storj/pkg/storj"
@@ -30,12 +32,21 @@ func (db *storageUsageDB) Store(ctx context.Context, stamps []storageusage.Stamp
 		return nil
 	}
 
-	query := `INSERT OR REPLACE INTO storage_usage(satellite_id, at_rest_total, interval_start) 
-			VALUES(?,?,?)`
+	query := `INSERT INTO storage_usage(satellite_id, at_rest_total, interval_start, interval_end)
+			VALUES(?,?,?,?)
+			ON CONFLICT (satellite_id, interval_start) DO UPDATE SET
+				at_rest_total = excluded.at_rest_total,
+				interval_end = excluded.interval_end`
 
 	return db.withTx(ctx, func(tx *sql.Tx) error {
 		for _, stamp := range stamps {
-			_, err = tx.ExecContext(ctx, query, stamp.SatelliteID, stamp.AtRestTotal, stamp.IntervalStart.UTC())
+			var intervalEnd *time.Time
+			if !stamp.IntervalEnd.IsZero() {
+				utc := stamp.IntervalEnd.UTC()
+				intervalEnd = &utc
+			}
+
+			_, err = tx.ExecContext(ctx, query, stamp.SatelliteID, stamp.AtRestTotal, stamp.IntervalStart.UTC(), intervalEnd)
 
 			if err != nil {
 				return err
@@ -47,18 +58,20 @@ func (db *storageUsageDB) Store(ctx context.Context, stamps []storageusage.Stamp
 }
 
 // GetDaily returns daily storage usage stamps for particular satellite
-// for provided time range
+// for provided time range, each covering the interval from the earliest
+// start to the latest end of the day
 func (db *storageUsageDB) GetDaily(ctx context.Context, satelliteID storj.NodeID, from, to time.Time) (_ []storageusage.Stamp, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	query := `SELECT satellite_id,
 					SUM(at_rest_total),
-					interval_start
+					MIN(interval_start),
+					MAX(interval_end)
 				FROM storage_usage
 				WHERE satellite_id = ?
 				AND ? <= interval_start AND interval_start <= ?
-				GROUP BY DATE(interval_start)
-				ORDER BY interval_start`
+				GROUP BY satellite_id, DATE(interval_start)
+				ORDER BY MIN(interval_start)`
 
 	rows, err := db.QueryContext(ctx, query, satelliteID, from.UTC(), to.UTC())
 	if err != nil {
@@ -73,9 +86,9 @@ func (db *storageUsageDB) GetDaily(ctx context.Context, satelliteID storj.NodeID
 	for rows.Next() {
 		var satellite storj.NodeID
 		var atRestTotal float64
-		var intervalStart time.Time
+		var intervalStart, intervalEnd aggregateTime
 
-		err = rows.Scan(&satellite, &atRestTotal, &intervalStart)
+		err = rows.Scan(&satellite, &atRestTotal, &intervalStart, &intervalEnd)
 		if err != nil {
 			return nil, err
 		}
@@ -83,7 +96,8 @@ func (db *storageUsageDB) GetDaily(ctx context.Context, satelliteID storj.NodeID
 		stamps = append(stamps, storageusage.Stamp{
 			SatelliteID:   satellite,
 			AtRestTotal:   atRestTotal,
-			IntervalStart: intervalStart,
+			IntervalStart: intervalStart.Time,
+			IntervalEnd:   intervalEnd.Time,
 		})
 	}
 
@@ -91,15 +105,16 @@ func (db *storageUsageDB) GetDaily(ctx context.Context, satelliteID storj.NodeID
 }
 
 // GetDailyTotal returns daily storage usage stamps summed across all known satellites
-// for provided time range
+// for provided time range, each covering the interval from the earliest
+// start to the latest end of the day
 func (db *storageUsageDB) GetDailyTotal(ctx context.Context, from, to time.Time) (_ []storageusage.Stamp, err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	query := `SELECT SUM(at_rest_total), interval_start 
+	query := `SELECT SUM(at_rest_total), MIN(interval_start), MAX(interval_end)
 				FROM storage_usage
 				WHERE ? <= interval_start AND interval_start <= ?
 				GROUP BY DATE(interval_start)
-				ORDER BY interval_start`
+				ORDER BY MIN(interval_start)`
 
 	rows, err := db.QueryContext(ctx, query, from.UTC(), to.UTC())
 	if err != nil {
@@ -113,16 +128,17 @@ func (db *storageUsageDB) GetDailyTotal(ctx context.Context, from, to time.Time)
 	var stamps []storageusage.Stamp
 	for rows.Next() {
 		var atRestTotal float64
-		var intervalStart time.Time
+		var intervalStart, intervalEnd aggregateTime
 
-		err = rows.Scan(&atRestTotal, &intervalStart)
+		err = rows.Scan(&atRestTotal, &intervalStart, &intervalEnd)
 		if err != nil {
 			return nil, err
 		}
 
 		stamps = append(stamps, storageusage.Stamp{
 			AtRestTotal:   atRestTotal,
-			IntervalStart: intervalStart,
+			IntervalStart: intervalStart.Time,
+			IntervalEnd:   intervalEnd.Time,
 		})
 	}
 
@@ -156,6 +172,22 @@ func (db *storageUsageDB) SatelliteSummary(ctx context.Context, satelliteID stor
 	return summary.Float64, err
 }
 
+// DeleteBefore deletes the storage usage stamps with an interval start before cutoff and returns the number of deleted stamps.
+func (db *storageUsageDB) DeleteBefore(ctx context.Context, cutoff time.Time) (_ int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := db.ExecContext(ctx, `DELETE FROM storage_usage WHERE interval_start < ?`, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
+
 // withTx is a helper method which executes callback in transaction scope
 func (db *storageUsageDB) withTx(ctx context.Context, cb func(tx *sql.Tx) error) error {
 	tx, err := db.Begin()
@@ -174,3 +206,39 @@ func (db *storageUsageDB) withTx(ctx context.Context, cb func(tx *sql.Tx) error)
 
 	return cb(tx)
 }
+
+// aggregateTime scans a timestamp returned by an aggregate function, which sqlite returns as text,
+// because the result doesn't carry the declared type of the column. NULL scans as the zero time.
+type aggregateTime struct {
+	Time time.Time
+}
+
+// Scan implements sql.Scanner.
+func (t *aggregateTime) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = value
+		return nil
+	case []byte:
+		return t.parse(string(value))
+	case string:
+		return t.parse(value)
+	default:
+		return errs.New("unsupported timestamp type %T", value)
+	}
+}
+
+// parse parses the timestamp the way the sqlite3 driver does for TIMESTAMP columns.
+func (t *aggregateTime) parse(s string) error {
+	s = strings.TrimSuffix(s, "Z")
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if parsed, err := time.ParseInLocation(format, s, time.UTC); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return errs.New("invalid timestamp %q", s)
+}
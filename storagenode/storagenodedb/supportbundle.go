@@ -0,0 +1,168 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/storj/pkg/storj"
+)
+
+// supportBundleFailures is the number of the latest piece deletion failures in the support bundle.
+const supportBundleFailures = 100
+
+// SupportBundle writes a zip archive with the diagnostics of the databases to w, so that operators
+// can send a single file when asking for support. The archive contains JSON documents with the
+// schema report, the disk usage, the table sizes and row counts, the integrity check results and
+// the recent errors, i.e. the databases which failed to open and the latest piece deletion failures.
+//
+// It doesn't contain any rows of the databases. The piece IDs of the deletion failures are replaced
+// with a hash, so they can be matched against the logs of the node but don't reveal the pieces.
+// Degraded databases are left out of the diagnostics except for their error. postgres databases
+// can't be checked for integrity, so the integrity results are left empty for them.
+func (db *DB) SupportBundle(ctx context.Context, w io.Writer) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	schema, err := db.SchemaReport(ctx)
+	if err != nil {
+		return err
+	}
+	diskUsage, err := db.DiskUsage(ctx)
+	if err != nil {
+		return err
+	}
+	tableSizes, err := db.TableSizes(ctx)
+	if err != nil {
+		return err
+	}
+	rowCounts, err := db.rowCounts(ctx)
+	if err != nil {
+		return err
+	}
+	problems, err := db.IntegrityCheck(ctx)
+	if err != nil && !ErrUnsupported.Has(err) {
+		return err
+	}
+	integrity := make(map[string]string, len(problems))
+	for filename, problem := range problems {
+		integrity[filename] = problem.Error()
+	}
+	recentErrors, err := db.recentErrors(ctx)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	for _, document := range []struct {
+		name    string
+		content interface{}
+	}{
+		{"schema.json", schema},
+		{"disk_usage.json", diskUsage},
+		{"table_sizes.json", tableSizes},
+		{"row_counts.json", rowCounts},
+		{"integrity.json", integrity},
+		{"errors.json", recentErrors},
+	} {
+		if err := writeBundleDocument(archive, document.name, document.content); err != nil {
+			return ErrDatabase.Wrap(errs.Combine(err, archive.Close()))
+		}
+	}
+	return ErrDatabase.Wrap(archive.Close())
+}
+
+// bundleErrors are the recent errors in the support bundle.
+type bundleErrors struct {
+	Degraded         map[string]string       `json:"degraded"`
+	DeletionFailures []bundleDeletionFailure `json:"deletion_failures"`
+}
+
+// bundleDeletionFailure is a piece deletion failure with the piece ID redacted.
+type bundleDeletionFailure struct {
+	SatelliteID      storj.NodeID `json:"satellite_id"`
+	PieceIDHash      string       `json:"piece_id_hash"`
+	DeletionFailedAt time.Time    `json:"deletion_failed_at"`
+}
+
+// recentErrors returns the errors of the degraded databases and the latest piece deletion failures.
+func (db *DB) recentErrors(ctx context.Context) (_ bundleErrors, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	recent := bundleErrors{
+		Degraded:         make(map[string]string, len(db.degraded)),
+		DeletionFailures: []bundleDeletionFailure{},
+	}
+	for dbName, err := range db.degraded {
+		recent.Degraded[db.filenameFromDBName(dbName)] = err.Error()
+	}
+
+	if _, degraded := db.degraded[PieceExpirationDBName]; degraded {
+		return recent, nil
+	}
+	failures, err := db.pieceExpirationDB.ListDeletionFailed(ctx, supportBundleFailures)
+	if err != nil {
+		return recent, err
+	}
+	for _, failure := range failures {
+		recent.DeletionFailures = append(recent.DeletionFailures, bundleDeletionFailure{
+			SatelliteID:      failure.SatelliteID,
+			PieceIDHash:      redactPieceID(failure.PieceID),
+			DeletionFailedAt: failure.DeletionFailedAt,
+		})
+	}
+	return recent, nil
+}
+
+// rowCounts returns the number of rows of every table, keyed like TableSizes.
+func (db *DB) rowCounts(ctx context.Context) (_ map[string]int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	counts := make(map[string]int64)
+	for dbName := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+
+		rawDB := db.rawDatabaseFromName(dbName)
+		tables, err := queryTableNames(ctx, rawDB, db.isPostgres())
+		if err != nil {
+			return nil, ErrDatabase.New("%s: %v", dbName, err)
+		}
+
+		for _, table := range tables {
+			var count int64
+			err := rawDB.QueryRowContext(ctx, `SELECT count(*) FROM "`+table+`"`).Scan(&count)
+			if err != nil {
+				return nil, ErrDatabase.New("%s: %s: %v", dbName, table, err)
+			}
+			counts[db.filenameFromDBName(dbName)+"/"+table] = count
+		}
+	}
+	return counts, nil
+}
+
+// redactPieceID returns the first 16 bytes of the SHA-256 hash of the piece ID, hex encoded.
+func redactPieceID(pieceID storj.PieceID) string {
+	hash := sha256.Sum256(pieceID.Bytes())
+	return hex.EncodeToString(hash[:16])
+}
+
+// writeBundleDocument writes content as an indented JSON document to the archive.
+func writeBundleDocument(archive *zip.Writer, name string, content interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(content)
+}
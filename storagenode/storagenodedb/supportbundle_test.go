@@ -0,0 +1,84 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storagenode/orders"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestSupportBundle(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	satelliteID, pieceID := testrand.NodeID(), testrand.PieceID()
+	require.NoError(t, db.PieceExpirationDB().SetExpiration(ctx, satelliteID, pieceID, time.Now()))
+	require.NoError(t, db.PieceExpirationDB().DeleteFailed(ctx, satelliteID, pieceID, time.Now()))
+
+	serialNumber := testrand.SerialNumber()
+	require.NoError(t, db.Orders().Enqueue(ctx, &orders.Info{
+		Limit: &pb.OrderLimit{
+			SerialNumber:    serialNumber,
+			SatelliteId:     satelliteID,
+			Action:          pb.PieceAction_GET,
+			OrderExpiration: time.Now().Add(time.Hour),
+		},
+		Order: &pb.Order{
+			SerialNumber: serialNumber,
+			Amount:       1,
+		},
+	}))
+
+	var buffer bytes.Buffer
+	require.NoError(t, db.SupportBundle(ctx, &buffer))
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	require.NoError(t, err)
+
+	var names []string
+	var contents []byte
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		contents = append(contents, content...)
+	}
+	require.ElementsMatch(t, []string{
+		"schema.json", "disk_usage.json", "table_sizes.json",
+		"row_counts.json", "integrity.json", "errors.json",
+	}, names)
+
+	require.Contains(t, string(contents), satelliteID.String())
+	require.Contains(t, string(contents), "piece_id_hash")
+
+	// neither the piece nor the order can be recovered from the bundle
+	for _, secret := range [][]byte{
+		[]byte(pieceID.String()), []byte(hex.EncodeToString(pieceID.Bytes())), pieceID.Bytes(),
+		[]byte(serialNumber.String()), []byte(hex.EncodeToString(serialNumber.Bytes())), serialNumber.Bytes(),
+	} {
+		require.False(t, bytes.Contains(contents, secret), "bundle contains %x", secret)
+	}
+}
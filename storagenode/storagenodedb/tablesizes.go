@@ -0,0 +1,79 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+// TableSizes returns the approximate size in bytes of the data of every table, keyed by
+// the database filename and the table name joined with a slash, e.g. "bandwidth.db/bandwidth_usage".
+//
+// The size is the sum of the lengths of all the values stored in the table, so it leaves out
+// indexes, page overhead and free pages, which a vacuum would reclaim. It's meant to point at
+// the tables which grow the most rather than to account for the size of the database files.
+// Degraded databases are left out.
+func (db *DB) TableSizes(ctx context.Context) (_ map[string]int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	sizes := make(map[string]int64)
+	for dbName := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+
+		rawDB := db.rawDatabaseFromName(dbName)
+		tables, err := queryTableNames(ctx, rawDB, db.isPostgres())
+		if err != nil {
+			return nil, ErrDatabase.New("%s: %v", dbName, err)
+		}
+
+		for _, table := range tables {
+			size, err := tableSize(ctx, rawDB, table, db.isPostgres())
+			if err != nil {
+				return nil, ErrDatabase.New("%s: %s: %v", dbName, table, err)
+			}
+			sizes[db.filenameFromDBName(dbName)+"/"+table] = size
+		}
+	}
+	return sizes, nil
+}
+
+// tableSize returns the sum of the lengths of all the values stored in the table.
+// postgres sums the sizes of the rows, which include the row headers.
+func tableSize(ctx context.Context, db queryer, table string, postgres bool) (_ int64, err error) {
+	if postgres {
+		var size int64
+		err = db.QueryRowContext(ctx, `SELECT COALESCE(SUM(pg_column_size(t.*)), 0)::BIGINT FROM "`+table+`" AS t`).Scan(&size)
+		return size, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	var lengths []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return 0, err
+		}
+		lengths = append(lengths, `COALESCE(LENGTH("`+column+`"), 0)`)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(lengths) == 0 {
+		return 0, nil
+	}
+
+	var size int64
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(SUM(`+strings.Join(lengths, " + ")+`), 0) FROM "`+table+`"`).Scan(&size)
+	return size, err
+}
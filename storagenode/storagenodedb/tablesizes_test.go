@@ -0,0 +1,44 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/pb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestTableSizes(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Bandwidth().Add(ctx, testrand.NodeID(), pb.PieceAction_GET, 1024, now))
+	}
+
+	sizes, err := db.TableSizes(ctx)
+	require.NoError(t, err)
+
+	bandwidth, ok := sizes["bandwidth.db/bandwidth_usage"]
+	require.True(t, ok)
+	reputation, ok := sizes["reputation.db/reputation"]
+	require.True(t, ok)
+
+	require.Zero(t, reputation)
+	// every row holds at least the 32 bytes of the satellite id
+	require.True(t, bandwidth > 100*32, bandwidth)
+}
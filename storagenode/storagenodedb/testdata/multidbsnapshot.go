@@ -38,6 +38,12 @@ var States = MultiDBStates{
 		&v22,
 		&v23,
 		&v24,
+		&v25,
+		&v26,
+		&v27,
+		&v28,
+		&v29,
+		&v30,
 	},
 }
 
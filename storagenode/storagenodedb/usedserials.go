@@ -5,6 +5,8 @@ package storagenodedb
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"time"
 
 	"github.com/zeebo/errs"
@@ -19,8 +21,36 @@ var ErrUsedSerials = errs.Class("usedserialsdb error")
 // UsedSerialsDBName represents the database name.
 const UsedSerialsDBName = "used_serial"
 
+// usedSerialsDB stores the used serial numbers. With a salt, the HMAC-SHA256 of the satellite ID and
+// the serial number is stored in place of the serial number, so that a copy of the database doesn't
+// reveal which serial numbers were used. The hashes can't be turned back into serial numbers, so
+// IterateAll fails with piecestore.ErrUsedSerialsHashed then, the in-memory filter is seeded from
+// IterateAllKeys instead. Changing the salt loses the used serials stored before until they expire.
+//
+// The hashes are longer than the serial numbers, which tells apart the rows stored before the salt was set.
 type usedSerialsDB struct {
 	migratableDB
+
+	salt []byte
+}
+
+// hashed returns whether the serial numbers are stored hashed.
+func (db *usedSerialsDB) hashed() bool {
+	return len(db.salt) > 0
+}
+
+var _ piecestore.HashedUsedSerials = (*usedSerialsDB)(nil)
+
+// SerialKey returns the value stored for the serial number of the satellite.
+func (db *usedSerialsDB) SerialKey(satelliteID storj.NodeID, serialNumber storj.SerialNumber) []byte {
+	if !db.hashed() {
+		return serialNumber.Bytes()
+	}
+
+	mac := hmac.New(sha256.New, db.salt)
+	_, _ = mac.Write(satelliteID.Bytes())
+	_, _ = mac.Write(serialNumber.Bytes())
+	return mac.Sum(nil)
 }
 
 // Add adds a serial to the database.
@@ -30,11 +60,23 @@ func (db *usedSerialsDB) Add(ctx context.Context, satelliteID storj.NodeID, seri
 	_, err = db.Exec(`
 		INSERT INTO
 			used_serial_(satellite_id, serial_number, expiration)
-		VALUES(?, ?, ?)`, satelliteID, serialNumber, expiration.UTC())
+		VALUES(?, ?, ?)`, satelliteID, db.SerialKey(satelliteID, serialNumber), expiration.UTC())
 
 	return ErrUsedSerials.Wrap(err)
 }
 
+// Exists checks whether the serial has been used.
+func (db *usedSerialsDB) Exists(ctx context.Context, satelliteID storj.NodeID, serialNumber storj.SerialNumber) (exists bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM used_serial_ WHERE satellite_id = ? AND serial_number = ?
+		)`, satelliteID, db.SerialKey(satelliteID, serialNumber)).Scan(&exists)
+
+	return exists, ErrUsedSerials.Wrap(err)
+}
+
 // DeleteExpired deletes expired serial numbers
 func (db *usedSerialsDB) DeleteExpired(ctx context.Context, now time.Time) (err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -45,10 +87,19 @@ func (db *usedSerialsDB) DeleteExpired(ctx context.Context, now time.Time) (err
 
 // IterateAll iterates all serials.
 // Note, this will lock the database and should only be used during startup.
+// It fails with piecestore.ErrUsedSerialsHashed when the serial numbers are stored hashed.
 func (db *usedSerialsDB) IterateAll(ctx context.Context, fn piecestore.SerialNumberFn) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	rows, err := db.Query(`SELECT satellite_id, serial_number, expiration FROM used_serial_`)
+	if db.hashed() {
+		return piecestore.ErrUsedSerialsHashed.New("serial numbers can't be listed")
+	}
+
+	// hashes stored while a salt was set are skipped
+	rows, err := db.Query(`
+		SELECT satellite_id, serial_number, expiration
+		FROM used_serial_
+		WHERE length(serial_number) = ?`, len(storj.SerialNumber{}))
 	if err != nil {
 		return ErrUsedSerials.Wrap(err)
 	}
@@ -69,3 +120,97 @@ func (db *usedSerialsDB) IterateAll(ctx context.Context, fn piecestore.SerialNum
 
 	return ErrUsedSerials.Wrap(rows.Err())
 }
+
+// IterateAllKeys iterates the values stored for all serials, see SerialKey.
+// Note, this will lock the database and should only be used during startup.
+func (db *usedSerialsDB) IterateAllKeys(ctx context.Context, fn piecestore.SerialKeyFn) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := db.Query(`SELECT satellite_id, serial_number FROM used_serial_`)
+	if err != nil {
+		return ErrUsedSerials.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, ErrUsedSerials.Wrap(rows.Close())) }()
+
+	for rows.Next() {
+		var satelliteID storj.NodeID
+		var key []byte
+
+		err := rows.Scan(&satelliteID, &key)
+		if err != nil {
+			return ErrUsedSerials.Wrap(err)
+		}
+
+		fn(satelliteID, key)
+	}
+
+	return ErrUsedSerials.Wrap(rows.Err())
+}
+
+// hashExisting replaces the serial numbers stored as they are with their hashes and returns the number of
+// replaced serial numbers. It's a no-op when the serial numbers aren't hashed or were hashed before.
+func (db *usedSerialsDB) hashExisting(ctx context.Context) (hashed int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if !db.hashed() {
+		return 0, nil
+	}
+
+	type usedSerial struct {
+		satelliteID  storj.NodeID
+		serialNumber storj.SerialNumber
+		expiration   time.Time
+	}
+	var serials []usedSerial
+
+	rows, err := db.Query(`
+		SELECT satellite_id, serial_number, expiration
+		FROM used_serial_
+		WHERE length(serial_number) = ?`, len(storj.SerialNumber{}))
+	if err != nil {
+		return 0, ErrUsedSerials.Wrap(err)
+	}
+	err = func() (err error) {
+		defer func() { err = errs.Combine(err, rows.Close()) }()
+
+		for rows.Next() {
+			var serial usedSerial
+			if err := rows.Scan(&serial.satelliteID, &serial.serialNumber, &serial.expiration); err != nil {
+				return err
+			}
+			serials = append(serials, serial)
+		}
+		return rows.Err()
+	}()
+	if err != nil {
+		return 0, ErrUsedSerials.Wrap(err)
+	}
+	if len(serials) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, ErrUsedSerials.Wrap(err)
+	}
+	for _, serial := range serials {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO
+				used_serial_(satellite_id, serial_number, expiration)
+			VALUES(?, ?, ?)
+			ON CONFLICT DO NOTHING`, serial.satelliteID, db.SerialKey(serial.satelliteID, serial.serialNumber), serial.expiration.UTC())
+		if err != nil {
+			return 0, ErrUsedSerials.Wrap(errs.Combine(err, tx.Rollback()))
+		}
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM used_serial_
+			WHERE satellite_id = ? AND serial_number = ?`, serial.satelliteID, serial.serialNumber)
+		if err != nil {
+			return 0, ErrUsedSerials.Wrap(errs.Combine(err, tx.Rollback()))
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, ErrUsedSerials.Wrap(err)
+	}
+	return len(serials), nil
+}
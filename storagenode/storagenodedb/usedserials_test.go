@@ -0,0 +1,89 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/pkg/storj"
+	"storj.io/storj/storagenode/piecestore"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestUsedSerialsHashed(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	config := storagenodedbtest.Config(ctx.Dir("storage"))
+
+	db := storagenodedbtest.Open(t, ctx, log, config)
+
+	satelliteID := testrand.NodeID()
+	expiration := time.Now().Add(time.Hour)
+	before := testrand.SerialNumber()
+	require.NoError(t, db.UsedSerials().Add(ctx, satelliteID, before, expiration))
+	require.NoError(t, db.Close())
+
+	storedSerials := func(db *storagenodedb.DB) (serials [][]byte) {
+		rows, err := db.Query(ctx, storagenodedb.UsedSerialsDBName, `SELECT serial_number FROM used_serial_`)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, rows.Close()) }()
+
+		for rows.Next() {
+			var serial []byte
+			require.NoError(t, rows.Scan(&serial))
+			serials = append(serials, serial)
+		}
+		require.NoError(t, rows.Err())
+		return serials
+	}
+
+	// the serials stored before the salt was set are hashed by CreateTables
+	config.UsedSerialsSalt = []byte("salt")
+	db = storagenodedbtest.Open(t, ctx, log.Named("hashed"), config)
+	defer ctx.Check(db.Close)
+	require.NoError(t, db.CreateTables(ctx))
+
+	stored := storedSerials(db)
+	require.Len(t, stored, 1)
+	require.NotEqual(t, before.Bytes(), stored[0])
+
+	after := testrand.SerialNumber()
+	require.NoError(t, db.UsedSerials().Add(ctx, satelliteID, after, expiration))
+	require.Error(t, db.UsedSerials().Add(ctx, satelliteID, after, expiration))
+
+	for _, serial := range []storj.SerialNumber{before, after} {
+		exists, err := db.UsedSerials().Exists(ctx, satelliteID, serial)
+		require.NoError(t, err)
+		require.True(t, exists)
+
+		for _, stored := range storedSerials(db) {
+			require.NotEqual(t, serial.Bytes(), stored)
+		}
+	}
+
+	exists, err := db.UsedSerials().Exists(ctx, testrand.NodeID(), after)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	exists, err = db.UsedSerials().Exists(ctx, satelliteID, testrand.SerialNumber())
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// the hashes can't be listed
+	err = db.UsedSerials().IterateAll(ctx, func(storj.NodeID, storj.SerialNumber, time.Time) {})
+	require.True(t, piecestore.ErrUsedSerialsHashed.Has(err))
+
+	require.NoError(t, db.UsedSerials().DeleteExpired(ctx, expiration.Add(time.Minute)))
+	require.Empty(t, storedSerials(db))
+}
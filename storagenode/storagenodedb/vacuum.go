@@ -0,0 +1,89 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+)
+
+// Vacuum runs VACUUM on all the databases to return their free pages to the file system,
+// which piece churn leaves behind in orders.db and the piece expiration database in particular.
+//
+// VACUUM rewrites the whole database and blocks writes to it while running, so it's best run
+// when the node isn't busy. The total number of reclaimed bytes is reported to monkit.
+// In-memory and degraded databases are skipped, postgres databases are vacuumed by its autovacuum.
+func (db *DB) Vacuum(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if db.isPostgres() {
+		return nil
+	}
+
+	var reclaimed int64
+	var errlist errs.Group
+	for dbName, mdb := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+
+		inMemory, err := isInMemory(ctx, mdb.GetDB())
+		if err != nil {
+			errlist.Add(ErrDatabase.New("%s: %v", dbName, err))
+			continue
+		}
+		if inMemory {
+			continue
+		}
+
+		before, err := databaseSize(ctx, mdb.GetDB())
+		if err != nil {
+			errlist.Add(ErrDatabase.New("%s: %v", dbName, err))
+			continue
+		}
+
+		if _, err := mdb.GetDB().ExecContext(ctx, "VACUUM"); err != nil {
+			errlist.Add(ErrDatabase.New("%s: %v", dbName, err))
+			continue
+		}
+
+		after, err := databaseSize(ctx, mdb.GetDB())
+		if err != nil {
+			errlist.Add(ErrDatabase.New("%s: %v", dbName, err))
+			continue
+		}
+
+		db.log.Debug("vacuumed database", zap.String("database", dbName), zap.Int64("reclaimed bytes", before-after))
+		reclaimed += before - after
+	}
+
+	mon.IntVal("vacuum_reclaimed_bytes").Observe(reclaimed)
+	return errlist.Err()
+}
+
+// databaseSize returns the size of the main database of sqlDB in bytes, based on its page count.
+// The size of a postgres database is the size of the tables in its schema, with their indexes.
+func databaseSize(ctx context.Context, sqlDB *sql.DB) (int64, error) {
+	if isPostgresDB(sqlDB) {
+		var size int64
+		err := sqlDB.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(pg_total_relation_size(c.oid)), 0)::BIGINT
+			FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = current_schema() AND c.relkind = 'r'
+		`).Scan(&size)
+		return size, err
+	}
+
+	var pageCount, pageSize int64
+	if err := sqlDB.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	if err := sqlDB.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pageCount * pageSize, nil
+}
@@ -0,0 +1,45 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/internal/testrand"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestVacuum(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	rawDB := db.RawDatabases()[storagenodedb.UsedSerialsDBName].GetDB()
+	pageCount := func() int64 {
+		var count int64
+		require.NoError(t, rawDB.QueryRow(`PRAGMA page_count`).Scan(&count))
+		return count
+	}
+
+	satelliteID := testrand.NodeID()
+	expiration := time.Now().Add(-time.Hour)
+	for i := 0; i < 2000; i++ {
+		require.NoError(t, db.UsedSerials().Add(ctx, satelliteID, testrand.SerialNumber(), expiration))
+	}
+	require.NoError(t, db.UsedSerials().DeleteExpired(ctx, time.Now()))
+
+	before := pageCount()
+	require.NoError(t, db.Vacuum(ctx))
+	require.True(t, pageCount() < before)
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// CompactVersionHistory trims the versions table of every database, which gets a row for every
+// applied migration step, to the latest rows. As the migrations only look at the latest version,
+// the schema versions stay the same. Degraded databases are skipped.
+func (db *DB) CompactVersionHistory(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	tail := db.versionHistoryTail
+	if tail < 1 {
+		tail = 1
+	}
+
+	// the versions table has no primary key, the rows are told apart by their physical location in postgres
+	rowID := "rowid"
+	if db.isPostgres() {
+		rowID = "ctid"
+	}
+
+	for dbName := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+
+		exists, err := db.hasVersionTable(ctx, dbName)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+
+		result, err := db.rawDatabaseFromName(dbName).ExecContext(ctx, `
+			DELETE FROM `+VersionTable+`
+			WHERE `+rowID+` NOT IN (
+				SELECT `+rowID+` FROM `+VersionTable+` ORDER BY version DESC LIMIT ?
+			)`, tail)
+		if err != nil {
+			return ErrDatabase.New("%s: %v", dbName, err)
+		}
+
+		if deleted, err := result.RowsAffected(); err == nil && deleted > 0 {
+			db.log.Debug("compacted version history", zap.String("database", dbName), zap.Int64("deleted", deleted))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestCompactVersionHistory(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	config := storagenodedbtest.Config(ctx.Dir("storage"))
+	config.VersionHistoryTail = 2
+	db, err := storagenodedb.New(log, config)
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	// compacting databases without versions does nothing
+	require.NoError(t, db.CompactVersionHistory(ctx))
+
+	require.NoError(t, db.CreateTables(ctx))
+
+	countVersions := func(dbName string) (count int) {
+		rawDB := db.RawDatabases()[dbName].GetDB()
+
+		// databases split off the info database without own migrations have no versions table
+		var tables int
+		err := rawDB.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = ?`, storagenodedb.VersionTable).Scan(&tables)
+		require.NoError(t, err)
+		if tables == 0 {
+			return 0
+		}
+
+		err = rawDB.QueryRow(`SELECT count(*) FROM ` + storagenodedb.VersionTable).Scan(&count)
+		require.NoError(t, err)
+		return count
+	}
+	require.True(t, countVersions(storagenodedb.DeprecatedInfoDBName) > 2)
+
+	before, err := db.SchemaReport(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, db.CompactVersionHistory(ctx))
+
+	after, err := db.SchemaReport(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	require.Equal(t, 2, countVersions(storagenodedb.DeprecatedInfoDBName))
+	for dbName := range db.RawDatabases() {
+		require.True(t, countVersions(dbName) <= 2, dbName)
+	}
+
+	// the migration still considers the databases up to date
+	require.NoError(t, db.CreateTables(ctx))
+
+	after, err = db.SchemaReport(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+}
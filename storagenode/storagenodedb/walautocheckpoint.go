@@ -0,0 +1,47 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// walAutocheckpointDrivers are the names of the registered sqlite3 drivers, keyed by their wal_autocheckpoint.
+var walAutocheckpointDrivers struct {
+	sync.Mutex
+	names map[int]string
+}
+
+// sqliteDriverName returns the name of a sqlite3 driver which sets the wal_autocheckpoint of every connection
+// to pages, registering it when needed. The pragma is per connection, so it can't be set once on the sql.DB.
+// Zero keeps the sqlite default.
+func sqliteDriverName(pages int) string {
+	if pages == 0 {
+		return "sqlite3"
+	}
+
+	walAutocheckpointDrivers.Lock()
+	defer walAutocheckpointDrivers.Unlock()
+
+	if name, ok := walAutocheckpointDrivers.names[pages]; ok {
+		return name
+	}
+	if walAutocheckpointDrivers.names == nil {
+		walAutocheckpointDrivers.names = make(map[int]string)
+	}
+
+	name := fmt.Sprintf("sqlite3_wal_autocheckpoint_%d", pages)
+	sql.Register(name, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec(fmt.Sprintf("PRAGMA wal_autocheckpoint = %d", pages), nil)
+			return err
+		},
+	})
+	walAutocheckpointDrivers.names[pages] = name
+	return name
+}
@@ -0,0 +1,57 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestWALAutocheckpoint(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	open := func(dir string, pages int) (*storagenodedb.DB, error) {
+		config := storagenodedbtest.Config(ctx.Dir(dir))
+		config.WALAutocheckpoint = pages
+		return storagenodedb.New(log, config)
+	}
+
+	checkpoint := func(db *storagenodedb.DB) map[string]int {
+		pages := map[string]int{}
+		for dbName, rawDB := range db.RawDatabases() {
+			var value int
+			require.NoError(t, rawDB.GetDB().QueryRow(`PRAGMA wal_autocheckpoint`).Scan(&value))
+			pages[dbName] = value
+		}
+		return pages
+	}
+
+	db, err := open("configured", 500)
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	for dbName, pages := range checkpoint(db) {
+		require.Equal(t, 500, pages, dbName)
+	}
+
+	defaultDB, err := open("default", 0)
+	require.NoError(t, err)
+	defer ctx.Check(defaultDB.Close)
+
+	for dbName, pages := range checkpoint(defaultDB) {
+		require.Equal(t, 1000, pages, dbName)
+	}
+
+	_, err = open("negative", -1)
+	require.Error(t, err)
+}
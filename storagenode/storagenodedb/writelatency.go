@@ -0,0 +1,85 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WriteLatencies measures how long a trivial write takes on every database, keyed by the database name.
+//
+// The probe rewrites the user version in the database header with its current value, so neither the
+// schema nor the data change and prepared statements stay valid, but the commit still has to reach the
+// disk. A slowly failing disk shows up as rising latencies before it fails outright.
+// In-memory and degraded databases are skipped, databases which fail the probe are logged and left out.
+func (db *DB) WriteLatencies(ctx context.Context) map[string]time.Duration {
+	defer mon.Task()(&ctx)(nil)
+
+	latencies := make(map[string]time.Duration)
+	for dbName, mdb := range db.sqlDatabases {
+		if _, degraded := db.degraded[dbName]; degraded {
+			continue
+		}
+
+		inMemory, err := isInMemory(ctx, mdb.GetDB())
+		if err != nil {
+			db.log.Error("failed to probe database write latency", zap.String("database", dbName), zap.Error(err))
+			continue
+		}
+		if inMemory {
+			continue
+		}
+
+		latency, err := probeWrite(ctx, mdb.GetDB())
+		if err != nil {
+			db.log.Error("failed to probe database write latency", zap.String("database", dbName), zap.Error(err))
+			continue
+		}
+		latencies[dbName] = latency
+	}
+	return latencies
+}
+
+// probeWrite returns how long it takes to commit a rewrite of the user version of the database.
+// postgres has no user version, so the probe commits a transaction with a transaction id instead,
+// which is written to the write-ahead log without changing any table.
+func probeWrite(ctx context.Context, sqlDB *sql.DB) (_ time.Duration, err error) {
+	start := time.Now()
+
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if isPostgresDB(sqlDB) {
+		var txid int64
+		if err = tx.QueryRowContext(ctx, `SELECT txid_current()`).Scan(&txid); err != nil {
+			return 0, err
+		}
+	} else {
+		var version int
+		if err = tx.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+			return 0, err
+		}
+		// pragmas don't take parameters
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, version)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
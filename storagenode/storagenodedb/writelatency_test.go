@@ -0,0 +1,44 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package storagenodedb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/storagenodedb"
+	"storj.io/storj/storagenode/storagenodedb/storagenodedbtest"
+)
+
+func TestWriteLatencies(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	db := storagenodedbtest.Open(t, ctx, log, storagenodedbtest.Config(ctx.Dir("storage")))
+	defer ctx.Check(db.Close)
+
+	rawDBs := db.RawDatabases()
+	schemaVersion := func(dbName string) (version int) {
+		err := rawDBs[dbName].GetDB().QueryRow(`PRAGMA schema_version`).Scan(&version)
+		require.NoError(t, err)
+		return version
+	}
+	before := schemaVersion(storagenodedb.OrdersDBName)
+
+	latencies := db.WriteLatencies(ctx)
+
+	require.Len(t, latencies, len(rawDBs))
+	for dbName := range rawDBs {
+		require.Contains(t, latencies, dbName)
+		require.True(t, latencies[dbName] > 0, dbName)
+	}
+
+	// the probe doesn't change the schema, which would invalidate the prepared statements
+	require.Equal(t, before, schemaVersion(storagenodedb.OrdersDBName))
+}
@@ -26,6 +26,8 @@ type DB interface {
 	Summary(ctx context.Context, from, to time.Time) (float64, error)
 	// SatelliteSummary returns aggregated storage usage for a particular satellite.
 	SatelliteSummary(ctx context.Context, satelliteID storj.NodeID, from, to time.Time) (float64, error)
+	// DeleteBefore deletes the storage usage stamps with an interval start before cutoff and returns the number of deleted stamps.
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int, error)
 }
 
 // Stamp is storage usage stamp for satellite from interval start till next interval.
@@ -33,4 +35,7 @@ type Stamp struct {
 	SatelliteID   storj.NodeID `json:"-"`
 	AtRestTotal   float64      `json:"atRestTotal"`
 	IntervalStart time.Time    `json:"intervalStart"`
+	// IntervalEnd is the end of the interval at rest total was tallied for,
+	// it's zero for stamps stored before the interval end was tracked.
+	IntervalEnd time.Time `json:"intervalEnd"`
 }
@@ -136,6 +136,75 @@ func TestEmptyStorageUsage(t *testing.T) {
 	})
 }
 
+func TestStorageUsageIntervalEnd(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		storageUsageDB := db.StorageUsage()
+
+		satelliteID := testrand.NodeID()
+		day := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+
+		err := storageUsageDB.Store(ctx, []storageusage.Stamp{
+			// stored before the interval end was tracked
+			{SatelliteID: satelliteID, AtRestTotal: 1, IntervalStart: day},
+			{SatelliteID: satelliteID, AtRestTotal: 2, IntervalStart: day.Add(24 * time.Hour), IntervalEnd: day.Add(48 * time.Hour)},
+		})
+		assert.NoError(t, err)
+
+		res, err := storageUsageDB.GetDaily(ctx, satelliteID, day, day.Add(48*time.Hour))
+		assert.NoError(t, err)
+		if assert.Len(t, res, 2) {
+			assert.True(t, res[0].IntervalEnd.IsZero())
+			assert.Equal(t, day.Add(48*time.Hour), res[1].IntervalEnd.UTC())
+		}
+
+		res, err = storageUsageDB.GetDailyTotal(ctx, day, day.Add(48*time.Hour))
+		assert.NoError(t, err)
+		if assert.Len(t, res, 2) {
+			assert.True(t, res[0].IntervalEnd.IsZero())
+			assert.Equal(t, day.Add(48*time.Hour), res[1].IntervalEnd.UTC())
+		}
+	})
+}
+
+func TestStorageUsageDailyInterval(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		storageUsageDB := db.StorageUsage()
+
+		first, second := testrand.NodeID(), testrand.NodeID()
+		day := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+
+		// several tallies of the same day
+		err := storageUsageDB.Store(ctx, []storageusage.Stamp{
+			{SatelliteID: first, AtRestTotal: 1, IntervalStart: day.Add(6 * time.Hour), IntervalEnd: day.Add(12 * time.Hour)},
+			{SatelliteID: first, AtRestTotal: 2, IntervalStart: day.Add(time.Hour), IntervalEnd: day.Add(6 * time.Hour)},
+			{SatelliteID: second, AtRestTotal: 4, IntervalStart: day.Add(12 * time.Hour), IntervalEnd: day.Add(18 * time.Hour)},
+		})
+		assert.NoError(t, err)
+
+		res, err := storageUsageDB.GetDaily(ctx, first, day, day.Add(24*time.Hour))
+		assert.NoError(t, err)
+		if assert.Len(t, res, 1) {
+			assert.Equal(t, 3.0, res[0].AtRestTotal)
+			assert.Equal(t, day.Add(time.Hour), res[0].IntervalStart.UTC())
+			assert.Equal(t, day.Add(12*time.Hour), res[0].IntervalEnd.UTC())
+		}
+
+		res, err = storageUsageDB.GetDailyTotal(ctx, day, day.Add(24*time.Hour))
+		assert.NoError(t, err)
+		if assert.Len(t, res, 1) {
+			assert.Equal(t, 7.0, res[0].AtRestTotal)
+			assert.Equal(t, day.Add(time.Hour), res[0].IntervalStart.UTC())
+			assert.Equal(t, day.Add(18*time.Hour), res[0].IntervalEnd.UTC())
+		}
+	})
+}
+
 // makeStorageUsageStamps creates storage usage stamps and expected summaries for provided satellites.
 // Creates one entry per day for 30 days with last date as beginning of provided endDate.
 func makeStorageUsageStamps(satellites []storj.NodeID, days int, endDate time.Time) ([]storageusage.Stamp, map[storj.NodeID]float64) {
@@ -159,3 +228,45 @@ func makeStorageUsageStamps(satellites []storj.NodeID, days int, endDate time.Ti
 
 	return stamps, summary
 }
+
+func TestStorageUsageDeleteBefore(t *testing.T) {
+	storagenodedbtest.Run(t, func(t *testing.T, db storagenode.DB) {
+		ctx := testcontext.New(t)
+		defer ctx.Cleanup()
+
+		storageUsageDB := db.StorageUsage()
+
+		cutoff := time.Date(2019, 9, 1, 0, 0, 0, 0, time.UTC)
+		satelliteID := testrand.NodeID()
+
+		var stamps []storageusage.Stamp
+		for _, intervalStart := range []time.Time{
+			cutoff.Add(-48 * time.Hour),
+			cutoff.Add(-time.Second),
+			cutoff,
+			cutoff.Add(24 * time.Hour),
+		} {
+			stamps = append(stamps, storageusage.Stamp{
+				SatelliteID:   satelliteID,
+				AtRestTotal:   1,
+				IntervalStart: intervalStart,
+			})
+		}
+		assert.NoError(t, storageUsageDB.Store(ctx, stamps))
+
+		// the stamp at the cutoff is kept
+		deleted, err := storageUsageDB.DeleteBefore(ctx, cutoff)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+
+		res, err := storageUsageDB.GetDaily(ctx, satelliteID, time.Time{}, cutoff.Add(48*time.Hour))
+		assert.NoError(t, err)
+		if assert.Len(t, res, 2) {
+			assert.True(t, res[0].IntervalStart.Equal(cutoff))
+		}
+
+		deleted, err = storageUsageDB.DeleteBefore(ctx, cutoff)
+		assert.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+}
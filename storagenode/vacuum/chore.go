@@ -0,0 +1,104 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package vacuum implements periodic vacuuming of the storage node databases,
+// so that the space freed by deleted rows is returned to the file system.
+package vacuum
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	monkit "gopkg.in/spacemonkeygo/monkit.v2"
+
+	"storj.io/storj/internal/sync2"
+	"storj.io/storj/storagenode/maintenance"
+)
+
+var mon = monkit.Package()
+
+// Config defines parameters for the vacuum chore.
+type Config struct {
+	Interval        time.Duration `help:"how frequently VACUUM is run on the storage node databases, 0 disables it" default:"168h0m0s"`
+	MaxLiveRequests int           `help:"the vacuum is skipped when more piecestore requests than this are being handled" default:"0"`
+}
+
+// DB is the database that is vacuumed.
+type DB interface {
+	// Vacuum runs VACUUM on all the databases.
+	Vacuum(ctx context.Context) error
+}
+
+// Activity tells how busy the storage node is.
+type Activity interface {
+	// LiveRequests returns the number of requests which are currently being handled.
+	LiveRequests() int
+}
+
+// Chore periodically runs VACUUM on the storage node databases when the node isn't busy,
+// as VACUUM blocks the writes to the database while it's running. The first VACUUM is run
+// an interval after the start.
+//
+// architecture: Chore
+type Chore struct {
+	log         *zap.Logger
+	db          DB
+	maintenance maintenance.Status
+	activity    Activity
+	config      Config
+
+	Loop sync2.Cycle
+}
+
+// NewChore creates a new vacuum chore.
+func NewChore(log *zap.Logger, db DB, maintenance maintenance.Status, activity Activity, config Config) *Chore {
+	return &Chore{
+		log:         log,
+		db:          db,
+		maintenance: maintenance,
+		activity:    activity,
+		config:      config,
+		Loop:        *sync2.NewCycle(config.Interval),
+	}
+}
+
+// Run runs the vacuum chore.
+func (chore *Chore) Run(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if chore.config.Interval <= 0 {
+		chore.log.Debug("vacuum chore is disabled")
+		return nil
+	}
+
+	// the loop runs right away, which would vacuum the databases on every restart
+	started := false
+	return chore.Loop.Run(ctx, func(ctx context.Context) error {
+		if !started {
+			started = true
+			return nil
+		}
+		if chore.maintenance.Paused() {
+			chore.log.Debug("maintenance mode enabled, deferring vacuum")
+			return nil
+		}
+		if live := chore.activity.LiveRequests(); live > chore.config.MaxLiveRequests {
+			chore.log.Debug("node is busy, skipping vacuum", zap.Int("live requests", live))
+			mon.Meter("vacuum_skipped_busy").Mark(1)
+			return nil
+		}
+
+		err := chore.db.Vacuum(ctx)
+		if err != nil {
+			chore.log.Error("error during vacuuming databases: ", zap.Error(err))
+		}
+		return nil
+	})
+}
+
+// Close stops the vacuum chore.
+func (chore *Chore) Close() (err error) {
+	chore.Loop.Close()
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2019 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package vacuum_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/storj/internal/testcontext"
+	"storj.io/storj/storagenode/maintenance"
+	"storj.io/storj/storagenode/vacuum"
+)
+
+type countingDB struct {
+	calls int64
+}
+
+func (db *countingDB) Vacuum(ctx context.Context) error {
+	atomic.AddInt64(&db.calls, 1)
+	return nil
+}
+
+type activity struct {
+	live int64
+}
+
+func (activity *activity) LiveRequests() int { return int(atomic.LoadInt64(&activity.live)) }
+
+func TestChore(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	db := &countingDB{}
+	mode := &maintenance.Mode{}
+	busy := &activity{}
+	chore := vacuum.NewChore(zaptest.NewLogger(t), db, mode, busy, vacuum.Config{Interval: time.Hour, MaxLiveRequests: 1})
+	defer ctx.Check(chore.Close)
+
+	ctx.Go(func() error {
+		return chore.Run(ctx)
+	})
+
+	// the run at the start doesn't vacuum, only the triggered one
+	chore.Loop.TriggerWait()
+	require.EqualValues(t, 1, atomic.LoadInt64(&db.calls))
+
+	// a busy node is not vacuumed
+	atomic.StoreInt64(&busy.live, 2)
+	chore.Loop.TriggerWait()
+	require.EqualValues(t, 1, atomic.LoadInt64(&db.calls))
+
+	// neither is a paused one
+	atomic.StoreInt64(&busy.live, 1)
+	mode.Pause()
+	chore.Loop.TriggerWait()
+	require.EqualValues(t, 1, atomic.LoadInt64(&db.calls))
+
+	mode.Resume()
+	chore.Loop.TriggerWait()
+	require.EqualValues(t, 2, atomic.LoadInt64(&db.calls))
+}
+
+func TestChoreDisabled(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	db := &countingDB{}
+	chore := vacuum.NewChore(zaptest.NewLogger(t), db, &maintenance.Mode{}, &activity{}, vacuum.Config{Interval: 0})
+	defer ctx.Check(chore.Close)
+
+	require.NoError(t, chore.Run(ctx))
+	require.Zero(t, atomic.LoadInt64(&db.calls))
+}
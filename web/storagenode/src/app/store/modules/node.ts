@@ -42,7 +42,8 @@ export const node = {
             status: StatusOffline,
             version: '',
             wallet: '',
-            isLastVersion: false
+            isLastVersion: false,
+            deletionFailedPieces: 0,
         },
         utilization: {
             bandwidth: {
@@ -74,6 +75,7 @@ export const node = {
             state.info.isLastVersion = nodeInfo.isUpToDate;
             state.info.version = nodeInfo.version;
             state.info.wallet = nodeInfo.wallet;
+            state.info.deletionFailedPieces = nodeInfo.deletionFailedPieces;
             state.utilization.diskSpace.used = nodeInfo.diskSpace.used;
             state.utilization.diskSpace.remaining = nodeInfo.diskSpace.available - nodeInfo.diskSpace.used;
             state.utilization.diskSpace.available = nodeInfo.diskSpace.available;
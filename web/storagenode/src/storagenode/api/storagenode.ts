@@ -45,7 +45,7 @@ export class SNOApi {
         const bandwidth: BandwidthInfo = new BandwidthInfo(json.bandwidth.used, json.bandwidth.available);
 
         return new Dashboard(json.nodeID, json.wallet, satellites, diskSpace, bandwidth,
-                                        new Date(json.lastPinged), version, json.upToDate);
+                                        new Date(json.lastPinged), version, json.upToDate, json.deletionFailedPieces);
     }
 
     /**
@@ -13,7 +13,8 @@ export class Dashboard {
         public bandwidth: BandwidthInfo,
         public lastPinged: Date,
         public version: Version,
-        public isUpToDate: boolean) {}
+        public isUpToDate: boolean,
+        public deletionFailedPieces: number) {}
 }
 
 /**